@@ -0,0 +1,306 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"go.uber.org/zap"
+
+	"github.com/kitbuilder587/fintech-bot/internal/agent"
+	"github.com/kitbuilder587/fintech-bot/internal/cache/memory"
+	"github.com/kitbuilder587/fintech-bot/internal/cassette"
+	"github.com/kitbuilder587/fintech-bot/internal/config"
+	"github.com/kitbuilder587/fintech-bot/internal/domain"
+	"github.com/kitbuilder587/fintech-bot/internal/feeds"
+	"github.com/kitbuilder587/fintech-bot/internal/fetch"
+	"github.com/kitbuilder587/fintech-bot/internal/llm"
+	"github.com/kitbuilder587/fintech-bot/internal/metrics"
+	"github.com/kitbuilder587/fintech-bot/internal/ops"
+	"github.com/kitbuilder587/fintech-bot/internal/prompt"
+	"github.com/kitbuilder587/fintech-bot/internal/repository/postgres"
+	"github.com/kitbuilder587/fintech-bot/internal/search"
+	"github.com/kitbuilder587/fintech-bot/internal/service"
+	"github.com/kitbuilder587/fintech-bot/internal/telegram"
+)
+
+// app держит все зависимости бота, чтобы закрыть их в правильном порядке
+type app struct {
+	cfg    *config.Config
+	logger *zap.Logger
+
+	db       *postgres.DB
+	cache    *memory.Cache
+	prompts  *prompt.Registry
+	querySvc service.QueryService
+	bot      *telegram.Bot
+	ops      *ops.Server
+	ingester *feeds.Ingester // nil, если сбор лент выключен
+}
+
+func newApp(ctx context.Context, cfg *config.Config, logger *zap.Logger) (*app, error) {
+	if cfg.Cache.Type != "memory" {
+		return nil, fmt.Errorf("unsupported cache type: %s", cfg.Cache.Type)
+	}
+
+	// кривые шаблоны должны ронять старт, а не первый запрос
+	prompts, err := prompt.New(prompt.Config{
+		Dir:      cfg.Prompts.Dir,
+		Version:  cfg.Prompts.Version,
+		Language: cfg.Prompts.Language,
+	}, logger)
+	if err != nil {
+		return nil, fmt.Errorf("load prompts: %w", err)
+	}
+
+	m := metrics.New()
+	// общий кеш для результатов поиска и ответов LLM, ключи не пересекаются
+	cache := memory.New()
+
+	llmClient, err := newLLMClient(cfg, logger, m, cache)
+	if err != nil {
+		cache.Stop()
+		return nil, err
+	}
+	routed, err := newStageRouter(llmClient, cfg, logger, m, cache)
+	if err != nil {
+		cache.Stop()
+		return nil, err
+	}
+
+	db, err := postgres.New(ctx, cfg.Database.URL)
+	if err != nil {
+		cache.Stop()
+		return nil, fmt.Errorf("connect database: %w", err)
+	}
+
+	if cfg.Database.AutoMigrate {
+		if err := migrateUp(ctx, db, logger); err != nil {
+			cache.Stop()
+			db.Close()
+			return nil, err
+		}
+	}
+
+	userRepo := postgres.NewUserRepo(db)
+	sourceRepo := postgres.NewSourceRepo(db)
+	worldModelRepo := postgres.NewWorldModelRepo(db)
+	usageSvc := service.NewUsageService(postgres.NewUsageRepo(db), logger, m)
+	articleRepo := postgres.NewArticleRepo(db)
+
+	searchClient, err := newSearchClient(cfg, articleRepo, logger, m)
+	if err != nil {
+		cache.Stop()
+		db.Close()
+		return nil, err
+	}
+	deepSearchClient, err := newDeepSearchClient(cfg, articleRepo, logger, m)
+	if err != nil {
+		cache.Stop()
+		db.Close()
+		return nil, err
+	}
+
+	// пробы /ready ходят мимо кассеты, пайплайн - через нее
+	var completer llm.Client = routed
+	var searcher search.SearchClient = searchClient
+	deepSearcher := deepSearchClient
+	if cfg.Cassette.Dir != "" {
+		tape, err := cassette.Open(cfg.Cassette.Dir, cassette.Mode(cfg.Cassette.Mode))
+		if err != nil {
+			cache.Stop()
+			db.Close()
+			return nil, err
+		}
+		logger.Warn("LLM and search go through cassette",
+			zap.String("dir", cfg.Cassette.Dir),
+			zap.String("mode", cfg.Cassette.Mode),
+		)
+		completer = tape.LLM(routed)
+		searcher = tape.Search(searchClient)
+		if deepSearchClient != nil {
+			deepSearcher = tape.Search(deepSearchClient)
+		}
+	}
+
+	// в кассету страницы не пишутся, а воспроизведение не должно ходить в сеть
+	var fetcher service.ContentFetcher
+	if cfg.Fetch.Enabled && cfg.Cassette.Dir == "" {
+		fetcher = fetch.New(cache, fetch.Config{
+			Concurrency: cfg.Fetch.Concurrency,
+			HostDelay:   cfg.Fetch.HostDelay,
+			Timeout:     cfg.Fetch.Timeout,
+			MaxChars:    cfg.Fetch.MaxChars,
+			CacheTTL:    cfg.Fetch.CacheTTL,
+		}, logger)
+	}
+
+	criticConfig := domain.CriticConfig{MaxRetries: 2}
+	critic := service.NewCriticService(completer, logger, criticConfig)
+	worldModel := service.NewWorldModelService(worldModelRepo, completer, logger).
+		WithBlocklist(sourceRepo)
+
+	coordinator := agent.NewCoordinator(agent.NewAllAgents(completer, logger), completer, logger).
+		WithSearch(searcher)
+
+	querySvc := service.NewQueryService(service.QueryServiceDeps{
+		Sources: sourceRepo,
+		LLM:     completer,
+		Search:  searcher,
+		Cache:   cache,
+		Logger:  logger,
+		Metrics: m,
+		Config: service.QueryConfig{
+			CacheTTL:        cfg.Cache.TTL,
+			SearchTimeout:   cfg.Timeouts.Source,
+			FetchTopK:       cfg.Fetch.TopK,
+			FetchedChars:    cfg.Fetch.MaxChars,
+			RecencyHalfLife: cfg.Search.RecencyHalfLife,
+		},
+		Critic:       critic,
+		CriticConfig: criticConfig,
+		WorldModel:   worldModel,
+		Coordinator:  service.NewCoordinatorAdapter(coordinator),
+		Usage:        usageSvc,
+		Prompts:      prompts,
+		DeepSearch:   deepSearcher,
+		Fetcher:      fetcher,
+	})
+
+	telegram.DefaultStrategy = func() domain.Strategy {
+		return domain.StrategyByType(domain.StrategyType(cfg.DefaultStrategy))
+	}
+
+	bot, err := telegram.New(telegram.BotConfig{
+		Token:             cfg.Telegram.Token,
+		Debug:             cfg.Log.Level == "debug",
+		RequestsPerMinute: cfg.RateLimit.RequestsPerMinute,
+	},
+		service.NewUserService(userRepo, logger),
+		service.NewSourceService(sourceRepo, logger),
+		querySvc,
+		usageSvc,
+		service.NewConversationService(postgres.NewConversationRepo(db), logger),
+		logger,
+		m,
+	)
+	if err != nil {
+		cache.Stop()
+		db.Close()
+		return nil, err
+	}
+
+	opsServer := ops.New(ops.Config{
+		Addr:        cfg.Ops.Addr,
+		EnablePprof: cfg.Ops.EnablePprof,
+	}, logger)
+	opsServer.AddCheck("database", db.Ping)
+	opsServer.AddCheck("llm_providers", llmClient.Ready)
+	opsServer.AddCheck("telegram", func(ctx context.Context) error {
+		if !bot.IsRunning() {
+			return errors.New("poller is not running")
+		}
+		return nil
+	})
+	if cfg.Ops.ProbeLLM {
+		opsServer.AddCheck("llm", ops.CachedCheck(ops.LLMProbe(llmClient), cfg.Ops.ProbeTTL))
+	}
+	if cfg.Ops.ProbeSearch {
+		opsServer.AddCheck("search", ops.CachedCheck(ops.SearchProbe(searchClient), cfg.Ops.ProbeTTL))
+	}
+
+	var ingester *feeds.Ingester
+	if cfg.Feeds.Enabled {
+		ingester = feeds.New(sourceRepo, articleRepo, feeds.Config{
+			Interval:        cfg.Feeds.Interval,
+			MaxItemsPerFeed: cfg.Feeds.MaxItemsPerFeed,
+			FullText:        cfg.Feeds.FullText,
+		}, logger)
+	}
+
+	logger.Info("application initialized",
+		zap.Strings("llm_providers", cfg.LLM.Providers),
+		zap.String("default_strategy", cfg.DefaultStrategy),
+		zap.String("prompt_version", prompts.Current().Version()),
+	)
+
+	return &app{
+		cfg:      cfg,
+		logger:   logger,
+		db:       db,
+		cache:    cache,
+		prompts:  prompts,
+		querySvc: querySvc,
+		bot:      bot,
+		ops:      opsServer,
+		ingester: ingester,
+	}, nil
+}
+
+// run блокируется до отмены ctx, затем гасит компоненты по порядку
+func (a *app) run(ctx context.Context) error {
+	if err := a.ops.Start(); err != nil {
+		a.shutdown()
+		return fmt.Errorf("start ops server: %w", err)
+	}
+
+	// подписка до старта бота, иначе ранний SIGHUP завершит процесс
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	go a.reloadPrompts(ctx, hup)
+	go a.prompts.Watch(ctx, a.cfg.Prompts.ReloadInterval)
+	if a.ingester != nil {
+		go a.ingester.Run(ctx)
+	}
+
+	// Run сам останавливает получение апдейтов и дожидается хендлеров
+	err := a.bot.Run(ctx)
+	if err != nil && !errors.Is(err, context.Canceled) {
+		a.logger.Error("bot stopped with error", zap.Error(err))
+	}
+
+	a.shutdown()
+	return nil
+}
+
+// reloadPrompts перечитывает шаблоны промптов на каждый SIGHUP
+func (a *app) reloadPrompts(ctx context.Context, hup <-chan os.Signal) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			if err := a.prompts.Reload(); err != nil {
+				a.logger.Error("prompts reload failed, keeping current version",
+					zap.String("version", a.prompts.Current().Version()),
+					zap.Error(err),
+				)
+			}
+		}
+	}
+}
+
+func (a *app) shutdown() {
+	ctx, cancel := context.WithTimeout(context.Background(), a.cfg.Timeouts.Shutdown)
+	defer cancel()
+
+	if err := a.ops.Shutdown(ctx); err != nil {
+		a.logger.Warn("ops server shutdown failed", zap.Error(err))
+	}
+
+	if w, ok := a.querySvc.(service.BackgroundWaiter); ok {
+		if err := w.WaitBackground(ctx); err != nil {
+			a.logger.Warn("background tasks did not finish in time", zap.Error(err))
+		}
+	}
+
+	a.cache.Stop()
+	a.bot.Close()
+	a.db.Close()
+
+	a.logger.Info("shutdown complete")
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/kitbuilder587/fintech-bot/internal/cache"
+	"github.com/kitbuilder587/fintech-bot/internal/config"
+	"github.com/kitbuilder587/fintech-bot/internal/llm"
+	"github.com/kitbuilder587/fintech-bot/internal/llm/failover"
+	"github.com/kitbuilder587/fintech-bot/internal/llm/gigachat"
+	"github.com/kitbuilder587/fintech-bot/internal/llm/mock"
+	"github.com/kitbuilder587/fintech-bot/internal/llm/openaicompat"
+	"github.com/kitbuilder587/fintech-bot/internal/llm/openrouter"
+	"github.com/kitbuilder587/fintech-bot/internal/metrics"
+	"github.com/kitbuilder587/fintech-bot/internal/retry"
+)
+
+// newLLMClient собирает цепочку провайдеров из LLM_PROVIDERS, каждый со своими метриками
+// и кешем ответов. Кеш снаружи метрик, чтобы попадания не искажали задержки провайдера
+func newLLMClient(cfg *config.Config, logger *zap.Logger, m *metrics.Metrics, responses cache.Cache) (*failover.Chain, error) {
+	providers := make([]failover.Provider, 0, len(cfg.LLM.Providers))
+	seen := make(map[string]bool, len(cfg.LLM.Providers))
+	for _, name := range cfg.LLM.Providers {
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate LLM provider: %s", name)
+		}
+		seen[name] = true
+
+		p, err := newProvider(name, "", cfg, logger, m, responses)
+		if err != nil {
+			return nil, err
+		}
+		providers = append(providers, p)
+	}
+
+	return failover.New(providers, breakerConfig(cfg), logger, m), nil
+}
+
+// stageNames - стадии, которые можно направить на свою модель в LLM_STAGE_MODELS.
+// agent покрывает всех агентов, agent:market - одного
+var stageNames = map[string]bool{
+	llm.StageExpand:     true,
+	llm.StageAnalyze:    true,
+	"agent":             true,
+	llm.StageSynthesize: true,
+	llm.StageCritic:     true,
+	llm.StageImprove:    true,
+	llm.StageExtraction: true,
+}
+
+// newStageRouter направляет стадии из LLM_STAGE_MODELS на свои модели. Если своя модель
+// не ответила, запрос уходит в общую цепочку, так что экономия не ломает ответы
+func newStageRouter(chain *failover.Chain, cfg *config.Config, logger *zap.Logger, m *metrics.Metrics, responses cache.Cache) (llm.Client, error) {
+	if len(cfg.LLM.Stages) == 0 {
+		return chain, nil
+	}
+
+	routes := make(map[string]llm.Client, len(cfg.LLM.Stages))
+	// одна и та же модель на нескольких стадиях делит breaker и метрики
+	shared := make(map[config.StageModel]llm.Client)
+	for stage, sm := range cfg.LLM.Stages {
+		if stage == "extract" {
+			stage = llm.StageExtraction
+		}
+		if !stageNames[stage] && !strings.HasPrefix(stage, "agent:") {
+			return nil, fmt.Errorf("unknown LLM stage in LLM_STAGE_MODELS: %s", stage)
+		}
+
+		client, ok := shared[sm]
+		if !ok {
+			p, err := newProvider(sm.Provider, sm.Model, cfg, logger, m, responses)
+			if err != nil {
+				return nil, fmt.Errorf("stage %s: %w", stage, err)
+			}
+			fallback := failover.Provider{Name: p.Name + " fallback", Client: chain}
+			client = failover.New([]failover.Provider{p, fallback}, breakerConfig(cfg), logger, m)
+			shared[sm] = client
+		}
+		routes[stage] = client
+
+		logger.Info("LLM stage routed",
+			zap.String("stage", stage),
+			zap.String("provider", sm.Provider),
+			zap.String("model", providerModel(sm.Provider, sm.Model, cfg)),
+		)
+	}
+	return llm.NewRouter(chain, routes), nil
+}
+
+// newProvider оборачивает клиента провайдера метриками и кешем. Провайдер со своей
+// моделью получает имя вида openrouter/gpt-4o-mini, чтобы метрики и breaker не смешивались
+func newProvider(name, model string, cfg *config.Config, logger *zap.Logger, m *metrics.Metrics, responses cache.Cache) (failover.Provider, error) {
+	client, err := newProviderClient(name, model, cfg, logger)
+	if err != nil {
+		return failover.Provider{}, err
+	}
+
+	label := name
+	if model != "" {
+		label = name + "/" + model
+	}
+	client = llm.NewInstrumentedClient(client, label, m)
+	if cfg.LLM.Cache.Enabled {
+		policy := llm.CachePolicy{
+			TTL:      cfg.LLM.Cache.TTL,
+			StageTTL: cfg.LLM.Cache.StageTTL,
+		}
+		client = llm.NewCachedClient(client, responses, name, providerModel(name, model, cfg), policy, m)
+	}
+	return failover.Provider{Name: label, Client: client}, nil
+}
+
+func breakerConfig(cfg *config.Config) failover.Config {
+	return failover.Config{
+		FailureThreshold: cfg.LLM.Breaker.FailureThreshold,
+		Cooldown:         cfg.LLM.Breaker.Cooldown,
+	}
+}
+
+// newProviderClient создает клиента провайдера; пустая model - модель из его настроек
+func newProviderClient(name, model string, cfg *config.Config, logger *zap.Logger) (llm.Client, error) {
+	model = providerModel(name, model, cfg)
+	switch name {
+	case "mock":
+		logger.Warn("using mock LLM provider")
+		return mock.New(), nil
+	case "openrouter":
+		return openrouter.New(openrouter.Config{
+			APIKey:  cfg.LLM.OpenRouter.APIKey,
+			Model:   model,
+			BaseURL: cfg.LLM.OpenRouter.BaseURL,
+			Timeout: cfg.Timeouts.Total,
+			Retry:   retryPolicy(cfg.LLM.Retry),
+		}, logger), nil
+	case "gigachat":
+		return gigachat.New(gigachat.Config{
+			AuthKey:      cfg.LLM.GigaChat.AuthKey,
+			ClientID:     cfg.LLM.GigaChat.ClientID,
+			ClientSecret: cfg.LLM.GigaChat.ClientSecret,
+			Scope:        cfg.LLM.GigaChat.Scope,
+			AuthURL:      cfg.LLM.GigaChat.AuthURL,
+			BaseURL:      cfg.LLM.GigaChat.BaseURL,
+			Model:        model,
+			Timeout:      cfg.Timeouts.Total,
+			Retry:        retryPolicy(cfg.LLM.Retry),
+		}, logger), nil
+	case "openai":
+		if model == "" {
+			return nil, config.ErrMissingOpenAI
+		}
+		timeout := cfg.LLM.OpenAI.Timeout
+		if timeout == 0 {
+			timeout = cfg.Timeouts.Total
+		}
+		return openaicompat.New(openaicompat.Config{
+			BaseURL:         cfg.LLM.OpenAI.BaseURL,
+			APIKey:          cfg.LLM.OpenAI.APIKey,
+			Model:           model,
+			Headers:         cfg.LLM.OpenAI.Headers,
+			Timeout:         timeout,
+			Retry:           retryPolicy(cfg.LLM.Retry),
+			DisableJSONMode: cfg.LLM.OpenAI.DisableJSONMode,
+		}, logger), nil
+	default:
+		return nil, fmt.Errorf("unknown LLM provider: %s", name)
+	}
+}
+
+// retryPolicy переводит настройки повторов в retry.Policy, общую для LLM и поиска
+func retryPolicy(c config.RetryConfig) retry.Policy {
+	return retry.Policy{
+		MaxAttempts: c.MaxAttempts,
+		BaseDelay:   c.BaseDelay,
+		MaxDelay:    c.MaxDelay,
+		MaxElapsed:  c.MaxElapsed,
+	}
+}
+
+// providerModel - модель провайдера для ключа кеша: смена модели не должна отдавать старые ответы.
+// Непустая model - переопределение для стадии
+func providerModel(name, model string, cfg *config.Config) string {
+	if model != "" {
+		return model
+	}
+	switch name {
+	case "openrouter":
+		return cfg.LLM.OpenRouter.Model
+	case "openai":
+		return cfg.LLM.OpenAI.Model
+	case "gigachat":
+		return cfg.LLM.GigaChat.Model
+	default:
+		return name
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"go.uber.org/zap"
+
+	"github.com/kitbuilder587/fintech-bot/internal/config"
+	"github.com/kitbuilder587/fintech-bot/internal/repository/postgres"
+	"github.com/kitbuilder587/fintech-bot/migrations"
+)
+
+var errMigrateUsage = errors.New("usage: bot migrate up|down|status")
+
+// runMigrate - подкоманда `bot migrate up|down|status`
+func runMigrate(args []string) error {
+	if len(args) != 1 {
+		return errMigrateUsage
+	}
+
+	cfg, err := config.LoadForMigrate()
+	if err != nil {
+		return fmt.Errorf("load config: %w", err)
+	}
+
+	logger, err := config.NewLogger(cfg.Log)
+	if err != nil {
+		return fmt.Errorf("create logger: %w", err)
+	}
+	defer logger.Sync()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	db, err := postgres.New(ctx, cfg.Database.URL)
+	if err != nil {
+		return fmt.Errorf("connect database: %w", err)
+	}
+	defer db.Close()
+
+	migrator, err := postgres.NewMigrator(db, migrations.FS, logger)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("applied %d migration(s)\n", applied)
+	case "down":
+		if err := migrator.Down(ctx); err != nil {
+			return err
+		}
+	case "status":
+		status, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		printMigrationStatus(status)
+	default:
+		return errMigrateUsage
+	}
+
+	return nil
+}
+
+// migrateUp применяет миграции при старте бота
+func migrateUp(ctx context.Context, db *postgres.DB, logger *zap.Logger) error {
+	migrator, err := postgres.NewMigrator(db, migrations.FS, logger)
+	if err != nil {
+		return err
+	}
+
+	applied, err := migrator.Up(ctx)
+	if err != nil {
+		return fmt.Errorf("migrate database: %w", err)
+	}
+	logger.Info("database schema is up to date", zap.Int("applied", applied))
+	return nil
+}
+
+func printMigrationStatus(status *postgres.MigrationStatus) {
+	dirty := ""
+	if status.Dirty {
+		dirty = " (dirty)"
+	}
+	fmt.Fprintf(os.Stdout, "current version: %d%s\n", status.Version, dirty)
+
+	for _, m := range status.Applied {
+		fmt.Fprintf(os.Stdout, "  [x] %03d_%s\n", m.Version, m.Name)
+	}
+	for _, m := range status.Pending {
+		fmt.Fprintf(os.Stdout, "  [ ] %03d_%s\n", m.Version, m.Name)
+	}
+}
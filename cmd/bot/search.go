@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+
+	"go.uber.org/zap"
+
+	"github.com/kitbuilder587/fintech-bot/internal/config"
+	"github.com/kitbuilder587/fintech-bot/internal/metrics"
+	"github.com/kitbuilder587/fintech-bot/internal/repository"
+	"github.com/kitbuilder587/fintech-bot/internal/search"
+	"github.com/kitbuilder587/fintech-bot/internal/search/brave"
+	"github.com/kitbuilder587/fintech-bot/internal/search/federated"
+	"github.com/kitbuilder587/fintech-bot/internal/search/local"
+	"github.com/kitbuilder587/fintech-bot/internal/search/searxng"
+	"github.com/kitbuilder587/fintech-bot/internal/search/tavily"
+)
+
+// newSearchClient собирает поисковики из SEARCH_PROVIDERS, каждый со своими метриками.
+// Один поисковик отдается как есть, несколько - цепочкой с переходом к следующему при ошибке
+func newSearchClient(cfg *config.Config, articles repository.ArticleRepository, logger *zap.Logger, m *metrics.Metrics) (search.SearchClient, error) {
+	providers := make([]search.Provider, 0, len(cfg.Search.Providers))
+	seen := make(map[string]bool, len(cfg.Search.Providers))
+	for _, name := range cfg.Search.Providers {
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate search provider: %s", name)
+		}
+		seen[name] = true
+
+		client, err := newSearchProvider(name, cfg, articles, logger, m)
+		if err != nil {
+			return nil, err
+		}
+		providers = append(providers, search.Provider{Name: name, Client: client})
+	}
+
+	if len(providers) == 1 {
+		return providers[0].Client, nil
+	}
+	return search.NewFallback(providers, logger), nil
+}
+
+// newDeepSearchClient - поиск глубокой стратегии по SEARCH_FEDERATED_PROVIDERS:
+// все поисковики сразу, выдача сливается через RRF. nil, если не настроен
+func newDeepSearchClient(cfg *config.Config, articles repository.ArticleRepository, logger *zap.Logger, m *metrics.Metrics) (search.SearchClient, error) {
+	if len(cfg.Search.Federated) == 0 {
+		return nil, nil
+	}
+
+	providers := make([]federated.Provider, 0, len(cfg.Search.Federated))
+	seen := make(map[string]bool, len(cfg.Search.Federated))
+	for _, name := range cfg.Search.Federated {
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate federated search provider: %s", name)
+		}
+		seen[name] = true
+
+		client, err := newSearchProvider(name, cfg, articles, logger, m)
+		if err != nil {
+			return nil, err
+		}
+		providers = append(providers, federated.Provider{Name: name, Client: client})
+	}
+
+	return federated.New(providers, federated.Config{Timeout: cfg.Search.FederatedTimeout}, logger), nil
+}
+
+func newSearchProvider(name string, cfg *config.Config, articles repository.ArticleRepository, logger *zap.Logger, m *metrics.Metrics) (search.SearchClient, error) {
+	var client search.SearchClient
+	switch name {
+	case "tavily":
+		client = tavily.New(tavily.Config{
+			APIKey:  cfg.Tavily.APIKey,
+			BaseURL: cfg.Tavily.BaseURL,
+			Timeout: cfg.Tavily.Timeout,
+			Retry:   retryPolicy(cfg.Tavily.Retry),
+		}, logger)
+	case "searxng":
+		client = searxng.New(searxng.Config{
+			BaseURL: cfg.Search.SearXNG.BaseURL,
+			APIKey:  cfg.Search.SearXNG.APIKey,
+			Timeout: cfg.Search.SearXNG.Timeout,
+		}, logger)
+	case "brave":
+		client = brave.New(brave.Config{
+			APIKey:  cfg.Search.Brave.APIKey,
+			BaseURL: cfg.Search.Brave.BaseURL,
+			Timeout: cfg.Search.Brave.Timeout,
+		}, logger)
+	case "local":
+		// статьи из лент источников, их собирает feeds.Ingester при FEEDS_ENABLED
+		client = local.New(articles, local.Config{})
+	default:
+		return nil, fmt.Errorf("unknown search provider: %s", name)
+	}
+	return search.NewInstrumentedClient(client, name, m), nil
+}
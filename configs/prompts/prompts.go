@@ -0,0 +1,8 @@
+// Package prompts вшивает шаблоны промптов в бинарник, их загружает prompt.Registry,
+// если каталог с шаблонами не задан
+package prompts
+
+import "embed"
+
+//go:embed */*.tmpl
+var FS embed.FS
@@ -6,9 +6,11 @@ import (
 	"fmt"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/kitbuilder587/fintech-bot/internal/domain"
 	"github.com/kitbuilder587/fintech-bot/internal/llm"
+	"github.com/kitbuilder587/fintech-bot/internal/prompt"
 	"github.com/kitbuilder587/fintech-bot/internal/search"
 	"go.uber.org/zap"
 )
@@ -50,6 +52,11 @@ type AgentRequest struct {
 	SearchResults []search.SearchResult
 	Context       string // от World Model
 	Strategy      domain.Strategy
+	Domains       []string             // домены источников пользователя, за них поиск агента не выходит
+	Exclude       []string             // блок-лист пользователя, поиск агента его не возвращает
+	Scopes        []domain.SourceScope // разделы сайтов источников, вне них поиск агента ничего не возвращает
+	// если задан и Strategy.MaxAnalysisIterations > 1, агент может искать сам
+	Search *SearchTool
 }
 
 func (r AgentRequest) Validate() error {
@@ -82,6 +89,7 @@ type BaseAgent struct {
 	expertise    []string
 	keywords     []string
 	systemPrompt string
+	promptName   string // если задан, системный промпт рендерится из шаблона
 	llmClient    llm.Client
 	logger       *zap.Logger
 }
@@ -131,9 +139,16 @@ func (b *BaseAgent) Process(ctx context.Context, req AgentRequest) (*AgentRespon
 		return nil, err
 	}
 
-	userPrompt := buildUserPrompt(req)
+	systemPrompt := b.systemPrompt
+	if b.promptName != "" {
+		var err error
+		systemPrompt, err = prompt.FromContext(ctx).Render(b.promptName, prompt.Vars{})
+		if err != nil {
+			return nil, err
+		}
+	}
 
-	content, err := b.llmClient.CompleteWithSystem(ctx, b.systemPrompt, userPrompt)
+	content, err := b.complete(llm.WithStage(ctx, llm.AgentStage(b.name)), systemPrompt, req)
 	if err != nil {
 		b.logger.Error("LLM call failed", zap.Error(err))
 		return nil, fmt.Errorf("llm call failed: %w", err)
@@ -153,6 +168,19 @@ func (b *BaseAgent) Process(ctx context.Context, req AgentRequest) (*AgentRespon
 	}, nil
 }
 
+// complete отвечает одним вызовом, а если есть поиск - в несколько ходов с функцией search.
+// Провайдер без функций (GigaChat) отвечает одним вызовом
+func (b *BaseAgent) complete(ctx context.Context, systemPrompt string, req AgentRequest) (string, error) {
+	if req.Search != nil && req.Strategy.MaxAnalysisIterations > 1 {
+		content, err := b.research(ctx, systemPrompt, req)
+		if !errors.Is(err, llm.ErrToolsUnsupported) {
+			return content, err
+		}
+		b.logger.Debug("tool calling unsupported, answering in one call", zap.String("agent", b.name))
+	}
+	return b.llmClient.CompleteWithSystem(ctx, systemPrompt, buildUserPrompt(req))
+}
+
 func buildUserPrompt(req AgentRequest) string {
 	var sb strings.Builder
 
@@ -169,13 +197,28 @@ func buildUserPrompt(req AgentRequest) string {
 	if len(req.SearchResults) > 0 {
 		sb.WriteString("Источники:\n")
 		for i, r := range req.SearchResults {
-			fmt.Fprintf(&sb, "[S%d] %s\nURL: %s\nСодержание: %s\n\n", i+1, r.Title, r.URL, r.Content)
+			fmt.Fprintf(&sb, "[S%d] %s\nURL: %s\n%sСодержание: %s\n\n", i+1, r.Title, r.URL, sourceMeta(r), r.Content)
+		}
+		if req.SearchResults[0].Trust != "" {
+			sb.WriteString("Уровень доверия задан пользователем: при противоречиях опирайтесь на источники с доверием high.\n\n")
 		}
 	}
 
 	return sb.String()
 }
 
+// sourceMeta - строки с датой публикации и уровнем доверия, если они известны
+func sourceMeta(r search.SearchResult) string {
+	var meta string
+	if published, ok := r.Published(); ok {
+		meta += "Дата: " + published.Format(time.DateOnly) + "\n"
+	}
+	if r.Trust != "" {
+		meta += "Доверие: " + string(r.Trust) + "\n"
+	}
+	return meta
+}
+
 // parseInsights вытаскивает инсайты из ответа LLM
 // TODO: может перейти на structured output вместо парсинга регулярками?
 func parseInsights(content string) []string {
@@ -197,16 +240,15 @@ func parseInsights(content string) []string {
 	return insights
 }
 
+// parseSourceRefs - маркеры источников из ответа; [S3 p.12] считается ссылкой на [S3]
 func parseSourceRefs(content string) []string {
-	re := regexp.MustCompile(`\[S(\d+)\]`)
-	matches := re.FindAllStringSubmatch(content, -1)
-
 	seen := make(map[string]bool)
 	var refs []string
-	for _, m := range matches {
-		if len(m) > 0 && !seen[m[0]] {
-			seen[m[0]] = true
-			refs = append(refs, m[0])
+	for _, c := range domain.ParseCitations(content) {
+		ref := fmt.Sprintf("[S%d]", c.Source)
+		if !seen[ref] {
+			seen[ref] = true
+			refs = append(refs, ref)
 		}
 	}
 	return refs
@@ -149,3 +149,10 @@ func TestBaseAgent_Expertise(t *testing.T) {
 		}
 	}
 }
+
+func TestParseSourceRefs(t *testing.T) {
+	got := parseSourceRefs("Объем BNPL [S3 p.12], рост [S1] и [S3, p. 14]")
+	if len(got) != 2 || got[0] != "[S3]" || got[1] != "[S1]" {
+		t.Errorf("parseSourceRefs() = %v, want [[S3] [S1]]", got)
+	}
+}
@@ -11,6 +11,8 @@ import (
 
 	"github.com/kitbuilder587/fintech-bot/internal/domain"
 	"github.com/kitbuilder587/fintech-bot/internal/llm"
+	"github.com/kitbuilder587/fintech-bot/internal/prompt"
+	"github.com/kitbuilder587/fintech-bot/internal/search"
 	"go.uber.org/zap"
 )
 
@@ -18,6 +20,7 @@ var ErrNoAgentResponses = errors.New("no agent responses received")
 
 type CoordinatorResponse struct {
 	FinalAnswer    string
+	SearchResults  []search.SearchResult // исходные источники и найденные агентами, в порядке [S#]
 	AgentResponses []AgentResponse
 	AgentsUsed     []string
 	ProcessingTime time.Duration
@@ -27,6 +30,7 @@ type CoordinatorResponse struct {
 type Coordinator struct {
 	agents        []Agent
 	llm           llm.Client
+	search        search.SearchClient // nil - агенты не ищут сами
 	logger        *zap.Logger
 	minConfidence float64
 }
@@ -43,6 +47,12 @@ func NewCoordinator(agents []Agent, llmClient llm.Client, logger *zap.Logger) *C
 	}
 }
 
+// WithSearch дает агентам функцию search по доменам пользователя
+func (c *Coordinator) WithSearch(client search.SearchClient) *Coordinator {
+	c.search = client
+	return c
+}
+
 func (c *Coordinator) Process(ctx context.Context, req AgentRequest) (*CoordinatorResponse, error) {
 	start := time.Now()
 
@@ -50,6 +60,14 @@ func (c *Coordinator) Process(ctx context.Context, req AgentRequest) (*Coordinat
 		return nil, err
 	}
 
+	// один SearchTool на запрос, чтобы номера [S#] у всех агентов совпадали
+	if req.Search == nil && c.search != nil && len(req.Domains) > 0 && req.Strategy.MaxAnalysisIterations > 1 {
+		req.Search = NewSearchTool(c.search, req.Domains, req.SearchResults).
+			WithTimeRange(string(req.Strategy.TimeWindow)).
+			WithExclude(req.Exclude).
+			WithScopes(req.Scopes)
+	}
+
 	maxAgents := c.maxAgentsFor(req.Strategy)
 	selected := c.selectAgents(req.Question, maxAgents)
 
@@ -80,8 +98,14 @@ func (c *Coordinator) Process(ctx context.Context, req AgentRequest) (*Coordinat
 		}
 	}
 
+	sources := req.SearchResults
+	if req.Search != nil {
+		sources = req.Search.Results()
+	}
+
 	return &CoordinatorResponse{
 		FinalAnswer:    answer,
+		SearchResults:  sources,
 		AgentResponses: responses,
 		AgentsUsed:     names,
 		ProcessingTime: time.Since(start),
@@ -127,20 +151,20 @@ func (c *Coordinator) selectAgents(question string, maxAgents int) []Agent {
 	return result
 }
 
-// runParallel запускает агентов параллельно
+// runParallel запускает агентов параллельно. Ответы идут в порядке выбора агентов,
+// а не завершения: иначе промпт синтеза меняется от запуска к запуску
 // FIXME: было бы неплохо добавить таймаут на каждого агента отдельно
 func (c *Coordinator) runParallel(ctx context.Context, agents []Agent, req AgentRequest) []AgentResponse {
 	if len(agents) == 0 {
 		return nil
 	}
 
-	var mu sync.Mutex
-	var results []AgentResponse
+	slots := make([]*AgentResponse, len(agents))
 	var wg sync.WaitGroup
 
-	for _, a := range agents {
+	for i, a := range agents {
 		wg.Add(1)
-		go func(agent Agent) {
+		go func(i int, agent Agent) {
 			defer wg.Done()
 
 			resp, err := agent.Process(ctx, req)
@@ -148,14 +172,18 @@ func (c *Coordinator) runParallel(ctx context.Context, agents []Agent, req Agent
 				c.logger.Warn("agent failed", zap.String("agent", agent.Name()), zap.Error(err))
 				return
 			}
-
-			mu.Lock()
-			results = append(results, *resp)
-			mu.Unlock()
-		}(a)
+			slots[i] = resp
+		}(i, a)
 	}
 
 	wg.Wait()
+
+	var results []AgentResponse
+	for _, resp := range slots {
+		if resp != nil {
+			results = append(results, *resp)
+		}
+	}
 	return results
 }
 
@@ -169,15 +197,13 @@ func (c *Coordinator) synthesize(ctx context.Context, responses []AgentResponse,
 		fmt.Fprintf(&buf, "[Expert %d: %s]\n%s\n\n", i+1, r.AgentName, r.Content)
 	}
 
-	sysPrompt := `Ты - Synthesizer, синтезируешь ответы нескольких экспертов в один связный текст.
-
-Ответы экспертов:
-` + buf.String() + `
-
-Отвечай на русском. Объедини точки зрения экспертов, выдели где они согласны, а где расходятся.
-Сохраняй ссылки на источники [S1], [S2] и т.д. Структура: сначала общая картина, потом детали, в конце выводы.`
+	sysPrompt, err := prompt.FromContext(ctx).Render(prompt.Synthesize, prompt.Vars{Experts: buf.String()})
+	if err != nil {
+		return "", err
+	}
 
-	return c.llm.CompleteWithSystem(ctx, sysPrompt, "User question: "+question)
+	// синтез - финальный ответ, его можно показывать по мере генерации
+	return llm.CompleteStreaming(llm.WithStage(ctx, llm.StageSynthesize), c.llm, sysPrompt, "User question: "+question)
 }
 
 func (c *Coordinator) maxAgentsFor(s domain.Strategy) int {
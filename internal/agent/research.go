@@ -0,0 +1,184 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+
+	"github.com/kitbuilder587/fintech-bot/internal/domain"
+	"github.com/kitbuilder587/fintech-bot/internal/llm"
+	"github.com/kitbuilder587/fintech-bot/internal/search"
+)
+
+const (
+	searchToolName = "search"
+	// результатов на один вызов search, чтобы не раздувать контекст агента
+	searchToolResults = 5
+	// вызовов search за один ход; остальные получают отказ
+	maxSearchCallsPerTurn = 3
+)
+
+var searchToolParams = json.RawMessage(`{
+	"type": "object",
+	"properties": {
+		"query": {"type": "string", "description": "Short web search query, in English or Russian"}
+	},
+	"required": ["query"]
+}`)
+
+// SearchTool - функция search, которой агенты сами ищут то, чего не хватило в источниках.
+// Ищет только по доменам пользователя. Общая для всех агентов запроса: найденное
+// складывается в один список и нумеруется [S#] дальше исходных источников
+type SearchTool struct {
+	client    search.SearchClient
+	domains   []string
+	exclude   []string
+	scopes    []domain.SourceScope
+	timeRange string
+
+	mu      sync.Mutex
+	results []search.SearchResult
+	index   map[string]int // URL -> позиция в results
+}
+
+func NewSearchTool(client search.SearchClient, domains []string, initial []search.SearchResult) *SearchTool {
+	t := &SearchTool{
+		client:  client,
+		domains: domains,
+		index:   make(map[string]int, len(initial)),
+	}
+	for _, r := range initial {
+		t.add(r)
+	}
+	return t
+}
+
+// WithTimeRange ограничивает поиск агентов окном времени стратегии
+func (t *SearchTool) WithTimeRange(timeRange string) *SearchTool {
+	t.timeRange = timeRange
+	return t
+}
+
+// WithExclude - домены блок-листа: уходят в ExcludeDomains и отсеиваются из выдачи
+// вместе с поддоменами, даже если поисковик исключения не поддерживает
+func (t *SearchTool) WithExclude(domains []string) *SearchTool {
+	t.exclude = domains
+	return t
+}
+
+// WithScopes - результаты с сайтов источников вне их разделов отбрасываются
+func (t *SearchTool) WithScopes(scopes []domain.SourceScope) *SearchTool {
+	t.scopes = scopes
+	return t
+}
+
+func (t *SearchTool) Definition() llm.Tool {
+	return llm.NewFunctionTool(searchToolName,
+		"Search the user's trusted sources for facts missing from the provided sources. "+
+			"New results are appended to the source list and can be cited by their [S#] numbers.",
+		searchToolParams)
+}
+
+// Results - исходные источники и все, что нашли агенты, в порядке номеров [S#]
+func (t *SearchTool) Results() []search.SearchResult {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]search.SearchResult(nil), t.results...)
+}
+
+// Call выполняет вызов search. Ошибки возвращаются текстом для модели,
+// чтобы она могла переформулировать запрос или ответить по тому, что есть
+func (t *SearchTool) Call(ctx context.Context, arguments string) string {
+	var args struct {
+		Query string `json:"query"`
+	}
+	if err := json.Unmarshal([]byte(arguments), &args); err != nil || strings.TrimSpace(args.Query) == "" {
+		return `error: arguments must be {"query": "..."}`
+	}
+
+	resp, err := t.client.Search(ctx, search.SearchRequest{
+		Query:          args.Query,
+		IncludeDomains: t.domains,
+		ExcludeDomains: t.exclude,
+		MaxResults:     searchToolResults,
+		TimeRange:      t.timeRange,
+	})
+	if err == nil {
+		resp.Results = search.WithinScopes(search.WithoutBlocked(resp.Results, t.exclude), t.scopes)
+	}
+	if errors.Is(err, search.ErrEmptyResults) || (err == nil && len(resp.Results) == 0) {
+		return "Ничего не найдено."
+	}
+	if err != nil {
+		return "error: search failed: " + err.Error()
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var sb strings.Builder
+	for _, r := range resp.Results {
+		n := t.add(r) + 1
+		fmt.Fprintf(&sb, "[S%d] %s\nURL: %s\n%sСодержание: %s\n\n", n, r.Title, r.URL, sourceMeta(r), r.Content)
+	}
+	return sb.String()
+}
+
+// add возвращает позицию источника, уже известные URL не дублируются. Вызывать под mu
+func (t *SearchTool) add(r search.SearchResult) int {
+	if i, ok := t.index[r.URL]; ok {
+		return i
+	}
+	t.results = append(t.results, r)
+	t.index[r.URL] = len(t.results) - 1
+	return len(t.results) - 1
+}
+
+// research отвечает в несколько ходов: пока ходы есть, модель может вызывать search.
+// Последний ход - обычный запрос по всем найденным источникам, так что вызовов LLM
+// не больше Strategy.MaxAnalysisIterations
+func (b *BaseAgent) research(ctx context.Context, system string, req AgentRequest) (string, error) {
+	tool := req.Search
+	messages := []llm.Message{
+		{Role: "system", Content: system},
+		{Role: "user", Content: buildUserPrompt(req) + "Если источников не хватает, вызови функцию search.\n"},
+	}
+	tools := []llm.Tool{tool.Definition()}
+
+	for turn := 1; turn < req.Strategy.MaxAnalysisIterations; turn++ {
+		msg, err := llm.CompleteWithTools(ctx, b.llmClient, messages, tools)
+		if err != nil {
+			return "", err
+		}
+		if len(msg.ToolCalls) == 0 {
+			return msg.Content, nil
+		}
+
+		messages = append(messages, msg)
+		for i, call := range msg.ToolCalls {
+			result := "error: too many searches in one turn"
+			switch {
+			case call.Function.Name != searchToolName:
+				result = "error: unknown function " + call.Function.Name
+			case i < maxSearchCallsPerTurn:
+				result = tool.Call(ctx, call.Function.Arguments)
+			}
+			messages = append(messages, llm.ToolMessage(call.ID, result))
+		}
+
+		b.logger.Debug("agent searched",
+			zap.String("agent", b.name),
+			zap.Int("turn", turn),
+			zap.Int("calls", len(msg.ToolCalls)),
+		)
+	}
+
+	final := req
+	final.SearchResults = tool.Results()
+	return b.llmClient.CompleteWithSystem(ctx, system, buildUserPrompt(final))
+}
@@ -0,0 +1,303 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/kitbuilder587/fintech-bot/internal/domain"
+	"github.com/kitbuilder587/fintech-bot/internal/llm"
+	"github.com/kitbuilder587/fintech-bot/internal/llm/mock"
+	"github.com/kitbuilder587/fintech-bot/internal/llm/openaicompat"
+	"github.com/kitbuilder587/fintech-bot/internal/llm/openrouter"
+	"github.com/kitbuilder587/fintech-bot/internal/retry"
+	"github.com/kitbuilder587/fintech-bot/internal/search"
+	searchMock "github.com/kitbuilder587/fintech-bot/internal/search/mock"
+)
+
+var initialSources = []search.SearchResult{
+	{Title: "Klarna IPO", URL: "https://example.com/ipo", Content: "Klarna filed for IPO"},
+}
+
+func searchCall(id, query string) llm.Message {
+	return llm.Message{Role: "assistant", ToolCalls: []llm.ToolCall{{
+		ID:       id,
+		Type:     "function",
+		Function: llm.ToolCallFunction{Name: "search", Arguments: `{"query": "` + query + `"}`},
+	}}}
+}
+
+func TestSearchTool_NumbersNewSourcesAfterInitial(t *testing.T) {
+	client := searchMock.New().WithResults([]search.SearchResult{
+		{Title: "Klarna IPO", URL: "https://example.com/ipo", Content: "duplicate"},
+		{Title: "BNPL rules", URL: "https://example.com/bnpl", Content: "EU rules"},
+	})
+	tool := NewSearchTool(client, []string{"example.com"}, initialSources)
+
+	out := tool.Call(context.Background(), `{"query": "bnpl regulation"}`)
+
+	if !strings.Contains(out, "[S1] Klarna IPO") || !strings.Contains(out, "[S2] BNPL rules") {
+		t.Errorf("Call() = %q, want known source as [S1] and new one as [S2]", out)
+	}
+	if got := tool.Results(); len(got) != 2 || got[1].URL != "https://example.com/bnpl" {
+		t.Errorf("Results() = %+v, want initial source plus one new", got)
+	}
+	req := client.LastRequest
+	if req.Query != "bnpl regulation" || len(req.IncludeDomains) != 1 || req.IncludeDomains[0] != "example.com" {
+		t.Errorf("search request = %+v, want query restricted to user domains", req)
+	}
+}
+
+func TestSearchTool_TimeRangeAndDates(t *testing.T) {
+	client := searchMock.New().WithResults([]search.SearchResult{
+		{Title: "BNPL rules", URL: "https://example.com/bnpl", Content: "EU rules", PublishedDate: "2025-03-14"},
+	})
+	tool := NewSearchTool(client, []string{"example.com"}, nil).WithTimeRange("month")
+
+	out := tool.Call(context.Background(), `{"query": "bnpl regulation"}`)
+
+	if client.LastRequest.TimeRange != "month" {
+		t.Errorf("TimeRange = %q, want strategy window month", client.LastRequest.TimeRange)
+	}
+	if !strings.Contains(out, "Дата: 2025-03-14") {
+		t.Errorf("Call() = %q, want publication date", out)
+	}
+}
+
+func TestSearchTool_DropsBlockedDomains(t *testing.T) {
+	client := searchMock.New().WithResults([]search.SearchResult{
+		{Title: "Forum thread", URL: "https://forum.example.com/t/1", Content: "rumours"},
+		{Title: "BNPL rules", URL: "https://example.com/bnpl", Content: "EU rules"},
+	})
+	tool := NewSearchTool(client, []string{"example.com"}, nil).WithExclude([]string{"forum.example.com"})
+
+	out := tool.Call(context.Background(), `{"query": "bnpl regulation"}`)
+
+	if req := client.LastRequest; len(req.ExcludeDomains) != 1 || req.ExcludeDomains[0] != "forum.example.com" {
+		t.Errorf("ExcludeDomains = %v, want blocklist", req.ExcludeDomains)
+	}
+	if strings.Contains(out, "Forum thread") || !strings.Contains(out, "[S1] BNPL rules") {
+		t.Errorf("Call() = %q, want blocked result dropped", out)
+	}
+}
+
+func TestSearchTool_DropsResultsOutsideScopes(t *testing.T) {
+	client := searchMock.New().WithResults([]search.SearchResult{
+		{Title: "Careers", URL: "https://example.com/careers/1", Content: "jobs"},
+		{Title: "BNPL rules", URL: "https://example.com/research/bnpl", Content: "EU rules"},
+	})
+	tool := NewSearchTool(client, []string{"example.com"}, nil).
+		WithScopes([]domain.SourceScope{domain.ScopeOf("https://example.com/research/")})
+
+	out := tool.Call(context.Background(), `{"query": "bnpl regulation"}`)
+
+	if strings.Contains(out, "Careers") || !strings.Contains(out, "[S1] BNPL rules") {
+		t.Errorf("Call() = %q, want only results from the source section", out)
+	}
+}
+
+func TestSearchTool_ErrorsAreTextForModel(t *testing.T) {
+	tool := NewSearchTool(searchMock.New(), []string{"example.com"}, nil)
+
+	if out := tool.Call(context.Background(), `not json`); !strings.HasPrefix(out, "error:") {
+		t.Errorf("Call(bad args) = %q, want error text", out)
+	}
+	if out := tool.Call(context.Background(), `{"query": "nothing"}`); out != "Ничего не найдено." {
+		t.Errorf("Call(no results) = %q", out)
+	}
+}
+
+func TestBaseAgent_ResearchLoop(t *testing.T) {
+	llmClient := mock.New().
+		WithTurns(searchCall("call_1", "klarna revenue")).
+		WithResponse("Выручка Klarna выросла [S2]")
+	searchClient := searchMock.New().WithResults([]search.SearchResult{
+		{Title: "Klarna revenue", URL: "https://example.com/revenue", Content: "Revenue up 20%"},
+	})
+	agent := NewBaseAgent("market-analyst", nil, nil, "system", llmClient, zap.NewNop())
+
+	resp, err := agent.Process(context.Background(), AgentRequest{
+		Question:      "Klarna revenue?",
+		SearchResults: initialSources,
+		Strategy:      domain.DeepStrategy(),
+		Search:        NewSearchTool(searchClient, []string{"example.com"}, initialSources),
+	})
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	if resp.Content != "Выручка Klarna выросла [S2]" || len(resp.SourceRefs) != 1 || resp.SourceRefs[0] != "[S2]" {
+		t.Errorf("response = %+v", resp)
+	}
+	if llmClient.ToolCallCount != 2 || llmClient.CallCount != 0 {
+		t.Errorf("tool turns = %d, plain calls = %d; want 2 and 0", llmClient.ToolCallCount, llmClient.CallCount)
+	}
+	// второй ход видит вызов функции и ее результат
+	last := llmClient.LastMessages
+	if len(last) != 4 || last[3].Role != "tool" || last[3].ToolCallID != "call_1" || !strings.Contains(last[3].Content, "[S2] Klarna revenue") {
+		t.Errorf("messages on second turn = %+v", last)
+	}
+}
+
+func TestBaseAgent_ResearchStopsAtMaxIterations(t *testing.T) {
+	llmClient := mock.New().
+		WithTurns(searchCall("call_1", "a"), searchCall("call_2", "b"), searchCall("call_3", "c")).
+		WithResponse("final [S1]")
+	searchClient := searchMock.New().WithResults([]search.SearchResult{
+		{Title: "More", URL: "https://example.com/more", Content: "more"},
+	})
+	agent := NewBaseAgent("market-analyst", nil, nil, "system", llmClient, zap.NewNop())
+
+	strategy := domain.DeepStrategy()
+	strategy.MaxAnalysisIterations = 3
+	resp, err := agent.Process(context.Background(), AgentRequest{
+		Question:      "Klarna?",
+		SearchResults: initialSources,
+		Strategy:      strategy,
+		Search:        NewSearchTool(searchClient, []string{"example.com"}, initialSources),
+	})
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	// два хода с поиском и финальный обычный запрос по всем источникам
+	if llmClient.ToolCallCount != 2 || llmClient.CallCount != 1 || resp.Content != "final [S1]" {
+		t.Errorf("tool turns = %d, plain calls = %d, content = %q", llmClient.ToolCallCount, llmClient.CallCount, resp.Content)
+	}
+	if !strings.Contains(llmClient.LastPrompt, "[S2] More") {
+		t.Errorf("final prompt should list found sources, got %q", llmClient.LastPrompt)
+	}
+}
+
+func TestBaseAgent_ToolsUnsupportedFallsBack(t *testing.T) {
+	llmClient := mock.New().WithResponse("plain answer")
+	llmClient.ToolsUnsupported = true
+	agent := NewBaseAgent("market-analyst", nil, nil, "system", llmClient, zap.NewNop())
+
+	resp, err := agent.Process(context.Background(), AgentRequest{
+		Question:      "Klarna?",
+		SearchResults: initialSources,
+		Strategy:      domain.DeepStrategy(),
+		Search:        NewSearchTool(searchMock.New(), []string{"example.com"}, initialSources),
+	})
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	if resp.Content != "plain answer" || llmClient.CallCount != 1 {
+		t.Errorf("content = %q after %d plain calls, want one plain call", resp.Content, llmClient.CallCount)
+	}
+}
+
+// TestBaseAgent_ProviderRejectsToolsFallsBack - настоящие клиенты переводят отказ
+// сервера от функций в ErrToolsUnsupported, и агент отвечает обычным вызовом
+func TestBaseAgent_ProviderRejectsToolsFallsBack(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		body      string
+		newClient func(url string) llm.Client
+	}{
+		{
+			name:   "openrouter 404",
+			status: http.StatusNotFound,
+			body:   `{"error":{"message":"No endpoints found that support tool use. Try disabling \"search\".","code":404}}`,
+			newClient: func(url string) llm.Client {
+				return openrouter.New(openrouter.Config{APIKey: "key", BaseURL: url, Retry: retry.Policy{MaxAttempts: 1}}, zap.NewNop())
+			},
+		},
+		{
+			name:   "vllm 400",
+			status: http.StatusBadRequest,
+			body:   `{"object":"error","message":"\"auto\" tool choice requires --enable-auto-tool-choice and --tool-call-parser to be set","type":"BadRequestError","code":400}`,
+			newClient: func(url string) llm.Client {
+				return openaicompat.New(openaicompat.Config{BaseURL: url, Model: "qwen", Retry: retry.Policy{MaxAttempts: 1}}, zap.NewNop())
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var toolCalls, plainCalls int
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var req llm.ChatRequest
+				json.NewDecoder(r.Body).Decode(&req)
+				if len(req.Tools) > 0 {
+					toolCalls++
+					w.WriteHeader(tt.status)
+					io.WriteString(w, tt.body)
+					return
+				}
+				plainCalls++
+				io.WriteString(w, `{"choices":[{"message":{"role":"assistant","content":"plain answer [S1]"}}]}`)
+			}))
+			defer server.Close()
+
+			agent := NewBaseAgent("market-analyst", nil, nil, "system", tt.newClient(server.URL), zap.NewNop())
+			resp, err := agent.Process(context.Background(), AgentRequest{
+				Question:      "Klarna?",
+				SearchResults: initialSources,
+				Strategy:      domain.DeepStrategy(),
+				Search:        NewSearchTool(searchMock.New(), []string{"example.com"}, initialSources),
+			})
+			if err != nil {
+				t.Fatalf("Process() error = %v", err)
+			}
+			if resp.Content != "plain answer [S1]" || toolCalls != 1 || plainCalls != 1 {
+				t.Errorf("content = %q after %d tool and %d plain calls, want one of each", resp.Content, toolCalls, plainCalls)
+			}
+		})
+	}
+}
+
+func TestCoordinator_MergesAgentSources(t *testing.T) {
+	llmClient := mock.New().
+		WithTurns(searchCall("call_1", "klarna revenue")).
+		WithResponse("Выручка выросла [S2]")
+	searchClient := searchMock.New().WithResults([]search.SearchResult{
+		{Title: "Klarna revenue", URL: "https://example.com/revenue", Content: "Revenue up 20%"},
+	})
+	coord := NewCoordinator([]Agent{NewMarketAgent(llmClient, zap.NewNop())}, llmClient, zap.NewNop()).
+		WithSearch(searchClient)
+
+	strategy := domain.DeepStrategy()
+	strategy.Type = domain.StrategyQuick // один агент, без синтеза
+	resp, err := coord.Process(context.Background(), AgentRequest{
+		Question:      "Klarna market revenue",
+		SearchResults: initialSources,
+		Strategy:      strategy,
+		Domains:       []string{"example.com"},
+	})
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	if len(resp.SearchResults) != 2 || resp.SearchResults[1].URL != "https://example.com/revenue" {
+		t.Errorf("SearchResults = %+v, want initial source plus the one found by agent", resp.SearchResults)
+	}
+}
+
+func TestCoordinator_NoSearchWithoutDomains(t *testing.T) {
+	llmClient := mock.New().WithResponse("answer [S1]")
+	coord := NewCoordinator([]Agent{NewMarketAgent(llmClient, zap.NewNop())}, llmClient, zap.NewNop()).
+		WithSearch(searchMock.New())
+
+	strategy := domain.DeepStrategy()
+	strategy.Type = domain.StrategyQuick
+	resp, err := coord.Process(context.Background(), AgentRequest{
+		Question:      "Klarna market",
+		SearchResults: initialSources,
+		Strategy:      strategy,
+	})
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	if llmClient.ToolCallCount != 0 || len(resp.SearchResults) != 1 {
+		t.Errorf("tool turns = %d, sources = %d; want no tools without user domains", llmClient.ToolCallCount, len(resp.SearchResults))
+	}
+}
@@ -0,0 +1,141 @@
+// Package cassette записывает ответы LLM и поиска в JSON файлы и отдает их обратно,
+// чтобы весь пайплайн можно было прогнать без сети.
+//
+// Ключ записи - хеш запроса целиком. Промпты с текущим годом (expand) дают
+// другой хеш в новом году, такую кассету придется перезаписать
+package cassette
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+type Mode string
+
+const (
+	// ModeRecord проксирует запросы в настоящий клиент и сохраняет успешные ответы
+	ModeRecord Mode = "record"
+	// ModeReplay отдает только записанное, сеть не трогает
+	ModeReplay Mode = "replay"
+)
+
+func (m Mode) IsValid() bool {
+	return m == ModeRecord || m == ModeReplay
+}
+
+var ErrMiss = errors.New("cassette: no recorded response")
+
+// Cassette - каталог с записями, общий для клиентов LLM и поиска
+type Cassette struct {
+	dir  string
+	mode Mode
+
+	mu     sync.Mutex
+	misses []string
+}
+
+func Open(dir string, mode Mode) (*Cassette, error) {
+	if !mode.IsValid() {
+		return nil, fmt.Errorf("cassette: unknown mode %q", mode)
+	}
+	if mode == ModeRecord {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("cassette: create dir: %w", err)
+		}
+	} else if _, err := os.Stat(dir); err != nil {
+		return nil, fmt.Errorf("cassette: %w", err)
+	}
+	return &Cassette{dir: dir, mode: mode}, nil
+}
+
+func (c *Cassette) Mode() Mode {
+	return c.mode
+}
+
+// Misses - запросы, которых не нашлось при воспроизведении. Пайплайн глушит
+// часть ошибок (расширение запроса, критик), поэтому тесту стоит проверить этот список
+func (c *Cassette) Misses() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.misses...)
+}
+
+// entry - файл кассеты: запрос для читателя и ответ
+type entry struct {
+	Request  json.RawMessage `json:"request"`
+	Response json.RawMessage `json:"response"`
+}
+
+// load читает ответ по запросу в out. При промахе запоминает его и возвращает ErrMiss
+func (c *Cassette) load(kind string, request, out any) error {
+	path, _, err := c.path(kind, request)
+	if err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		c.mu.Lock()
+		c.misses = append(c.misses, filepath.Base(path))
+		c.mu.Unlock()
+		return fmt.Errorf("%w: %s (%s)", ErrMiss, filepath.Base(path), describe(request))
+	}
+	if err != nil {
+		return fmt.Errorf("cassette: read %s: %w", path, err)
+	}
+
+	var e entry
+	if err := json.Unmarshal(data, &e); err != nil {
+		return fmt.Errorf("cassette: parse %s: %w", path, err)
+	}
+	if err := json.Unmarshal(e.Response, out); err != nil {
+		return fmt.Errorf("cassette: parse response %s: %w", path, err)
+	}
+	return nil
+}
+
+func (c *Cassette) save(kind string, request, response any) error {
+	path, reqJSON, err := c.path(kind, request)
+	if err != nil {
+		return err
+	}
+	respJSON, err := json.Marshal(response)
+	if err != nil {
+		return fmt.Errorf("cassette: marshal response: %w", err)
+	}
+
+	data, err := json.MarshalIndent(entry{Request: reqJSON, Response: respJSON}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("cassette: marshal entry: %w", err)
+	}
+	// через временный файл, чтобы оборванная запись не оставила битую кассету
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("cassette: write %s: %w", path, err)
+	}
+	return os.Rename(tmp, path)
+}
+
+func (c *Cassette) path(kind string, request any) (string, []byte, error) {
+	reqJSON, err := json.Marshal(request)
+	if err != nil {
+		return "", nil, fmt.Errorf("cassette: marshal request: %w", err)
+	}
+	sum := sha256.Sum256(append([]byte(kind+":"), reqJSON...))
+	return filepath.Join(c.dir, fmt.Sprintf("%s_%x.json", kind, sum[:8])), reqJSON, nil
+}
+
+// describe - начало запроса для сообщения о промахе
+func describe(request any) string {
+	data, _ := json.Marshal(request)
+	const limit = 200
+	if len(data) > limit {
+		return string(data[:limit]) + "..."
+	}
+	return string(data)
+}
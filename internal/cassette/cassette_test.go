@@ -0,0 +1,140 @@
+package cassette_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kitbuilder587/fintech-bot/internal/cassette"
+	"github.com/kitbuilder587/fintech-bot/internal/llm"
+	llmMock "github.com/kitbuilder587/fintech-bot/internal/llm/mock"
+	"github.com/kitbuilder587/fintech-bot/internal/search"
+	searchMock "github.com/kitbuilder587/fintech-bot/internal/search/mock"
+)
+
+func TestLLM_RecordThenReplay(t *testing.T) {
+	dir := t.TempDir()
+	ctx := llm.WithStage(context.Background(), llm.StageExpand)
+
+	rec, err := cassette.Open(dir, cassette.ModeRecord)
+	if err != nil {
+		t.Fatalf("Open(record) error = %v", err)
+	}
+	inner := llmMock.New().WithResponses("first", "second")
+	recorder := rec.LLM(inner)
+
+	if _, err := recorder.CompleteWithSystem(ctx, "sys", "one"); err != nil {
+		t.Fatalf("record error = %v", err)
+	}
+	if _, err := recorder.CompleteWithSystem(llm.WithJSONMode(ctx), "sys", "one"); err != nil {
+		t.Fatalf("record error = %v", err)
+	}
+
+	play, err := cassette.Open(dir, cassette.ModeReplay)
+	if err != nil {
+		t.Fatalf("Open(replay) error = %v", err)
+	}
+	player := play.LLM(nil)
+
+	got, err := player.CompleteWithSystem(ctx, "sys", "one")
+	if err != nil || got != "first" {
+		t.Errorf("replay = %q, %v, want first", got, err)
+	}
+	// JSON режим - другой запрос, у него своя запись
+	got, err = player.CompleteWithSystem(llm.WithJSONMode(ctx), "sys", "one")
+	if err != nil || got != "second" {
+		t.Errorf("replay json = %q, %v, want second", got, err)
+	}
+	if inner.CallCount != 2 {
+		t.Errorf("inner calls = %d, want 2 from recording only", inner.CallCount)
+	}
+}
+
+func TestLLM_ReplayMiss(t *testing.T) {
+	play, err := cassette.Open(t.TempDir(), cassette.ModeReplay)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+
+	_, err = play.LLM(nil).CompleteWithSystem(context.Background(), "sys", "unknown")
+	if !errors.Is(err, cassette.ErrMiss) {
+		t.Fatalf("error = %v, want ErrMiss", err)
+	}
+	if misses := play.Misses(); len(misses) != 1 {
+		t.Errorf("Misses() = %v, want one", misses)
+	}
+}
+
+func TestLLM_HistoryChangesKey(t *testing.T) {
+	dir := t.TempDir()
+	rec, _ := cassette.Open(dir, cassette.ModeRecord)
+	if _, err := rec.LLM(llmMock.New()).CompleteWithSystem(context.Background(), "sys", "why?"); err != nil {
+		t.Fatalf("record error = %v", err)
+	}
+
+	play, _ := cassette.Open(dir, cassette.ModeReplay)
+	ctx := llm.WithHistory(context.Background(), []llm.Message{{Role: "user", Content: "earlier"}})
+	if _, err := play.LLM(nil).CompleteWithSystem(ctx, "sys", "why?"); !errors.Is(err, cassette.ErrMiss) {
+		t.Errorf("error = %v, want ErrMiss for a follow-up with history", err)
+	}
+}
+
+func TestLLM_ErrorsAreNotRecorded(t *testing.T) {
+	dir := t.TempDir()
+	rec, _ := cassette.Open(dir, cassette.ModeRecord)
+
+	boom := errors.New("boom")
+	if _, err := rec.LLM(llmMock.New().WithError(boom)).CompleteWithSystem(context.Background(), "sys", "p"); !errors.Is(err, boom) {
+		t.Fatalf("error = %v, want boom", err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("cassette has %d files after a failed call, want 0", len(entries))
+	}
+}
+
+func TestSearch_RecordThenReplay(t *testing.T) {
+	dir := t.TempDir()
+	inner := searchMock.New()
+	inner.Results = []search.SearchResult{{Title: "Klarna", URL: "https://example.com/klarna", Content: "IPO", Score: 0.9}}
+
+	rec, _ := cassette.Open(dir, cassette.ModeRecord)
+	req := search.SearchRequest{Query: "klarna ipo", IncludeDomains: []string{"b.com", "a.com"}, MaxResults: 5}
+	if _, err := rec.Search(inner).Search(context.Background(), req); err != nil {
+		t.Fatalf("record error = %v", err)
+	}
+
+	play, _ := cassette.Open(dir, cassette.ModeReplay)
+	// порядок доменов на ключ не влияет
+	req.IncludeDomains = []string{"a.com", "b.com"}
+	resp, err := play.Search(nil).Search(context.Background(), req)
+	if err != nil {
+		t.Fatalf("replay error = %v", err)
+	}
+	if len(resp.Results) != 1 || resp.Results[0].URL != "https://example.com/klarna" || resp.Results[0].Score != 0.9 {
+		t.Errorf("replay results = %+v", resp.Results)
+	}
+
+	req.Query = "klarna revenue"
+	if _, err := play.Search(nil).Search(context.Background(), req); !errors.Is(err, cassette.ErrMiss) {
+		t.Errorf("error = %v, want ErrMiss", err)
+	}
+}
+
+func TestOpen(t *testing.T) {
+	if _, err := cassette.Open(t.TempDir(), "rewind"); err == nil {
+		t.Error("Open() with unknown mode should fail")
+	}
+	if _, err := cassette.Open(filepath.Join(t.TempDir(), "missing"), cassette.ModeReplay); err == nil {
+		t.Error("Open(replay) of a missing dir should fail")
+	}
+
+	dir := filepath.Join(t.TempDir(), "new", "tape")
+	if _, err := cassette.Open(dir, cassette.ModeRecord); err != nil {
+		t.Fatalf("Open(record) error = %v", err)
+	}
+	if _, err := os.Stat(dir); err != nil {
+		t.Errorf("record mode should create the dir: %v", err)
+	}
+}
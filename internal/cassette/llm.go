@@ -0,0 +1,106 @@
+package cassette
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/kitbuilder587/fintech-bot/internal/llm"
+)
+
+// llmRequest - все, от чего зависит ответ модели. Стадия в ключ не входит,
+// но пишется в файл, чтобы кассету было удобно читать
+type llmRequest struct {
+	System   string        `json:"system"`
+	Prompt   string        `json:"prompt"`
+	History  []llm.Message `json:"history,omitempty"`
+	JSONMode bool          `json:"json_mode,omitempty"`
+}
+
+type llmResponse struct {
+	Stage   string `json:"stage"`
+	Content string `json:"content"`
+}
+
+// LLMClient пишет или воспроизводит ответы llm.Client.
+// Стриминг не поддерживает: llm.OpenStream отдаст записанный ответ одним куском
+type LLMClient struct {
+	next     llm.Client
+	cassette *Cassette
+}
+
+// LLM оборачивает next; при воспроизведении next не нужен и может быть nil
+func (c *Cassette) LLM(next llm.Client) *LLMClient {
+	return &LLMClient{next: next, cassette: c}
+}
+
+func (c *LLMClient) CompleteWithSystem(ctx context.Context, system, prompt string) (string, error) {
+	req := llmRequest{
+		System:   system,
+		Prompt:   prompt,
+		History:  llm.HistoryFrom(ctx),
+		JSONMode: llm.JSONModeFrom(ctx),
+	}
+
+	if c.cassette.mode == ModeReplay {
+		var resp llmResponse
+		if err := c.cassette.load("llm", req, &resp); err != nil {
+			return "", err
+		}
+		return resp.Content, nil
+	}
+
+	content, err := c.next.CompleteWithSystem(ctx, system, prompt)
+	if err != nil {
+		return "", err
+	}
+	if err := c.cassette.save("llm", req, llmResponse{Stage: llm.StageFrom(ctx), Content: content}); err != nil {
+		return "", fmt.Errorf("record llm response: %w", err)
+	}
+	return content, nil
+}
+
+// toolRequest - ход агента с функциями: вся переписка и описание функций
+type toolRequest struct {
+	Messages []llm.Message `json:"messages"`
+	Tools    []llm.Tool    `json:"tools"`
+}
+
+type toolResponse struct {
+	Stage   string      `json:"stage"`
+	Message llm.Message `json:"message"`
+	// провайдер без функций: при воспроизведении агент так же откатится на обычный запрос
+	Unsupported bool `json:"unsupported,omitempty"`
+}
+
+func (c *LLMClient) CompleteWithTools(ctx context.Context, messages []llm.Message, tools []llm.Tool) (llm.Message, error) {
+	req := toolRequest{Messages: messages, Tools: tools}
+
+	if c.cassette.mode == ModeReplay {
+		var resp toolResponse
+		if err := c.cassette.load("llm", req, &resp); err != nil {
+			return llm.Message{}, err
+		}
+		if resp.Unsupported {
+			return llm.Message{}, llm.ErrToolsUnsupported
+		}
+		return resp.Message, nil
+	}
+
+	msg, err := llm.CompleteWithTools(ctx, c.next, messages, tools)
+	if errors.Is(err, llm.ErrToolsUnsupported) {
+		if err := c.cassette.save("llm", req, toolResponse{Stage: llm.StageFrom(ctx), Unsupported: true}); err != nil {
+			return llm.Message{}, fmt.Errorf("record llm response: %w", err)
+		}
+		return llm.Message{}, llm.ErrToolsUnsupported
+	}
+	if err != nil {
+		return llm.Message{}, err
+	}
+	if err := c.cassette.save("llm", req, toolResponse{Stage: llm.StageFrom(ctx), Message: msg}); err != nil {
+		return llm.Message{}, fmt.Errorf("record llm response: %w", err)
+	}
+	return msg, nil
+}
+
+var _ llm.ToolClient = (*LLMClient)(nil)
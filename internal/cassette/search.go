@@ -0,0 +1,59 @@
+package cassette
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/kitbuilder587/fintech-bot/internal/search"
+)
+
+// SearchClient пишет или воспроизводит ответы search.SearchClient
+type SearchClient struct {
+	next     search.SearchClient
+	cassette *Cassette
+}
+
+// Search оборачивает next; при воспроизведении next не нужен и может быть nil
+func (c *Cassette) Search(next search.SearchClient) *SearchClient {
+	return &SearchClient{next: next, cassette: c}
+}
+
+func (c *SearchClient) Search(ctx context.Context, req search.SearchRequest) (*search.SearchResponse, error) {
+	key := normalize(req)
+
+	if c.cassette.mode == ModeReplay {
+		var resp search.SearchResponse
+		if err := c.cassette.load("search", key, &resp); err != nil {
+			return nil, err
+		}
+		return &resp, nil
+	}
+
+	resp, err := c.next.Search(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.cassette.save("search", key, resp); err != nil {
+		return nil, fmt.Errorf("record search response: %w", err)
+	}
+	return resp, nil
+}
+
+// normalize сортирует домены: порядок источников пользователя не должен менять ключ
+func normalize(req search.SearchRequest) search.SearchRequest {
+	req.IncludeDomains = sorted(req.IncludeDomains)
+	req.ExcludeDomains = sorted(req.ExcludeDomains)
+	return req
+}
+
+func sorted(list []string) []string {
+	if len(list) == 0 {
+		return nil
+	}
+	out := append([]string(nil), list...)
+	sort.Strings(out)
+	return out
+}
+
+var _ search.SearchClient = (*SearchClient)(nil)
@@ -3,7 +3,9 @@ package config
 import (
 	"errors"
 	"os"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/kitbuilder587/fintech-bot/internal/domain"
@@ -13,6 +15,10 @@ var (
 	ErrMissingToken    = errors.New("TELEGRAM_BOT_TOKEN is required")
 	ErrMissingDB       = errors.New("DATABASE_URL is required")
 	ErrInvalidStrategy = errors.New("invalid default strategy")
+	ErrMissingOpenAI   = errors.New("OPENAI_BASE_URL and OPENAI_MODEL are required for the openai provider")
+	ErrInvalidCassette = errors.New("CASSETTE_MODE must be record or replay")
+	ErrMissingSearXNG  = errors.New("SEARXNG_BASE_URL is required for the searxng search provider")
+	ErrMissingBrave    = errors.New("BRAVE_API_KEY is required for the brave search provider")
 )
 
 type Config struct {
@@ -20,10 +26,16 @@ type Config struct {
 	Database        DatabaseConfig
 	LLM             LLMConfig
 	Tavily          TavilyConfig
+	Search          SearchConfig
 	Log             LogConfig
 	Timeouts        TimeoutConfig
 	Cache           CacheConfig
 	RateLimit       RateLimitConfig
+	Ops             OpsConfig
+	Prompts         PromptsConfig
+	Cassette        CassetteConfig
+	Feeds           FeedsConfig
+	Fetch           FetchConfig
 	DefaultStrategy string
 }
 
@@ -32,13 +44,46 @@ type TelegramConfig struct {
 }
 
 type DatabaseConfig struct {
-	URL string
+	URL         string
+	AutoMigrate bool
 }
 
 type LLMConfig struct {
 	Provider   string
+	Providers  []string // цепочка отказоустойчивости, по умолчанию только Provider
+	Breaker    BreakerConfig
+	Retry      RetryConfig
 	OpenRouter OpenRouterConfig
 	GigaChat   GigaChatConfig
+	OpenAI     OpenAICompatConfig
+	Cache      LLMCacheConfig
+	Stages     map[string]StageModel // стадия пайплайна -> своя модель, остальные идут в Providers
+}
+
+// StageModel - провайдер и модель для стадии, пустая Model - модель провайдера по умолчанию
+type StageModel struct {
+	Provider string
+	Model    string
+}
+
+// LLMCacheConfig - кеш ответов LLM на одинаковые запросы
+type LLMCacheConfig struct {
+	Enabled  bool
+	TTL      time.Duration
+	StageTTL map[string]time.Duration // 0 отключает кеш стадии
+}
+
+// RetryConfig - повторы HTTP запросов при сетевых ошибках, 429 и 5xx
+type RetryConfig struct {
+	MaxAttempts int // вместе с первой попыткой
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+	MaxElapsed  time.Duration
+}
+
+type BreakerConfig struct {
+	FailureThreshold int
+	Cooldown         time.Duration
 }
 
 type OpenRouterConfig struct {
@@ -47,6 +92,16 @@ type OpenRouterConfig struct {
 	BaseURL string
 }
 
+// OpenAICompatConfig - любой сервер с OpenAI API (vLLM, Ollama, LM Studio)
+type OpenAICompatConfig struct {
+	BaseURL         string
+	APIKey          string
+	Model           string
+	Headers         map[string]string
+	Timeout         time.Duration // 0 - общий TOTAL_TIMEOUT_SEC
+	DisableJSONMode bool
+}
+
 type GigaChatConfig struct {
 	AuthKey      string
 	ClientID     string
@@ -54,12 +109,57 @@ type GigaChatConfig struct {
 	Scope        string
 	AuthURL      string
 	BaseURL      string
+	Model        string
 }
 
 type TavilyConfig struct {
 	APIKey  string
 	BaseURL string
 	Timeout time.Duration
+	Retry   RetryConfig
+}
+
+// SearchConfig - поисковики по порядку опроса, каждый следующий - запасной
+type SearchConfig struct {
+	Providers []string
+	// глубокое исследование опрашивает эти поисковики параллельно, пустой - как все
+	Federated        []string
+	FederatedTimeout time.Duration // на один поисковик
+	// за сколько вдвое падает оценка результата; у старых новостей финтеха она ниже
+	RecencyHalfLife time.Duration
+	SearXNG         SearXNGConfig
+	Brave           BraveConfig
+}
+
+type SearXNGConfig struct {
+	BaseURL string
+	APIKey  string
+	Timeout time.Duration
+}
+
+type BraveConfig struct {
+	APIKey  string
+	BaseURL string
+	Timeout time.Duration
+}
+
+// FeedsConfig - сбор статей из RSS/Atom лент источников для поискового провайдера local
+type FeedsConfig struct {
+	Enabled         bool
+	Interval        time.Duration
+	MaxItemsPerFeed int
+	FullText        bool
+}
+
+// FetchConfig - загрузка полного текста страниц для глубокой стратегии
+type FetchConfig struct {
+	Enabled     bool
+	TopK        int // сколько первых результатов скачивать
+	Concurrency int
+	HostDelay   time.Duration
+	Timeout     time.Duration
+	MaxChars    int
+	CacheTTL    time.Duration
 }
 
 type LogConfig struct {
@@ -67,8 +167,9 @@ type LogConfig struct {
 }
 
 type TimeoutConfig struct {
-	Source time.Duration
-	Total  time.Duration
+	Source   time.Duration
+	Total    time.Duration
+	Shutdown time.Duration
 }
 
 type CacheConfig struct {
@@ -80,16 +181,71 @@ type RateLimitConfig struct {
 	RequestsPerMinute int
 }
 
+type OpsConfig struct {
+	Addr        string
+	EnablePprof bool
+	ProbeLLM    bool // платные пробы в /ready, по умолчанию выключены
+	ProbeSearch bool
+	ProbeTTL    time.Duration
+}
+
+// PromptsConfig - шаблоны промптов: <Dir>/<Version>/*.tmpl
+type PromptsConfig struct {
+	Dir            string
+	Version        string
+	Language       string
+	ReloadInterval time.Duration // 0 - только по SIGHUP
+}
+
+// CassetteConfig - запись и воспроизведение ответов LLM и поиска, пустой Dir отключает
+type CassetteConfig struct {
+	Dir  string
+	Mode string // record или replay
+}
+
 func Load() (*Config, error) {
-	cfg := &Config{
+	cfg := load()
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// LoadForMigrate - конфиг для `bot migrate`, там нужен только DATABASE_URL
+func LoadForMigrate() (*Config, error) {
+	cfg := load()
+	if cfg.Database.URL == "" {
+		return nil, ErrMissingDB
+	}
+	return cfg, nil
+}
+
+func load() *Config {
+	provider := getEnvOrDefault("LLM_PROVIDER", "mock")
+
+	return &Config{
 		Telegram: TelegramConfig{
 			Token: os.Getenv("TELEGRAM_BOT_TOKEN"),
 		},
 		Database: DatabaseConfig{
-			URL: os.Getenv("DATABASE_URL"),
+			URL:         os.Getenv("DATABASE_URL"),
+			AutoMigrate: getEnvBoolOrDefault("DB_AUTO_MIGRATE", true),
 		},
 		LLM: LLMConfig{
-			Provider: getEnvOrDefault("LLM_PROVIDER", "mock"),
+			Provider:  provider,
+			Providers: getEnvListOrDefault("LLM_PROVIDERS", []string{provider}),
+			Breaker: BreakerConfig{
+				FailureThreshold: getEnvIntOrDefault("LLM_BREAKER_THRESHOLD", 3),
+				Cooldown:         time.Duration(getEnvIntOrDefault("LLM_BREAKER_COOLDOWN_SEC", 30)) * time.Second,
+			},
+			Retry: RetryConfig{
+				MaxAttempts: getEnvIntOrDefault("LLM_RETRY_MAX_ATTEMPTS", 3),
+				BaseDelay:   time.Duration(getEnvIntOrDefault("LLM_RETRY_BASE_DELAY_MS", 500)) * time.Millisecond,
+				MaxDelay:    time.Duration(getEnvIntOrDefault("LLM_RETRY_MAX_DELAY_SEC", 8)) * time.Second,
+				MaxElapsed:  time.Duration(getEnvIntOrDefault("LLM_RETRY_MAX_ELAPSED_SEC", 20)) * time.Second,
+			},
 			OpenRouter: OpenRouterConfig{
 				APIKey:  os.Getenv("OPENROUTER_API_KEY"),
 				Model:   getEnvOrDefault("OPENROUTER_MODEL", "deepseek/deepseek-chat"),
@@ -102,19 +258,57 @@ func Load() (*Config, error) {
 				Scope:        getEnvOrDefault("GIGACHAT_SCOPE", "GIGACHAT_API_PERS"),
 				AuthURL:      getEnvOrDefault("GIGACHAT_AUTH_URL", "https://ngw.devices.sberbank.ru:9443/api/v2/oauth"),
 				BaseURL:      getEnvOrDefault("GIGACHAT_BASE_URL", "https://gigachat.devices.sberbank.ru/api/v1"),
+				Model:        getEnvOrDefault("GIGACHAT_MODEL", "GigaChat"),
+			},
+			Cache: LLMCacheConfig{
+				Enabled:  getEnvBoolOrDefault("LLM_CACHE_ENABLED", true),
+				TTL:      time.Duration(getEnvIntOrDefault("LLM_CACHE_TTL_SEC", 21600)) * time.Second,
+				StageTTL: getEnvSecondsMap("LLM_CACHE_STAGE_TTL_SEC", defaultLLMCacheStageTTL),
+			},
+			Stages: getEnvStageModels("LLM_STAGE_MODELS"),
+			OpenAI: OpenAICompatConfig{
+				BaseURL:         os.Getenv("OPENAI_BASE_URL"),
+				APIKey:          os.Getenv("OPENAI_API_KEY"),
+				Model:           os.Getenv("OPENAI_MODEL"),
+				Headers:         getEnvHeaders("OPENAI_HEADERS"),
+				Timeout:         time.Duration(getEnvIntOrDefault("OPENAI_TIMEOUT_SEC", 0)) * time.Second,
+				DisableJSONMode: !getEnvBoolOrDefault("OPENAI_JSON_MODE", true),
 			},
 		},
 		Tavily: TavilyConfig{
 			APIKey:  os.Getenv("TAVILY_API_KEY"),
 			BaseURL: getEnvOrDefault("TAVILY_BASE_URL", "https://api.tavily.com"),
 			Timeout: time.Duration(getEnvIntOrDefault("TAVILY_TIMEOUT_SEC", 30)) * time.Second,
+			Retry: RetryConfig{
+				MaxAttempts: getEnvIntOrDefault("TAVILY_RETRY_MAX_ATTEMPTS", 4),
+				BaseDelay:   time.Duration(getEnvIntOrDefault("TAVILY_RETRY_BASE_DELAY_MS", 1000)) * time.Millisecond,
+				MaxDelay:    time.Duration(getEnvIntOrDefault("TAVILY_RETRY_MAX_DELAY_SEC", 4)) * time.Second,
+				MaxElapsed:  time.Duration(getEnvIntOrDefault("TAVILY_RETRY_MAX_ELAPSED_SEC", 15)) * time.Second,
+			},
+		},
+		Search: SearchConfig{
+			Providers:        getEnvListOrDefault("SEARCH_PROVIDERS", []string{"tavily"}),
+			Federated:        getEnvListOrDefault("SEARCH_FEDERATED_PROVIDERS", nil),
+			FederatedTimeout: time.Duration(getEnvIntOrDefault("SEARCH_FEDERATED_TIMEOUT_SEC", 10)) * time.Second,
+			RecencyHalfLife:  time.Duration(getEnvIntOrDefault("SEARCH_RECENCY_HALF_LIFE_DAYS", 180)) * 24 * time.Hour,
+			SearXNG: SearXNGConfig{
+				BaseURL: os.Getenv("SEARXNG_BASE_URL"),
+				APIKey:  os.Getenv("SEARXNG_API_KEY"),
+				Timeout: time.Duration(getEnvIntOrDefault("SEARXNG_TIMEOUT_SEC", 30)) * time.Second,
+			},
+			Brave: BraveConfig{
+				APIKey:  os.Getenv("BRAVE_API_KEY"),
+				BaseURL: getEnvOrDefault("BRAVE_BASE_URL", "https://api.search.brave.com/res/v1"),
+				Timeout: time.Duration(getEnvIntOrDefault("BRAVE_TIMEOUT_SEC", 30)) * time.Second,
+			},
 		},
 		Log: LogConfig{
 			Level: getEnvOrDefault("LOG_LEVEL", "info"),
 		},
 		Timeouts: TimeoutConfig{
-			Source: time.Duration(getEnvIntOrDefault("SOURCE_TIMEOUT_SEC", 15)) * time.Second,
-			Total:  time.Duration(getEnvIntOrDefault("TOTAL_TIMEOUT_SEC", 60)) * time.Second,
+			Source:   time.Duration(getEnvIntOrDefault("SOURCE_TIMEOUT_SEC", 15)) * time.Second,
+			Total:    time.Duration(getEnvIntOrDefault("TOTAL_TIMEOUT_SEC", 60)) * time.Second,
+			Shutdown: time.Duration(getEnvIntOrDefault("SHUTDOWN_TIMEOUT_SEC", 30)) * time.Second,
 		},
 		Cache: CacheConfig{
 			Type: getEnvOrDefault("CACHE_TYPE", "memory"),
@@ -123,14 +317,40 @@ func Load() (*Config, error) {
 		RateLimit: RateLimitConfig{
 			RequestsPerMinute: getEnvIntOrDefault("RATE_LIMIT_PER_MINUTE", 10),
 		},
+		Ops: OpsConfig{
+			Addr:        getEnvOrDefault("OPS_ADDR", ":8080"),
+			EnablePprof: getEnvBoolOrDefault("OPS_PPROF", false),
+			ProbeLLM:    getEnvBoolOrDefault("OPS_PROBE_LLM", false),
+			ProbeSearch: getEnvBoolOrDefault("OPS_PROBE_SEARCH", false),
+			ProbeTTL:    time.Duration(getEnvIntOrDefault("OPS_PROBE_TTL_SEC", 300)) * time.Second,
+		},
+		Prompts: PromptsConfig{
+			Dir:            getEnvOrDefault("PROMPTS_DIR", "configs/prompts"),
+			Version:        getEnvOrDefault("PROMPTS_VERSION", "v2"),
+			Language:       getEnvOrDefault("PROMPTS_LANGUAGE", "Russian"),
+			ReloadInterval: time.Duration(getEnvIntOrDefault("PROMPTS_RELOAD_SEC", 5)) * time.Second,
+		},
+		Cassette: CassetteConfig{
+			Dir:  os.Getenv("CASSETTE_DIR"),
+			Mode: getEnvOrDefault("CASSETTE_MODE", "replay"),
+		},
+		Feeds: FeedsConfig{
+			Enabled:         getEnvBoolOrDefault("FEEDS_ENABLED", false),
+			Interval:        time.Duration(getEnvIntOrDefault("FEEDS_POLL_INTERVAL_MIN", 60)) * time.Minute,
+			MaxItemsPerFeed: getEnvIntOrDefault("FEEDS_MAX_ITEMS", 20),
+			FullText:        getEnvBoolOrDefault("FEEDS_FULL_TEXT", true),
+		},
+		Fetch: FetchConfig{
+			Enabled:     getEnvBoolOrDefault("FETCH_ENABLED", true),
+			TopK:        getEnvIntOrDefault("FETCH_TOP_K", 5),
+			Concurrency: getEnvIntOrDefault("FETCH_CONCURRENCY", 4),
+			HostDelay:   time.Duration(getEnvIntOrDefault("FETCH_HOST_DELAY_MS", 1000)) * time.Millisecond,
+			Timeout:     time.Duration(getEnvIntOrDefault("FETCH_TIMEOUT_SEC", 10)) * time.Second,
+			MaxChars:    getEnvIntOrDefault("FETCH_MAX_CHARS", 6000),
+			CacheTTL:    time.Duration(getEnvIntOrDefault("FETCH_CACHE_TTL_MIN", 24*60)) * time.Minute,
+		},
 		DefaultStrategy: getEnvOrDefault("DEFAULT_STRATEGY", "standard"),
 	}
-
-	if err := cfg.Validate(); err != nil {
-		return nil, err
-	}
-
-	return cfg, nil
 }
 
 func (c *Config) Validate() error {
@@ -143,6 +363,28 @@ func (c *Config) Validate() error {
 	if !domain.StrategyType(c.DefaultStrategy).IsValid() {
 		return ErrInvalidStrategy
 	}
+	// адрес и модель локального сервера угадать нельзя, лучше упасть на старте
+	for _, p := range c.LLM.Providers {
+		if p == "openai" && (c.LLM.OpenAI.BaseURL == "" || c.LLM.OpenAI.Model == "") {
+			return ErrMissingOpenAI
+		}
+	}
+	for _, s := range c.LLM.Stages {
+		if s.Provider == "openai" && c.LLM.OpenAI.BaseURL == "" {
+			return ErrMissingOpenAI
+		}
+	}
+	for _, p := range slices.Concat(c.Search.Providers, c.Search.Federated) {
+		switch {
+		case p == "searxng" && c.Search.SearXNG.BaseURL == "":
+			return ErrMissingSearXNG
+		case p == "brave" && c.Search.Brave.APIKey == "":
+			return ErrMissingBrave
+		}
+	}
+	if c.Cassette.Dir != "" && c.Cassette.Mode != "record" && c.Cassette.Mode != "replay" {
+		return ErrInvalidCassette
+	}
 	return nil
 }
 
@@ -161,3 +403,86 @@ func getEnvIntOrDefault(key string, defaultValue int) int {
 	}
 	return defaultValue
 }
+
+// getEnvListOrDefault читает список через запятую, пустые элементы пропускает
+func getEnvListOrDefault(key string, defaultValue []string) []string {
+	var list []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	if len(list) == 0 {
+		return defaultValue
+	}
+	return list
+}
+
+// getEnvHeaders читает заголовки в виде "Name: value; Other: value"
+func getEnvHeaders(key string) map[string]string {
+	var headers map[string]string
+	for _, pair := range strings.Split(os.Getenv(key), ";") {
+		name, value, ok := strings.Cut(pair, ":")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			continue
+		}
+		if headers == nil {
+			headers = make(map[string]string)
+		}
+		headers[name] = strings.TrimSpace(value)
+	}
+	return headers
+}
+
+// По умолчанию кешируются только expand, critic и extraction: ответы агентов и синтез
+// из кеша отдавали бы на одинаковый вопрос один и тот же текст по часам.
+// Включить обратно можно через LLM_CACHE_STAGE_TTL_SEC, например "agent=3600"
+const defaultLLMCacheStageTTL = "analyze=0,synthesize=0,improve=0,agent=0"
+
+// getEnvSecondsMap читает "critic=3600,improve=0" в длительности по ключам поверх defaultValue,
+// кривые пары пропускает
+func getEnvSecondsMap(key, defaultValue string) map[string]time.Duration {
+	var result map[string]time.Duration
+	for _, pair := range strings.Split(defaultValue+","+os.Getenv(key), ",") {
+		name, value, ok := strings.Cut(pair, "=")
+		name = strings.TrimSpace(name)
+		seconds, err := strconv.Atoi(strings.TrimSpace(value))
+		if !ok || name == "" || err != nil || seconds < 0 {
+			continue
+		}
+		if result == nil {
+			result = make(map[string]time.Duration)
+		}
+		result[name] = time.Duration(seconds) * time.Second
+	}
+	return result
+}
+
+func getEnvBoolOrDefault(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if boolVal, err := strconv.ParseBool(value); err == nil {
+			return boolVal
+		}
+	}
+	return defaultValue
+}
+
+// getEnvStageModels читает "expand=openrouter:openai/gpt-4o-mini,critic=gigachat" в модели по стадиям.
+// Модель отделяется первым двоеточием, так что "openai:qwen2.5:7b" тоже работает
+func getEnvStageModels(key string) map[string]StageModel {
+	var result map[string]StageModel
+	for _, pair := range strings.Split(os.Getenv(key), ",") {
+		stage, route, ok := strings.Cut(pair, "=")
+		stage = strings.TrimSpace(stage)
+		provider, model, _ := strings.Cut(strings.TrimSpace(route), ":")
+		if !ok || stage == "" || provider == "" {
+			continue
+		}
+		if result == nil {
+			result = make(map[string]StageModel)
+		}
+		result[stage] = StageModel{Provider: provider, Model: strings.TrimSpace(model)}
+	}
+	return result
+}
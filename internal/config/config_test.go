@@ -2,7 +2,9 @@ package config
 
 import (
 	"os"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestLoad(t *testing.T) {
@@ -85,9 +87,103 @@ func TestDefaults(t *testing.T) {
 	if cfg.Timeouts.Total.Seconds() != 60 {
 		t.Errorf("Timeouts.Total = %v, want 60s", cfg.Timeouts.Total)
 	}
+	if cfg.Timeouts.Shutdown.Seconds() != 30 {
+		t.Errorf("Timeouts.Shutdown = %v, want 30s", cfg.Timeouts.Shutdown)
+	}
 	if cfg.LLM.Provider != "mock" {
 		t.Errorf("LLM.Provider = %v, want mock", cfg.LLM.Provider)
 	}
+	if len(cfg.LLM.Providers) != 1 || cfg.LLM.Providers[0] != "mock" {
+		t.Errorf("LLM.Providers = %v, want [mock]", cfg.LLM.Providers)
+	}
+	if cfg.LLM.Breaker.FailureThreshold != 3 || cfg.LLM.Breaker.Cooldown.Seconds() != 30 {
+		t.Errorf("LLM.Breaker = %+v, want threshold 3 and 30s cooldown", cfg.LLM.Breaker)
+	}
+	if !cfg.Database.AutoMigrate {
+		t.Error("Database.AutoMigrate = false, want true")
+	}
+	if cfg.Ops.Addr != ":8080" {
+		t.Errorf("Ops.Addr = %v, want :8080", cfg.Ops.Addr)
+	}
+	if cfg.Ops.EnablePprof || cfg.Ops.ProbeLLM || cfg.Ops.ProbeSearch {
+		t.Error("pprof and paid readiness probes should be disabled by default")
+	}
+	if cfg.LLM.Retry.MaxAttempts != 3 || cfg.Tavily.Retry.MaxAttempts != 4 {
+		t.Errorf("retry attempts = %d/%d, want 3/4", cfg.LLM.Retry.MaxAttempts, cfg.Tavily.Retry.MaxAttempts)
+	}
+	if cfg.Prompts.Dir != "configs/prompts" || cfg.Prompts.Version != "v2" || cfg.Prompts.Language != "Russian" {
+		t.Errorf("Prompts = %+v, want configs/prompts v2 in Russian", cfg.Prompts)
+	}
+}
+
+func TestLoadForMigrate(t *testing.T) {
+	clearEnvVars()
+	defer clearEnvVars()
+
+	if _, err := LoadForMigrate(); err != ErrMissingDB {
+		t.Errorf("LoadForMigrate() error = %v, want %v", err, ErrMissingDB)
+	}
+
+	// токен телеграма для миграций не нужен
+	os.Setenv("DATABASE_URL", "postgres://localhost:5432/test")
+	cfg, err := LoadForMigrate()
+	if err != nil {
+		t.Fatalf("LoadForMigrate() error = %v", err)
+	}
+	if cfg.Database.URL != "postgres://localhost:5432/test" {
+		t.Errorf("Database.URL = %v", cfg.Database.URL)
+	}
+}
+
+func TestGetEnvBoolOrDefault(t *testing.T) {
+	tests := []struct {
+		name       string
+		envValue   string
+		defaultVal bool
+		want       bool
+	}{
+		{"true", "true", false, true},
+		{"zero", "0", true, false},
+		{"empty string", "", true, true},
+		{"invalid bool", "maybe", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Setenv("TEST_BOOL", tt.envValue)
+			defer os.Unsetenv("TEST_BOOL")
+
+			got := getEnvBoolOrDefault("TEST_BOOL", tt.defaultVal)
+			if got != tt.want {
+				t.Errorf("getEnvBoolOrDefault() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetEnvListOrDefault(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  []string
+	}{
+		{"unset", "", []string{"default"}},
+		{"single", "gigachat", []string{"gigachat"}},
+		{"ordered list", "openrouter, gigachat", []string{"openrouter", "gigachat"}},
+		{"only separators", " , ,", []string{"default"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Setenv("TEST_LIST", tt.value)
+			defer os.Unsetenv("TEST_LIST")
+
+			got := getEnvListOrDefault("TEST_LIST", []string{"default"})
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("getEnvListOrDefault() = %v, want %v", got, tt.want)
+			}
+		})
+	}
 }
 
 func TestGetEnvIntOrDefault(t *testing.T) {
@@ -205,22 +301,277 @@ func TestValidate_ValidStrategies(t *testing.T) {
 	}
 }
 
+func TestLoad_OpenAIProvider(t *testing.T) {
+	clearEnvVars()
+	defer clearEnvVars()
+	os.Setenv("TELEGRAM_BOT_TOKEN", "test_token")
+	os.Setenv("DATABASE_URL", "postgres://localhost:5432/test")
+	os.Setenv("LLM_PROVIDER", "openai")
+
+	if _, err := Load(); err != ErrMissingOpenAI {
+		t.Fatalf("Load() error = %v, want %v", err, ErrMissingOpenAI)
+	}
+
+	os.Setenv("OPENAI_BASE_URL", "http://vllm:8000/v1")
+	os.Setenv("OPENAI_MODEL", "qwen2.5-14b-instruct")
+	os.Setenv("OPENAI_HEADERS", "X-Tenant: risk; X-Trace: a:b ;broken")
+	os.Setenv("OPENAI_JSON_MODE", "false")
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	openai := cfg.LLM.OpenAI
+	if openai.BaseURL != "http://vllm:8000/v1" || openai.Model != "qwen2.5-14b-instruct" || !openai.DisableJSONMode {
+		t.Errorf("LLM.OpenAI = %+v", openai)
+	}
+	if len(openai.Headers) != 2 || openai.Headers["X-Tenant"] != "risk" || openai.Headers["X-Trace"] != "a:b" {
+		t.Errorf("LLM.OpenAI.Headers = %v, want X-Tenant and X-Trace", openai.Headers)
+	}
+}
+
+func TestLoad_StageModels(t *testing.T) {
+	clearEnvVars()
+	defer clearEnvVars()
+	os.Setenv("TELEGRAM_BOT_TOKEN", "test_token")
+	os.Setenv("DATABASE_URL", "postgres://localhost:5432/test")
+	os.Setenv("LLM_STAGE_MODELS", "expand=openrouter:openai/gpt-4o-mini, critic=gigachat ,broken,=openrouter:x,agent=openai:qwen2.5:7b")
+
+	if _, err := Load(); err != ErrMissingOpenAI {
+		t.Fatalf("Load() error = %v, want %v for an openai stage without base URL", err, ErrMissingOpenAI)
+	}
+
+	os.Setenv("OPENAI_BASE_URL", "http://vllm:8000/v1")
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	want := map[string]StageModel{
+		"expand": {Provider: "openrouter", Model: "openai/gpt-4o-mini"},
+		"critic": {Provider: "gigachat"},
+		"agent":  {Provider: "openai", Model: "qwen2.5:7b"},
+	}
+	if len(cfg.LLM.Stages) != len(want) {
+		t.Fatalf("LLM.Stages = %v, want %v", cfg.LLM.Stages, want)
+	}
+	for stage, m := range want {
+		if cfg.LLM.Stages[stage] != m {
+			t.Errorf("LLM.Stages[%s] = %+v, want %+v", stage, cfg.LLM.Stages[stage], m)
+		}
+	}
+}
+
+func TestLoad_LLMCacheStageTTL(t *testing.T) {
+	clearEnvVars()
+	defer clearEnvVars()
+	os.Setenv("TELEGRAM_BOT_TOKEN", "test_token")
+	os.Setenv("DATABASE_URL", "postgres://localhost:5432/test")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	for _, stage := range []string{"analyze", "synthesize", "improve", "agent"} {
+		if ttl, ok := cfg.LLM.Cache.StageTTL[stage]; !ok || ttl != 0 {
+			t.Errorf("StageTTL[%s] = %v (set %v), want 0 by default", stage, ttl, ok)
+		}
+	}
+	for _, stage := range []string{"expand", "critic", "extraction"} {
+		if _, ok := cfg.LLM.Cache.StageTTL[stage]; ok {
+			t.Errorf("StageTTL[%s] is set, want the common TTL", stage)
+		}
+	}
+
+	os.Setenv("LLM_CACHE_STAGE_TTL_SEC", "agent=3600, critic=0")
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	want := map[string]time.Duration{
+		"analyze":    0,
+		"synthesize": 0,
+		"improve":    0,
+		"agent":      time.Hour,
+		"critic":     0,
+	}
+	if len(cfg.LLM.Cache.StageTTL) != len(want) {
+		t.Fatalf("StageTTL = %v, want %v", cfg.LLM.Cache.StageTTL, want)
+	}
+	for stage, ttl := range want {
+		if cfg.LLM.Cache.StageTTL[stage] != ttl {
+			t.Errorf("StageTTL[%s] = %v, want %v", stage, cfg.LLM.Cache.StageTTL[stage], ttl)
+		}
+	}
+}
+
+func TestLoad_Cassette(t *testing.T) {
+	clearEnvVars()
+	defer clearEnvVars()
+	os.Setenv("TELEGRAM_BOT_TOKEN", "test_token")
+	os.Setenv("DATABASE_URL", "postgres://localhost:5432/test")
+	os.Setenv("CASSETTE_DIR", "testdata/session")
+	os.Setenv("CASSETTE_MODE", "rewind")
+
+	if _, err := Load(); err != ErrInvalidCassette {
+		t.Fatalf("Load() error = %v, want %v", err, ErrInvalidCassette)
+	}
+
+	os.Setenv("CASSETTE_MODE", "record")
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Cassette.Dir != "testdata/session" || cfg.Cassette.Mode != "record" {
+		t.Errorf("Cassette = %+v", cfg.Cassette)
+	}
+}
+
+func TestLoad_SearchProviders(t *testing.T) {
+	clearEnvVars()
+	defer clearEnvVars()
+	os.Setenv("TELEGRAM_BOT_TOKEN", "test_token")
+	os.Setenv("DATABASE_URL", "postgres://localhost:5432/test")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(cfg.Search.Providers) != 1 || cfg.Search.Providers[0] != "tavily" {
+		t.Errorf("Search.Providers = %v, want [tavily] by default", cfg.Search.Providers)
+	}
+
+	os.Setenv("SEARCH_PROVIDERS", "tavily, searxng, brave")
+	if _, err := Load(); err != ErrMissingSearXNG {
+		t.Fatalf("Load() error = %v, want %v", err, ErrMissingSearXNG)
+	}
+	os.Setenv("SEARXNG_BASE_URL", "http://searxng:8080")
+	if _, err := Load(); err != ErrMissingBrave {
+		t.Fatalf("Load() error = %v, want %v", err, ErrMissingBrave)
+	}
+	os.Setenv("BRAVE_API_KEY", "key")
+
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(cfg.Search.Providers) != 3 || cfg.Search.Providers[1] != "searxng" {
+		t.Errorf("Search.Providers = %v", cfg.Search.Providers)
+	}
+	if len(cfg.Search.Federated) != 0 || cfg.Search.FederatedTimeout != 10*time.Second {
+		t.Errorf("federated = %v, timeout %v; want off with 10s", cfg.Search.Federated, cfg.Search.FederatedTimeout)
+	}
+
+	os.Setenv("SEARCH_PROVIDERS", "tavily")
+	os.Unsetenv("BRAVE_API_KEY")
+	os.Setenv("SEARCH_FEDERATED_PROVIDERS", "tavily,brave")
+	if _, err := Load(); err != ErrMissingBrave {
+		t.Errorf("Load() error = %v, want %v for a federated provider", err, ErrMissingBrave)
+	}
+}
+
+func TestLoad_Feeds(t *testing.T) {
+	clearEnvVars()
+	defer clearEnvVars()
+	os.Setenv("TELEGRAM_BOT_TOKEN", "test_token")
+	os.Setenv("DATABASE_URL", "postgres://localhost:5432/test")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Feeds.Enabled || cfg.Feeds.Interval != time.Hour || !cfg.Feeds.FullText {
+		t.Errorf("Feeds = %+v, want disabled, hourly, with full text", cfg.Feeds)
+	}
+
+	os.Setenv("FEEDS_ENABLED", "true")
+	os.Setenv("FEEDS_POLL_INTERVAL_MIN", "15")
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if !cfg.Feeds.Enabled || cfg.Feeds.Interval != 15*time.Minute {
+		t.Errorf("Feeds = %+v, want enabled every 15 minutes", cfg.Feeds)
+	}
+}
+
+func TestLoad_Fetch(t *testing.T) {
+	clearEnvVars()
+	defer clearEnvVars()
+	os.Setenv("TELEGRAM_BOT_TOKEN", "test_token")
+	os.Setenv("DATABASE_URL", "postgres://localhost:5432/test")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if !cfg.Fetch.Enabled || cfg.Fetch.TopK != 5 || cfg.Fetch.HostDelay != time.Second {
+		t.Errorf("Fetch = %+v, want enabled, top 5, 1s host delay", cfg.Fetch)
+	}
+
+	os.Setenv("FETCH_ENABLED", "false")
+	os.Setenv("FETCH_TOP_K", "3")
+	os.Setenv("FETCH_HOST_DELAY_MS", "250")
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Fetch.Enabled || cfg.Fetch.TopK != 3 || cfg.Fetch.HostDelay != 250*time.Millisecond {
+		t.Errorf("Fetch = %+v, want disabled, top 3, 250ms host delay", cfg.Fetch)
+	}
+}
+
 func clearEnvVars() {
 	envVars := []string{
 		"TELEGRAM_BOT_TOKEN",
 		"DATABASE_URL",
+		"DB_AUTO_MIGRATE",
 		"LLM_PROVIDER",
+		"LLM_PROVIDERS",
+		"LLM_BREAKER_THRESHOLD",
+		"LLM_BREAKER_COOLDOWN_SEC",
 		"OPENROUTER_API_KEY",
 		"OPENROUTER_MODEL",
 		"GIGACHAT_CLIENT_ID",
 		"GIGACHAT_CLIENT_SECRET",
+		"OPENAI_BASE_URL",
+		"OPENAI_API_KEY",
+		"OPENAI_MODEL",
+		"OPENAI_HEADERS",
+		"OPENAI_TIMEOUT_SEC",
+		"OPENAI_JSON_MODE",
 		"TAVILY_API_KEY",
 		"LOG_LEVEL",
 		"SOURCE_TIMEOUT_SEC",
 		"TOTAL_TIMEOUT_SEC",
+		"SHUTDOWN_TIMEOUT_SEC",
 		"CACHE_TYPE",
 		"CACHE_TTL_SEC",
 		"DEFAULT_STRATEGY",
+		"OPS_ADDR",
+		"OPS_PPROF",
+		"OPS_PROBE_LLM",
+		"OPS_PROBE_SEARCH",
+		"LLM_RETRY_MAX_ATTEMPTS",
+		"TAVILY_RETRY_MAX_ATTEMPTS",
+		"PROMPTS_DIR",
+		"PROMPTS_VERSION",
+		"PROMPTS_LANGUAGE",
+		"PROMPTS_RELOAD_SEC",
+		"LLM_STAGE_MODELS",
+		"LLM_CACHE_TTL_SEC",
+		"LLM_CACHE_STAGE_TTL_SEC",
+		"CASSETTE_DIR",
+		"CASSETTE_MODE",
+		"SEARCH_PROVIDERS",
+		"SEARXNG_BASE_URL",
+		"BRAVE_API_KEY",
+		"SEARCH_FEDERATED_PROVIDERS",
+		"FEEDS_ENABLED",
+		"FEEDS_POLL_INTERVAL_MIN",
+		"FETCH_ENABLED",
+		"FETCH_TOP_K",
+		"FETCH_HOST_DELAY_MS",
 	}
 	for _, v := range envVars {
 		os.Unsetenv(v)
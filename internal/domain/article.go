@@ -0,0 +1,41 @@
+package domain
+
+import "time"
+
+// Feed - RSS/Atom лента источника. ETag и LastModified - для условных запросов,
+// чтобы не качать ленту, которая не менялась
+type Feed struct {
+	ID           int64
+	URL          string
+	Domain       string // домен источника, по нему статьи фильтруются как IncludeDomains
+	ETag         string
+	LastModified string
+	PolledAt     time.Time
+	CreatedAt    time.Time
+}
+
+// Article - статья из ленты с полным текстом
+type Article struct {
+	ID          int64
+	FeedID      int64
+	Domain      string
+	URL         string
+	Title       string
+	Content     string
+	PublishedAt time.Time // нулевое, если лента даты не дала
+	FetchedAt   time.Time
+}
+
+// ArticleQuery - полнотекстовый поиск по статьям
+type ArticleQuery struct {
+	Text    string
+	Domains []string  // пусто - все домены
+	Since   time.Time // нулевое - без ограничения по дате
+	Limit   int
+}
+
+// ArticleHit - найденная статья и ее релевантность (ts_rank, не нормирована)
+type ArticleHit struct {
+	Article
+	Rank float64
+}
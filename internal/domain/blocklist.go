@@ -0,0 +1,48 @@
+package domain
+
+import (
+	"strings"
+	"unicode"
+)
+
+const (
+	MaxBlockedDomainsPerUser = 100
+)
+
+// NormalizeDomain приводит ввод пользователя к домену для блок-листа:
+// "https://www.Example.com/page" и "example.com" дают "example.com"
+func NormalizeDomain(input string) (string, error) {
+	host := Host(input)
+	if !strings.Contains(host, ".") || strings.HasPrefix(host, ".") || strings.Contains(host, "..") {
+		return "", ErrInvalidDomain
+	}
+	for _, r := range host {
+		if r != '.' && r != '-' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			return "", ErrInvalidDomain
+		}
+	}
+	return host, nil
+}
+
+// DomainMatches - host совпадает с domain или является его поддоменом
+func DomainMatches(host, domain string) bool {
+	host = strings.TrimPrefix(strings.ToLower(host), "www.")
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
+
+// IsBlocked - URL ведет на заблокированный домен или его поддомен
+func IsBlocked(rawURL string, blocked []string) bool {
+	if len(blocked) == 0 {
+		return false
+	}
+	host := Host(rawURL)
+	if host == "" {
+		return false
+	}
+	for _, d := range blocked {
+		if DomainMatches(host, d) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,62 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNormalizeDomain(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{"example.com", "example.com", false},
+		{"  WWW.Example.COM  ", "example.com", false},
+		{"https://www.contentfarm.io/articles/1?x=1", "contentfarm.io", false},
+		{"http://news.example.com:8080", "news.example.com", false},
+		{"банки.рф", "банки.рф", false},
+		{"", "", true},
+		{"localhost", "", true},
+		{"exa mple.com", "", true},
+		{"example..com", "", true},
+		{"ex_ample.com", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := NormalizeDomain(tt.input)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidDomain) {
+					t.Errorf("NormalizeDomain(%q) error = %v, want ErrInvalidDomain", tt.input, err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("NormalizeDomain(%q) = %q, %v; want %q", tt.input, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsBlocked(t *testing.T) {
+	blocked := []string{"contentfarm.io", "spam.example.com"}
+
+	tests := []struct {
+		url  string
+		want bool
+	}{
+		{"https://contentfarm.io/post", true},
+		{"https://www.contentfarm.io/post", true},
+		{"https://blog.contentfarm.io/post", true},
+		{"https://notcontentfarm.io/post", false},
+		{"https://spam.example.com/x", true},
+		{"https://example.com/x", false},
+		{"not a url", false},
+	}
+	for _, tt := range tests {
+		if got := IsBlocked(tt.url, blocked); got != tt.want {
+			t.Errorf("IsBlocked(%q) = %v, want %v", tt.url, got, tt.want)
+		}
+	}
+}
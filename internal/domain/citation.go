@@ -0,0 +1,44 @@
+package domain
+
+import (
+	"regexp"
+	"strconv"
+)
+
+// [S3], [S3 p.12], [S3, p. 12], [S3 стр. 12] - модели пишут страницу по-разному
+var citationRe = regexp.MustCompile(`\[S(\d+)(?:,?\s*(?:p|стр)\.\s*(\d+))?\]`)
+
+// Citation - ссылка ответа на источник, Page - страница документа, 0 если не указана
+type Citation struct {
+	Source int
+	Page   int
+}
+
+// ParseCitations - ссылки в порядке появления в тексте, без повторов
+func ParseCitations(text string) []Citation {
+	var out []Citation
+	seen := make(map[Citation]bool)
+	for _, m := range citationRe.FindAllStringSubmatch(text, -1) {
+		var c Citation
+		c.Source, _ = strconv.Atoi(m[1])
+		if m[2] != "" {
+			c.Page, _ = strconv.Atoi(m[2])
+		}
+		if !seen[c] {
+			seen[c] = true
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// CitedPages - страницы, на которые ссылается текст, по номеру источника
+func CitedPages(text string) map[int][]int {
+	pages := make(map[int][]int)
+	for _, c := range ParseCitations(text) {
+		if c.Page > 0 {
+			pages[c.Source] = append(pages[c.Source], c.Page)
+		}
+	}
+	return pages
+}
@@ -0,0 +1,24 @@
+package domain
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseCitations(t *testing.T) {
+	text := "Рынок вырос [S1], объем BNPL [S3 p.12] и [S3, p. 14]; прогноз [S2 стр. 5], повтор [S3 p.12] [S1]"
+
+	got := ParseCitations(text)
+	want := []Citation{{1, 0}, {3, 12}, {3, 14}, {2, 5}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseCitations() = %+v, want %+v", got, want)
+	}
+}
+
+func TestCitedPages(t *testing.T) {
+	got := CitedPages("[S1] [S3 p.12] [S3 p.2] [S2] [S3 p.12]")
+	want := map[int][]int{3: {12, 2}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("CitedPages() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,20 @@
+package domain
+
+import "time"
+
+// MaxConversationTurns - сколько предыдущих ответов берется в контекст уточняющего вопроса
+const MaxConversationTurns = 3
+
+// ConversationTurn - вопрос пользователя и ответ бота в чате.
+// По MessageIDs находим ход, когда пользователь отвечает реплаем на сообщение бота
+type ConversationTurn struct {
+	ID         int64
+	ChatID     int64
+	UserID     int64
+	ParentID   int64 // ход, уточнением которого был вопрос; 0 если это новый вопрос
+	MessageIDs []int // сообщения ответа (длинный ответ разбит на несколько)
+	Question   string
+	Answer     string
+	Sources    []SourceRef
+	CreatedAt  time.Time
+}
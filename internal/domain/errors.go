@@ -15,6 +15,13 @@ var (
 	ErrSourceLimitReached = errors.New("source limit reached")
 )
 
+var (
+	ErrInvalidDomain        = errors.New("invalid domain")
+	ErrDomainAlreadyBlocked = errors.New("domain already blocked")
+	ErrDomainNotBlocked     = errors.New("domain not blocked")
+	ErrBlocklistFull        = errors.New("blocked domains limit reached")
+)
+
 var (
 	ErrUserNotFound = errors.New("user not found")
 )
@@ -23,6 +30,7 @@ var (
 	ErrEmptyQuery       = errors.New("empty query")
 	ErrQueryTooLong     = errors.New("query too long")
 	ErrNoSources        = errors.New("no sources available")
+	ErrNoTrustedSources = errors.New("no high-trust sources")
 	ErrAllSourcesFailed = errors.New("all sources failed")
 	ErrLLMFailed        = errors.New("llm request failed")
 	ErrNoResults        = errors.New("no results found")
@@ -31,6 +39,8 @@ var (
 var (
 	ErrInvalidMaxRetries  = errors.New("max retries must be non-negative")
 	ErrMaxRetriesExceeded = errors.New("max retries cannot exceed 10")
+	// ответ критика не разобрался и после повторов: ответ остается непроверенным
+	ErrCriticUnparsable = errors.New("critic response could not be parsed")
 )
 
 var (
@@ -2,6 +2,7 @@ package domain
 
 import (
 	"strings"
+	"time"
 )
 
 const MaxQueryLength = 1000
@@ -11,6 +12,8 @@ type QueryRequest struct {
 	Text         string
 	OnlyReliable bool
 	Strategy     Strategy
+	// предыдущие ходы диалога от старых к новым, если вопрос - уточнение
+	History []ConversationTurn
 }
 
 func (q *QueryRequest) Validate() error {
@@ -46,4 +49,8 @@ type SourceRef struct {
 	Title      string
 	URL        string
 	TrustLevel TrustLevel
+	// нулевое время - поисковик дату не знает
+	PublishedAt time.Time
+	// страницы документа, на которые ссылается ответ, по возрастанию
+	Pages []int
 }
@@ -0,0 +1,116 @@
+package domain
+
+import (
+	"net/url"
+	"strings"
+)
+
+// Host - хост URL в нижнем регистре, без www. и порта. Схему можно не указывать.
+// Единственное место, где URL источников и результатов приводятся к домену
+func Host(rawURL string) string {
+	host, _, _ := splitURL(rawURL)
+	return host
+}
+
+// SourceScope - часть сайта, которую покрывает источник: хост и префикс пути.
+// Path всегда начинается и заканчивается на "/", у источника на весь сайт он "/"
+type SourceScope struct {
+	Host string
+	Path string
+}
+
+// ScopeOf строит область по URL источника. Последний сегмент с точкой считается
+// страницей, а не разделом: kpmg.com/xx/en/financial-services.html -> kpmg.com/xx/en/
+func ScopeOf(rawURL string) SourceScope {
+	host, path, ok := splitURL(rawURL)
+	if !ok {
+		return SourceScope{}
+	}
+
+	if i := strings.LastIndex(path, "/"); strings.Contains(path[i+1:], ".") {
+		path = path[:i]
+	}
+	return SourceScope{Host: host, Path: strings.TrimRight(path, "/") + "/"}
+}
+
+// String - область в виде host/path, для источника на весь сайт - только хост
+func (s SourceScope) String() string {
+	if s.Path == "/" {
+		return s.Host
+	}
+	return s.Host + strings.TrimRight(s.Path, "/")
+}
+
+// Contains - URL лежит в области. Поддомены входят только в область на весь сайт:
+// blog.kpmg.com не относится к разделу kpmg.com/xx/en/
+func (s SourceScope) Contains(rawURL string) bool {
+	host, path, ok := splitURL(rawURL)
+	if !ok || s.Host == "" {
+		return false
+	}
+	if host != s.Host {
+		return s.Path == "/" && DomainMatches(host, s.Host)
+	}
+	return strings.HasPrefix(strings.TrimRight(path, "/")+"/", s.Path)
+}
+
+// narrower - s уже other: сначала более точный хост, потом более длинный путь
+func (s SourceScope) narrower(other SourceScope) bool {
+	if len(s.Host) != len(other.Host) {
+		return len(s.Host) > len(other.Host)
+	}
+	return len(s.Path) > len(other.Path)
+}
+
+// NarrowestScope - индекс самой узкой области, содержащей URL, или -1
+func NarrowestScope(rawURL string, scopes []SourceScope) int {
+	best := -1
+	for i, s := range scopes {
+		if s.Contains(rawURL) && (best < 0 || s.narrower(scopes[best])) {
+			best = i
+		}
+	}
+	return best
+}
+
+// InScope - URL с сайта одного из источников лежит в области хотя бы одного из них.
+// Сайты без источников не ограничиваются: их отсекает IncludeDomains, если поисковик его понимает
+func InScope(rawURL string, scopes []SourceScope) bool {
+	host := Host(rawURL)
+	covered := false
+	for _, s := range scopes {
+		if s.Contains(rawURL) {
+			return true
+		}
+		if DomainMatches(host, s.Host) {
+			covered = true
+		}
+	}
+	return !covered
+}
+
+// splitURL - хост по правилам Host и путь, всегда начинающийся с "/"
+func splitURL(rawURL string) (host, path string, ok bool) {
+	rawURL = strings.TrimSpace(rawURL)
+	if rawURL == "" {
+		return "", "", false
+	}
+	if !strings.Contains(rawURL, "://") {
+		rawURL = "https://" + rawURL
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", "", false
+	}
+	host = strings.TrimPrefix(strings.TrimSuffix(strings.ToLower(u.Hostname()), "."), "www.")
+	if host == "" {
+		return "", "", false
+	}
+
+	path = u.Path
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return host, path, true
+}
@@ -0,0 +1,120 @@
+package domain
+
+import "testing"
+
+func TestHost(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://www.KPMG.com/xx/en/", "kpmg.com"},
+		{"http://example.com:8080/path", "example.com"},
+		{"example.com/path", "example.com"},
+		{"https://example.com./", "example.com"},
+		{"", ""},
+		{"http://", ""},
+		{"://invalid", ""},
+	}
+
+	for _, tt := range tests {
+		if got := Host(tt.url); got != tt.want {
+			t.Errorf("Host(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestScopeOf(t *testing.T) {
+	tests := []struct {
+		url  string
+		want SourceScope
+	}{
+		{"https://www.bcg.com", SourceScope{"bcg.com", "/"}},
+		{"https://www.gartner.com/en", SourceScope{"gartner.com", "/en/"}},
+		{"https://a16z.com/fintech/", SourceScope{"a16z.com", "/fintech/"}},
+		{"https://kpmg.com/xx/en/what-we-do/industries/financial-services.html", SourceScope{"kpmg.com", "/xx/en/what-we-do/industries/"}},
+		{"https://www.hsbc.com/news-and-views/views/hsbc-views?topic=digital-innovation", SourceScope{"hsbc.com", "/news-and-views/views/hsbc-views/"}},
+		{"not a url\x7f", SourceScope{}},
+	}
+
+	for _, tt := range tests {
+		if got := ScopeOf(tt.url); got != tt.want {
+			t.Errorf("ScopeOf(%q) = %+v, want %+v", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestSourceScope_Contains(t *testing.T) {
+	section := ScopeOf("https://www.pwc.com/us/en/industries/financial-services/fintech.html")
+	site := ScopeOf("https://mckinsey.com")
+
+	tests := []struct {
+		scope SourceScope
+		url   string
+		want  bool
+	}{
+		{section, "https://www.pwc.com/us/en/industries/financial-services/fintech/bnpl.html", true},
+		{section, "https://pwc.com/us/en/industries/financial-services", true},
+		{section, "https://www.pwc.com/us/en/industries/financial-services-2/x", false},
+		{section, "https://www.pwc.com/gx/en/news.html", false},
+		{section, "https://blog.pwc.com/us/en/industries/financial-services/x", false},
+		{site, "https://www.mckinsey.com/featured-insights", true},
+		{site, "https://insights.mckinsey.com/report", true},
+		{site, "https://notmckinsey.com/report", false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.scope.Contains(tt.url); got != tt.want {
+			t.Errorf("%s.Contains(%q) = %v, want %v", tt.scope, tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestNarrowestScope(t *testing.T) {
+	scopes := []SourceScope{
+		ScopeOf("https://kpmg.com"),
+		ScopeOf("https://kpmg.com/xx/en/insights/"),
+		ScopeOf("https://kpmg.com/xx/en/"),
+		ScopeOf("https://home.kpmg.com"),
+	}
+
+	tests := []struct {
+		url  string
+		want int
+	}{
+		{"https://kpmg.com/xx/en/insights/fintech.html", 1},
+		{"https://kpmg.com/xx/en/about.html", 2},
+		{"https://kpmg.com/us/en/", 0},
+		{"https://home.kpmg.com/xx/en/insights/", 3},
+		{"https://deloitte.com/", -1},
+	}
+
+	for _, tt := range tests {
+		if got := NarrowestScope(tt.url, scopes); got != tt.want {
+			t.Errorf("NarrowestScope(%q) = %d, want %d", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestInScope(t *testing.T) {
+	scopes := []SourceScope{
+		ScopeOf("https://kpmg.com/xx/en/what-we-do/industries/financial-services.html"),
+		ScopeOf("https://mckinsey.com"),
+	}
+
+	tests := []struct {
+		url  string
+		want bool
+	}{
+		{"https://kpmg.com/xx/en/what-we-do/industries/banking.html", true},
+		{"https://kpmg.com/us/en/careers.html", false},
+		{"https://blog.kpmg.com/fintech", false},
+		{"https://insights.mckinsey.com/report", true},
+		{"https://example.com/news", true},
+	}
+
+	for _, tt := range tests {
+		if got := InScope(tt.url, scopes); got != tt.want {
+			t.Errorf("InScope(%q) = %v, want %v", tt.url, got, tt.want)
+		}
+	}
+}
@@ -42,19 +42,10 @@ func (s *Source) Validate() error {
 }
 
 func (s *Source) Domain() string {
-	if s.URL == "" {
-		return ""
-	}
-
-	u, err := url.Parse(s.URL)
-	if err != nil {
-		return ""
-	}
-
-	host := u.Host
-	if len(host) > 4 && host[:4] == "www." {
-		host = host[4:]
-	}
+	return Host(s.URL)
+}
 
-	return host
+// Scope - раздел сайта, к которому относится источник, см. ScopeOf
+func (s *Source) Scope() SourceScope {
+	return ScopeOf(s.URL)
 }
@@ -89,9 +89,9 @@ func TestSource_Domain(t *testing.T) {
 			wantDomain: "example.com",
 		},
 		{
-			name:       "domain with port",
+			name:       "port dropped",
 			url:        "https://example.com:8080",
-			wantDomain: "example.com:8080",
+			wantDomain: "example.com",
 		},
 		{
 			name:       "domain with path and query",
@@ -1,6 +1,9 @@
 package domain
 
-import "errors"
+import (
+	"errors"
+	"time"
+)
 
 var (
 	ErrInvalidStrategyType   = errors.New("invalid strategy type")
@@ -8,6 +11,7 @@ var (
 	ErrInvalidMaxResults     = errors.New("max results must be between 1 and 100")
 	ErrInvalidAnalysisIter   = errors.New("max analysis iterations must be at least 1")
 	ErrInvalidTimeoutSeconds = errors.New("timeout seconds must be at least 1")
+	ErrInvalidTimeWindow     = errors.New("invalid time window")
 )
 
 type StrategyType string
@@ -28,6 +32,41 @@ func (s StrategyType) IsValid() bool {
 
 func (s StrategyType) String() string { return string(s) }
 
+// TimeWindow - за какой период искать публикации. Пустое окно - за все время
+type TimeWindow string
+
+const (
+	TimeWindowAny   TimeWindow = ""
+	TimeWindowDay   TimeWindow = "day"
+	TimeWindowWeek  TimeWindow = "week"
+	TimeWindowMonth TimeWindow = "month"
+	TimeWindowYear  TimeWindow = "year"
+)
+
+func (w TimeWindow) IsValid() bool {
+	switch w {
+	case TimeWindowAny, TimeWindowDay, TimeWindowWeek, TimeWindowMonth, TimeWindowYear:
+		return true
+	}
+	return false
+}
+
+// Duration - длина окна, 0 - без ограничения
+func (w TimeWindow) Duration() time.Duration {
+	const day = 24 * time.Hour
+	switch w {
+	case TimeWindowDay:
+		return day
+	case TimeWindowWeek:
+		return 7 * day
+	case TimeWindowMonth:
+		return 30 * day
+	case TimeWindowYear:
+		return 365 * day
+	}
+	return 0
+}
+
 // Strategy - конфиг стратегии исследования
 type Strategy struct {
 	Type                  StrategyType
@@ -36,6 +75,7 @@ type Strategy struct {
 	MaxAnalysisIterations int
 	UseCritic             bool
 	TimeoutSeconds        int
+	TimeWindow            TimeWindow // только публикации за этот период
 }
 
 // Validate проверяет что все поля в допустимых диапазонах.
@@ -56,6 +96,9 @@ func (s Strategy) Validate() error {
 	if s.TimeoutSeconds < 1 {
 		return ErrInvalidTimeoutSeconds
 	}
+	if !s.TimeWindow.IsValid() {
+		return ErrInvalidTimeWindow
+	}
 	return nil
 }
 
@@ -93,3 +136,15 @@ func DeepStrategy() Strategy {
 		TimeoutSeconds:        180,
 	}
 }
+
+// StrategyByType - предустановленная стратегия по типу, для невалидного типа - standard
+func StrategyByType(t StrategyType) Strategy {
+	switch t {
+	case StrategyQuick:
+		return QuickStrategy()
+	case StrategyDeep:
+		return DeepStrategy()
+	default:
+		return StandardStrategy()
+	}
+}
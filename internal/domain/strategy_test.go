@@ -146,6 +146,30 @@ func TestStrategy_Validate(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "unknown time window is invalid",
+			strategy: Strategy{
+				Type:                  StrategyQuick,
+				MaxQueries:            1,
+				MaxResults:            5,
+				MaxAnalysisIterations: 1,
+				TimeoutSeconds:        30,
+				TimeWindow:            "decade",
+			},
+			wantErr: true,
+		},
+		{
+			name: "month window is valid",
+			strategy: Strategy{
+				Type:                  StrategyQuick,
+				MaxQueries:            1,
+				MaxResults:            5,
+				MaxAnalysisIterations: 1,
+				TimeoutSeconds:        30,
+				TimeWindow:            TimeWindowMonth,
+			},
+			wantErr: false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -270,3 +294,24 @@ func TestStrategyType_String(t *testing.T) {
 		})
 	}
 }
+
+func TestStrategyByType(t *testing.T) {
+	tests := []struct {
+		name         string
+		strategyType StrategyType
+		want         StrategyType
+	}{
+		{"quick", StrategyQuick, StrategyQuick},
+		{"standard", StrategyStandard, StrategyStandard},
+		{"deep", StrategyDeep, StrategyDeep},
+		{"invalid falls back to standard", "ultra", StrategyStandard},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := StrategyByType(tt.strategyType).Type; got != tt.want {
+				t.Errorf("StrategyByType(%q).Type = %v, want %v", tt.strategyType, got, tt.want)
+			}
+		})
+	}
+}
@@ -17,6 +17,18 @@ func (t TrustLevel) IsValid() bool {
 	}
 }
 
+// Weight - множитель оценки результата при ранжировании. Неизвестный уровень - как medium
+func (t TrustLevel) Weight() float64 {
+	switch t {
+	case TrustHigh:
+		return 1
+	case TrustLow:
+		return 0.4
+	default:
+		return 0.7
+	}
+}
+
 func (t TrustLevel) String() string {
 	return string(t)
 }
@@ -0,0 +1,88 @@
+package domain
+
+import (
+	"strings"
+	"time"
+)
+
+// UsageRecord - расход токенов одного вызова LLM
+type UsageRecord struct {
+	UserID           int64
+	Provider         string
+	Model            string
+	Stage            string
+	PromptTokens     int
+	CompletionTokens int
+	CostUSD          float64
+	CreatedAt        time.Time
+}
+
+// UsageTotals - сумма расхода за период, в разрезе стадии или целиком
+type UsageTotals struct {
+	Stage            string // пусто для итога по всем стадиям
+	Calls            int64
+	PromptTokens     int64
+	CompletionTokens int64
+	CostUSD          float64
+}
+
+func (t UsageTotals) Tokens() int64 {
+	return t.PromptTokens + t.CompletionTokens
+}
+
+// UsageReport - сводка для команды /usage
+type UsageReport struct {
+	Today        UsageTotals
+	Month        UsageTotals   // последние 30 дней
+	MonthByStage []UsageTotals // по убыванию стоимости
+}
+
+// SumUsage складывает итоги по стадиям в один
+func SumUsage(byStage []UsageTotals) UsageTotals {
+	var total UsageTotals
+	for _, t := range byStage {
+		total.Calls += t.Calls
+		total.PromptTokens += t.PromptTokens
+		total.CompletionTokens += t.CompletionTokens
+		total.CostUSD += t.CostUSD
+	}
+	return total
+}
+
+// ModelPrice - цена за миллион токенов в долларах
+type ModelPrice struct {
+	PromptPerMillion     float64
+	CompletionPerMillion float64
+}
+
+func (p ModelPrice) Cost(promptTokens, completionTokens int) float64 {
+	return (float64(promptTokens)*p.PromptPerMillion + float64(completionTokens)*p.CompletionPerMillion) / 1_000_000
+}
+
+// ModelPrices - примерные цены провайдеров, GigaChat пересчитан из рублей.
+// Ключ - имя модели или его префикс (openrouter дописывает версию: deepseek-chat-v3-0324)
+var ModelPrices = map[string]ModelPrice{
+	"deepseek/deepseek-chat": {PromptPerMillion: 0.30, CompletionPerMillion: 0.85},
+	"GigaChat":               {PromptPerMillion: 2.20, CompletionPerMillion: 2.20},
+	"GigaChat-Pro":           {PromptPerMillion: 16.50, CompletionPerMillion: 16.50},
+	"GigaChat-Max":           {PromptPerMillion: 21.50, CompletionPerMillion: 21.50},
+	"mock":                   {},
+}
+
+// PriceFor ищет цену по точному имени модели, иначе по самому длинному префиксу
+func PriceFor(model string) (ModelPrice, bool) {
+	if p, ok := ModelPrices[model]; ok {
+		return p, true
+	}
+
+	var best string
+	for name := range ModelPrices {
+		if strings.HasPrefix(model, name) && len(name) > len(best) {
+			best = name
+		}
+	}
+	if best == "" {
+		return ModelPrice{}, false
+	}
+	return ModelPrices[best], true
+}
@@ -0,0 +1,38 @@
+package domain
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPriceFor(t *testing.T) {
+	tests := []struct {
+		model  string
+		want   ModelPrice
+		wantOK bool
+	}{
+		{"deepseek/deepseek-chat", ModelPrices["deepseek/deepseek-chat"], true},
+		{"deepseek/deepseek-chat-v3-0324", ModelPrices["deepseek/deepseek-chat"], true},
+		{"GigaChat-Pro-preview", ModelPrices["GigaChat-Pro"], true},
+		{"GigaChat:2.0.28.2", ModelPrices["GigaChat"], true},
+		{"unknown/model", ModelPrice{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.model, func(t *testing.T) {
+			got, ok := PriceFor(tt.model)
+			if ok != tt.wantOK || got != tt.want {
+				t.Errorf("PriceFor(%q) = %+v, %v; want %+v, %v", tt.model, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestModelPrice_Cost(t *testing.T) {
+	p := ModelPrice{PromptPerMillion: 1, CompletionPerMillion: 2}
+
+	got := p.Cost(500_000, 250_000)
+	if math.Abs(got-1.0) > 1e-9 {
+		t.Errorf("Cost() = %v, want 1.0", got)
+	}
+}
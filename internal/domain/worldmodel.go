@@ -71,11 +71,12 @@ func (t EntityType) String() string { return string(t) }
 
 // ResearchSession - сессия исследования
 type ResearchSession struct {
-	ID        string
-	UserID    int64
-	Question  string
-	Strategy  string
-	CreatedAt time.Time
+	ID            string
+	UserID        int64
+	Question      string
+	Strategy      string
+	PromptVersion string // версия шаблонов промптов, например v1@3f2a9c1d
+	CreatedAt     time.Time
 }
 
 func (s *ResearchSession) Validate() error {
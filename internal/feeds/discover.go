@@ -0,0 +1,86 @@
+package feeds
+
+import (
+	"context"
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var (
+	linkTagRe = regexp.MustCompile(`(?is)<link\b[^>]*>`)
+	attrRe    = regexp.MustCompile(`(?is)\b(rel|type|href)\s*=\s*["']([^"']*)["']`)
+)
+
+// типичные адреса лент, если страница источника на них не ссылается
+var commonFeedPaths = []string{"/feed", "/rss", "/feed.xml", "/rss.xml", "/atom.xml", "/index.xml"}
+
+// discover ищет ленты источника: сам URL, если он уже лента, потом
+// <link rel="alternate"> на странице, потом типичные адреса на том же хосте
+func (in *Ingester) discover(ctx context.Context, sourceURL string) ([]string, error) {
+	body, err := in.get(ctx, sourceURL)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := Parse(body); err == nil {
+		return []string{sourceURL}, nil
+	}
+
+	if links := feedLinks(sourceURL, string(body)); len(links) > 0 {
+		return links, nil
+	}
+
+	base, err := url.Parse(sourceURL)
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range commonFeedPaths {
+		candidate := base.Scheme + "://" + base.Host + path
+		body, err := in.get(ctx, candidate)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			continue
+		}
+		if _, err := Parse(body); err == nil {
+			return []string{candidate}, nil
+		}
+	}
+	return nil, nil
+}
+
+// feedLinks - абсолютные адреса из <link rel="alternate" type="application/rss+xml|atom+xml">
+func feedLinks(pageURL, page string) []string {
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return nil
+	}
+
+	var links []string
+	seen := make(map[string]bool)
+	for _, tag := range linkTagRe.FindAllString(page, -1) {
+		attrs := make(map[string]string)
+		for _, m := range attrRe.FindAllStringSubmatch(tag, -1) {
+			attrs[strings.ToLower(m[1])] = html.UnescapeString(strings.TrimSpace(m[2]))
+		}
+		if !strings.Contains(strings.ToLower(attrs["rel"]), "alternate") {
+			continue
+		}
+		typ := strings.ToLower(attrs["type"])
+		if typ != "application/rss+xml" && typ != "application/atom+xml" {
+			continue
+		}
+		ref, err := url.Parse(attrs["href"])
+		if err != nil || attrs["href"] == "" {
+			continue
+		}
+		link := base.ResolveReference(ref).String()
+		if !seen[link] {
+			seen[link] = true
+			links = append(links, link)
+		}
+	}
+	return links
+}
@@ -0,0 +1,46 @@
+package feeds
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+// блоки, текст которых к статье не относится. По выражению на тег: обратных ссылок в RE2 нет
+var noiseRes = func() []*regexp.Regexp {
+	var res []*regexp.Regexp
+	for _, tag := range []string{"script", "style", "noscript", "nav", "header", "footer", "aside", "form"} {
+		res = append(res, regexp.MustCompile(`(?is)<`+tag+`\b.*?</`+tag+`\s*>`))
+	}
+	return res
+}()
+
+var (
+	tagRe   = regexp.MustCompile(`(?s)<[^>]*>`)
+	spaceRe = regexp.MustCompile(`\s+`)
+
+	articleRe = regexp.MustCompile(`(?is)<article\b[^>]*>(.*)</article>`)
+	mainRe    = regexp.MustCompile(`(?is)<main\b[^>]*>(.*)</main>`)
+	bodyRe    = regexp.MustCompile(`(?is)<body\b[^>]*>(.*)</body>`)
+)
+
+// htmlText превращает HTML в текст: без тегов, со снятыми сущностями и сжатыми пробелами
+func htmlText(s string) string {
+	for _, re := range noiseRes {
+		s = re.ReplaceAllString(s, " ")
+	}
+	s = tagRe.ReplaceAllString(s, " ")
+	s = html.UnescapeString(s)
+	return strings.TrimSpace(spaceRe.ReplaceAllString(s, " "))
+}
+
+// pageText - текст статьи со страницы: <article>, иначе <main>, иначе <body>.
+// Без разбора DOM, но для страниц аналитики этого хватает
+func pageText(page string) string {
+	for _, re := range []*regexp.Regexp{articleRe, mainRe, bodyRe} {
+		if m := re.FindStringSubmatch(page); m != nil {
+			return htmlText(m[1])
+		}
+	}
+	return htmlText(page)
+}
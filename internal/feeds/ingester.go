@@ -0,0 +1,289 @@
+package feeds
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/kitbuilder587/fintech-bot/internal/domain"
+	"github.com/kitbuilder587/fintech-bot/internal/netguard"
+	"github.com/kitbuilder587/fintech-bot/internal/repository"
+	"github.com/kitbuilder587/fintech-bot/internal/retry"
+)
+
+const (
+	// короче - считаем анонсом и идем за полным текстом на страницу статьи
+	minFullTextLen = 1000
+	// длиннее не храним: в промпт все равно уйдет начало
+	maxContentLen = 50000
+	// больше не читаем ни ленту, ни страницу
+	maxBodySize = 5 << 20
+)
+
+type Config struct {
+	Interval         time.Duration // между опросами лент
+	DiscoverInterval time.Duration // повторный поиск лент источника, у которого их не нашлось
+	MaxItemsPerFeed  int           // новых записей за опрос одной ленты
+	FullText         bool          // качать страницу статьи, если в ленте только анонс
+	Timeout          time.Duration // на один HTTP запрос
+	Retry            retry.Policy  // пустая - retry.DefaultPolicy()
+	UserAgent        string
+	AllowPrivate     bool // ходить и во внутреннюю сеть, только для тестов
+}
+
+// Ingester по расписанию находит ленты источников всех пользователей и
+// складывает новые статьи в ArticleRepository
+type Ingester struct {
+	sources  repository.SourceRepository
+	articles repository.ArticleRepository
+	cfg      Config
+	client   *retry.Client
+	logger   *zap.Logger
+
+	mu         sync.Mutex
+	discovered map[string]time.Time // URL источника -> когда искали его ленты
+}
+
+func New(sources repository.SourceRepository, articles repository.ArticleRepository, cfg Config, logger *zap.Logger) *Ingester {
+	if cfg.Interval == 0 {
+		cfg.Interval = time.Hour
+	}
+	if cfg.DiscoverInterval == 0 {
+		cfg.DiscoverInterval = 24 * time.Hour
+	}
+	if cfg.MaxItemsPerFeed == 0 {
+		cfg.MaxItemsPerFeed = 20
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = 30 * time.Second
+	}
+	if cfg.Retry == (retry.Policy{}) {
+		cfg.Retry = retry.DefaultPolicy()
+	}
+	if cfg.UserAgent == "" {
+		cfg.UserAgent = "fintech-bot feed reader"
+	}
+
+	// адреса источников задают пользователи, во внутреннюю сеть по ним не ходим
+	httpClient := &http.Client{Timeout: cfg.Timeout}
+	if !cfg.AllowPrivate {
+		httpClient.Transport = netguard.Transport()
+	}
+
+	return &Ingester{
+		sources:    sources,
+		articles:   articles,
+		cfg:        cfg,
+		client:     retry.NewClient(httpClient, cfg.Retry),
+		logger:     logger,
+		discovered: make(map[string]time.Time),
+	}
+}
+
+// Run опрашивает ленты сразу и затем раз в Interval, пока не отменен ctx
+func (in *Ingester) Run(ctx context.Context) {
+	ticker := time.NewTicker(in.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := in.RunOnce(ctx); err != nil && ctx.Err() == nil {
+			in.logger.Error("feed ingestion failed", zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce ищет ленты новых источников и опрашивает все известные ленты.
+// Ошибка одной ленты не мешает остальным, она только пишется в лог
+func (in *Ingester) RunOnce(ctx context.Context) error {
+	urls, err := in.sources.ListURLs(ctx)
+	if err != nil {
+		return fmt.Errorf("list sources: %w", err)
+	}
+	for _, u := range urls {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		in.discoverSource(ctx, u)
+	}
+
+	feeds, err := in.articles.ListFeeds(ctx)
+	if err != nil {
+		return fmt.Errorf("list feeds: %w", err)
+	}
+	for i := range feeds {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		saved, err := in.poll(ctx, &feeds[i])
+		if err != nil {
+			in.logger.Warn("feed poll failed", zap.String("feed", feeds[i].URL), zap.Error(err))
+			continue
+		}
+		if saved > 0 {
+			in.logger.Info("feed articles saved", zap.String("feed", feeds[i].URL), zap.Int("count", saved))
+		}
+	}
+	return nil
+}
+
+func (in *Ingester) discoverSource(ctx context.Context, sourceURL string) {
+	in.mu.Lock()
+	last, ok := in.discovered[sourceURL]
+	if ok && time.Since(last) < in.cfg.DiscoverInterval {
+		in.mu.Unlock()
+		return
+	}
+	in.discovered[sourceURL] = time.Now()
+	in.mu.Unlock()
+
+	links, err := in.discover(ctx, sourceURL)
+	if err != nil {
+		in.logger.Debug("feed discovery failed", zap.String("source", sourceURL), zap.Error(err))
+		return
+	}
+
+	src := domain.Source{URL: sourceURL}
+	for _, link := range links {
+		feed := &domain.Feed{URL: link, Domain: src.Domain()}
+		if err := in.articles.UpsertFeed(ctx, feed); err != nil {
+			in.logger.Warn("save feed failed", zap.String("feed", link), zap.Error(err))
+		}
+	}
+}
+
+// poll качает ленту условным запросом и сохраняет новые статьи
+func (in *Ingester) poll(ctx context.Context, feed *domain.Feed) (int, error) {
+	req, err := in.newRequest(ctx, feed.URL)
+	if err != nil {
+		return 0, err
+	}
+	if feed.ETag != "" {
+		req.Header.Set("If-None-Match", feed.ETag)
+	}
+	if feed.LastModified != "" {
+		req.Header.Set("If-Modified-Since", feed.LastModified)
+	}
+
+	resp, err := in.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	feed.PolledAt = time.Now()
+	switch resp.StatusCode {
+	case http.StatusNotModified:
+		return 0, in.articles.UpdateFeedState(ctx, feed)
+	case http.StatusOK:
+	default:
+		return 0, fmt.Errorf("status %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBodySize))
+	if err != nil {
+		return 0, fmt.Errorf("read feed: %w", err)
+	}
+	items, err := Parse(body)
+	if err != nil {
+		return 0, err
+	}
+
+	var articles []domain.Article
+	for _, it := range items {
+		if len(articles) == in.cfg.MaxItemsPerFeed {
+			break
+		}
+		exists, err := in.articles.ArticleExists(ctx, it.URL)
+		if err != nil {
+			return 0, err
+		}
+		if exists {
+			continue
+		}
+		articles = append(articles, domain.Article{
+			FeedID:      feed.ID,
+			Domain:      feed.Domain,
+			URL:         it.URL,
+			Title:       it.Title,
+			Content:     truncate(in.fullText(ctx, it), maxContentLen),
+			PublishedAt: it.Published,
+		})
+	}
+
+	saved, err := in.articles.SaveArticles(ctx, articles)
+	if err != nil {
+		return saved, err
+	}
+
+	// состояние - только после сохранения, иначе при сбое статьи потеряются до следующего изменения ленты
+	feed.ETag = resp.Header.Get("ETag")
+	feed.LastModified = resp.Header.Get("Last-Modified")
+	return saved, in.articles.UpdateFeedState(ctx, feed)
+}
+
+// fullText - текст из ленты, а если там анонс - текст страницы статьи
+func (in *Ingester) fullText(ctx context.Context, it Item) string {
+	if !in.cfg.FullText || len(it.Content) >= minFullTextLen {
+		return it.Content
+	}
+
+	page, err := in.get(ctx, it.URL)
+	if err != nil {
+		in.logger.Debug("article fetch failed", zap.String("url", it.URL), zap.Error(err))
+		return it.Content
+	}
+	if text := pageText(string(page)); len(text) > len(it.Content) {
+		return text
+	}
+	return it.Content
+}
+
+func (in *Ingester) get(ctx context.Context, u string) ([]byte, error) {
+	req, err := in.newRequest(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := in.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("get %s: status %d", u, resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxBodySize))
+}
+
+func (in *Ingester) newRequest(ctx context.Context, u string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("User-Agent", in.cfg.UserAgent)
+	return req, nil
+}
+
+// truncate режет по границе символа, а не байта
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n])
+}
@@ -0,0 +1,148 @@
+package feeds
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/kitbuilder587/fintech-bot/internal/domain"
+	"github.com/kitbuilder587/fintech-bot/internal/repository"
+	"github.com/kitbuilder587/fintech-bot/internal/retry"
+)
+
+// site - источник с лентой на /rss, ссылкой на нее со страницы и статьями
+type site struct {
+	mu           sync.Mutex
+	feedRequests []*http.Request
+	articleHits  int
+}
+
+func (s *site) handler(url func() string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/insights", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html><head><link rel="alternate" type="application/rss+xml" href="/rss"></head></html>`)
+	})
+	mux.HandleFunc("/rss", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.feedRequests = append(s.feedRequests, r)
+		s.mu.Unlock()
+
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		fmt.Fprintf(w, `<rss><channel>
+			<item><title>Klarna IPO</title><link>%[1]s/klarna</link><description>Teaser</description></item>
+			<item><title>Long</title><link>%[1]s/long</link><description>%[2]s</description></item>
+		</channel></rss>`, url(), strings.Repeat("full text ", 200))
+	})
+	mux.HandleFunc("/klarna", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.articleHits++
+		s.mu.Unlock()
+		fmt.Fprint(w, `<html><body><article><p>Klarna filed for an IPO in New York</p></article></body></html>`)
+	})
+	mux.HandleFunc("/long", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.articleHits++
+		s.mu.Unlock()
+	})
+	return mux
+}
+
+func newTestIngester(t *testing.T) (*Ingester, *site, *repository.MockArticleRepository, string) {
+	t.Helper()
+
+	s := &site{}
+	var server *httptest.Server
+	server = httptest.NewServer(s.handler(func() string { return server.URL }))
+	t.Cleanup(server.Close)
+
+	sources := repository.NewMockSourceRepository()
+	sources.Create(context.Background(), &domain.Source{UserID: 1, URL: server.URL + "/insights"})
+	sources.Create(context.Background(), &domain.Source{UserID: 2, URL: server.URL + "/insights"})
+	articles := repository.NewMockArticleRepository()
+
+	in := New(sources, articles, Config{FullText: true, Retry: retry.Policy{MaxAttempts: 1}, AllowPrivate: true}, zap.NewNop())
+	return in, s, articles, server.URL
+}
+
+func TestIngester_DiscoversFeedAndSavesArticles(t *testing.T) {
+	in, s, articles, baseURL := newTestIngester(t)
+
+	if err := in.RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce() error = %v", err)
+	}
+
+	feeds, _ := articles.ListFeeds(context.Background())
+	if len(feeds) != 1 || feeds[0].URL != baseURL+"/rss" || feeds[0].ETag != `"v1"` {
+		t.Fatalf("feeds = %+v, want one discovered feed with ETag", feeds)
+	}
+
+	saved := articles.Articles()
+	if len(saved) != 2 {
+		t.Fatalf("articles = %+v, want 2", saved)
+	}
+	if saved[0].Content != "Klarna filed for an IPO in New York" {
+		t.Errorf("teaser article content = %q, want full text from the page", saved[0].Content)
+	}
+	if saved[0].Domain != feeds[0].Domain || saved[0].FeedID != feeds[0].ID {
+		t.Errorf("article = %+v, want feed domain and id", saved[0])
+	}
+	// у длинной статьи текст уже в ленте, страницу не качаем
+	if s.articleHits != 1 {
+		t.Errorf("article page fetched %d times, want only for the teaser", s.articleHits)
+	}
+}
+
+func TestIngester_ConditionalPoll(t *testing.T) {
+	in, s, articles, _ := newTestIngester(t)
+	ctx := context.Background()
+
+	in.RunOnce(ctx)
+	in.RunOnce(ctx)
+
+	if len(s.feedRequests) != 2 {
+		t.Fatalf("feed requested %d times, want 2", len(s.feedRequests))
+	}
+	if got := s.feedRequests[1].Header.Get("If-None-Match"); got != `"v1"` {
+		t.Errorf("second poll If-None-Match = %q, want stored ETag", got)
+	}
+	if len(articles.Articles()) != 2 || s.articleHits != 1 {
+		t.Errorf("articles = %d, page fetches = %d; 304 must not refetch", len(articles.Articles()), s.articleHits)
+	}
+	feeds, _ := articles.ListFeeds(ctx)
+	if feeds[0].PolledAt.IsZero() {
+		t.Error("PolledAt not updated on 304")
+	}
+}
+
+func TestIngester_RefusesPrivateAddresses(t *testing.T) {
+	hits := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+	}))
+	t.Cleanup(server.Close)
+
+	sources := repository.NewMockSourceRepository()
+	sources.Create(context.Background(), &domain.Source{UserID: 1, URL: server.URL + "/insights"})
+	articles := repository.NewMockArticleRepository()
+	in := New(sources, articles, Config{Retry: retry.Policy{MaxAttempts: 1}}, zap.NewNop())
+
+	if err := in.RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce() error = %v", err)
+	}
+	if hits != 0 {
+		t.Errorf("loopback source requested %d times, want none", hits)
+	}
+	if feeds, _ := articles.ListFeeds(context.Background()); len(feeds) != 0 {
+		t.Errorf("feeds = %+v, want none", feeds)
+	}
+}
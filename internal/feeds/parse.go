@@ -0,0 +1,166 @@
+// Package feeds находит RSS/Atom ленты источников, опрашивает их и складывает
+// статьи с полным текстом в локальное хранилище для поиска
+package feeds
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"strings"
+	"time"
+)
+
+var ErrNotFeed = errors.New("not an RSS or Atom feed")
+
+// Item - запись ленты. Content - текст из ленты без разметки, часто только анонс
+type Item struct {
+	Title     string
+	URL       string
+	Published time.Time
+	Content   string
+}
+
+// RSS 2.0 и RSS 1.0 (RDF): у второго item лежат рядом с channel, а не внутри
+type rssDoc struct {
+	Channel struct {
+		Items []rssItem `xml:"item"`
+	} `xml:"channel"`
+	Items []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string `xml:"title"`
+	Link        string `xml:"link"`
+	GUID        string `xml:"guid"`
+	PubDate     string `xml:"pubDate"`
+	Date        string `xml:"http://purl.org/dc/elements/1.1/ date"`
+	Description string `xml:"description"`
+	Encoded     string `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+}
+
+type atomDoc struct {
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomEntry struct {
+	Title string `xml:"title"`
+	Links []struct {
+		Href string `xml:"href,attr"`
+		Rel  string `xml:"rel,attr"`
+	} `xml:"link"`
+	Published string `xml:"published"`
+	Updated   string `xml:"updated"`
+	Summary   string `xml:"summary"`
+	Content   string `xml:"content"`
+}
+
+// Parse разбирает RSS 2.0, RSS 1.0 и Atom. Записи без ссылки пропускаются
+func Parse(data []byte) ([]Item, error) {
+	root, err := rootElement(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []Item
+	switch root {
+	case "rss", "RDF":
+		var doc rssDoc
+		if err := xml.Unmarshal(data, &doc); err != nil {
+			return nil, err
+		}
+		for _, it := range append(doc.Channel.Items, doc.Items...) {
+			link := strings.TrimSpace(it.Link)
+			if link == "" && strings.HasPrefix(it.GUID, "http") {
+				link = strings.TrimSpace(it.GUID)
+			}
+			content := it.Encoded
+			if content == "" {
+				content = it.Description
+			}
+			items = append(items, Item{
+				Title:     htmlText(it.Title),
+				URL:       link,
+				Published: parseDate(firstNonEmpty(it.PubDate, it.Date)),
+				Content:   htmlText(content),
+			})
+		}
+
+	case "feed":
+		var doc atomDoc
+		if err := xml.Unmarshal(data, &doc); err != nil {
+			return nil, err
+		}
+		for _, e := range doc.Entries {
+			var link string
+			for _, l := range e.Links {
+				if l.Rel == "" || l.Rel == "alternate" {
+					link = strings.TrimSpace(l.Href)
+					break
+				}
+			}
+			items = append(items, Item{
+				Title:     htmlText(e.Title),
+				URL:       link,
+				Published: parseDate(firstNonEmpty(e.Published, e.Updated)),
+				Content:   htmlText(firstNonEmpty(e.Content, e.Summary)),
+			})
+		}
+
+	default:
+		return nil, ErrNotFeed
+	}
+
+	out := items[:0]
+	for _, it := range items {
+		if it.URL != "" {
+			out = append(out, it)
+		}
+	}
+	return out, nil
+}
+
+// rootElement - имя корневого элемента без пространства имен
+func rootElement(data []byte) (string, error) {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	dec.Strict = false
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return "", ErrNotFeed
+		}
+		if start, ok := tok.(xml.StartElement); ok {
+			return start.Name.Local, nil
+		}
+	}
+}
+
+var dateLayouts = []string{
+	time.RFC1123Z,
+	time.RFC1123,
+	time.RFC3339,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"2 Jan 2006 15:04:05 -0700",
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+}
+
+// parseDate понимает форматы RSS и Atom; нераспознанная дата - нулевое время
+func parseDate(s string) time.Time {
+	s = strings.TrimSpace(s)
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,103 @@
+package feeds
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+const rssFeed = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:content="http://purl.org/rss/1.0/modules/content/">
+<channel>
+	<title>Insights</title>
+	<item>
+		<title>Klarna &amp; BNPL</title>
+		<link>https://example.com/klarna</link>
+		<pubDate>Mon, 02 Jun 2025 10:00:00 +0000</pubDate>
+		<description>Short teaser</description>
+		<content:encoded><![CDATA[<p>Full <b>text</b> of the article</p>]]></content:encoded>
+	</item>
+	<item>
+		<title>No link</title>
+	</item>
+</channel>
+</rss>`
+
+const atomFeed = `<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+	<title>Research</title>
+	<entry>
+		<title>Open banking</title>
+		<link rel="self" href="https://example.com/entry.xml"/>
+		<link rel="alternate" href="https://example.com/open-banking"/>
+		<updated>2025-05-01T08:00:00Z</updated>
+		<summary type="html">&lt;p&gt;PSD3 is coming&lt;/p&gt;</summary>
+	</entry>
+</feed>`
+
+func TestParse_RSS(t *testing.T) {
+	items, err := Parse([]byte(rssFeed))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	if len(items) != 1 {
+		t.Fatalf("Parse() = %d items, want 1 (items without link skipped)", len(items))
+	}
+	it := items[0]
+	if it.Title != "Klarna & BNPL" || it.URL != "https://example.com/klarna" {
+		t.Errorf("item = %+v", it)
+	}
+	if it.Content != "Full text of the article" {
+		t.Errorf("Content = %q, want content:encoded without tags", it.Content)
+	}
+	if !it.Published.Equal(time.Date(2025, 6, 2, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("Published = %v", it.Published)
+	}
+}
+
+func TestParse_Atom(t *testing.T) {
+	items, err := Parse([]byte(atomFeed))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	if len(items) != 1 || items[0].URL != "https://example.com/open-banking" {
+		t.Fatalf("Parse() = %+v, want alternate link", items)
+	}
+	if items[0].Content != "PSD3 is coming" || items[0].Published.IsZero() {
+		t.Errorf("item = %+v, want summary text and updated date", items[0])
+	}
+}
+
+func TestParse_NotFeed(t *testing.T) {
+	for _, data := range []string{"<html><body>page</body></html>", "not xml at all"} {
+		if _, err := Parse([]byte(data)); !errors.Is(err, ErrNotFeed) {
+			t.Errorf("Parse(%q) error = %v, want ErrNotFeed", data, err)
+		}
+	}
+}
+
+func TestFeedLinks(t *testing.T) {
+	page := `<html><head>
+		<link rel="stylesheet" href="/style.css">
+		<link rel="alternate" type="application/rss+xml" title="RSS" href="/insights/rss?x=1&amp;y=2">
+		<link type="application/atom+xml" rel="alternate" href="https://cdn.example.com/atom.xml">
+	</head></html>`
+
+	links := feedLinks("https://example.com/insights/", page)
+
+	want := []string{"https://example.com/insights/rss?x=1&y=2", "https://cdn.example.com/atom.xml"}
+	if strings.Join(links, " ") != strings.Join(want, " ") {
+		t.Errorf("feedLinks() = %v, want %v", links, want)
+	}
+}
+
+func TestPageText(t *testing.T) {
+	page := `<html><body><nav>Menu</nav><article><h1>Title</h1><script>var x;</script><p>Body &mdash; text</p></article><footer>(c)</footer></body></html>`
+
+	if got := pageText(page); got != "Title Body — text" {
+		t.Errorf("pageText() = %q", got)
+	}
+}
@@ -0,0 +1,283 @@
+package fetch
+
+import (
+	"encoding/xml"
+	"html"
+	"regexp"
+	"strings"
+)
+
+// Извлечение основного текста страницы в духе Readability: абзацы начисляют очки
+// родителю и деду, очки штрафуются за долю текста в ссылках, побеждает контейнер
+// с наибольшим счетом. DOM строится encoding/xml в нестрогом режиме: HTML-парсера
+// в стандартной библиотеке нет, а для статей этого хватает
+
+// теги, содержимое которых в текст не попадает
+var skipTags = map[string]bool{
+	"script": true, "style": true, "noscript": true, "svg": true, "iframe": true,
+	"template": true, "nav": true, "header": true, "footer": true, "aside": true,
+	"form": true, "button": true, "select": true, "head": true,
+}
+
+// блочные теги: их текст отделяется от соседей переводом строки
+var blockTags = map[string]bool{
+	"p": true, "div": true, "section": true, "article": true, "main": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"li": true, "ul": true, "ol": true, "pre": true, "blockquote": true,
+	"table": true, "tr": true, "br": true, "figcaption": true, "dd": true, "dt": true,
+}
+
+var (
+	// вырезаются до разбора: в них бывает "<", который ломает xml.Decoder
+	rawBlockRes = func() []*regexp.Regexp {
+		var res []*regexp.Regexp
+		for _, tag := range []string{"script", "style", "noscript", "svg", "template"} {
+			res = append(res, regexp.MustCompile(`(?is)<`+tag+`\b.*?</`+tag+`\s*>`))
+		}
+		return res
+	}()
+	positiveRe = regexp.MustCompile(`article|body|content|entry|main|page|post|text|blog|story|report|insight`)
+	negativeRe = regexp.MustCompile(`comment|footer|sidebar|menu|share|social|promo|related|banner|cookie|subscribe|newsletter|breadcrumb|popup|modal|widget|\bad\b|ads|sponsor`)
+	spaceRe    = regexp.MustCompile(`[ \t\r\n\f]+`)
+)
+
+type node struct {
+	tag      string // пусто у текстового узла
+	attrs    string // class и id в нижнем регистре
+	text     string
+	parent   *node
+	children []*node
+}
+
+// Extract возвращает заголовок и основной текст HTML-страницы.
+// Абзацы в тексте разделены пустой строкой
+func Extract(page string) (title, text string) {
+	root := parseHTML(page)
+
+	if t := find(root, "title"); t != nil {
+		title = collapse(textOf(t))
+	}
+
+	body := find(root, "body")
+	if body == nil {
+		body = root
+	}
+
+	if best := bestCandidate(body); best != nil {
+		text = render(best)
+	}
+	// короткий текст лучшего контейнера - скорее ошибка оценки, чем короткая статья
+	if len(text) < 250 {
+		if all := render(body); len(all) > len(text) {
+			text = all
+		}
+	}
+	return title, text
+}
+
+func parseHTML(page string) *node {
+	for _, re := range rawBlockRes {
+		page = re.ReplaceAllString(page, " ")
+	}
+
+	dec := xml.NewDecoder(strings.NewReader(page))
+	dec.Strict = false
+	dec.AutoClose = xml.HTMLAutoClose
+	dec.Entity = xml.HTMLEntity
+
+	root := &node{tag: "#root"}
+	cur := root
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			// на битой разметке оставляем то, что успели разобрать
+			return root
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			n := &node{tag: strings.ToLower(t.Name.Local), parent: cur}
+			for _, a := range t.Attr {
+				if a.Name.Local == "class" || a.Name.Local == "id" {
+					n.attrs += " " + strings.ToLower(a.Value)
+				}
+			}
+			cur.children = append(cur.children, n)
+			cur = n
+		case xml.EndElement:
+			if cur.parent != nil {
+				cur = cur.parent
+			}
+		case xml.CharData:
+			cur.children = append(cur.children, &node{text: string(t), parent: cur})
+		}
+	}
+}
+
+func find(n *node, tag string) *node {
+	if n.tag == tag {
+		return n
+	}
+	for _, c := range n.children {
+		if f := find(c, tag); f != nil {
+			return f
+		}
+	}
+	return nil
+}
+
+// bestCandidate - контейнер с наибольшим счетом после штрафа за ссылки
+func bestCandidate(body *node) *node {
+	scores := make(map[*node]float64)
+
+	var walk func(n *node)
+	walk = func(n *node) {
+		if skipTags[n.tag] {
+			return
+		}
+		if n.tag == "p" || n.tag == "pre" || n.tag == "blockquote" || n.tag == "td" {
+			scoreParagraph(n, scores)
+		}
+		for _, c := range n.children {
+			walk(c)
+		}
+	}
+	walk(body)
+
+	var best *node
+	var bestScore float64
+	for n, s := range scores {
+		s *= 1 - linkDensity(n)
+		if best == nil || s > bestScore {
+			best, bestScore = n, s
+		}
+	}
+	return best
+}
+
+func scoreParagraph(p *node, scores map[*node]float64) {
+	text := collapse(textOf(p))
+	if len(text) < 25 {
+		return
+	}
+
+	score := 1 + float64(strings.Count(text, ",")) + min(float64(len(text))/100, 3)
+	if parent := p.parent; parent != nil {
+		initScore(parent, scores)
+		scores[parent] += score
+		if grand := parent.parent; grand != nil {
+			initScore(grand, scores)
+			scores[grand] += score / 2
+		}
+	}
+}
+
+// initScore - стартовый счет по тегу и class/id
+func initScore(n *node, scores map[*node]float64) {
+	if _, ok := scores[n]; ok {
+		return
+	}
+	var s float64
+	switch n.tag {
+	case "article", "main":
+		s = 10
+	case "div", "section":
+		s = 5
+	case "pre", "td", "blockquote":
+		s = 3
+	case "ul", "ol", "li", "form":
+		s = -3
+	case "h1", "h2", "h3", "h4", "h5", "h6", "th":
+		s = -5
+	}
+	if positiveRe.MatchString(n.attrs) {
+		s += 25
+	}
+	if negativeRe.MatchString(n.attrs) {
+		s -= 25
+	}
+	scores[n] = s
+}
+
+// linkDensity - доля текста узла внутри ссылок
+func linkDensity(n *node) float64 {
+	total := len(collapse(textOf(n)))
+	if total == 0 {
+		return 0
+	}
+	var inLinks int
+	var walk func(n *node)
+	walk = func(n *node) {
+		if n.tag == "a" {
+			inLinks += len(collapse(textOf(n)))
+			return
+		}
+		for _, c := range n.children {
+			walk(c)
+		}
+	}
+	walk(n)
+	return float64(inLinks) / float64(total)
+}
+
+// textOf - весь текст поддерева без служебных блоков
+func textOf(n *node) string {
+	var sb strings.Builder
+	var walk func(n *node)
+	walk = func(n *node) {
+		if n.tag == "" {
+			sb.WriteString(n.text)
+			return
+		}
+		if skipTags[n.tag] {
+			return
+		}
+		for _, c := range n.children {
+			walk(c)
+		}
+		sb.WriteByte(' ')
+	}
+	walk(n)
+	return sb.String()
+}
+
+// render - текст поддерева, блочные теги разделены пустой строкой
+func render(n *node) string {
+	var blocks []string
+	var cur strings.Builder
+	flush := func() {
+		if s := collapse(cur.String()); s != "" {
+			blocks = append(blocks, s)
+		}
+		cur.Reset()
+	}
+
+	var walk func(n *node)
+	walk = func(n *node) {
+		if n.tag == "" {
+			cur.WriteString(n.text)
+			return
+		}
+		if skipTags[n.tag] {
+			return
+		}
+		if blockTags[n.tag] {
+			flush()
+		}
+		for _, c := range n.children {
+			walk(c)
+		}
+		if blockTags[n.tag] {
+			flush()
+		} else {
+			cur.WriteByte(' ')
+		}
+	}
+	walk(n)
+	flush()
+
+	return strings.Join(blocks, "\n\n")
+}
+
+func collapse(s string) string {
+	return strings.TrimSpace(spaceRe.ReplaceAllString(html.UnescapeString(s), " "))
+}
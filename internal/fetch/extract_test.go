@@ -0,0 +1,72 @@
+package fetch
+
+import (
+	"strings"
+	"testing"
+)
+
+const articlePage = `<!DOCTYPE html>
+<html>
+<head>
+  <title>Klarna files for IPO</title>
+  <script>if (a < b && c) { track("view"); }</script>
+  <style>.x > p { color: red }</style>
+</head>
+<body>
+  <nav><a href="/">Home</a> <a href="/news">News</a> <a href="/about">About</a></nav>
+  <div class="sidebar related">
+    <p><a href="/a">Another story about payments and banks</a></p>
+    <p><a href="/b">Yet another story about lending, cards, wallets</a></p>
+  </div>
+  <div class="post-content">
+    <h1>Klarna files for IPO</h1>
+    <p>Klarna, the Swedish buy now, pay later company, filed for an initial public offering in New York on Friday.</p>
+    <p>The company reported revenue of $2.8 billion last year, up 24%, and its first annual profit since 2019.</p>
+    <p>Analysts expect the listing to value the lender at around $15 billion, well below its 2021 peak of $45.6 billion.</p>
+    <p>Mortgage &amp; card volumes were not disclosed.<br>The offering is led by Goldman Sachs, JPMorgan and Morgan Stanley.</p>
+  </div>
+  <footer><p>Copyright 2025, Some Media Group, all rights reserved, no reuse.</p></footer>
+</body>
+</html>`
+
+func TestExtract(t *testing.T) {
+	title, text := Extract(articlePage)
+
+	if title != "Klarna files for IPO" {
+		t.Errorf("title = %q", title)
+	}
+	for _, want := range []string{"initial public offering", "$2.8 billion", "Mortgage & card", "Goldman Sachs"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("text should contain %q, got:\n%s", want, text)
+		}
+	}
+	for _, noise := range []string{"track(", "color: red", "Another story", "Copyright", "About"} {
+		if strings.Contains(text, noise) {
+			t.Errorf("text should not contain %q, got:\n%s", noise, text)
+		}
+	}
+	if !strings.Contains(text, "2019.\n\nAnalysts") {
+		t.Errorf("paragraphs should be separated by blank line, got:\n%s", text)
+	}
+}
+
+func TestExtract_FallsBackToBody(t *testing.T) {
+	// абзацев нет - берется весь текст страницы
+	page := `<html><body><div><span>Interest rates</span> <b>stay at 5.25%</b></div></body></html>`
+
+	_, text := Extract(page)
+	if text != "Interest rates stay at 5.25%" {
+		t.Errorf("text = %q", text)
+	}
+}
+
+func TestExtract_BrokenMarkup(t *testing.T) {
+	page := `<html><body><div class="article"><p>Unclosed paragraph about, central bank, digital currencies in Europe
+<p>Second paragraph with <i>unclosed tags and more words, more words
+</div>`
+
+	_, text := Extract(page)
+	if !strings.Contains(text, "central bank") || !strings.Contains(text, "Second paragraph") {
+		t.Errorf("text = %q", text)
+	}
+}
@@ -0,0 +1,450 @@
+// Package fetch скачивает страницы из выдачи и достает из них основной текст.
+// Поисковик отдает сниппет в пару абзацев, глубокому исследованию нужна сама статья.
+// Обход вежливый: общий лимит параллельных загрузок, пауза между запросами
+// к одному хосту и проверка robots.txt
+package fetch
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+	"golang.org/x/text/encoding/htmlindex"
+
+	"github.com/kitbuilder587/fintech-bot/internal/cache"
+	"github.com/kitbuilder587/fintech-bot/internal/netguard"
+	"github.com/kitbuilder587/fintech-bot/internal/retry"
+	"github.com/kitbuilder587/fintech-bot/internal/search"
+)
+
+var (
+	ErrDisallowed         = errors.New("fetch disallowed by robots.txt")
+	ErrUnsupportedContent = errors.New("unsupported content type")
+)
+
+const (
+	// больше не читаем: статьи укладываются с запасом, а видео отсекается раньше
+	maxBodySize = 5 << 20
+	// годовые отчеты с графикой весят десятки мегабайт
+	maxPDFSize = 20 << 20
+)
+
+type Config struct {
+	Concurrency  int           // одновременных загрузок на все хосты
+	HostDelay    time.Duration // между запросами к одному хосту
+	Timeout      time.Duration // на один HTTP запрос
+	MaxChars     int           // текста страницы в результате, в символах
+	CacheTTL     time.Duration // для страниц и robots.txt
+	Retry        retry.Policy  // пустая - одна попытка: страницы не критичны
+	UserAgent    string
+	AllowPrivate bool // ходить и во внутреннюю сеть, только для тестов
+}
+
+type Page struct {
+	URL   string
+	Title string
+	Text  string
+	// у PDF - все страницы целиком, Text - их начало в пределах MaxChars
+	Pages []search.DocPage
+}
+
+type Fetcher struct {
+	cache  cache.Cache
+	cfg    Config
+	client *retry.Client
+	logger *zap.Logger
+
+	sem chan struct{}
+
+	mu    sync.Mutex
+	hosts map[string]*host
+}
+
+// host - очередь запросов к одному хосту. lock - канал, а не мьютекс,
+// чтобы ожидание очереди прерывалось отменой ctx
+type host struct {
+	lock chan struct{}
+	next time.Time // раньше этого момента к хосту не ходим
+}
+
+func New(c cache.Cache, cfg Config, logger *zap.Logger) *Fetcher {
+	if cfg.Concurrency == 0 {
+		cfg.Concurrency = 4
+	}
+	if cfg.HostDelay == 0 {
+		cfg.HostDelay = time.Second
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	if cfg.MaxChars == 0 {
+		cfg.MaxChars = 6000
+	}
+	if cfg.CacheTTL == 0 {
+		cfg.CacheTTL = 24 * time.Hour
+	}
+	if cfg.Retry == (retry.Policy{}) {
+		cfg.Retry = retry.Policy{MaxAttempts: 1}
+	}
+	if cfg.UserAgent == "" {
+		cfg.UserAgent = "fintech-bot page fetcher"
+	}
+
+	// ссылки из выдачи ведут куда угодно, во внутреннюю сеть по ним не ходим
+	httpClient := &http.Client{Timeout: cfg.Timeout}
+	if !cfg.AllowPrivate {
+		httpClient.Transport = netguard.Transport()
+	}
+
+	return &Fetcher{
+		cache:  c,
+		cfg:    cfg,
+		client: retry.NewClient(httpClient, cfg.Retry),
+		logger: logger,
+		sem:    make(chan struct{}, cfg.Concurrency),
+		hosts:  make(map[string]*host),
+	}
+}
+
+// Enrich заменяет Content первых topK результатов текстом их страниц.
+// Из PDF берутся страницы, больше всего похожие на запрос, с пометками [p.N].
+// Страница, которую не удалось скачать или которая короче сниппета, оставляет сниппет.
+// Исходный слайс не меняется: он может лежать в кеше поиска
+func (f *Fetcher) Enrich(ctx context.Context, query string, results []search.SearchResult, topK int) []search.SearchResult {
+	out := make([]search.SearchResult, len(results))
+	copy(out, results)
+
+	n := min(topK, len(out))
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			page, err := f.Fetch(ctx, out[i].URL)
+			if err != nil {
+				f.logger.Debug("page fetch failed", zap.String("url", out[i].URL), zap.Error(err))
+				return
+			}
+			if len(page.Pages) > 0 {
+				out[i].Pages = selectPages(page.Pages, query, f.cfg.MaxChars)
+				out[i].Content = search.PagesContent(out[i].Pages)
+				return
+			}
+			if len(page.Text) > len(out[i].Content) {
+				out[i].Content = page.Text
+			}
+		}()
+	}
+	wg.Wait()
+
+	return out
+}
+
+// Fetch скачивает страницу и возвращает ее основной текст. Удачные загрузки
+// кешируются на CacheTTL
+func (f *Fetcher) Fetch(ctx context.Context, rawURL string) (*Page, error) {
+	key := pageKey(rawURL)
+	if cached, ok := f.cache.Get(key); ok {
+		if page, ok := cached.(*Page); ok {
+			return page, nil
+		}
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid url %q", rawURL)
+	}
+
+	h := f.host(u.Host)
+	select {
+	case h.lock <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	defer func() { <-h.lock }()
+
+	rules := f.robots(ctx, h, u)
+	if !rules.allowed(u.EscapedPath()) {
+		return nil, ErrDisallowed
+	}
+
+	resp, body, err := f.get(ctx, h, u.String())
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("get %s: status %d", u, resp.StatusCode)
+	}
+
+	page, err := toPage(u.String(), resp.Header.Get("Content-Type"), body)
+	if err != nil {
+		return nil, err
+	}
+	page.Text = truncate(page.Text, f.cfg.MaxChars)
+
+	f.cache.Set(key, page, f.cfg.CacheTTL)
+	return page, nil
+}
+
+// toPage - HTML разбирается экстрактором, простой текст берется как есть, PDF - по страницам.
+// Текст в другой кодировке (windows-1251, koi8-r) перекодируется в UTF-8
+func toPage(u, contentType string, body []byte) (*Page, error) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		// без заголовка угадываем по содержимому
+		mediaType, _, _ = strings.Cut(http.DetectContentType(body), ";")
+	}
+	// сайты отчетов часто отдают PDF как octet-stream
+	if mediaType == "application/pdf" || (mediaType == "application/octet-stream" && isPDF(body)) {
+		return pdfToPage(u, body)
+	}
+
+	switch mediaType {
+	case "text/html", "application/xhtml+xml":
+		body, err = toUTF8(body, params["charset"], true)
+		if err != nil {
+			return nil, err
+		}
+		title, text := Extract(string(body))
+		return &Page{URL: u, Title: title, Text: text}, nil
+	case "text/plain":
+		body, err = toUTF8(body, params["charset"], false)
+		if err != nil {
+			return nil, err
+		}
+		return &Page{URL: u, Text: strings.TrimSpace(string(body))}, nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedContent, mediaType)
+	}
+}
+
+var metaCharsetRe = regexp.MustCompile(`(?i)<meta[^>]+charset\s*=\s*["']?([\w.:-]+)`)
+
+// toUTF8 перекодирует тело по charset из заголовка, а у HTML без него - по <meta> в начале
+// страницы. Без указанной кодировки тело должно быть в UTF-8
+func toUTF8(body []byte, charset string, html bool) ([]byte, error) {
+	if charset == "" && html && !utf8.Valid(body) {
+		if m := metaCharsetRe.FindSubmatch(body[:min(len(body), 1024)]); m != nil {
+			charset = string(m[1])
+		}
+	}
+	if charset == "" {
+		if !utf8.Valid(body) {
+			return nil, fmt.Errorf("%w: body is not utf-8", ErrUnsupportedContent)
+		}
+		return body, nil
+	}
+
+	enc, err := htmlindex.Get(charset)
+	if err != nil {
+		return nil, fmt.Errorf("%w: charset %s", ErrUnsupportedContent, charset)
+	}
+	if name, _ := htmlindex.Name(enc); name == "utf-8" {
+		if !utf8.Valid(body) {
+			return nil, fmt.Errorf("%w: body is not utf-8", ErrUnsupportedContent)
+		}
+		return body, nil
+	}
+	out, err := enc.NewDecoder().Bytes(body)
+	if err != nil {
+		return nil, fmt.Errorf("%w: decode %s: %v", ErrUnsupportedContent, charset, err)
+	}
+	return out, nil
+}
+
+func isPDF(body []byte) bool {
+	return strings.HasPrefix(string(body[:min(len(body), 5)]), "%PDF-")
+}
+
+// pdfToPage - текст документа и его страницы; пустые страницы пропускаются,
+// но номера остаются как в файле
+func pdfToPage(u string, body []byte) (*Page, error) {
+	title, texts, err := ExtractPDF(body)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &Page{URL: u, Title: title}
+	var text []string
+	for i, t := range texts {
+		if t == "" {
+			continue
+		}
+		page.Pages = append(page.Pages, search.DocPage{Number: i + 1, Text: t})
+		text = append(text, t)
+	}
+	page.Text = strings.Join(text, "\n\n")
+	return page, nil
+}
+
+// selectPages выбирает страницы, где чаще встречаются слова запроса, пока они
+// помещаются в maxChars, и возвращает их в порядке документа. Если не поместилась
+// ни одна, первая по счету обрезается
+func selectPages(pages []search.DocPage, query string, maxChars int) []search.DocPage {
+	terms := queryTerms(query)
+	scores := make([]int, len(pages))
+	for i, p := range pages {
+		text := strings.ToLower(p.Text)
+		for _, t := range terms {
+			scores[i] += min(strings.Count(text, t), 5)
+		}
+	}
+
+	order := make([]int, len(pages))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return scores[order[a]] > scores[order[b]] })
+
+	var selected []search.DocPage
+	used := 0
+	for _, i := range order {
+		n := utf8.RuneCountInString(pages[i].Text)
+		if used+n > maxChars {
+			continue
+		}
+		selected = append(selected, pages[i])
+		used += n
+	}
+	if len(selected) == 0 && len(pages) > 0 {
+		first := pages[order[0]]
+		first.Text = truncate(first.Text, maxChars)
+		return []search.DocPage{first}
+	}
+
+	sort.Slice(selected, func(a, b int) bool { return selected[a].Number < selected[b].Number })
+	return selected
+}
+
+// queryTerms - слова запроса длиннее двух букв в нижнем регистре
+func queryTerms(query string) []string {
+	var terms []string
+	for _, w := range strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if utf8.RuneCountInString(w) > 2 && !slices.Contains(terms, w) {
+			terms = append(terms, w)
+		}
+	}
+	return terms
+}
+
+// robots - правила хоста из кеша или свежий robots.txt. Вызывается под h.lock
+func (f *Fetcher) robots(ctx context.Context, h *host, u *url.URL) *robots {
+	key := "robots:" + u.Scheme + "://" + u.Host
+	if cached, ok := f.cache.Get(key); ok {
+		if rules, ok := cached.(*robots); ok {
+			return rules
+		}
+	}
+
+	var rules *robots
+	resp, body, err := f.get(ctx, h, u.Scheme+"://"+u.Host+"/robots.txt")
+	switch {
+	case err != nil:
+		if ctx.Err() != nil {
+			return disallowAll // отмена - не повод запоминать запрет
+		}
+		rules = disallowAll
+	case resp.StatusCode == http.StatusOK:
+		rules = parseRobots(body, f.cfg.UserAgent)
+	case resp.StatusCode >= 400 && resp.StatusCode < 500:
+		// robots.txt нет или он закрыт - по RFC 9309 ограничений нет
+		rules = allowAll
+	default:
+		rules = disallowAll
+	}
+
+	f.cache.Set(key, rules, f.cfg.CacheTTL)
+	return rules
+}
+
+// get выдерживает паузу хоста и занимает слот общего лимита. Вызывается под h.lock
+func (f *Fetcher) get(ctx context.Context, h *host, u string) (*http.Response, []byte, error) {
+	if wait := time.Until(h.next); wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		}
+	}
+
+	select {
+	case f.sem <- struct{}{}:
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	}
+	defer func() { <-f.sem }()
+	defer func() { h.next = time.Now().Add(f.cfg.HostDelay) }()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("User-Agent", f.cfg.UserAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml,text/plain;q=0.9,application/pdf;q=0.8")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	limit := int64(maxBodySize)
+	if mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mediaType == "application/pdf" || mediaType == "application/octet-stream" {
+		limit = maxPDFSize
+	}
+	// байт сверх лимита нужен, чтобы отличить файл ровно в лимит от обрезанного
+	body, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, nil, fmt.Errorf("read %s: %w", u, err)
+	}
+	if int64(len(body)) > limit {
+		// обрезанный HTML читается, а у PDF без конца теряются объекты и страницы
+		if isPDF(body) {
+			return nil, nil, fmt.Errorf("%w: pdf larger than %d MB", ErrUnsupportedContent, limit>>20)
+		}
+		body = body[:limit]
+	}
+	return resp, body, nil
+}
+
+func (f *Fetcher) host(name string) *host {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	h, ok := f.hosts[name]
+	if !ok {
+		h = &host{lock: make(chan struct{}, 1)}
+		f.hosts[name] = h
+	}
+	return h
+}
+
+func pageKey(u string) string {
+	hash := sha256.Sum256([]byte(search.CanonicalURL(u)))
+	return fmt.Sprintf("page:%x", hash[:8])
+}
+
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n]) + "..."
+}
@@ -0,0 +1,315 @@
+package fetch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/text/encoding/charmap"
+
+	"github.com/kitbuilder587/fintech-bot/internal/cache/memory"
+	"github.com/kitbuilder587/fintech-bot/internal/search"
+)
+
+// site - сервер с robots.txt и счетчиком запросов к страницам
+type site struct {
+	mu    sync.Mutex
+	hits  map[string]int
+	times []time.Time
+}
+
+func newSite(t *testing.T, robotsStatus int) (*site, *httptest.Server) {
+	s := &site{hits: make(map[string]int)}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.hits[r.URL.Path]++
+		s.times = append(s.times, time.Now())
+		s.mu.Unlock()
+
+		switch {
+		case r.URL.Path == "/robots.txt":
+			w.WriteHeader(robotsStatus)
+			fmt.Fprint(w, "User-agent: *\nDisallow: /private/\n")
+		case r.URL.Path == "/report.pdf":
+			w.Header().Set("Content-Type", "application/pdf")
+			w.Write(reportPDF())
+		case r.URL.Path == "/download":
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Write(reportPDF())
+		case r.URL.Path == "/scan.pdf":
+			w.Header().Set("Content-Type", "application/pdf")
+			fmt.Fprint(w, "%PDF-1.7")
+		case r.URL.Path == "/notes.txt":
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			fmt.Fprint(w, "  plain text notes  ")
+		case r.URL.Path == "/cp1251":
+			w.Header().Set("Content-Type", "text/html; charset=windows-1251")
+			body, _ := charmap.Windows1251.NewEncoder().String("<p>Платежи выросли</p>")
+			fmt.Fprint(w, body)
+		case r.URL.Path == "/koi8":
+			// кодировка только в <meta>, в заголовке ее нет
+			w.Header().Set("Content-Type", "text/html")
+			body, _ := charmap.KOI8R.NewEncoder().String(`<html><head><meta charset="koi8-r"></head><body><p>Рынок BNPL</p></body></html>`)
+			fmt.Fprint(w, body)
+		case r.URL.Path == "/unknown-charset":
+			w.Header().Set("Content-Type", "text/html; charset=x-martian")
+			fmt.Fprint(w, "<p>text</p>")
+		case r.URL.Path == "/huge.pdf":
+			w.Header().Set("Content-Type", "application/pdf")
+			w.Write(reportPDF())
+			w.Write(make([]byte, maxPDFSize))
+		default:
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			fmt.Fprint(w, articlePage)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return s, server
+}
+
+func (s *site) count(path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.hits[path]
+}
+
+func newFetcher(t *testing.T, cfg Config) *Fetcher {
+	c := memory.New()
+	t.Cleanup(c.Stop)
+	if cfg.HostDelay == 0 {
+		cfg.HostDelay = time.Millisecond
+	}
+	cfg.AllowPrivate = true
+	return New(c, cfg, zap.NewNop())
+}
+
+func TestFetcher_Fetch(t *testing.T) {
+	s, server := newSite(t, http.StatusOK)
+	f := newFetcher(t, Config{})
+
+	page, err := f.Fetch(context.Background(), server.URL+"/news/klarna")
+	if err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+	if page.Title != "Klarna files for IPO" || !strings.Contains(page.Text, "initial public offering") {
+		t.Errorf("page = %+v", page)
+	}
+
+	// повторно - из кеша, robots.txt тоже не перечитывается
+	if _, err := f.Fetch(context.Background(), server.URL+"/news/klarna"); err != nil {
+		t.Fatalf("Fetch() cached error = %v", err)
+	}
+	if s.count("/news/klarna") != 1 || s.count("/robots.txt") != 1 {
+		t.Errorf("page hits = %d, robots hits = %d; want 1 and 1", s.count("/news/klarna"), s.count("/robots.txt"))
+	}
+}
+
+func TestFetcher_Fetch_RefusesPrivateAddresses(t *testing.T) {
+	s, server := newSite(t, http.StatusOK)
+	c := memory.New()
+	t.Cleanup(c.Stop)
+	f := New(c, Config{HostDelay: time.Millisecond}, zap.NewNop())
+
+	// robots.txt уже не скачать, так что до страницы дело не доходит
+	if _, err := f.Fetch(context.Background(), server.URL+"/news/klarna"); err == nil {
+		t.Fatal("Fetch() error = nil, want refusal")
+	}
+	if s.count("/news/klarna") != 0 || s.count("/robots.txt") != 0 {
+		t.Error("loopback site must not be requested")
+	}
+}
+
+func TestFetcher_Fetch_Robots(t *testing.T) {
+	tests := []struct {
+		name         string
+		robotsStatus int
+		path         string
+		wantErr      error
+	}{
+		{"disallowed path", http.StatusOK, "/private/deal", ErrDisallowed},
+		{"allowed path", http.StatusOK, "/news", nil},
+		{"no robots.txt", http.StatusNotFound, "/private/deal", nil},
+		{"robots.txt server error", http.StatusServiceUnavailable, "/news", ErrDisallowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, server := newSite(t, tt.robotsStatus)
+			f := newFetcher(t, Config{})
+
+			_, err := f.Fetch(context.Background(), server.URL+tt.path)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Fetch() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil && s.count(tt.path) != 0 {
+				t.Errorf("disallowed page was requested")
+			}
+		})
+	}
+}
+
+func TestFetcher_Fetch_ContentTypes(t *testing.T) {
+	_, server := newSite(t, http.StatusOK)
+	f := newFetcher(t, Config{})
+
+	page, err := f.Fetch(context.Background(), server.URL+"/notes.txt")
+	if err != nil || page.Text != "plain text notes" {
+		t.Errorf("plain text: page = %+v, err = %v", page, err)
+	}
+
+	page, err = f.Fetch(context.Background(), server.URL+"/cp1251")
+	if err != nil || page.Text != "Платежи выросли" {
+		t.Errorf("cp1251: page = %+v, err = %v", page, err)
+	}
+	page, err = f.Fetch(context.Background(), server.URL+"/koi8")
+	if err != nil || page.Text != "Рынок BNPL" {
+		t.Errorf("koi8-r from meta: page = %+v, err = %v", page, err)
+	}
+	if _, err := f.Fetch(context.Background(), server.URL+"/unknown-charset"); !errors.Is(err, ErrUnsupportedContent) {
+		t.Errorf("unknown charset: error = %v, want ErrUnsupportedContent", err)
+	}
+	// обрезанный по лимиту PDF не разбираем: конец файла с объектами потерян
+	if _, err := f.Fetch(context.Background(), server.URL+"/huge.pdf"); !errors.Is(err, ErrUnsupportedContent) {
+		t.Errorf("huge.pdf: error = %v, want ErrUnsupportedContent", err)
+	}
+	if _, err := f.Fetch(context.Background(), server.URL+"/scan.pdf"); !errors.Is(err, ErrNoPDFText) {
+		t.Errorf("scan.pdf: error = %v, want ErrNoPDFText", err)
+	}
+}
+
+func TestFetcher_Fetch_PDF(t *testing.T) {
+	_, server := newSite(t, http.StatusOK)
+	f := newFetcher(t, Config{})
+
+	for _, path := range []string{"/report.pdf", "/download"} {
+		page, err := f.Fetch(context.Background(), server.URL+path)
+		if err != nil {
+			t.Fatalf("%s: Fetch() error = %v", path, err)
+		}
+		if page.Title != "Annual Report" {
+			t.Errorf("%s: title = %q, want Annual Report", path, page.Title)
+		}
+		// пустая вторая страница пропущена, номера как в файле
+		if len(page.Pages) != 2 || page.Pages[0].Number != 1 || page.Pages[1].Number != 3 {
+			t.Errorf("%s: pages = %+v, want pages 1 and 3", path, page.Pages)
+		}
+		if !strings.HasPrefix(page.Text, "Global Fintech Report") || !strings.Contains(page.Text, "every region") {
+			t.Errorf("%s: text = %q, want all pages", path, page.Text)
+		}
+	}
+}
+
+func TestFetcher_Fetch_HostDelay(t *testing.T) {
+	s, server := newSite(t, http.StatusNotFound)
+	f := newFetcher(t, Config{HostDelay: 50 * time.Millisecond, Concurrency: 8})
+
+	var wg sync.WaitGroup
+	for i := range 3 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			f.Fetch(context.Background(), fmt.Sprintf("%s/news/%d", server.URL, i))
+		}()
+	}
+	wg.Wait()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// robots.txt и три страницы - строго по очереди с паузой
+	if len(s.times) != 4 {
+		t.Fatalf("requests = %d, want 4", len(s.times))
+	}
+	for i := 1; i < len(s.times); i++ {
+		if gap := s.times[i].Sub(s.times[i-1]); gap < 45*time.Millisecond {
+			t.Errorf("gap between requests %d and %d = %v, want >= HostDelay", i-1, i, gap)
+		}
+	}
+}
+
+func TestFetcher_Fetch_MaxChars(t *testing.T) {
+	_, server := newSite(t, http.StatusOK)
+	f := newFetcher(t, Config{MaxChars: 20})
+
+	page, err := f.Fetch(context.Background(), server.URL+"/news")
+	if err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+	if got := len([]rune(page.Text)); got != 23 {
+		t.Errorf("text runes = %d, want 20 plus ellipsis", got)
+	}
+}
+
+func TestFetcher_Enrich(t *testing.T) {
+	_, server := newSite(t, http.StatusOK)
+	f := newFetcher(t, Config{})
+
+	results := []search.SearchResult{
+		{Title: "A", URL: server.URL + "/news/a", Content: "snippet a"},
+		{Title: "B", URL: server.URL + "/private/b", Content: "snippet b"},
+		{Title: "C", URL: server.URL + "/news/c", Content: "snippet c"},
+	}
+
+	enriched := f.Enrich(context.Background(), "klarna ipo", results, 2)
+
+	if !strings.Contains(enriched[0].Content, "initial public offering") {
+		t.Errorf("top result should get page text, got %q", enriched[0].Content)
+	}
+	if enriched[1].Content != "snippet b" {
+		t.Errorf("disallowed page should keep snippet, got %q", enriched[1].Content)
+	}
+	if enriched[2].Content != "snippet c" {
+		t.Errorf("result beyond topK should keep snippet, got %q", enriched[2].Content)
+	}
+	if results[0].Content != "snippet a" {
+		t.Errorf("Enrich must not modify input slice")
+	}
+}
+
+func TestFetcher_Enrich_PDFPages(t *testing.T) {
+	_, server := newSite(t, http.StatusOK)
+	// влезает только одна страница отчета
+	f := newFetcher(t, Config{MaxChars: 80})
+
+	results := []search.SearchResult{{Title: "Report", URL: server.URL + "/report.pdf", Content: "snippet"}}
+
+	enriched := f.Enrich(context.Background(), "BNPL volume by region", results, 1)
+
+	if len(enriched[0].Pages) != 1 || enriched[0].Pages[0].Number != 3 {
+		t.Fatalf("pages = %+v, want the page matching the query", enriched[0].Pages)
+	}
+	if !strings.HasPrefix(enriched[0].Content, "[p.3] BNPL volume") {
+		t.Errorf("content = %q, want page-numbered text", enriched[0].Content)
+	}
+}
+
+func TestSelectPages(t *testing.T) {
+	pages := []search.DocPage{
+		{Number: 1, Text: "Contents"},
+		{Number: 2, Text: "Payments overview"},
+		{Number: 5, Text: "Open banking: open banking APIs in Europe"},
+		{Number: 7, Text: strings.Repeat("banking ", 20)},
+	}
+
+	got := selectPages(pages, "open banking", 70)
+
+	var numbers []int
+	for _, p := range got {
+		numbers = append(numbers, p.Number)
+	}
+	// страница 7 похожа больше всех, но не влезает; остальное - в порядке документа
+	if fmt.Sprint(numbers) != "[1 2 5]" {
+		t.Errorf("selected pages = %v, want [1 2 5]", numbers)
+	}
+
+	got = selectPages(pages[3:], "open banking", 10)
+	if len(got) != 1 || got[0].Text != "banking ba..." {
+		t.Errorf("oversized page = %+v, want truncated first page", got)
+	}
+}
@@ -0,0 +1,420 @@
+package fetch
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// Разбор PDF без внешних зависимостей, только ради текста. Таблица xref не читается:
+// объекты ищутся сканированием файла, так что битые и дописанные PDF тоже читаются.
+// Не поддерживаются шифрование, фильтры кроме FlateDecode и шрифты без ToUnicode
+// с нестандартной кодировкой - такие страницы выходят пустыми
+
+var ErrNoPDFText = errors.New("pdf has no extractable text")
+
+const (
+	// больше страниц в отчетах почти не бывает, а файл на тысячу страниц - уже не отчет
+	maxPDFPages = 300
+	// вложенность форм и дерева страниц. Циклы и повторы отсекаются по номерам
+	// объектов, глубина только бережет стек
+	maxPDFDepth = 16
+	// вложенность [ и << в одном значении. Парсер рекурсивный, а переполнение стека
+	// в Go не ловится recover - файл из одних [ иначе роняет весь процесс
+	maxPDFNesting = 32
+	// распаковки на один поток и на весь файл: 20 МБ мелких zlib-бомб
+	// в ObjStm, потоках страниц и CMap иначе разворачиваются в гигабайты
+	maxPDFStream   = 32 << 20
+	maxPDFInflated = 64 << 20
+	// записей ToUnicode на все шрифты файла, у CJK-шрифта их десятки тысяч
+	maxPDFCMapEntries = 1 << 18
+)
+
+var errPDFBudget = errors.New("pdf decompression budget exhausted")
+
+var objRe = regexp.MustCompile(`(\d+)\s+(\d+)\s+obj\b`)
+
+type pdfName string
+
+type pdfRef struct{ num, gen int }
+
+type pdfDict map[pdfName]any
+
+type pdfStream struct {
+	dict pdfDict
+	raw  []byte
+}
+
+type pdfDoc struct {
+	objects   map[int]any
+	trailers  []pdfDict
+	fonts     map[int]*pdfFont // по номеру объекта шрифта
+	encrypted bool
+	budget    pdfBudget
+}
+
+// pdfBudget - сколько еще можно распаковать и разобрать в этом файле
+type pdfBudget struct {
+	inflated int // байт после FlateDecode
+	cmap     int // записей ToUnicode
+}
+
+// ExtractPDF возвращает заголовок из Info и текст страниц по порядку.
+// Пустая страница (скан, картинка) остается пустой строкой, чтобы номера не съехали
+func ExtractPDF(data []byte) (title string, pages []string, err error) {
+	// файл приходит из интернета, а Enrich зовет разбор в горутинах без recover:
+	// паника на битом PDF не должна ронять бота
+	defer func() {
+		if r := recover(); r != nil {
+			title, pages, err = "", nil, fmt.Errorf("%w: malformed pdf: %v", ErrUnsupportedContent, r)
+		}
+	}()
+
+	if !bytes.HasPrefix(bytes.TrimLeft(data, " \t\r\n"), []byte("%PDF-")) {
+		return "", nil, fmt.Errorf("%w: not a pdf", ErrUnsupportedContent)
+	}
+
+	doc := parsePDF(data)
+	if doc.encrypted {
+		return "", nil, fmt.Errorf("%w: encrypted pdf", ErrUnsupportedContent)
+	}
+
+	found := false
+	for _, page := range doc.pages() {
+		// бюджет кончился - дальше страницы вышли бы пустыми, а не настоящими
+		if doc.budget.inflated == 0 {
+			break
+		}
+		text := doc.pageText(page)
+		if text != "" {
+			found = true
+		}
+		pages = append(pages, text)
+		if len(pages) == maxPDFPages {
+			break
+		}
+	}
+	if !found {
+		return "", nil, ErrNoPDFText
+	}
+
+	return doc.title(), pages, nil
+}
+
+func parsePDF(data []byte) *pdfDoc {
+	doc := &pdfDoc{
+		objects: make(map[int]any),
+		fonts:   make(map[int]*pdfFont),
+		budget:  pdfBudget{inflated: maxPDFInflated, cmap: maxPDFCMapEntries},
+	}
+
+	for pos := 0; pos < len(data); {
+		loc := objRe.FindSubmatchIndex(data[pos:])
+		if loc == nil {
+			break
+		}
+		num, _ := strconv.Atoi(string(data[pos+loc[2] : pos+loc[3]]))
+		start := pos + loc[1]
+
+		p := &pdfParser{data: data, pos: start}
+		obj := p.value()
+		end := p.pos
+		if d, ok := obj.(pdfDict); ok {
+			if raw, next, ok := streamData(data, p.pos); ok {
+				obj = &pdfStream{dict: d, raw: raw}
+				end = next
+			}
+		}
+		doc.objects[num] = obj
+
+		if s, ok := obj.(*pdfStream); ok {
+			switch s.dict["Type"] {
+			case pdfName("ObjStm"):
+				doc.unpackObjStm(s)
+			case pdfName("XRef"):
+				doc.trailers = append(doc.trailers, s.dict)
+			}
+		}
+
+		if i := bytes.Index(data[end:], []byte("endobj")); i >= 0 {
+			end += i + len("endobj")
+		}
+		pos = max(end, start)
+	}
+
+	// классический трейлер, дописанные обновления добавляют новые
+	for _, loc := range regexp.MustCompile(`trailer\s*<<`).FindAllIndex(data, -1) {
+		p := &pdfParser{data: data, pos: loc[0] + len("trailer")}
+		if d, ok := p.value().(pdfDict); ok {
+			doc.trailers = append(doc.trailers, d)
+		}
+	}
+	for _, t := range doc.trailers {
+		if _, ok := t["Encrypt"]; ok {
+			doc.encrypted = true
+		}
+	}
+
+	return doc
+}
+
+// streamData - данные потока после словаря. Длину не берем из /Length:
+// она бывает косвенной ссылкой или просто неверной
+func streamData(data []byte, pos int) (raw []byte, next int, ok bool) {
+	rest := data[pos:]
+	trimmed := bytes.TrimLeft(rest, " \t\r\n")
+	if !bytes.HasPrefix(trimmed, []byte("stream")) {
+		return nil, 0, false
+	}
+	start := pos + len(rest) - len(trimmed) + len("stream")
+	if start < len(data) && data[start] == '\r' {
+		start++
+	}
+	if start < len(data) && data[start] == '\n' {
+		start++
+	}
+
+	end := bytes.Index(data[start:], []byte("endstream"))
+	if end < 0 {
+		return data[start:], len(data), true
+	}
+	raw = bytes.TrimRight(data[start:start+end], "\r\n")
+	return raw, start + end + len("endstream"), true
+}
+
+// unpackObjStm достает объекты, сжатые в поток (PDF 1.5+): там обычно лежат
+// страницы и шрифты
+func (d *pdfDoc) unpackObjStm(s *pdfStream) {
+	data, err := d.decodeStream(s)
+	if err != nil {
+		return
+	}
+	n, ok1 := pdfInt(d.resolve(s.dict["N"]))
+	first, ok2 := pdfInt(d.resolve(s.dict["First"]))
+	if !ok1 || !ok2 || first > len(data) {
+		return
+	}
+
+	header := &pdfParser{data: data[:first]}
+	for range n {
+		num, ok1 := pdfInt(header.value())
+		off, ok2 := pdfInt(header.value())
+		if !ok1 || !ok2 || off >= len(data)-first {
+			return
+		}
+		p := &pdfParser{data: data, pos: first + off}
+		d.objects[num] = p.value()
+	}
+}
+
+// pdfInt - неотрицательное целое; числа из файла нельзя брать в срезы не проверив
+func pdfInt(v any) (int, bool) {
+	f, ok := v.(float64)
+	if !ok || f < 0 || f > math.MaxInt32 || f != math.Trunc(f) {
+		return 0, false
+	}
+	return int(f), true
+}
+
+func (d *pdfDoc) resolve(v any) any {
+	for range maxPDFDepth {
+		ref, ok := v.(pdfRef)
+		if !ok {
+			return v
+		}
+		v = d.objects[ref.num]
+	}
+	return nil
+}
+
+func (d *pdfDoc) dict(v any) pdfDict {
+	switch o := d.resolve(v).(type) {
+	case pdfDict:
+		return o
+	case *pdfStream:
+		return o.dict
+	}
+	return nil
+}
+
+func (d *pdfDoc) title() string {
+	for i := len(d.trailers) - 1; i >= 0; i-- {
+		if info := d.dict(d.trailers[i]["Info"]); info != nil {
+			if t, ok := d.resolve(info["Title"]).([]byte); ok {
+				return strings.TrimSpace(textString(t))
+			}
+		}
+	}
+	return ""
+}
+
+// pdfPage - словарь страницы и ресурсы, в том числе унаследованные от родителей
+type pdfPage struct {
+	dict      pdfDict
+	resources pdfDict
+}
+
+// pages обходит дерево страниц от каталога. Без каталога - все объекты /Page
+// в порядке номеров, это лучше, чем ничего
+func (d *pdfDoc) pages() []pdfPage {
+	var pages []pdfPage
+	// общий на все обходы: узел, не давший страниц в одном, не даст их и в другом
+	visited := make(map[int]bool)
+	for i := len(d.trailers) - 1; i >= 0 && len(pages) == 0; i-- {
+		if root := d.dict(d.trailers[i]["Root"]); root != nil {
+			d.walkPages(root["Pages"], nil, visited, 0, &pages)
+		}
+	}
+	if len(pages) > 0 {
+		return pages
+	}
+
+	for _, num := range d.objectNumbers() {
+		if dict := d.dict(pdfRef{num: num}); dict["Type"] == pdfName("Catalog") {
+			d.walkPages(dict["Pages"], nil, visited, 0, &pages)
+			if len(pages) > 0 {
+				return pages
+			}
+		}
+	}
+	for _, num := range d.objectNumbers() {
+		if dict := d.dict(pdfRef{num: num}); dict["Type"] == pdfName("Page") {
+			pages = append(pages, pdfPage{dict: dict, resources: d.dict(dict["Resources"])})
+		}
+	}
+	return pages
+}
+
+func (d *pdfDoc) objectNumbers() []int {
+	nums := make([]int, 0, len(d.objects))
+	for n := range d.objects {
+		nums = append(nums, n)
+	}
+	sort.Ints(nums)
+	return nums
+}
+
+// walkPages заходит в каждый объект один раз: узел, перечисленный в /Kids
+// многократно или ссылающийся сам на себя, иначе дает экспоненциальный обход
+func (d *pdfDoc) walkPages(node any, resources pdfDict, visited map[int]bool, depth int, pages *[]pdfPage) {
+	if ref, ok := node.(pdfRef); ok {
+		if visited[ref.num] {
+			return
+		}
+		visited[ref.num] = true
+	}
+	dict := d.dict(node)
+	if dict == nil || depth > maxPDFDepth || len(*pages) >= maxPDFPages {
+		return
+	}
+	if r := d.dict(dict["Resources"]); r != nil {
+		resources = r
+	}
+
+	kids, ok := d.resolve(dict["Kids"]).([]any)
+	if !ok {
+		*pages = append(*pages, pdfPage{dict: dict, resources: resources})
+		return
+	}
+	for _, kid := range kids {
+		d.walkPages(kid, resources, visited, depth+1, pages)
+	}
+}
+
+func (d *pdfDoc) pageText(page pdfPage) string {
+	var content []byte
+	switch c := d.resolve(page.dict["Contents"]).(type) {
+	case *pdfStream:
+		content, _ = d.decodeStream(c)
+	case []any:
+		for _, part := range c {
+			if s, ok := d.resolve(part).(*pdfStream); ok {
+				data, err := d.decodeStream(s)
+				if err == nil {
+					content = append(append(content, data...), '\n')
+				}
+			}
+		}
+	}
+
+	w := &textWriter{}
+	d.runContent(content, page.resources, w, make(map[int]bool), 0)
+	return w.String()
+}
+
+// decodeStream распаковывает поток в пределах бюджета файла
+func (d *pdfDoc) decodeStream(s *pdfStream) ([]byte, error) {
+	var filters []any
+	switch f := s.dict["Filter"].(type) {
+	case nil:
+		return s.raw, nil
+	case pdfName:
+		filters = []any{f}
+	case []any:
+		filters = f
+	}
+
+	data := s.raw
+	for _, f := range filters {
+		if f != pdfName("FlateDecode") {
+			return nil, fmt.Errorf("unsupported filter %v", f)
+		}
+		if d.budget.inflated == 0 {
+			return nil, errPDFBudget
+		}
+		r, err := zlib.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		// обрезанный поток часто читается почти целиком, берем что есть
+		out, err := io.ReadAll(io.LimitReader(r, int64(min(maxPDFStream, d.budget.inflated))))
+		d.budget.inflated -= len(out)
+		if err != nil && len(out) == 0 {
+			return nil, err
+		}
+		data = out
+	}
+	return data, nil
+}
+
+// textString - строка вне потока страницы (Info): UTF-16BE с BOM или PDFDocEncoding
+func textString(b []byte) string {
+	if len(b) >= 2 && b[0] == 0xFE && b[1] == 0xFF {
+		return utf16BE(b[2:])
+	}
+	return latin1(b)
+}
+
+func utf16BE(b []byte) string {
+	u := make([]uint16, 0, len(b)/2)
+	for i := 0; i+1 < len(b); i += 2 {
+		u = append(u, uint16(b[i])<<8|uint16(b[i+1]))
+	}
+	return string(utf16.Decode(u))
+}
+
+// winAnsi - символы WinAnsiEncoding в 0x80-0x9F, остальное совпадает с Latin-1
+var winAnsi = map[byte]rune{
+	0x80: '€', 0x85: '…', 0x91: '‘', 0x92: '’', 0x93: '“', 0x94: '”',
+	0x95: '•', 0x96: '–', 0x97: '—', 0x99: '™',
+}
+
+func latin1(b []byte) string {
+	var sb strings.Builder
+	for _, c := range b {
+		if r, ok := winAnsi[c]; ok {
+			sb.WriteRune(r)
+		} else {
+			sb.WriteRune(rune(c))
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,653 @@
+package fetch
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// pdfKeyword - слово без косой черты: оператор потока страницы, true/false/null, R
+type pdfKeyword string
+
+// pdfDelim - закрывающий ] или >> без пары
+type pdfDelim byte
+
+// pdfParser читает и объекты файла, и потоки страниц: синтаксис у них общий
+type pdfParser struct {
+	data    []byte
+	pos     int
+	nesting int  // открытых [ и << над текущим значением
+	tooDeep bool // вложенность превысила maxPDFNesting, дальше данных нет
+}
+
+func isPDFSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n' || c == '\f' || c == 0
+}
+
+func isPDFDelim(c byte) bool {
+	return strings.IndexByte("()<>[]{}/%", c) >= 0
+}
+
+func (p *pdfParser) skipSpace() {
+	for p.pos < len(p.data) {
+		c := p.data[p.pos]
+		switch {
+		case isPDFSpace(c):
+			p.pos++
+		case c == '%':
+			for p.pos < len(p.data) && p.data[p.pos] != '\n' && p.data[p.pos] != '\r' {
+				p.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+// value - следующее значение; "12 0 R" превращается в ссылку. nil - конец данных
+func (p *pdfParser) value() any {
+	v := p.next()
+	num, ok := v.(float64)
+	if !ok || num != math.Trunc(num) || num < 0 {
+		return v
+	}
+
+	save := p.pos
+	if gen, ok := p.next().(float64); ok {
+		if p.next() == pdfKeyword("R") {
+			return pdfRef{num: int(num), gen: int(gen)}
+		}
+	}
+	p.pos = save
+	return num
+}
+
+func (p *pdfParser) next() any {
+	p.skipSpace()
+	if p.pos >= len(p.data) || p.tooDeep {
+		return nil
+	}
+
+	c := p.data[p.pos]
+	switch {
+	case c == '/':
+		return p.name()
+	case c == '(':
+		return p.literal()
+	case (c == '[' || c == '<' && p.peek(1) == '<') && p.nesting >= maxPDFNesting:
+		// дальше не разбираем: для всех вызывающих это конец данных
+		p.tooDeep = true
+		return nil
+	case c == '<' && p.peek(1) == '<':
+		p.pos += 2
+		p.nesting++
+		defer func() { p.nesting-- }()
+		return p.dictBody()
+	case c == '<':
+		return p.hex()
+	case c == '[':
+		p.pos++
+		p.nesting++
+		defer func() { p.nesting-- }()
+		var arr []any
+		for {
+			v := p.value()
+			if v == nil || v == pdfDelim(']') {
+				return arr
+			}
+			arr = append(arr, v)
+		}
+	case c == ']':
+		p.pos++
+		return pdfDelim(']')
+	case c == '>' && p.peek(1) == '>':
+		p.pos += 2
+		return pdfDelim('>')
+	case c == '+' || c == '-' || c == '.' || (c >= '0' && c <= '9'):
+		start := p.pos
+		p.pos++
+		for p.pos < len(p.data) && (p.data[p.pos] == '.' || (p.data[p.pos] >= '0' && p.data[p.pos] <= '9')) {
+			p.pos++
+		}
+		f, err := strconv.ParseFloat(string(p.data[start:p.pos]), 64)
+		if err != nil {
+			return pdfKeyword(p.data[start:p.pos])
+		}
+		return f
+	case isPDFDelim(c):
+		// одиночные { } > и прочий мусор пропускаем, чтобы не зациклиться
+		p.pos++
+		return pdfKeyword(string(c))
+	default:
+		start := p.pos
+		for p.pos < len(p.data) && !isPDFSpace(p.data[p.pos]) && !isPDFDelim(p.data[p.pos]) {
+			p.pos++
+		}
+		switch kw := string(p.data[start:p.pos]); kw {
+		case "true":
+			return true
+		case "false":
+			return false
+		case "null":
+			return pdfKeyword("null")
+		default:
+			return pdfKeyword(kw)
+		}
+	}
+}
+
+func (p *pdfParser) peek(n int) byte {
+	if p.pos+n < len(p.data) {
+		return p.data[p.pos+n]
+	}
+	return 0
+}
+
+func (p *pdfParser) dictBody() pdfDict {
+	d := make(pdfDict)
+	for {
+		k := p.next()
+		key, ok := k.(pdfName)
+		if !ok {
+			return d // >> или конец данных
+		}
+		v := p.value()
+		if v == pdfDelim('>') || v == nil {
+			return d
+		}
+		d[key] = v
+	}
+}
+
+func (p *pdfParser) name() pdfName {
+	p.pos++
+	var sb strings.Builder
+	for p.pos < len(p.data) && !isPDFSpace(p.data[p.pos]) && !isPDFDelim(p.data[p.pos]) {
+		c := p.data[p.pos]
+		if c == '#' && p.pos+2 < len(p.data) {
+			if b, err := strconv.ParseUint(string(p.data[p.pos+1:p.pos+3]), 16, 8); err == nil {
+				sb.WriteByte(byte(b))
+				p.pos += 3
+				continue
+			}
+		}
+		sb.WriteByte(c)
+		p.pos++
+	}
+	return pdfName(sb.String())
+}
+
+func (p *pdfParser) literal() []byte {
+	p.pos++
+	var out []byte
+	depth := 1
+	for p.pos < len(p.data) {
+		c := p.data[p.pos]
+		p.pos++
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return out
+			}
+		case '\\':
+			if p.pos >= len(p.data) {
+				return out
+			}
+			e := p.data[p.pos]
+			p.pos++
+			switch e {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			case 'f':
+				c = '\f'
+			case '\r':
+				if p.pos < len(p.data) && p.data[p.pos] == '\n' {
+					p.pos++
+				}
+				continue
+			case '\n':
+				continue
+			default:
+				if e >= '0' && e <= '7' {
+					n := int(e - '0')
+					for i := 0; i < 2 && p.pos < len(p.data) && p.data[p.pos] >= '0' && p.data[p.pos] <= '7'; i++ {
+						n = n*8 + int(p.data[p.pos]-'0')
+						p.pos++
+					}
+					c = byte(n)
+				} else {
+					c = e
+				}
+			}
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+func (p *pdfParser) hex() []byte {
+	p.pos++
+	var digits []byte
+	for p.pos < len(p.data) && p.data[p.pos] != '>' {
+		if c := p.data[p.pos]; !isPDFSpace(c) {
+			digits = append(digits, c)
+		}
+		p.pos++
+	}
+	p.pos++
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+
+	out := make([]byte, 0, len(digits)/2)
+	for i := 0; i < len(digits); i += 2 {
+		b, err := strconv.ParseUint(string(digits[i:i+2]), 16, 8)
+		if err != nil {
+			return out
+		}
+		out = append(out, byte(b))
+	}
+	return out
+}
+
+// skipInlineImage пропускает данные встроенной картинки после оператора ID до EI
+func (p *pdfParser) skipInlineImage() {
+	for i := p.pos + 1; i+2 <= len(p.data); i++ {
+		if p.data[i] == 'E' && p.data[i+1] == 'I' && isPDFSpace(p.data[i-1]) &&
+			(i+2 == len(p.data) || isPDFSpace(p.data[i+2])) {
+			p.pos = i + 2
+			return
+		}
+	}
+	p.pos = len(p.data)
+}
+
+// runContent выполняет текстовые операторы потока страницы или формы.
+// forms - формы, уже выполненные на этой странице
+func (d *pdfDoc) runContent(content []byte, resources pdfDict, w *textWriter, forms map[int]bool, depth int) {
+	if depth > maxPDFDepth {
+		return
+	}
+
+	p := &pdfParser{data: content}
+	var operands []any
+	var font *pdfFont
+
+	for {
+		tok := p.next()
+		if tok == nil {
+			return
+		}
+		op, ok := tok.(pdfKeyword)
+		if !ok {
+			operands = append(operands, tok)
+			continue
+		}
+
+		switch op {
+		case "BT":
+			w.hasY = false
+		case "ET":
+			w.space()
+		case "Tf":
+			if len(operands) >= 1 {
+				if name, ok := operands[0].(pdfName); ok {
+					font = d.font(resources, name)
+				}
+			}
+		case "Tj":
+			w.show(font, lastString(operands))
+		case "'", "\"":
+			w.newline()
+			w.show(font, lastString(operands))
+		case "TJ":
+			if len(operands) == 0 {
+				break
+			}
+			arr, _ := operands[len(operands)-1].([]any)
+			for _, el := range arr {
+				switch v := el.(type) {
+				case []byte:
+					w.show(font, v)
+				case float64:
+					// сдвиг больше трети em - пробел между словами
+					if v < -300 {
+						w.space()
+					}
+				}
+			}
+		case "Td", "TD":
+			if len(operands) >= 2 {
+				tx, _ := operands[len(operands)-2].(float64)
+				ty, _ := operands[len(operands)-1].(float64)
+				if ty != 0 {
+					w.newline()
+				} else if tx != 0 {
+					w.space()
+				}
+			}
+		case "Tm":
+			if len(operands) >= 6 {
+				y, _ := operands[5].(float64)
+				if w.hasY && math.Abs(y-w.y) > 1 {
+					w.newline()
+				} else {
+					w.space()
+				}
+				w.y, w.hasY = y, true
+			}
+		case "T*":
+			w.newline()
+		case "Do":
+			if len(operands) >= 1 {
+				if name, ok := operands[0].(pdfName); ok {
+					d.runForm(resources, name, w, forms, depth)
+				}
+			}
+		case "ID":
+			p.skipInlineImage()
+		}
+		operands = operands[:0]
+	}
+}
+
+// runForm - текст бывает спрятан в XObject-форме, которую страница рисует через Do.
+// Каждая форма выполняется на странице один раз: повторно нарисованный логотип или
+// колонтитул текста не добавит, а форма, рисующая себя или соседей многократно,
+// не разгонит обход экспоненциально
+func (d *pdfDoc) runForm(resources pdfDict, name pdfName, w *textWriter, forms map[int]bool, depth int) {
+	ref, ok := d.dict(resources["XObject"])[name].(pdfRef)
+	if !ok || forms[ref.num] {
+		return
+	}
+	forms[ref.num] = true
+
+	form, ok := d.resolve(ref).(*pdfStream)
+	if !ok || form.dict["Subtype"] != pdfName("Form") {
+		return
+	}
+	data, err := d.decodeStream(form)
+	if err != nil {
+		return
+	}
+	if r := d.dict(form.dict["Resources"]); r != nil {
+		resources = r
+	}
+	d.runContent(data, resources, w, forms, depth+1)
+}
+
+func lastString(operands []any) []byte {
+	if len(operands) == 0 {
+		return nil
+	}
+	b, _ := operands[len(operands)-1].([]byte)
+	return b
+}
+
+// textWriter собирает текст страницы: строки по переносам в потоке, пробелы по сдвигам
+type textWriter struct {
+	sb   strings.Builder
+	y    float64
+	hasY bool
+}
+
+func (w *textWriter) show(font *pdfFont, b []byte) {
+	if len(b) == 0 {
+		return
+	}
+	w.sb.WriteString(font.decode(b))
+}
+
+func (w *textWriter) space() {
+	w.sb.WriteByte(' ')
+}
+
+func (w *textWriter) newline() {
+	w.sb.WriteByte('\n')
+}
+
+// String схлопывает пробелы и пустые строки и склеивает переносы слов
+// ("финан-\nсовый" -> "финансовый")
+func (w *textWriter) String() string {
+	var lines []string
+	for _, line := range strings.Split(w.sb.String(), "\n") {
+		line = strings.Join(strings.Fields(line), " ")
+		if line == "" {
+			continue
+		}
+		if n := len(lines); n > 0 && strings.HasSuffix(lines[n-1], "-") && startsLower(line) {
+			lines[n-1] = strings.TrimSuffix(lines[n-1], "-") + line
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func startsLower(s string) bool {
+	for _, r := range s {
+		return unicode.IsLower(r)
+	}
+	return false
+}
+
+// pdfFont переводит коды символов строки в текст
+type pdfFont struct {
+	codeLen   int // байт на код: 1 у простых шрифтов, обычно 2 у Type0
+	toUnicode map[uint32]string
+	// простой шрифт без ToUnicode: коды - WinAnsi с заменами из /Differences
+	simple      bool
+	differences map[byte]rune
+}
+
+// decode; nil-шрифт (Tf не встретился) читаем как простой
+func (f *pdfFont) decode(b []byte) string {
+	if f == nil {
+		return latin1(b)
+	}
+
+	var sb strings.Builder
+	for i := 0; i+f.codeLen <= len(b); i += f.codeLen {
+		var code uint32
+		for _, c := range b[i : i+f.codeLen] {
+			code = code<<8 | uint32(c)
+		}
+		if s, ok := f.toUnicode[code]; ok {
+			sb.WriteString(s)
+			continue
+		}
+		if !f.simple {
+			continue // CID без ToUnicode не расшифровать
+		}
+		if r, ok := f.differences[byte(code)]; ok {
+			sb.WriteRune(r)
+		} else {
+			sb.WriteString(latin1([]byte{byte(code)}))
+		}
+	}
+	return sb.String()
+}
+
+func (d *pdfDoc) font(resources pdfDict, name pdfName) *pdfFont {
+	ref := d.dict(resources["Font"])[name]
+	if r, ok := ref.(pdfRef); ok {
+		if f, ok := d.fonts[r.num]; ok {
+			return f
+		}
+	}
+
+	dict := d.dict(ref)
+	f := &pdfFont{codeLen: 1, simple: true}
+	if dict["Subtype"] == pdfName("Type0") {
+		f.codeLen, f.simple = 2, false
+	}
+	if cmap, ok := d.resolve(dict["ToUnicode"]).(*pdfStream); ok {
+		if data, err := d.decodeStream(cmap); err == nil {
+			f.parseCMap(data, &d.budget)
+		}
+	}
+	if enc := d.dict(dict["Encoding"]); enc != nil {
+		f.differences = differences(d.resolve(enc["Differences"]))
+	}
+
+	if r, ok := ref.(pdfRef); ok {
+		d.fonts[r.num] = f
+	}
+	return f
+}
+
+// parseCMap читает из ToUnicode длину кода и соответствия bfchar/bfrange.
+// Записей берется не больше, чем осталось в budget
+func (f *pdfFont) parseCMap(data []byte, budget *pdfBudget) {
+	f.toUnicode = make(map[uint32]string)
+	p := &pdfParser{data: data}
+	var operands []any
+
+	for {
+		tok := p.next()
+		if tok == nil {
+			return
+		}
+		kw, ok := tok.(pdfKeyword)
+		if !ok {
+			operands = append(operands, tok)
+			continue
+		}
+
+		switch kw {
+		case "endcodespacerange":
+			if len(operands) >= 1 {
+				if lo, ok := operands[0].([]byte); ok && len(lo) > 0 && len(lo) <= 4 {
+					f.codeLen = len(lo)
+				}
+			}
+		case "endbfchar":
+			for i := 0; i+1 < len(operands); i += 2 {
+				src, ok1 := operands[i].([]byte)
+				dst, ok2 := operands[i+1].([]byte)
+				if ok1 && ok2 && budget.cmap > 0 {
+					f.toUnicode[bigEndian(src)] = utf16BE(dst)
+					budget.cmap--
+				}
+			}
+		case "endbfrange":
+			for i := 0; i+2 < len(operands); i += 3 {
+				lo, ok1 := operands[i].([]byte)
+				hi, ok2 := operands[i+1].([]byte)
+				if !ok1 || !ok2 {
+					continue
+				}
+				f.addRange(bigEndian(lo), bigEndian(hi), operands[i+2], budget)
+			}
+		}
+		if strings.HasPrefix(string(kw), "end") || strings.HasPrefix(string(kw), "begin") {
+			operands = operands[:0]
+		}
+	}
+}
+
+func (f *pdfFont) addRange(lo, hi uint32, dst any, budget *pdfBudget) {
+	if hi < lo || hi-lo > 0xFFFF || budget.cmap == 0 {
+		return
+	}
+	if hi-lo >= uint32(budget.cmap) {
+		hi = lo + uint32(budget.cmap) - 1
+	}
+	switch v := dst.(type) {
+	case []byte:
+		units := make([]uint16, 0, len(v)/2)
+		for i := 0; i+1 < len(v); i += 2 {
+			units = append(units, uint16(v[i])<<8|uint16(v[i+1]))
+		}
+		if len(units) == 0 {
+			return
+		}
+		for c := lo; c <= hi; c++ {
+			f.toUnicode[c] = utf16String(units)
+			units[len(units)-1]++
+			budget.cmap--
+		}
+	case []any:
+		for i, el := range v {
+			if b, ok := el.([]byte); ok && lo+uint32(i) <= hi {
+				f.toUnicode[lo+uint32(i)] = utf16BE(b)
+				budget.cmap--
+			}
+		}
+	}
+}
+
+func utf16String(units []uint16) string {
+	b := make([]byte, 0, len(units)*2)
+	for _, u := range units {
+		b = append(b, byte(u>>8), byte(u))
+	}
+	return utf16BE(b)
+}
+
+func bigEndian(b []byte) uint32 {
+	var n uint32
+	for _, c := range b {
+		n = n<<8 | uint32(c)
+	}
+	return n
+}
+
+// differences - /Differences [код /имя /имя код /имя ...]
+func differences(v any) map[byte]rune {
+	arr, ok := v.([]any)
+	if !ok {
+		return nil
+	}
+	out := make(map[byte]rune)
+	code := 0
+	for _, el := range arr {
+		switch x := el.(type) {
+		case float64:
+			code = int(x)
+		case pdfName:
+			if r, ok := glyphRune(string(x)); ok && code >= 0 && code < 256 {
+				out[byte(code)] = r
+			}
+			code++
+		}
+	}
+	return out
+}
+
+var glyphNames = map[string]rune{
+	"space": ' ', "period": '.', "comma": ',', "colon": ':', "semicolon": ';',
+	"hyphen": '-', "endash": '–', "emdash": '—', "quoteright": '’', "quoteleft": '‘',
+	"quotedblleft": '“', "quotedblright": '”', "quotesingle": '\'', "quotedbl": '"',
+	"parenleft": '(', "parenright": ')', "percent": '%', "dollar": '$', "euro": '€',
+	"slash": '/', "ampersand": '&', "question": '?', "exclam": '!', "bullet": '•',
+	"zero": '0', "one": '1', "two": '2', "three": '3', "four": '4',
+	"five": '5', "six": '6', "seven": '7', "eight": '8', "nine": '9',
+	"fi": 'ﬁ', "fl": 'ﬂ',
+}
+
+// glyphRune - символ по имени глифа Adobe: "a", "one", "uni0416"
+func glyphRune(name string) (rune, bool) {
+	if r, ok := glyphNames[name]; ok {
+		return r, true
+	}
+	if len(name) == 1 {
+		return rune(name[0]), true
+	}
+	if hex, ok := strings.CutPrefix(name, "uni"); ok && len(hex) == 4 {
+		if n, err := strconv.ParseUint(hex, 16, 32); err == nil {
+			return rune(n), true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,351 @@
+package fetch
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// buildPDF собирает файл из тел объектов: objects[0] - объект 1. Таблицу xref
+// не пишем, разбор ее и не читает
+func buildPDF(trailer string, objects ...string) []byte {
+	var b strings.Builder
+	b.WriteString("%PDF-1.5\n%\xe2\xe3\xcf\xd3\n")
+	for i, obj := range objects {
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	if trailer != "" {
+		fmt.Fprintf(&b, "trailer\n%s\n", trailer)
+	}
+	b.WriteString("%%EOF\n")
+	return []byte(b.String())
+}
+
+func pdfStreamObj(dict, data string) string {
+	return fmt.Sprintf("<< %s /Length %d >>\nstream\n%s\nendstream", dict, len(data), data)
+}
+
+func flateStreamObj(dict, data string) string {
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	zw.Write([]byte(data))
+	zw.Close()
+	return pdfStreamObj("/Filter /FlateDecode "+dict, buf.String())
+}
+
+// reportPDF - две страницы текста простым шрифтом и пустая страница между ними
+func reportPDF() []byte {
+	return buildPDF("<< /Root 1 0 R /Info 9 0 R >>",
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R 4 0 R 5 0 R] /Count 3 /Resources << /Font << /F1 6 0 R >> >> >>",
+		"<< /Type /Page /Parent 2 0 R /Contents 7 0 R >>",
+		"<< /Type /Page /Parent 2 0 R >>",
+		"<< /Type /Page /Parent 2 0 R /Contents [8 0 R 10 0 R] /Resources << /Font << /F1 6 0 R >> /XObject << /Fm1 11 0 R >> >> >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding << /Differences [150 /endash] >> >>",
+		pdfStreamObj("", "BT /F1 24 Tf 72 720 Td (Global Fintech Report) Tj 0 -30 Td [(Payments re) -20 (venue grew) -400 (7%)] TJ\n"+
+			"(\\(year over year\\)) ' ET"),
+		flateStreamObj("", "BT /F1 12 Tf 1 0 0 1 72 700 Tm (BNPL volume 2024 \\226 2025) Tj 1 0 0 1 72 686 Tm (grew in every re-) Tj\n"+
+			"1 0 0 1 72 672 Tm (gion) Tj ET"),
+		"<< /Title <FEFF0041006E006E00750061006C0020005200650070006F00720074> >>",
+		pdfStreamObj("", "BI /W 2 /H 2 /BPC 8 /CS /G ID \x00(\xff) EI\n/Fm1 Do"),
+		pdfStreamObj("/Type /XObject /Subtype /Form /Resources << /Font << /F1 6 0 R >> >>",
+			"BT /F1 9 Tf 72 60 Td (Source: company filings) Tj ET"),
+	)
+}
+
+func TestExtractPDF(t *testing.T) {
+	title, pages, err := ExtractPDF(reportPDF())
+	if err != nil {
+		t.Fatalf("ExtractPDF() error = %v", err)
+	}
+
+	if title != "Annual Report" {
+		t.Errorf("title = %q, want Annual Report", title)
+	}
+	want := []string{
+		"Global Fintech Report\nPayments revenue grew 7%\n(year over year)",
+		"",
+		"BNPL volume 2024 – 2025\ngrew in every region\nSource: company filings",
+	}
+	if len(pages) != len(want) {
+		t.Fatalf("pages = %q, want %d pages", pages, len(want))
+	}
+	for i := range want {
+		if pages[i] != want[i] {
+			t.Errorf("page %d = %q, want %q", i+1, pages[i], want[i])
+		}
+	}
+}
+
+func TestExtractPDF_ToUnicode(t *testing.T) {
+	cmap := "/CIDInit /ProcSet findresource begin 12 dict begin begincmap\n" +
+		"1 begincodespacerange <0000> <FFFF> endcodespacerange\n" +
+		"2 beginbfchar <0003> <0020> <0010> <0025> endbfchar\n" +
+		"2 beginbfrange <0020> <0025> <0440> <0030> <0032> [<0424> <0438> <043D>] endbfrange\n" +
+		"endcmap CMapName currentdict /CMap defineresource pop end end"
+
+	data := buildPDF("<< /Root 1 0 R >>",
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /Resources << /Font << /F2 4 0 R >> >> /Contents 5 0 R >>",
+		"<< /Type /Font /Subtype /Type0 /BaseFont /ABCDEF+Arial /Encoding /Identity-H /ToUnicode 6 0 R >>",
+		flateStreamObj("", "BT /F2 10 Tf <003000310032> Tj <0003> Tj <00200021002200230024> Tj <0010> Tj ET"),
+		flateStreamObj("", cmap),
+	)
+
+	_, pages, err := ExtractPDF(data)
+	if err != nil {
+		t.Fatalf("ExtractPDF() error = %v", err)
+	}
+	if len(pages) != 1 || pages[0] != "Фин рстуф%" {
+		t.Errorf("pages = %q, want [\"Фин рстуф%%\"]", pages)
+	}
+}
+
+func TestExtractPDF_ObjectStream(t *testing.T) {
+	// каталог, дерево страниц и шрифт сжаты в поток объектов, корень - в XRef-потоке
+	objs := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /Resources << /Font << /F1 4 0 R >> >> /Contents 6 0 R >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Times-Roman >>",
+	}
+	var header, body strings.Builder
+	for i, obj := range objs {
+		fmt.Fprintf(&header, "%d %d ", i+1, body.Len())
+		body.WriteString(obj + "\n")
+	}
+	objStm := flateStreamObj(fmt.Sprintf("/Type /ObjStm /N %d /First %d", len(objs), header.Len()), header.String()+body.String())
+
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.7\n")
+	fmt.Fprintf(&b, "5 0 obj\n%s\nendobj\n", objStm)
+	fmt.Fprintf(&b, "6 0 obj\n%s\nendobj\n", flateStreamObj("", "BT /F1 11 Tf 50 700 Td (Open banking adoption) Tj ET"))
+	fmt.Fprintf(&b, "7 0 obj\n%s\nendobj\n", flateStreamObj("/Type /XRef /Root 1 0 R /Size 8", "\x01\x00\x00"))
+	b.WriteString("startxref\n0\n%%EOF\n")
+
+	_, pages, err := ExtractPDF(b.Bytes())
+	if err != nil {
+		t.Fatalf("ExtractPDF() error = %v", err)
+	}
+	if len(pages) != 1 || pages[0] != "Open banking adoption" {
+		t.Errorf("pages = %q, want [\"Open banking adoption\"]", pages)
+	}
+}
+
+func TestExtractPDF_Errors(t *testing.T) {
+	scan := buildPDF("<< /Root 1 0 R >>",
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /Contents 4 0 R >>",
+		pdfStreamObj("", "q 612 0 0 792 0 0 cm /Im1 Do Q"),
+	)
+	encrypted := buildPDF("<< /Root 1 0 R /Encrypt 2 0 R >>",
+		"<< /Type /Catalog /Pages 3 0 R >>",
+		"<< /Filter /Standard /V 2 >>",
+	)
+
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"not a pdf", []byte("<html></html>"), ErrUnsupportedContent},
+		{"header only", []byte("%PDF-1.7"), ErrNoPDFText},
+		{"scanned", scan, ErrNoPDFText},
+		{"encrypted", encrypted, ErrUnsupportedContent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := ExtractPDF(tt.data); !errors.Is(err, tt.want) {
+				t.Errorf("ExtractPDF() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+// TestExtractPDF_Malformed - битые числа и структура не должны ронять разбор
+func TestExtractPDF_Malformed(t *testing.T) {
+	objStm := func(dict, data string) []byte {
+		return buildPDF("<< /Root 1 0 R >>", "<< /Type /Catalog /Pages 2 0 R >>", flateStreamObj("/Type /ObjStm "+dict, data))
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"negative first", objStm("/N 1 /First -3", "1 0 << >>")},
+		{"fractional first", objStm("/N 1 /First 2.5", "1 0 << >>")},
+		{"first beyond data", objStm("/N 1 /First 999", "1 0 << >>")},
+		{"negative offset", objStm("/N 1 /First 5", "3 -4 << /Type /Page >>")},
+		{"negative count", objStm("/N -1 /First 4", "3 0 << >>")},
+		{"truncated stream", []byte("%PDF-1.4\n1 0 obj\n<< /Filter /FlateDecode >>\nstream\nx\x9c")},
+		{"unbalanced", []byte("%PDF-1.4\n1 0 obj\n<< /Kids [[[ << /Type /Page /Contents (")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := ExtractPDF(tt.data); err == nil {
+				t.Errorf("ExtractPDF() error = nil, want error for malformed pdf")
+			}
+		})
+	}
+}
+
+// TestExtractPDF_DeepNesting - рекурсивный парсер на тысячах [ и << переполнил бы стек,
+// а это fatal error, а не паника
+func TestExtractPDF_DeepNesting(t *testing.T) {
+	const depth = 1 << 20
+	text := "BT /F1 12 Tf (Cover) Tj ET"
+	page := func(contents string) []byte {
+		return buildPDF("<< /Root 1 0 R >>",
+			"<< /Type /Catalog /Pages 2 0 R >>",
+			"<< /Type /Pages /Kids [3 0 R] >>",
+			"<< /Type /Page /Contents 4 0 R /Resources << /Font << /F1 5 0 R >> >> >>",
+			contents,
+			"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+			strings.Repeat("[", depth),
+			strings.Repeat("<< /A ", depth),
+		)
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"nested objects", page(pdfStreamObj("", text))},
+		{"nested content", page(flateStreamObj("", text+" "+strings.Repeat("[", depth)))},
+		{"nested dicts in content", page(flateStreamObj("", text+" "+strings.Repeat("<< /A ", depth)))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, pages, err := ExtractPDF(tt.data)
+			if err != nil {
+				t.Fatalf("ExtractPDF() error = %v", err)
+			}
+			if len(pages) != 1 || pages[0] != "Cover" {
+				t.Errorf("pages = %q, want [Cover]", pages)
+			}
+		})
+	}
+}
+
+// TestExtractPDF_InflateBudget - мелкие zlib-бомбы по отдельности укладываются в предел
+// потока, а вместе без общего бюджета развернулись бы больше чем в гигабайт
+func TestExtractPDF_InflateBudget(t *testing.T) {
+	bomb := flateStreamObj("", strings.Repeat(" ", maxPDFStream))
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R 4 0 R] >>",
+		"<< /Type /Page /Contents 5 0 R /Resources << /Font << /F1 6 0 R >> >> >>",
+		"", // вторая страница, ее Contents - все бомбы
+		pdfStreamObj("", "BT /F1 12 Tf (Cover) Tj ET"),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+	}
+	var refs strings.Builder
+	for i := range 40 {
+		objects = append(objects, bomb)
+		fmt.Fprintf(&refs, "%d 0 R ", len(objects))
+		if i == 0 {
+			refs.WriteString("7 0 R ") // один поток дважды
+		}
+	}
+	objects[3] = "<< /Type /Page /Contents [" + refs.String() + "] >>"
+	data := buildPDF("<< /Root 1 0 R >>", objects...)
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	_, pages, err := ExtractPDF(data)
+	runtime.ReadMemStats(&after)
+
+	if err != nil {
+		t.Fatalf("ExtractPDF() error = %v", err)
+	}
+	if len(pages) == 0 || pages[0] != "Cover" {
+		t.Errorf("pages = %q, want Cover first", pages)
+	}
+	if alloc := after.TotalAlloc - before.TotalAlloc; alloc > 8*maxPDFInflated {
+		t.Errorf("allocated %d MB, want bounded by the inflate budget", alloc>>20)
+	}
+}
+
+func TestParseCMap_Budget(t *testing.T) {
+	var cmap strings.Builder
+	cmap.WriteString("1 begincodespacerange <00000000> <FFFFFFFF> endcodespacerange\n")
+	for i := range 16 {
+		fmt.Fprintf(&cmap, "1 beginbfrange <%04X0000> <%04XFFFF> <0041> endbfrange\n", i, i)
+	}
+	cmap.WriteString("1 beginbfchar <FFFF0000> <0042> endbfchar\n")
+
+	budget := pdfBudget{cmap: maxPDFCMapEntries}
+	f := &pdfFont{}
+	f.parseCMap([]byte(cmap.String()), &budget)
+
+	if len(f.toUnicode) != maxPDFCMapEntries || budget.cmap != 0 {
+		t.Errorf("entries = %d, budget left = %d; want %d and 0", len(f.toUnicode), budget.cmap, maxPDFCMapEntries)
+	}
+	if f.toUnicode[0] != "A" || f.toUnicode[1] != "B" {
+		t.Errorf("first entries = %q %q, want A B", f.toUnicode[0], f.toUnicode[1])
+	}
+}
+
+// TestExtractPDF_Cycles - повторы и циклы в дереве страниц и формах: без учета
+// посещенных объектов обход растет как N^maxPDFDepth
+func TestExtractPDF_Cycles(t *testing.T) {
+	kids := strings.Repeat("2 0 R ", 20)
+	selfKids := buildPDF("<< /Root 1 0 R >>",
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids ["+kids+"3 0 R] >>",
+		"<< /Type /Page /Contents 4 0 R /Resources << /Font << /F1 5 0 R >> >> >>",
+		pdfStreamObj("", "BT /F1 12 Tf (Cover) Tj ET"),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+	)
+
+	// форма 4 рисует себя и форму 5 по 20 раз, форма 5 - форму 4
+	draws := strings.Repeat("/A Do /B Do ", 20)
+	formRes := "/Resources << /Font << /F1 6 0 R >> /XObject << /A 4 0 R /B 5 0 R >> >>"
+	selfForms := buildPDF("<< /Root 1 0 R >>",
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] >>",
+		"<< /Type /Page /Contents 7 0 R "+formRes+" >>",
+		pdfStreamObj("/Type /XObject /Subtype /Form "+formRes, "BT /F1 9 Tf (Logo) Tj ET "+draws),
+		pdfStreamObj("/Type /XObject /Subtype /Form "+formRes, draws),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+		pdfStreamObj("", draws),
+	)
+
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"pages node lists itself", selfKids, "Cover"},
+		{"forms draw each other", selfForms, "Logo"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			done := make(chan []string, 1)
+			go func() {
+				_, pages, _ := ExtractPDF(tt.data)
+				done <- pages
+			}()
+
+			select {
+			case pages := <-done:
+				if len(pages) != 1 || pages[0] != tt.want {
+					t.Errorf("pages = %q, want [%q]", pages, tt.want)
+				}
+			case <-time.After(2 * time.Second):
+				t.Fatal("ExtractPDF() did not finish in 2s")
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package fetch
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+)
+
+// robots - правила robots.txt для нашего агента. Пустые правила разрешают все
+type robots struct {
+	rules []robotsRule
+}
+
+type robotsRule struct {
+	allow   bool
+	pattern string
+}
+
+var allowAll = &robots{}
+
+// disallowAll - для robots.txt, который не ответил из-за ошибки сервера:
+// по RFC 9309 это значит, что обходить сайт пока нельзя
+var disallowAll = &robots{rules: []robotsRule{{allow: false, pattern: "/"}}}
+
+// parseRobots берет группу, где User-agent совпадает с agent, иначе группу "*".
+// Группы с одинаковым агентом объединяются, как требует RFC 9309
+func parseRobots(data []byte, agent string) *robots {
+	agent = strings.ToLower(agent)
+
+	var own, common []robotsRule
+	var foundOwn bool
+	var groupAgents []string
+	inRules := false
+
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		line := sc.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			// User-agent после правил начинает новую группу
+			if inRules {
+				groupAgents = nil
+				inRules = false
+			}
+			groupAgents = append(groupAgents, strings.ToLower(value))
+		case "allow", "disallow":
+			inRules = true
+			if key == "disallow" && value == "" {
+				continue // пустой Disallow ничего не запрещает
+			}
+			rule := robotsRule{allow: key == "allow", pattern: value}
+			for _, a := range groupAgents {
+				switch {
+				case a == "*":
+					common = append(common, rule)
+				case strings.Contains(agent, a):
+					own = append(own, rule)
+					foundOwn = true
+				}
+			}
+		}
+	}
+
+	if foundOwn {
+		return &robots{rules: own}
+	}
+	return &robots{rules: common}
+}
+
+// allowed - побеждает самое длинное совпавшее правило, при равной длине - Allow
+func (r *robots) allowed(path string) bool {
+	if path == "" {
+		path = "/"
+	}
+	best, allow := -1, true
+	for _, rule := range r.rules {
+		if !matchRobots(rule.pattern, path) {
+			continue
+		}
+		n := len(rule.pattern)
+		if n > best || (n == best && rule.allow) {
+			best, allow = n, rule.allow
+		}
+	}
+	return allow
+}
+
+// matchRobots - префиксное совпадение с * (любая последовательность) и $ (конец пути)
+func matchRobots(pattern, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	pattern = strings.TrimSuffix(pattern, "$")
+
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+	pos := len(parts[0])
+	for _, part := range parts[1:] {
+		i := strings.Index(path[pos:], part)
+		if i < 0 {
+			return false
+		}
+		pos += i + len(part)
+	}
+	if !anchored {
+		return true
+	}
+	// с $ последний кусок должен упираться в конец пути
+	last := parts[len(parts)-1]
+	return strings.HasSuffix(path, last) && (len(parts) > 1 || pos == len(path))
+}
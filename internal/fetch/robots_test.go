@@ -0,0 +1,67 @@
+package fetch
+
+import "testing"
+
+func TestParseRobots(t *testing.T) {
+	data := []byte(`
+# общие правила
+User-agent: *
+Disallow: /private/
+Allow: /private/public-report
+
+User-agent: BadBot
+Disallow: /
+`)
+
+	r := parseRobots(data, "fintech-bot page fetcher")
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"/insights/fintech", true},
+		{"/private/notes", false},
+		{"/private/public-report", true},
+		{"", true},
+	}
+	for _, tt := range tests {
+		if got := r.allowed(tt.path); got != tt.want {
+			t.Errorf("allowed(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestParseRobots_OwnGroupOverridesCommon(t *testing.T) {
+	data := []byte(`
+User-agent: *
+Disallow: /
+
+User-agent: fintech-bot
+Disallow: /admin
+`)
+
+	r := parseRobots(data, "fintech-bot page fetcher")
+	if !r.allowed("/articles/1") || r.allowed("/admin/users") {
+		t.Errorf("own group should replace the * group, rules = %+v", r.rules)
+	}
+}
+
+func TestMatchRobots(t *testing.T) {
+	tests := []struct {
+		pattern, path string
+		want          bool
+	}{
+		{"/reports", "/reports/2024", true},
+		{"/reports", "/news", false},
+		{"/*.pdf", "/files/annual.pdf", true},
+		{"/*.pdf$", "/files/annual.pdf?download=1", false},
+		{"/*.pdf$", "/files/annual.pdf", true},
+		{"/search$", "/search", true},
+		{"/search$", "/search/advanced", false},
+	}
+	for _, tt := range tests {
+		if got := matchRobots(tt.pattern, tt.path); got != tt.want {
+			t.Errorf("matchRobots(%q, %q) = %v, want %v", tt.pattern, tt.path, got, tt.want)
+		}
+	}
+}
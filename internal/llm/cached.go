@@ -0,0 +1,167 @@
+package llm
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kitbuilder587/fintech-bot/internal/cache"
+)
+
+// CacheRecorder - часть metrics.Metrics, нужная CachedClient
+type CacheRecorder interface {
+	RecordLLMCacheHit(provider, stage string)
+	RecordLLMCacheMiss(provider, stage string)
+}
+
+// CachePolicy - сколько хранить ответы каждой стадии
+type CachePolicy struct {
+	TTL time.Duration
+	// TTL по стадиям: ключ - стадия целиком ("critic") или часть до двоеточия ("agent"
+	// для всех agent:*). 0 отключает кеш стадии, например для недетерминированных
+	StageTTL map[string]time.Duration
+}
+
+// TTLFor - срок хранения ответа стадии, 0 - не кешировать.
+// Вызовы без стадии (пробы готовности и т.п.) не кешируются никогда
+func (p CachePolicy) TTLFor(stage string) time.Duration {
+	if stage == StageUnknown {
+		return 0
+	}
+	if ttl, ok := p.StageTTL[stage]; ok {
+		return ttl
+	}
+	if prefix, _, found := strings.Cut(stage, ":"); found {
+		if ttl, ok := p.StageTTL[prefix]; ok {
+			return ttl
+		}
+	}
+	return p.TTL
+}
+
+// CachedClient отдает сохраненный ответ на точно такой же запрос к той же модели.
+// Попадание в кеш не доходит до провайдера и не тратит токены
+type CachedClient struct {
+	next     Client
+	cache    cache.Cache
+	provider string
+	model    string
+	policy   CachePolicy
+	metrics  CacheRecorder
+}
+
+func NewCachedClient(next Client, c cache.Cache, provider, model string, policy CachePolicy, m CacheRecorder) *CachedClient {
+	return &CachedClient{
+		next:     next,
+		cache:    c,
+		provider: provider,
+		model:    model,
+		policy:   policy,
+		metrics:  m,
+	}
+}
+
+func (c *CachedClient) CompleteWithSystem(ctx context.Context, system, prompt string) (string, error) {
+	key, ttl, cached, ok := c.lookup(ctx, system, prompt)
+	if ok {
+		return cached, nil
+	}
+
+	resp, err := c.next.CompleteWithSystem(ctx, system, prompt)
+	if err == nil && ttl > 0 && resp != "" {
+		c.cache.Set(key, resp, ttl)
+	}
+	return resp, err
+}
+
+// StreamWithSystem при попадании отдает ответ одним куском,
+// иначе стримит от провайдера и сохраняет ответ, если поток завершился без ошибок
+func (c *CachedClient) StreamWithSystem(ctx context.Context, system, prompt string) (<-chan StreamDelta, error) {
+	key, ttl, cached, ok := c.lookup(ctx, system, prompt)
+	if ok {
+		out := make(chan StreamDelta, 1)
+		out <- StreamDelta{Content: cached}
+		close(out)
+		return out, nil
+	}
+
+	deltas, err := OpenStream(ctx, c.next, system, prompt)
+	if err != nil || ttl <= 0 {
+		return deltas, err
+	}
+
+	out := make(chan StreamDelta)
+	go func() {
+		defer close(out)
+
+		var sb strings.Builder
+		var streamErr error
+		for d := range deltas {
+			if d.Err != nil {
+				streamErr = d.Err
+			}
+			sb.WriteString(d.Content)
+			select {
+			case out <- d:
+			case <-ctx.Done():
+				streamErr = ctx.Err()
+			}
+		}
+
+		// недочитанный или оборванный ответ не сохраняем
+		if streamErr == nil && sb.Len() > 0 {
+			c.cache.Set(key, sb.String(), ttl)
+		}
+	}()
+	return out, nil
+}
+
+// CompleteWithTools не кеширует: между ходами агент получает свежие результаты поиска
+func (c *CachedClient) CompleteWithTools(ctx context.Context, messages []Message, tools []Tool) (Message, error) {
+	return CompleteWithTools(ctx, c.next, messages, tools)
+}
+
+// lookup считает ключ и TTL стадии и ищет ответ в кеше; стадии без TTL пропускаются без метрик
+func (c *CachedClient) lookup(ctx context.Context, system, prompt string) (key string, ttl time.Duration, resp string, ok bool) {
+	stage := StageFrom(ctx)
+	ttl = c.policy.TTLFor(stage)
+	if ttl <= 0 {
+		return "", 0, "", false
+	}
+
+	key = c.key(ctx, system, prompt)
+	if v, found := c.cache.Get(key); found {
+		if s, isString := v.(string); isString {
+			if c.metrics != nil {
+				c.metrics.RecordLLMCacheHit(c.provider, stage)
+			}
+			return key, ttl, s, true
+		}
+	}
+	if c.metrics != nil {
+		c.metrics.RecordLLMCacheMiss(c.provider, stage)
+	}
+	return key, ttl, "", false
+}
+
+// key - хеш всего, от чего зависит ответ: модель, промпты, история и JSON режим
+func (c *CachedClient) key(ctx context.Context, system, prompt string) string {
+	h := sha256.New()
+	for _, part := range []string{c.provider, c.model, system, prompt} {
+		fmt.Fprintf(h, "%d:%s", len(part), part)
+	}
+	for _, m := range HistoryFrom(ctx) {
+		fmt.Fprintf(h, "%d:%s%d:%s", len(m.Role), m.Role, len(m.Content), m.Content)
+	}
+	if JSONModeFrom(ctx) {
+		h.Write([]byte("json"))
+	}
+	return fmt.Sprintf("llm:%x", h.Sum(nil)[:16])
+}
+
+var (
+	_ StreamingClient = (*CachedClient)(nil)
+	_ ToolClient      = (*CachedClient)(nil)
+)
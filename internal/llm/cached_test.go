@@ -0,0 +1,155 @@
+package llm_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kitbuilder587/fintech-bot/internal/cache/memory"
+	"github.com/kitbuilder587/fintech-bot/internal/llm"
+	"github.com/kitbuilder587/fintech-bot/internal/llm/mock"
+)
+
+type fakeCacheRecorder struct {
+	hits   []string
+	misses []string
+}
+
+func (r *fakeCacheRecorder) RecordLLMCacheHit(_, stage string) {
+	r.hits = append(r.hits, stage)
+}
+
+func (r *fakeCacheRecorder) RecordLLMCacheMiss(_, stage string) {
+	r.misses = append(r.misses, stage)
+}
+
+func newCachedClient(t *testing.T, next llm.Client, policy llm.CachePolicy, rec *fakeCacheRecorder) *llm.CachedClient {
+	t.Helper()
+	c := memory.New()
+	t.Cleanup(c.Stop)
+	return llm.NewCachedClient(next, c, "openrouter", "deepseek/deepseek-chat", policy, rec)
+}
+
+func TestCachedClient_RepeatedCallHitsCache(t *testing.T) {
+	next := mock.New().WithResponses("first", "second")
+	rec := &fakeCacheRecorder{}
+	client := newCachedClient(t, next, llm.CachePolicy{TTL: time.Hour}, rec)
+	ctx := llm.WithStage(context.Background(), llm.StageExpand)
+
+	for i := 0; i < 2; i++ {
+		resp, err := client.CompleteWithSystem(ctx, "system", "prompt")
+		if err != nil {
+			t.Fatalf("call %d: %v", i, err)
+		}
+		if resp != "first" {
+			t.Errorf("call %d: response = %q, want first", i, resp)
+		}
+	}
+
+	if next.CallCount != 1 {
+		t.Errorf("provider called %d times, want 1", next.CallCount)
+	}
+	if len(rec.misses) != 1 || len(rec.hits) != 1 || rec.hits[0] != llm.StageExpand {
+		t.Errorf("misses = %v, hits = %v", rec.misses, rec.hits)
+	}
+}
+
+func TestCachedClient_KeyIncludesPromptAndHistory(t *testing.T) {
+	next := mock.New().WithResponse("ok")
+	client := newCachedClient(t, next, llm.CachePolicy{TTL: time.Hour}, &fakeCacheRecorder{})
+	ctx := llm.WithStage(context.Background(), llm.StageAnalyze)
+
+	calls := []struct {
+		ctx    context.Context
+		system string
+		prompt string
+	}{
+		{ctx, "system", "prompt"},
+		{ctx, "other system", "prompt"},
+		{ctx, "system", "other prompt"},
+		{llm.WithHistory(ctx, []llm.Message{{Role: "user", Content: "before"}}), "system", "prompt"},
+		{llm.WithJSONMode(ctx), "system", "prompt"},
+	}
+	for _, c := range calls {
+		if _, err := client.CompleteWithSystem(c.ctx, c.system, c.prompt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if next.CallCount != len(calls) {
+		t.Errorf("provider called %d times, want %d", next.CallCount, len(calls))
+	}
+}
+
+func TestCachedClient_StageOptOut(t *testing.T) {
+	policy := llm.CachePolicy{
+		TTL:      time.Hour,
+		StageTTL: map[string]time.Duration{llm.StageImprove: 0, "agent": 0},
+	}
+
+	for _, stage := range []string{llm.StageImprove, llm.AgentStage("market"), llm.StageUnknown} {
+		t.Run(stage, func(t *testing.T) {
+			next := mock.New().WithResponse("ok")
+			rec := &fakeCacheRecorder{}
+			client := newCachedClient(t, next, policy, rec)
+			ctx := llm.WithStage(context.Background(), stage)
+
+			for i := 0; i < 2; i++ {
+				if _, err := client.CompleteWithSystem(ctx, "system", "prompt"); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if next.CallCount != 2 {
+				t.Errorf("provider called %d times, want 2", next.CallCount)
+			}
+			if len(rec.hits)+len(rec.misses) != 0 {
+				t.Errorf("opted out stage recorded hits %v, misses %v", rec.hits, rec.misses)
+			}
+		})
+	}
+}
+
+func TestCachedClient_ErrorsNotCached(t *testing.T) {
+	next := mock.New().WithError(llm.ErrRateLimit)
+	client := newCachedClient(t, next, llm.CachePolicy{TTL: time.Hour}, &fakeCacheRecorder{})
+	ctx := llm.WithStage(context.Background(), llm.StageCritic)
+
+	if _, err := client.CompleteWithSystem(ctx, "system", "prompt"); !errors.Is(err, llm.ErrRateLimit) {
+		t.Fatalf("error = %v, want ErrRateLimit", err)
+	}
+
+	next.Error = nil
+	next.Response = "recovered"
+	resp, err := client.CompleteWithSystem(ctx, "system", "prompt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp != "recovered" {
+		t.Errorf("response = %q, want recovered", resp)
+	}
+}
+
+func TestCachedClient_StreamStoresFullAnswer(t *testing.T) {
+	next := mock.New().WithResponse("streamed answer text")
+	client := newCachedClient(t, next, llm.CachePolicy{TTL: time.Hour}, &fakeCacheRecorder{})
+	ctx := llm.WithStage(context.Background(), llm.StageSynthesize)
+	ctx = llm.WithStreamSink(ctx, func(string) {})
+
+	first, err := llm.CompleteStreaming(ctx, client, "system", "prompt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := llm.CompleteStreaming(ctx, client, "system", "prompt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if first != "streamed answer text" || second != first {
+		t.Errorf("first = %q, second = %q", first, second)
+	}
+	if next.StreamCallCount != 1 {
+		t.Errorf("provider streamed %d times, want 1", next.StreamCallCount)
+	}
+}
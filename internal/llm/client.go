@@ -10,8 +10,24 @@ var (
 	ErrRequestFailed = errors.New("request failed")
 	ErrEmptyResponse = errors.New("empty response")
 	ErrRateLimit     = errors.New("rate limit exceeded")
+	// токен не получен из-за сети или сбоя сервера авторизации. Ключ при этом может
+	// быть верным, поэтому, в отличие от ErrAuthFailed, ошибка временная
+	ErrTokenUnavailable = errors.New("auth token unavailable")
 )
 
 type Client interface {
 	CompleteWithSystem(ctx context.Context, system, prompt string) (string, error)
 }
+
+// StreamDelta - очередной кусок ответа; Err приходит последним сообщением
+type StreamDelta struct {
+	Content string
+	Err     error
+}
+
+// StreamingClient - клиент, умеющий отдавать ответ по мере генерации.
+// Канал закрывается после последнего куска или ошибки
+type StreamingClient interface {
+	Client
+	StreamWithSystem(ctx context.Context, system, prompt string) (<-chan StreamDelta, error)
+}
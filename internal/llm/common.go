@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 
 	"go.uber.org/zap"
 )
@@ -12,15 +13,61 @@ import (
 type ChatRequest struct {
 	Model    string    `json:"model"`
 	Messages []Message `json:"messages"`
+	Stream   bool      `json:"stream,omitempty"`
+	// без include_usage OpenAI-совместимые API не присылают usage в потоке
+	StreamOptions *StreamOptions `json:"stream_options,omitempty"`
+	// json_object заставляет модель вернуть валидный JSON; GigaChat поле не поддерживает
+	ResponseFormat *ResponseFormat `json:"response_format,omitempty"`
+	Tools          []Tool          `json:"tools,omitempty"`
+}
+
+type StreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
 }
 
 type Message struct {
 	Role    string `json:"role"`
 	Content string `json:"content"`
+	// ход модели с вызовами функций и ответ функции на конкретный вызов
+	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string     `json:"tool_call_id,omitempty"`
 }
 
 type ChatResponse struct {
+	Model   string   `json:"model,omitempty"`
 	Choices []Choice `json:"choices"`
+	Usage   *Usage   `json:"usage,omitempty"`
+}
+
+// APIError - поле error в теле ответа. OpenAI и vLLM присылают объект,
+// Ollama и LM Studio - просто строку
+type APIError struct {
+	Message string
+	Type    string
+	Code    string // у OpenAI строка, у vLLM число
+}
+
+func (e *APIError) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		e.Message = text
+		return nil
+	}
+
+	var obj struct {
+		Message string          `json:"message"`
+		Type    string          `json:"type"`
+		Code    json.RawMessage `json:"code"`
+	}
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return err
+	}
+	e.Message = obj.Message
+	e.Type = obj.Type
+	if len(obj.Code) > 0 && string(obj.Code) != "null" {
+		e.Code = strings.Trim(string(obj.Code), `"`)
+	}
+	return nil
 }
 
 type Choice struct {
@@ -28,12 +75,18 @@ type Choice struct {
 }
 
 func NewChatRequest(model, system, prompt string) ChatRequest {
+	return NewConversationRequest(model, system, nil, prompt)
+}
+
+// NewConversationRequest - запрос с предыдущими репликами диалога между system и текущим вопросом
+func NewConversationRequest(model, system string, history []Message, prompt string) ChatRequest {
+	messages := make([]Message, 0, len(history)+2)
+	messages = append(messages, Message{Role: "system", Content: system})
+	messages = append(messages, history...)
+	messages = append(messages, Message{Role: "user", Content: prompt})
 	return ChatRequest{
-		Model: model,
-		Messages: []Message{
-			{Role: "system", Content: system},
-			{Role: "user", Content: prompt},
-		},
+		Model:    model,
+		Messages: messages,
 	}
 }
 
@@ -67,7 +120,12 @@ func ExtractContent(resp *ChatResponse) (string, error) {
 	return resp.Choices[0].Message.Content, nil
 }
 
-func DoRequest(client *http.Client, req *http.Request) ([]byte, int, error) {
+// HTTPDoer - *http.Client или *retry.Client
+type HTTPDoer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+func DoRequest(client HTTPDoer, req *http.Request) ([]byte, int, error) {
 	resp, err := client.Do(req)
 	if err != nil {
 		return nil, 0, fmt.Errorf("%w: %v", ErrRequestFailed, err)
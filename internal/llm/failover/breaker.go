@@ -0,0 +1,148 @@
+package failover
+
+import (
+	"sync"
+	"time"
+)
+
+type State int
+
+const (
+	StateClosed   State = iota // запросы идут
+	StateHalfOpen              // пропускаем один пробный запрос
+	StateOpen                  // провайдер отдыхает до конца cooldown
+	StateDisabled              // ключ не подошел, без рестарта не вернется
+)
+
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateHalfOpen:
+		return "half_open"
+	case StateOpen:
+		return "open"
+	case StateDisabled:
+		return "disabled"
+	default:
+		return "unknown"
+	}
+}
+
+// breaker размыкается после threshold ошибок подряд и через cooldown
+// пропускает один пробный запрос
+type breaker struct {
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	mu       sync.Mutex
+	state    State
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+func newBreaker(threshold int, cooldown time.Duration) *breaker {
+	return &breaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		now:       time.Now,
+	}
+}
+
+// allow решает, можно ли сейчас идти в провайдера
+func (b *breaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case StateClosed:
+		return true
+	case StateOpen:
+		if b.now().Sub(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.state = StateHalfOpen
+		b.probing = true
+		return true
+	case StateHalfOpen:
+		// пробный запрос уже в полете, остальные идут к следующему провайдеру
+		if b.probing {
+			return false
+		}
+		b.probing = true
+		return true
+	default:
+		return false
+	}
+}
+
+func (b *breaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == StateDisabled {
+		return
+	}
+	b.state = StateClosed
+	b.failures = 0
+	b.probing = false
+}
+
+func (b *breaker) failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case StateHalfOpen:
+		b.open()
+	case StateClosed:
+		b.failures++
+		if b.failures >= b.threshold {
+			b.open()
+		}
+	}
+}
+
+// release отпускает пробу без вердикта - например, запрос отменил сам пользователь
+func (b *breaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+func (b *breaker) disable() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.state = StateDisabled
+	b.probing = false
+}
+
+func (b *breaker) currentState() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+func (b *breaker) open() {
+	b.state = StateOpen
+	b.openedAt = b.now()
+	b.failures = 0
+	b.probing = false
+}
+
+// available - пойдет ли следующий запрос в провайдера (для /ready)
+func (b *breaker) available() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case StateClosed, StateHalfOpen:
+		return true
+	case StateOpen:
+		return b.now().Sub(b.openedAt) >= b.cooldown
+	default:
+		return false
+	}
+}
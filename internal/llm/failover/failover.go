@@ -0,0 +1,275 @@
+package failover
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/kitbuilder587/fintech-bot/internal/llm"
+)
+
+var ErrNoProvidersAvailable = errors.New("no LLM providers available")
+
+type Provider struct {
+	Name   string
+	Client llm.Client
+}
+
+type Config struct {
+	FailureThreshold int           // ошибок подряд до размыкания
+	Cooldown         time.Duration // сколько провайдер отдыхает перед пробой
+}
+
+// StateRecorder - часть metrics.Metrics, нужная цепочке
+type StateRecorder interface {
+	SetLLMBreakerState(provider string, state int)
+}
+
+type member struct {
+	Provider
+	breaker *breaker
+}
+
+// Chain перебирает провайдеров по порядку, пропуская тех, у кого разомкнут breaker
+type Chain struct {
+	members []*member
+	logger  *zap.Logger
+	metrics StateRecorder
+}
+
+func New(providers []Provider, cfg Config, logger *zap.Logger, m StateRecorder) *Chain {
+	if cfg.FailureThreshold <= 0 {
+		cfg.FailureThreshold = 3
+	}
+	if cfg.Cooldown <= 0 {
+		cfg.Cooldown = 30 * time.Second
+	}
+
+	c := &Chain{
+		logger:  logger,
+		metrics: m,
+	}
+	for _, p := range providers {
+		mb := &member{Provider: p, breaker: newBreaker(cfg.FailureThreshold, cfg.Cooldown)}
+		c.members = append(c.members, mb)
+		c.recordState(mb)
+	}
+	return c
+}
+
+func (c *Chain) CompleteWithSystem(ctx context.Context, system, prompt string) (string, error) {
+	var errs []error
+	for _, mb := range c.members {
+		if !mb.breaker.allow() {
+			continue
+		}
+
+		resp, err := mb.Client.CompleteWithSystem(ctx, system, prompt)
+		if err == nil {
+			c.onSuccess(mb)
+			return resp, nil
+		}
+		// отмена запроса - не вина провайдера
+		if ctx.Err() != nil {
+			mb.breaker.release()
+			return "", err
+		}
+
+		c.onFailure(mb, err)
+		errs = append(errs, fmt.Errorf("%s: %w", mb.Name, err))
+	}
+
+	return "", c.exhausted(errs)
+}
+
+// CompleteWithTools пропускает провайдеров без поддержки функций, не считая это отказом.
+// Если такие были, ошибка оборачивает llm.ErrToolsUnsupported: вызывающий откатится
+// на обычный запрос, и его сможет обслужить провайдер без функций
+func (c *Chain) CompleteWithTools(ctx context.Context, messages []llm.Message, tools []llm.Tool) (llm.Message, error) {
+	var errs []error
+	unsupported := false
+	for _, mb := range c.members {
+		if !mb.breaker.allow() {
+			continue
+		}
+
+		msg, err := llm.CompleteWithTools(ctx, mb.Client, messages, tools)
+		if err == nil {
+			c.onSuccess(mb)
+			return msg, nil
+		}
+		if errors.Is(err, llm.ErrToolsUnsupported) {
+			mb.breaker.release()
+			unsupported = true
+			continue
+		}
+		if ctx.Err() != nil {
+			mb.breaker.release()
+			return llm.Message{}, err
+		}
+
+		c.onFailure(mb, err)
+		errs = append(errs, fmt.Errorf("%s: %w", mb.Name, err))
+	}
+
+	if unsupported {
+		if len(errs) == 0 {
+			return llm.Message{}, llm.ErrToolsUnsupported
+		}
+		return llm.Message{}, fmt.Errorf("%w: %w", llm.ErrToolsUnsupported, c.exhausted(errs))
+	}
+	return llm.Message{}, c.exhausted(errs)
+}
+
+// StreamWithSystem переключается на следующего провайдера, только пока
+// не пришел первый кусок: показанный пользователю текст уже не переиграть
+func (c *Chain) StreamWithSystem(ctx context.Context, system, prompt string) (<-chan llm.StreamDelta, error) {
+	var errs []error
+	for _, mb := range c.members {
+		if !mb.breaker.allow() {
+			continue
+		}
+
+		deltas, first, err := openFirst(ctx, mb.Client, system, prompt)
+		if err == nil {
+			return c.forward(ctx, mb, first, deltas), nil
+		}
+		if ctx.Err() != nil {
+			mb.breaker.release()
+			return nil, err
+		}
+
+		c.onFailure(mb, err)
+		errs = append(errs, fmt.Errorf("%s: %w", mb.Name, err))
+	}
+
+	return nil, c.exhausted(errs)
+}
+
+// Ready возвращает ошибку, если ни один провайдер сейчас не принимает запросы
+func (c *Chain) Ready(ctx context.Context) error {
+	states := make([]string, 0, len(c.members))
+	for _, mb := range c.members {
+		if mb.breaker.available() {
+			return nil
+		}
+		states = append(states, mb.Name+"="+mb.breaker.currentState().String())
+	}
+	return fmt.Errorf("%w: %s", ErrNoProvidersAvailable, strings.Join(states, ", "))
+}
+
+// States - текущее состояние breaker'ов по именам провайдеров
+func (c *Chain) States() map[string]State {
+	states := make(map[string]State, len(c.members))
+	for _, mb := range c.members {
+		states[mb.Name] = mb.breaker.currentState()
+	}
+	return states
+}
+
+func (c *Chain) onSuccess(mb *member) {
+	before := mb.breaker.currentState()
+	mb.breaker.success()
+	if before != StateClosed {
+		c.logger.Info("llm provider recovered", zap.String("provider", mb.Name))
+	}
+	c.recordState(mb)
+}
+
+func (c *Chain) onFailure(mb *member, err error) {
+	// с чужим ключом (401) повторять бессмысленно, выключаем до рестарта.
+	// Не полученный по сети токен - обычный отказ, после паузы провайдер пробуется снова
+	if errors.Is(err, llm.ErrAuthFailed) && !errors.Is(err, llm.ErrTokenUnavailable) {
+		mb.breaker.disable()
+		c.logger.Error("llm provider disabled: authentication failed",
+			zap.String("provider", mb.Name),
+		)
+		c.recordState(mb)
+		return
+	}
+
+	mb.breaker.failure()
+	c.logger.Warn("llm provider failed, trying next",
+		zap.String("provider", mb.Name),
+		zap.String("breaker", mb.breaker.currentState().String()),
+		zap.Error(err),
+	)
+	c.recordState(mb)
+}
+
+func (c *Chain) exhausted(errs []error) error {
+	if len(errs) == 0 {
+		return ErrNoProvidersAvailable
+	}
+	return fmt.Errorf("%w: %w", ErrNoProvidersAvailable, errors.Join(errs...))
+}
+
+func (c *Chain) recordState(mb *member) {
+	if c.metrics != nil {
+		c.metrics.SetLLMBreakerState(mb.Name, int(mb.breaker.currentState()))
+	}
+}
+
+// forward отдает первый кусок и остаток потока, а в конце обновляет breaker
+func (c *Chain) forward(ctx context.Context, mb *member, first llm.StreamDelta, deltas <-chan llm.StreamDelta) <-chan llm.StreamDelta {
+	out := make(chan llm.StreamDelta)
+	go func() {
+		defer close(out)
+
+		var streamErr error
+		emit := func(d llm.StreamDelta) {
+			if d.Err != nil {
+				streamErr = d.Err
+			}
+			select {
+			case out <- d:
+			case <-ctx.Done():
+			}
+		}
+
+		emit(first)
+		for d := range deltas {
+			emit(d)
+		}
+
+		switch {
+		case ctx.Err() != nil:
+			mb.breaker.release()
+		case streamErr != nil:
+			c.onFailure(mb, streamErr)
+		default:
+			c.onSuccess(mb)
+		}
+	}()
+	return out
+}
+
+// openFirst открывает поток и дожидается первого куска, чтобы ошибку
+// провайдера можно было обработать до того, как что-то ушло пользователю
+func openFirst(ctx context.Context, client llm.Client, system, prompt string) (<-chan llm.StreamDelta, llm.StreamDelta, error) {
+	deltas, err := llm.OpenStream(ctx, client, system, prompt)
+	if err != nil {
+		return nil, llm.StreamDelta{}, err
+	}
+
+	first, ok := <-deltas
+	if !ok {
+		if err := ctx.Err(); err != nil {
+			return nil, llm.StreamDelta{}, err
+		}
+		return nil, llm.StreamDelta{}, llm.ErrEmptyResponse
+	}
+	if first.Err != nil {
+		return nil, llm.StreamDelta{}, first.Err
+	}
+	return deltas, first, nil
+}
+
+var (
+	_ llm.StreamingClient = (*Chain)(nil)
+	_ llm.ToolClient      = (*Chain)(nil)
+)
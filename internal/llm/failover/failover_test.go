@@ -0,0 +1,265 @@
+package failover
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/kitbuilder587/fintech-bot/internal/llm"
+	"github.com/kitbuilder587/fintech-bot/internal/llm/gigachat"
+	"github.com/kitbuilder587/fintech-bot/internal/llm/mock"
+	"github.com/kitbuilder587/fintech-bot/internal/retry"
+)
+
+type fakeStates struct {
+	states map[string]int
+}
+
+func (f *fakeStates) SetLLMBreakerState(provider string, state int) {
+	f.states[provider] = state
+}
+
+func newTestChain(cfg Config, providers ...Provider) (*Chain, *fakeStates) {
+	rec := &fakeStates{states: make(map[string]int)}
+	return New(providers, cfg, zap.NewNop(), rec), rec
+}
+
+func TestBreaker_Transitions(t *testing.T) {
+	now := time.Unix(0, 0)
+	b := newBreaker(2, time.Minute)
+	b.now = func() time.Time { return now }
+
+	b.failure()
+	if b.currentState() != StateClosed {
+		t.Fatalf("state after 1 failure = %v, want closed", b.currentState())
+	}
+	b.failure()
+	if b.currentState() != StateOpen || b.allow() {
+		t.Fatalf("state after 2 failures = %v, want open and rejecting", b.currentState())
+	}
+
+	now = now.Add(time.Minute)
+	if !b.allow() {
+		t.Fatal("breaker should let a probe through after cooldown")
+	}
+	if b.currentState() != StateHalfOpen || b.allow() {
+		t.Fatal("only one probe should be allowed in half-open state")
+	}
+
+	b.failure()
+	if b.currentState() != StateOpen {
+		t.Fatalf("failed probe should reopen breaker, got %v", b.currentState())
+	}
+
+	now = now.Add(time.Minute)
+	b.allow()
+	b.success()
+	if b.currentState() != StateClosed || !b.allow() {
+		t.Fatalf("successful probe should close breaker, got %v", b.currentState())
+	}
+}
+
+func TestBreaker_ReleaseKeepsHalfOpen(t *testing.T) {
+	now := time.Unix(0, 0)
+	b := newBreaker(1, time.Minute)
+	b.now = func() time.Time { return now }
+
+	b.failure()
+	now = now.Add(time.Minute)
+	b.allow()
+	b.release()
+
+	if b.currentState() != StateHalfOpen || !b.allow() {
+		t.Error("released probe should let the next request probe again")
+	}
+}
+
+func TestChain_FailsOverToNextProvider(t *testing.T) {
+	primary := mock.New().WithError(fmt.Errorf("openrouter: %w", llm.ErrRateLimit))
+	secondary := mock.New().WithResponse("from gigachat")
+
+	chain, _ := newTestChain(Config{FailureThreshold: 2, Cooldown: time.Minute},
+		Provider{Name: "openrouter", Client: primary},
+		Provider{Name: "gigachat", Client: secondary},
+	)
+
+	for i := 0; i < 3; i++ {
+		got, err := chain.CompleteWithSystem(context.Background(), "system", "prompt")
+		if err != nil || got != "from gigachat" {
+			t.Fatalf("call %d: got %q, %v; want answer from secondary", i, got, err)
+		}
+	}
+
+	// после двух ошибок подряд breaker разомкнут и primary больше не дергаем
+	if primary.CallCount != 2 {
+		t.Errorf("primary calls = %d, want 2", primary.CallCount)
+	}
+	if chain.States()["openrouter"] != StateOpen {
+		t.Errorf("primary state = %v, want open", chain.States()["openrouter"])
+	}
+}
+
+func TestChain_AuthFailureDisablesProvider(t *testing.T) {
+	primary := mock.New().WithError(llm.ErrAuthFailed)
+	secondary := mock.New().WithResponse("ok")
+
+	chain, rec := newTestChain(Config{FailureThreshold: 5, Cooldown: time.Millisecond},
+		Provider{Name: "openrouter", Client: primary},
+		Provider{Name: "gigachat", Client: secondary},
+	)
+
+	chain.CompleteWithSystem(context.Background(), "system", "prompt")
+	time.Sleep(5 * time.Millisecond)
+	chain.CompleteWithSystem(context.Background(), "system", "prompt")
+
+	if primary.CallCount != 1 {
+		t.Errorf("primary calls = %d, want 1: auth failures must not be retried", primary.CallCount)
+	}
+	if rec.states["openrouter"] != int(StateDisabled) {
+		t.Errorf("recorded state = %d, want disabled", rec.states["openrouter"])
+	}
+}
+
+// TestChain_TokenOutageIsNotAuthFailure - сбой сервера авторизации GigaChat
+// размыкает breaker, но не выключает провайдера до рестарта
+func TestChain_TokenOutageIsNotAuthFailure(t *testing.T) {
+	var authDown atomic.Bool
+	authDown.Store(true)
+	authServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if authDown.Load() {
+			// обрыв соединения - ошибка транспорта, а не ответ сервера
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			conn.Close()
+			return
+		}
+		fmt.Fprintf(w, `{"access_token":"token","expires_at":%d}`, time.Now().Add(time.Hour).UnixMilli())
+	}))
+	defer authServer.Close()
+	apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","content":"ok"}}]}`)
+	}))
+	defer apiServer.Close()
+
+	client := gigachat.New(gigachat.Config{
+		AuthKey: "key",
+		AuthURL: authServer.URL,
+		BaseURL: apiServer.URL,
+		Retry:   retry.Policy{MaxAttempts: 1},
+	}, zap.NewNop())
+	chain, _ := newTestChain(Config{FailureThreshold: 1, Cooldown: time.Millisecond},
+		Provider{Name: "gigachat", Client: client},
+	)
+
+	_, err := chain.CompleteWithSystem(context.Background(), "system", "prompt")
+	if !errors.Is(err, llm.ErrTokenUnavailable) {
+		t.Fatalf("error = %v, want ErrTokenUnavailable", err)
+	}
+	if state := chain.States()["gigachat"]; state != StateOpen {
+		t.Fatalf("state after token outage = %v, want open", state)
+	}
+
+	authDown.Store(false)
+	time.Sleep(5 * time.Millisecond)
+	if resp, err := chain.CompleteWithSystem(context.Background(), "system", "prompt"); err != nil || resp != "ok" {
+		t.Errorf("after auth recovered: resp = %q, err = %v; want ok", resp, err)
+	}
+}
+
+func TestChain_AllProvidersFailed(t *testing.T) {
+	chain, _ := newTestChain(Config{FailureThreshold: 1, Cooldown: time.Minute},
+		Provider{Name: "openrouter", Client: mock.New().WithError(llm.ErrRateLimit)},
+		Provider{Name: "gigachat", Client: mock.New().WithError(llm.ErrAuthFailed)},
+	)
+
+	_, err := chain.CompleteWithSystem(context.Background(), "system", "prompt")
+	if !errors.Is(err, ErrNoProvidersAvailable) || !errors.Is(err, llm.ErrRateLimit) {
+		t.Errorf("error = %v, want ErrNoProvidersAvailable wrapping provider errors", err)
+	}
+
+	if err := chain.Ready(context.Background()); !errors.Is(err, ErrNoProvidersAvailable) {
+		t.Errorf("Ready() = %v, want ErrNoProvidersAvailable", err)
+	}
+
+	_, err = chain.CompleteWithSystem(context.Background(), "system", "prompt")
+	if !errors.Is(err, ErrNoProvidersAvailable) {
+		t.Errorf("error with all breakers open = %v, want ErrNoProvidersAvailable", err)
+	}
+}
+
+func TestChain_CanceledContextIsNotAFailure(t *testing.T) {
+	primary := mock.New().WithDelay(time.Second)
+	chain, _ := newTestChain(Config{FailureThreshold: 1, Cooldown: time.Minute},
+		Provider{Name: "openrouter", Client: primary},
+		Provider{Name: "gigachat", Client: mock.New()},
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := chain.CompleteWithSystem(ctx, "system", "prompt"); !errors.Is(err, context.Canceled) {
+		t.Errorf("error = %v, want context.Canceled", err)
+	}
+	if chain.States()["openrouter"] != StateClosed {
+		t.Errorf("state = %v, cancellation must not open the breaker", chain.States()["openrouter"])
+	}
+}
+
+func TestChain_StreamFailsOverBeforeFirstChunk(t *testing.T) {
+	primary := mock.New().WithError(llm.ErrRateLimit)
+	secondary := mock.New().WithResponse("streamed answer")
+
+	chain, _ := newTestChain(Config{FailureThreshold: 3, Cooldown: time.Minute},
+		Provider{Name: "openrouter", Client: primary},
+		Provider{Name: "gigachat", Client: secondary},
+	)
+
+	deltas, err := chain.StreamWithSystem(context.Background(), "system", "prompt")
+	if err != nil {
+		t.Fatalf("StreamWithSystem() error = %v", err)
+	}
+
+	var got string
+	for d := range deltas {
+		if d.Err != nil {
+			t.Fatalf("stream error = %v", d.Err)
+		}
+		got += d.Content
+	}
+	if got != "streamed answer" {
+		t.Errorf("streamed = %q, want %q", got, "streamed answer")
+	}
+	if secondary.StreamCallCount != 1 {
+		t.Errorf("secondary stream calls = %d, want 1", secondary.StreamCallCount)
+	}
+}
+
+func TestChain_ToolsSkipUnsupportedProvider(t *testing.T) {
+	gigachat := mock.New()
+	gigachat.ToolsUnsupported = true
+	openrouter := mock.New().WithResponse("with tools")
+
+	chain, _ := newTestChain(Config{FailureThreshold: 1, Cooldown: time.Minute},
+		Provider{Name: "gigachat", Client: gigachat},
+		Provider{Name: "openrouter", Client: openrouter},
+	)
+
+	msg, err := chain.CompleteWithTools(context.Background(), nil, nil)
+	if err != nil || msg.Content != "with tools" {
+		t.Fatalf("got %+v, %v; want answer from openrouter", msg, err)
+	}
+	if chain.States()["gigachat"] != StateClosed {
+		t.Errorf("gigachat state = %v, missing tools is not a failure", chain.States()["gigachat"])
+	}
+
+	only, _ := newTestChain(Config{}, Provider{Name: "gigachat", Client: gigachat})
+	if _, err := only.CompleteWithTools(context.Background(), nil, nil); !errors.Is(err, llm.ErrToolsUnsupported) {
+		t.Errorf("error = %v, want ErrToolsUnsupported", err)
+	}
+}
@@ -17,8 +17,11 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/kitbuilder587/fintech-bot/internal/llm"
+	"github.com/kitbuilder587/fintech-bot/internal/retry"
 )
 
+const defaultModel = "GigaChat"
+
 type Config struct {
 	AuthKey      string // готовый ключ авторизации (предпочтительно)
 	ClientID     string // альтернатива: будет base64(id:secret)
@@ -26,7 +29,9 @@ type Config struct {
 	Scope        string
 	AuthURL      string
 	BaseURL      string
-	Timeout      time.Duration
+	Model        string        // пустая - GigaChat
+	Timeout      time.Duration // на одну попытку
+	Retry        retry.Policy  // пустая - retry.DefaultPolicy()
 }
 
 type Client struct {
@@ -34,7 +39,8 @@ type Client struct {
 	scope   string
 	authURL string
 	baseURL string
-	client  *http.Client
+	model   string
+	client  *retry.Client
 	logger  *zap.Logger
 
 	mu          sync.RWMutex
@@ -49,12 +55,18 @@ func New(cfg Config, logger *zap.Logger) *Client {
 	if cfg.BaseURL == "" {
 		cfg.BaseURL = "https://gigachat.devices.sberbank.ru/api/v1"
 	}
+	if cfg.Model == "" {
+		cfg.Model = defaultModel
+	}
 	if cfg.Scope == "" {
 		cfg.Scope = "GIGACHAT_API_PERS"
 	}
 	if cfg.Timeout == 0 {
 		cfg.Timeout = 60 * time.Second
 	}
+	if cfg.Retry == (retry.Policy{}) {
+		cfg.Retry = retry.DefaultPolicy()
+	}
 
 	// У Сбера самоподписанный сертификат, приходится отключать проверку
 	transport := &http.Transport{
@@ -73,7 +85,8 @@ func New(cfg Config, logger *zap.Logger) *Client {
 		scope:   cfg.Scope,
 		authURL: cfg.AuthURL,
 		baseURL: cfg.BaseURL,
-		client:  &http.Client{Timeout: cfg.Timeout, Transport: transport},
+		model:   cfg.Model,
+		client:  retry.NewClient(&http.Client{Timeout: cfg.Timeout, Transport: transport}, cfg.Retry),
 		logger:  logger,
 	}
 }
@@ -88,26 +101,11 @@ func (c *Client) CompleteWithSystem(ctx context.Context, system, prompt string)
 }
 
 func (c *Client) completeWithRetry(ctx context.Context, system, prompt string, isRetry bool) (string, error) {
-	token, err := c.getToken(ctx)
+	httpReq, err := c.newRequest(ctx, llm.NewConversationRequest(c.model, system, llm.HistoryFrom(ctx), prompt))
 	if err != nil {
 		return "", err
 	}
 
-	req := llm.NewChatRequest("GigaChat", system, prompt)
-
-	body, err := json.Marshal(req)
-	if err != nil {
-		return "", fmt.Errorf("marshal request: %w", err)
-	}
-
-	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/chat/completions", bytes.NewReader(body))
-	if err != nil {
-		return "", fmt.Errorf("create request: %w", err)
-	}
-
-	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Authorization", "Bearer "+token)
-
 	respBody, statusCode, err := llm.DoRequest(c.client, httpReq)
 	if err != nil {
 		return "", err
@@ -118,10 +116,8 @@ func (c *Client) completeWithRetry(ctx context.Context, system, prompt string, i
 		if isRetry {
 			return "", llm.ErrAuthFailed
 		}
-		c.invalidateToken()
-		_, err = c.getToken(ctx)
-		if err != nil {
-			return "", llm.ErrAuthFailed
+		if err := c.renewToken(ctx); err != nil {
+			return "", err
 		}
 		return c.completeWithRetry(ctx, system, prompt, true)
 	}
@@ -135,9 +131,79 @@ func (c *Client) completeWithRetry(ctx context.Context, system, prompt string, i
 		return "", err
 	}
 
+	model := chatResp.Model
+	if model == "" {
+		model = c.model
+	}
+	llm.ReportUsage(ctx, "gigachat", model, chatResp.Usage)
+
 	return llm.ExtractContent(chatResp)
 }
 
+func (c *Client) StreamWithSystem(ctx context.Context, system, prompt string) (<-chan llm.StreamDelta, error) {
+	return c.streamWithRetry(ctx, system, prompt, false)
+}
+
+func (c *Client) streamWithRetry(ctx context.Context, system, prompt string, isRetry bool) (<-chan llm.StreamDelta, error) {
+	req := llm.NewConversationRequest(c.model, system, llm.HistoryFrom(ctx), prompt)
+	req.Stream = true
+
+	httpReq, err := c.newRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	body, statusCode, errBody, err := llm.DoStreamRequest(c.client, httpReq)
+	if err != nil {
+		return nil, err
+	}
+
+	if statusCode == http.StatusUnauthorized {
+		if isRetry {
+			return nil, llm.ErrAuthFailed
+		}
+		if err := c.renewToken(ctx); err != nil {
+			return nil, err
+		}
+		return c.streamWithRetry(ctx, system, prompt, true)
+	}
+
+	if statusCode != http.StatusOK {
+		return nil, llm.HandleHTTPError(statusCode, errBody, c.logger, "gigachat")
+	}
+
+	return llm.ReadSSE(ctx, body, "gigachat", c.model), nil
+}
+
+func (c *Client) newRequest(ctx context.Context, req llm.ChatRequest) (*http.Request, error) {
+	token, err := c.getToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+token)
+
+	return httpReq, nil
+}
+
+// renewToken сбрасывает протухший токен и получает новый
+func (c *Client) renewToken(ctx context.Context) error {
+	c.invalidateToken()
+	_, err := c.getToken(ctx)
+	return err
+}
+
 func (c *Client) getToken(ctx context.Context) (string, error) {
 	c.mu.RLock()
 	if c.accessToken != "" && time.Now().Before(c.tokenExpiry.Add(-5*time.Minute)) {
@@ -172,9 +238,11 @@ func (c *Client) refreshToken(ctx context.Context) (string, error) {
 	httpReq.Header.Set("Accept", "application/json")
 	httpReq.Header.Set("RqUID", uuid.New().String()) // Сбер требует уникальный id запроса
 
+	// сбой сети или сервера авторизации - не повод считать ключ неверным:
+	// ErrAuthFailed выключает провайдера до рестарта
 	resp, err := c.client.Do(httpReq)
 	if err != nil {
-		return "", fmt.Errorf("%w: %v", llm.ErrAuthFailed, err)
+		return "", fmt.Errorf("%w: %w", llm.ErrTokenUnavailable, err)
 	}
 	defer resp.Body.Close()
 
@@ -184,12 +252,15 @@ func (c *Client) refreshToken(ctx context.Context) (string, error) {
 			zap.Int("status", resp.StatusCode),
 			zap.String("body", string(body)),
 		)
-		return "", llm.ErrAuthFailed
+		if resp.StatusCode == http.StatusUnauthorized {
+			return "", llm.ErrAuthFailed
+		}
+		return "", fmt.Errorf("%w: status %d", llm.ErrTokenUnavailable, resp.StatusCode)
 	}
 
 	var authResp authResponse
 	if err := json.NewDecoder(resp.Body).Decode(&authResp); err != nil {
-		return "", fmt.Errorf("decode auth response: %w", err)
+		return "", fmt.Errorf("%w: decode auth response: %w", llm.ErrTokenUnavailable, err)
 	}
 
 	c.accessToken = authResp.AccessToken
@@ -208,3 +279,5 @@ func (c *Client) invalidateToken() {
 	c.accessToken = ""
 	c.tokenExpiry = time.Time{}
 }
+
+var _ llm.StreamingClient = (*Client)(nil)
@@ -3,6 +3,8 @@ package gigachat
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -11,6 +13,7 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/kitbuilder587/fintech-bot/internal/llm"
+	"github.com/kitbuilder587/fintech-bot/internal/retry"
 )
 
 func TestClient_CompleteWithSystem(t *testing.T) {
@@ -70,6 +73,7 @@ func TestClient_CompleteWithSystem(t *testing.T) {
 				AuthURL:      authServer.URL,
 				BaseURL:      apiServer.URL,
 				Timeout:      5 * time.Second,
+				Retry:        retry.Policy{MaxAttempts: 1},
 			}, logger)
 
 			result, err := client.CompleteWithSystem(context.Background(), "system", "prompt")
@@ -136,3 +140,104 @@ func TestClient_TokenCaching(t *testing.T) {
 		t.Errorf("auth calls = %d, want 1", authCalls)
 	}
 }
+
+func TestClient_StreamWithSystem_RefreshesToken(t *testing.T) {
+	var tokenCalls int
+	authServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tokenCalls++
+		json.NewEncoder(w).Encode(authResponse{
+			AccessToken: fmt.Sprintf("token-%d", tokenCalls),
+			ExpiresAt:   time.Now().Add(30 * time.Minute).UnixMilli(),
+		})
+	}))
+	defer authServer.Close()
+
+	apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// первый токен считаем протухшим
+		if r.Header.Get("Authorization") == "Bearer token-1" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(`data: {"choices":[{"delta":{"content":"ok"}}]}` + "\n\n"))
+		w.Write([]byte("data: [DONE]\n\n"))
+	}))
+	defer apiServer.Close()
+
+	client := New(Config{
+		AuthKey: "key",
+		AuthURL: authServer.URL,
+		BaseURL: apiServer.URL,
+		Timeout: 5 * time.Second,
+	}, zap.NewNop())
+
+	deltas, err := client.StreamWithSystem(context.Background(), "system", "prompt")
+	if err != nil {
+		t.Fatalf("StreamWithSystem() error = %v", err)
+	}
+
+	var got string
+	for d := range deltas {
+		if d.Err != nil {
+			t.Fatalf("stream error = %v", d.Err)
+		}
+		got += d.Content
+	}
+	if got != "ok" {
+		t.Errorf("streamed text = %q, want ok", got)
+	}
+	if tokenCalls != 2 {
+		t.Errorf("token requests = %d, want 2", tokenCalls)
+	}
+}
+
+func TestClient_TokenErrors(t *testing.T) {
+	apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("chat API must not be called without a token")
+	}))
+	defer apiServer.Close()
+
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	down.Close()
+
+	tests := []struct {
+		name    string
+		authURL string
+		status  int
+		wantErr error
+	}{
+		// только отказ сервера авторизации в ключе окончательный
+		{"bad key", "", http.StatusUnauthorized, llm.ErrAuthFailed},
+		{"auth server error", "", http.StatusServiceUnavailable, llm.ErrTokenUnavailable},
+		{"auth server unreachable", down.URL, 0, llm.ErrTokenUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			authURL := tt.authURL
+			if authURL == "" {
+				authServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					w.WriteHeader(tt.status)
+				}))
+				defer authServer.Close()
+				authURL = authServer.URL
+			}
+
+			client := New(Config{
+				AuthKey: "key",
+				AuthURL: authURL,
+				BaseURL: apiServer.URL,
+				Timeout: 5 * time.Second,
+				Retry:   retry.Policy{MaxAttempts: 1},
+			}, zap.NewNop())
+
+			_, err := client.CompleteWithSystem(context.Background(), "system", "prompt")
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == llm.ErrTokenUnavailable && errors.Is(err, llm.ErrAuthFailed) {
+				t.Errorf("error = %v must not be ErrAuthFailed", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,16 @@
+package llm
+
+import "context"
+
+type historyKey struct{}
+
+// WithHistory добавляет к следующим вызовам предыдущие реплики диалога (user/assistant, от старых к новым).
+// Клиенты кладут их между системным промптом и текущим вопросом
+func WithHistory(ctx context.Context, history []Message) context.Context {
+	return context.WithValue(ctx, historyKey{}, history)
+}
+
+func HistoryFrom(ctx context.Context) []Message {
+	history, _ := ctx.Value(historyKey{}).([]Message)
+	return history
+}
@@ -0,0 +1,108 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// MetricsRecorder - часть metrics.Metrics, нужная декоратору
+type MetricsRecorder interface {
+	RecordLLMRequest(provider, status string, duration time.Duration)
+}
+
+// InstrumentedClient пишет в метрики статус и длительность каждого вызова
+type InstrumentedClient struct {
+	next     Client
+	provider string
+	metrics  MetricsRecorder
+}
+
+func NewInstrumentedClient(next Client, provider string, m MetricsRecorder) *InstrumentedClient {
+	return &InstrumentedClient{
+		next:     next,
+		provider: provider,
+		metrics:  m,
+	}
+}
+
+func (c *InstrumentedClient) CompleteWithSystem(ctx context.Context, system, prompt string) (string, error) {
+	start := time.Now()
+	resp, err := c.next.CompleteWithSystem(ctx, system, prompt)
+	c.metrics.RecordLLMRequest(c.provider, Status(err), time.Since(start))
+	return resp, err
+}
+
+// StreamWithSystem пишет метрику, когда поток дочитан до конца
+func (c *InstrumentedClient) StreamWithSystem(ctx context.Context, system, prompt string) (<-chan StreamDelta, error) {
+	start := time.Now()
+	deltas, err := OpenStream(ctx, c.next, system, prompt)
+	if err != nil {
+		c.metrics.RecordLLMRequest(c.provider, Status(err), time.Since(start))
+		return nil, err
+	}
+
+	out := make(chan StreamDelta)
+	go func() {
+		defer close(out)
+
+		var streamErr error
+		empty := true
+		for d := range deltas {
+			if d.Err != nil {
+				streamErr = d.Err
+			} else if d.Content != "" {
+				empty = false
+			}
+			select {
+			case out <- d:
+			case <-ctx.Done():
+				streamErr = ctx.Err()
+			}
+		}
+
+		if streamErr == nil && empty {
+			streamErr = ErrEmptyResponse
+		}
+		c.metrics.RecordLLMRequest(c.provider, Status(streamErr), time.Since(start))
+	}()
+	return out, nil
+}
+
+func (c *InstrumentedClient) CompleteWithTools(ctx context.Context, messages []Message, tools []Tool) (Message, error) {
+	start := time.Now()
+	msg, err := CompleteWithTools(ctx, c.next, messages, tools)
+	// до провайдера запрос не дошел, мерить нечего
+	if errors.Is(err, ErrToolsUnsupported) {
+		return msg, err
+	}
+	c.metrics.RecordLLMRequest(c.provider, Status(err), time.Since(start))
+	return msg, err
+}
+
+// Status переводит ошибку клиента в значение label status
+func Status(err error) string {
+	switch {
+	case err == nil:
+		return "success"
+	case errors.Is(err, ErrRateLimit):
+		return "rate_limited"
+	case errors.Is(err, ErrAuthFailed):
+		return "auth_failed"
+	case errors.Is(err, ErrEmptyResponse):
+		return "empty_response"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.Is(err, ErrRequestFailed), errors.Is(err, ErrTokenUnavailable):
+		return "request_failed"
+	default:
+		return "error"
+	}
+}
+
+var (
+	_ StreamingClient = (*InstrumentedClient)(nil)
+	_ ToolClient      = (*InstrumentedClient)(nil)
+)
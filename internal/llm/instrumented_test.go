@@ -0,0 +1,79 @@
+package llm_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/kitbuilder587/fintech-bot/internal/llm"
+	"github.com/kitbuilder587/fintech-bot/internal/llm/mock"
+)
+
+type recordedCall struct {
+	provider string
+	status   string
+}
+
+type fakeRecorder struct {
+	calls []recordedCall
+}
+
+func (r *fakeRecorder) RecordLLMRequest(provider, status string, _ time.Duration) {
+	r.calls = append(r.calls, recordedCall{provider: provider, status: status})
+}
+
+func TestInstrumentedClient_RecordsStatus(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus string
+	}{
+		{"success", nil, "success"},
+		{"rate limit", fmt.Errorf("openrouter: %w", llm.ErrRateLimit), "rate_limited"},
+		{"auth", llm.ErrAuthFailed, "auth_failed"},
+		{"empty", llm.ErrEmptyResponse, "empty_response"},
+		{"timeout", fmt.Errorf("%w: %w", llm.ErrRequestFailed, context.DeadlineExceeded), "timeout"},
+		{"request failed", fmt.Errorf("%w: status 500", llm.ErrRequestFailed), "request_failed"},
+		{"unknown", errors.New("boom"), "error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := &fakeRecorder{}
+			next := mock.New().WithResponse("ok")
+			if tt.err != nil {
+				next = next.WithError(tt.err)
+			}
+			client := llm.NewInstrumentedClient(next, "openrouter", rec)
+
+			_, err := client.CompleteWithSystem(context.Background(), "system", "prompt")
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("error = %v, want %v", err, tt.err)
+			}
+			if len(rec.calls) != 1 {
+				t.Fatalf("recorded %d calls, want 1", len(rec.calls))
+			}
+			if rec.calls[0].provider != "openrouter" || rec.calls[0].status != tt.wantStatus {
+				t.Errorf("recorded %+v, want openrouter/%s", rec.calls[0], tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestInstrumentedClient_StreamRecordsOnce(t *testing.T) {
+	rec := &fakeRecorder{}
+	client := llm.NewInstrumentedClient(mock.New().WithResponse("a b c"), "gigachat", rec)
+
+	deltas, err := client.StreamWithSystem(context.Background(), "system", "prompt")
+	if err != nil {
+		t.Fatalf("StreamWithSystem() error = %v", err)
+	}
+	for range deltas {
+	}
+
+	if len(rec.calls) != 1 || rec.calls[0].status != "success" {
+		t.Errorf("recorded %+v, want single success", rec.calls)
+	}
+}
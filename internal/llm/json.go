@@ -0,0 +1,153 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrInvalidJSON - модель так и не вернула JSON нужной структуры
+var ErrInvalidJSON = errors.New("invalid JSON response")
+
+// DefaultJSONAttempts - сколько раз CompleteJSON спрашивает модель, считая первый вызов
+const DefaultJSONAttempts = 3
+
+// Validator - дополнительная проверка распарсенного ответа.
+// Ошибка уходит модели в повторном запросе, поэтому лучше писать ее понятно
+type Validator interface {
+	Validate() error
+}
+
+// ResponseFormat - поле response_format OpenAI-совместимых API
+type ResponseFormat struct {
+	Type string `json:"type"`
+}
+
+type jsonModeKey struct{}
+
+// WithJSONMode просит провайдера включить JSON режим, если он его поддерживает
+func WithJSONMode(ctx context.Context) context.Context {
+	return context.WithValue(ctx, jsonModeKey{}, true)
+}
+
+func JSONModeFrom(ctx context.Context) bool {
+	on, _ := ctx.Value(jsonModeKey{}).(bool)
+	return on
+}
+
+// CompleteJSON запрашивает у модели JSON и разбирает его в T.
+// Если ответ не парсится или не проходит Validate, модель переспрашивается
+// с текстом ошибки, всего не больше attempts вызовов
+func CompleteJSON[T any](ctx context.Context, c Client, system, prompt string, attempts int) (T, error) {
+	var zero T
+	if attempts <= 0 {
+		attempts = DefaultJSONAttempts
+	}
+
+	ctx = WithJSONMode(ctx)
+	current := prompt
+	var lastErr error
+
+	for i := 0; i < attempts; i++ {
+		response, err := c.CompleteWithSystem(ctx, system, current)
+		if err != nil {
+			return zero, err
+		}
+
+		result, err := ParseJSON[T](response)
+		if err == nil {
+			return result, nil
+		}
+		lastErr = err
+
+		if ctx.Err() != nil {
+			return zero, ctx.Err()
+		}
+		current = repairPrompt(prompt, response, err)
+	}
+
+	return zero, lastErr
+}
+
+// ParseJSON достает JSON из ответа модели (markdown блок, текст вокруг) и разбирает в T.
+// Для срезов ищется массив, для остального - объект
+func ParseJSON[T any](response string) (T, error) {
+	var result T
+	open := byte('{')
+	if k := reflect.TypeOf(result); k != nil && (k.Kind() == reflect.Slice || k.Kind() == reflect.Array) {
+		open = '['
+	}
+	if err := json.Unmarshal([]byte(extractJSON(response, open)), &result); err != nil {
+		return result, fmt.Errorf("%w: %v", ErrInvalidJSON, err)
+	}
+	if v, ok := any(&result).(Validator); ok {
+		if err := v.Validate(); err != nil {
+			return result, fmt.Errorf("%w: %v", ErrInvalidJSON, err)
+		}
+	}
+	return result, nil
+}
+
+// ExtractJSON вырезает первый JSON объект из текста ответа. Массивы не ищутся:
+// ссылка [S1] в тексте перед объектом иначе приняла бы себя за начало JSON
+func ExtractJSON(s string) string {
+	return extractJSON(s, '{')
+}
+
+// extractJSON вырезает первое значение, начинающееся с open ({ или [)
+func extractJSON(s string, open byte) string {
+	start := strings.IndexByte(s, open)
+	if start == -1 {
+		return strings.TrimSpace(s)
+	}
+
+	closing := byte('}')
+	if open == '[' {
+		closing = ']'
+	}
+
+	depth := 0
+	inString := false
+	escaped := false
+	for i := start; i < len(s); i++ {
+		ch := s[i]
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case ch == '\\':
+				escaped = true
+			case ch == '"':
+				inString = false
+			}
+			continue
+		}
+		switch ch {
+		case '"':
+			inString = true
+		case open:
+			depth++
+		case closing:
+			depth--
+			if depth == 0 {
+				return s[start : i+1]
+			}
+		}
+	}
+
+	return s[start:]
+}
+
+func repairPrompt(prompt, response string, err error) string {
+	// по рунам: ответ обычно на русском, срез по байтам рвет UTF-8
+	if utf8.RuneCountInString(response) > 2000 {
+		response = string([]rune(response)[:2000]) + "..."
+	}
+	return fmt.Sprintf("%s\n\n=== YOUR PREVIOUS RESPONSE ===\n%s\n\n"+
+		"It could not be used: %v\nRespond again with valid JSON only, following the required format.",
+		prompt, response, err)
+}
@@ -0,0 +1,157 @@
+package llm_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/kitbuilder587/fintech-bot/internal/llm"
+	"github.com/kitbuilder587/fintech-bot/internal/llm/mock"
+)
+
+type verdict struct {
+	Approved *bool    `json:"approved"`
+	Score    float64  `json:"score"`
+	Tags     []string `json:"tags"`
+}
+
+func (v *verdict) Validate() error {
+	if v.Approved == nil {
+		return errors.New("approved is required")
+	}
+	return nil
+}
+
+func TestExtractJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"plain", `{"a": 1}`, `{"a": 1}`},
+		{"markdown block", "```json\n{\"a\": 1}\n```", `{"a": 1}`},
+		{"text around", `Here you go: {"a": {"b": 2}} hope it helps`, `{"a": {"b": 2}}`},
+		{"braces in strings", `{"a": "}{"} tail`, `{"a": "}{"}`},
+		{"escaped quote", `{"a": "say \"}\""} tail`, `{"a": "say \"}\""}`},
+		{"citation before object", `По данным [S1] ответ: {"approved": true}`, `{"approved": true}`},
+		{"no json", "  nothing here ", "nothing here"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := llm.ExtractJSON(tt.in); got != tt.want {
+				t.Errorf("ExtractJSON() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseJSON_Array(t *testing.T) {
+	got, err := llm.ParseJSON[[]string]("```\n[\"bnpl\", \"klarna\"]\n```")
+	if err != nil || len(got) != 2 || got[1] != "klarna" {
+		t.Errorf("ParseJSON() = %v, %v; want [bnpl klarna]", got, err)
+	}
+}
+
+func TestCompleteJSON_RepairPromptKeepsUTF8(t *testing.T) {
+	client := mock.New().WithResponses(strings.Repeat("ответ ", 500), `{"approved": true}`)
+
+	if _, err := llm.CompleteJSON[verdict](context.Background(), client, "system", "prompt", 2); err != nil {
+		t.Fatalf("CompleteJSON() error = %v", err)
+	}
+	if retry := client.AllCalls[1].Prompt; !utf8.ValidString(retry) {
+		t.Errorf("retry prompt is not valid UTF-8: previous response cut mid-rune")
+	}
+}
+
+func TestCompleteJSON_ParsesFencedResponse(t *testing.T) {
+	client := mock.New().WithResponse("```json\n{\"approved\": true, \"score\": 0.8, \"tags\": [\"x\"]}\n```")
+
+	got, err := llm.CompleteJSON[verdict](context.Background(), client, "system", "prompt", 3)
+	if err != nil {
+		t.Fatalf("CompleteJSON() error = %v", err)
+	}
+	if got.Approved == nil || !*got.Approved || got.Score != 0.8 || len(got.Tags) != 1 {
+		t.Errorf("CompleteJSON() = %+v", got)
+	}
+	if client.CallCount != 1 {
+		t.Errorf("CallCount = %d, want 1", client.CallCount)
+	}
+}
+
+func TestCompleteJSON_RepromptsWithError(t *testing.T) {
+	client := mock.New().WithResponses(
+		"I think it is fine",
+		`{"score": 0.5}`,
+		`{"approved": false, "score": 0.5}`,
+	)
+
+	got, err := llm.CompleteJSON[verdict](context.Background(), client, "system", "prompt", 3)
+	if err != nil {
+		t.Fatalf("CompleteJSON() error = %v", err)
+	}
+	if got.Approved == nil || *got.Approved {
+		t.Errorf("Approved = %v, want false", got.Approved)
+	}
+	if client.CallCount != 3 {
+		t.Fatalf("CallCount = %d, want 3", client.CallCount)
+	}
+
+	if client.AllCalls[0].Prompt != "prompt" {
+		t.Errorf("first prompt = %q, want original prompt", client.AllCalls[0].Prompt)
+	}
+	second := client.AllCalls[1].Prompt
+	if !strings.HasPrefix(second, "prompt") || !strings.Contains(second, "I think it is fine") {
+		t.Errorf("retry prompt should include original prompt and previous response: %q", second)
+	}
+	if !strings.Contains(client.AllCalls[2].Prompt, "approved is required") {
+		t.Errorf("retry prompt should include validation error: %q", client.AllCalls[2].Prompt)
+	}
+}
+
+func TestCompleteJSON_GivesUp(t *testing.T) {
+	client := mock.New().WithResponse("not json at all")
+
+	_, err := llm.CompleteJSON[verdict](context.Background(), client, "system", "prompt", 2)
+	if !errors.Is(err, llm.ErrInvalidJSON) {
+		t.Errorf("CompleteJSON() error = %v, want %v", err, llm.ErrInvalidJSON)
+	}
+	if client.CallCount != 2 {
+		t.Errorf("CallCount = %d, want 2", client.CallCount)
+	}
+}
+
+func TestCompleteJSON_ClientError(t *testing.T) {
+	client := mock.New().WithError(llm.ErrRateLimit)
+
+	_, err := llm.CompleteJSON[verdict](context.Background(), client, "system", "prompt", 3)
+	if !errors.Is(err, llm.ErrRateLimit) {
+		t.Errorf("CompleteJSON() error = %v, want %v", err, llm.ErrRateLimit)
+	}
+	if client.CallCount != 1 {
+		t.Errorf("CallCount = %d, want 1 (client errors are not retried)", client.CallCount)
+	}
+}
+
+type jsonModeProbe struct{ on bool }
+
+func (p *jsonModeProbe) CompleteWithSystem(ctx context.Context, system, prompt string) (string, error) {
+	p.on = llm.JSONModeFrom(ctx)
+	return `{"approved": true}`, nil
+}
+
+func TestCompleteJSON_EnablesJSONMode(t *testing.T) {
+	if llm.JSONModeFrom(context.Background()) {
+		t.Fatal("JSON mode should be off by default")
+	}
+
+	probe := &jsonModeProbe{}
+	if _, err := llm.CompleteJSON[verdict](context.Background(), probe, "system", "prompt", 1); err != nil {
+		t.Fatalf("CompleteJSON() error = %v", err)
+	}
+	if !probe.on {
+		t.Error("CompleteJSON should enable JSON mode for the provider")
+	}
+}
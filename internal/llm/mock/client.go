@@ -10,18 +10,33 @@ import (
 
 type Client struct {
 	Response string
-	Error    error
-	Delay    time.Duration
-
-	CallCount  int
-	LastSystem string
-	LastPrompt string
-	AllCalls   []LLMCall
+	// если заданы, отдаются по очереди, а после них - Response
+	Responses []string
+	Error     error
+	Delay     time.Duration
+	// пауза между кусками в StreamWithSystem
+	ChunkDelay time.Duration
+	// если задан, каждый успешный вызов отчитывается им как провайдер "mock"
+	Usage *llm.Usage
+	// ходы модели для CompleteWithTools по очереди, после них - текст из Responses/Response
+	Turns []llm.Message
+	// CompleteWithTools отвечает llm.ErrToolsUnsupported, как GigaChat
+	ToolsUnsupported bool
+
+	CallCount       int
+	StreamCallCount int
+	ToolCallCount   int
+	LastSystem      string
+	LastPrompt      string
+	LastMessages    []llm.Message
+	LastTools       []llm.Tool
+	AllCalls        []LLMCall
 }
 
 type LLMCall struct {
-	System string
-	Prompt string
+	System  string
+	Prompt  string
+	History []llm.Message
 }
 
 func New() *Client {
@@ -35,6 +50,11 @@ func (c *Client) WithResponse(response string) *Client {
 	return c
 }
 
+func (c *Client) WithResponses(responses ...string) *Client {
+	c.Responses = responses
+	return c
+}
+
 func (c *Client) WithError(err error) *Client {
 	c.Error = err
 	return c
@@ -45,11 +65,26 @@ func (c *Client) WithDelay(delay time.Duration) *Client {
 	return c
 }
 
+func (c *Client) WithChunkDelay(delay time.Duration) *Client {
+	c.ChunkDelay = delay
+	return c
+}
+
+func (c *Client) WithTurns(turns ...llm.Message) *Client {
+	c.Turns = turns
+	return c
+}
+
+func (c *Client) WithUsage(usage llm.Usage) *Client {
+	c.Usage = &usage
+	return c
+}
+
 func (c *Client) CompleteWithSystem(ctx context.Context, system, prompt string) (string, error) {
 	c.CallCount++
 	c.LastSystem = system
 	c.LastPrompt = prompt
-	c.AllCalls = append(c.AllCalls, LLMCall{System: system, Prompt: prompt})
+	c.AllCalls = append(c.AllCalls, LLMCall{System: system, Prompt: prompt, History: llm.HistoryFrom(ctx)})
 
 	if c.Delay > 0 {
 		select {
@@ -63,11 +98,77 @@ func (c *Client) CompleteWithSystem(ctx context.Context, system, prompt string)
 		return "", c.Error
 	}
 
+	llm.ReportUsage(ctx, "mock", "mock", c.Usage)
+	if len(c.Responses) > 0 {
+		resp := c.Responses[0]
+		c.Responses = c.Responses[1:]
+		return resp, nil
+	}
 	return c.Response, nil
 }
 
+// StreamWithSystem отдает Response по словам, ошибка приходит одним куском
+func (c *Client) StreamWithSystem(ctx context.Context, system, prompt string) (<-chan llm.StreamDelta, error) {
+	c.StreamCallCount++
+	resp, err := c.CompleteWithSystem(ctx, system, prompt)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan llm.StreamDelta)
+	go func() {
+		defer close(out)
+		for _, word := range strings.SplitAfter(resp, " ") {
+			if c.ChunkDelay > 0 {
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(c.ChunkDelay):
+				}
+			}
+			select {
+			case out <- llm.StreamDelta{Content: word}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+// CompleteWithTools отдает очередной ход из Turns, а когда они кончились - текстовый ответ
+func (c *Client) CompleteWithTools(ctx context.Context, messages []llm.Message, tools []llm.Tool) (llm.Message, error) {
+	if c.ToolsUnsupported {
+		return llm.Message{}, llm.ErrToolsUnsupported
+	}
+	c.ToolCallCount++
+	c.LastMessages = messages
+	c.LastTools = tools
+
+	if c.Error != nil {
+		return llm.Message{}, c.Error
+	}
+
+	llm.ReportUsage(ctx, "mock", "mock", c.Usage)
+	if len(c.Turns) > 0 {
+		turn := c.Turns[0]
+		c.Turns = c.Turns[1:]
+		return turn, nil
+	}
+	content := c.Response
+	if len(c.Responses) > 0 {
+		content = c.Responses[0]
+		c.Responses = c.Responses[1:]
+	}
+	return llm.Message{Role: "assistant", Content: content}, nil
+}
+
 func (c *Client) Reset() {
 	c.CallCount = 0
+	c.StreamCallCount = 0
+	c.ToolCallCount = 0
+	c.LastMessages = nil
+	c.LastTools = nil
 	c.LastSystem = ""
 	c.LastPrompt = ""
 	c.AllCalls = nil
@@ -82,4 +183,7 @@ func (c *Client) HasCriticCall() bool {
 	return false
 }
 
-var _ llm.Client = (*Client)(nil)
+var (
+	_ llm.StreamingClient = (*Client)(nil)
+	_ llm.ToolClient      = (*Client)(nil)
+)
@@ -0,0 +1,226 @@
+// Package openaicompat - клиент для любого сервера с OpenAI Chat Completions API:
+// vLLM, Ollama, LM Studio, on-prem шлюзы и сам OpenAI
+package openaicompat
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/kitbuilder587/fintech-bot/internal/llm"
+	"github.com/kitbuilder587/fintech-bot/internal/retry"
+)
+
+// имя провайдера в метриках и учете расхода, совпадает с LLM_PROVIDER
+const providerName = "openai"
+
+type Config struct {
+	BaseURL string // например http://vllm:8000/v1, без /chat/completions
+	APIKey  string // локальным серверам обычно не нужен
+	Model   string
+	// дополнительные заголовки, например для авторизации на шлюзе
+	Headers map[string]string
+	Timeout time.Duration // на одну попытку
+	Retry   retry.Policy  // пустая - retry.DefaultPolicy()
+	// не все серверы понимают response_format, без него JSON просим только промптом
+	DisableJSONMode bool
+}
+
+type Client struct {
+	baseURL  string
+	apiKey   string
+	model    string
+	headers  map[string]string
+	jsonMode bool
+	client   *retry.Client
+	logger   *zap.Logger
+}
+
+func New(cfg Config, logger *zap.Logger) *Client {
+	if cfg.Timeout == 0 {
+		cfg.Timeout = 60 * time.Second
+	}
+	if cfg.Retry == (retry.Policy{}) {
+		cfg.Retry = retry.DefaultPolicy()
+	}
+
+	return &Client{
+		baseURL:  strings.TrimRight(cfg.BaseURL, "/"),
+		apiKey:   cfg.APIKey,
+		model:    cfg.Model,
+		headers:  cfg.Headers,
+		jsonMode: !cfg.DisableJSONMode,
+		client:   retry.NewClient(&http.Client{Timeout: cfg.Timeout}, cfg.Retry),
+		logger:   logger,
+	}
+}
+
+type chatResponse struct {
+	llm.ChatResponse
+	Error *llm.APIError `json:"error,omitempty"`
+}
+
+func (c *Client) CompleteWithSystem(ctx context.Context, system, prompt string) (string, error) {
+	resp, err := c.complete(ctx, llm.NewConversationRequest(c.model, system, llm.HistoryFrom(ctx), prompt))
+	if err != nil {
+		return "", err
+	}
+	return llm.ExtractContent(resp)
+}
+
+// CompleteWithTools - один ход модели со списком функций. Сервер должен поддерживать
+// tools: у vLLM это флаг --enable-auto-tool-choice, иначе он ответит 400 с упоминанием
+// tool в тексте ошибки. Такой ответ - ErrToolsUnsupported, агент ответит обычным вызовом
+func (c *Client) CompleteWithTools(ctx context.Context, messages []llm.Message, tools []llm.Tool) (llm.Message, error) {
+	resp, err := c.complete(ctx, llm.NewToolRequest(c.model, messages, tools))
+	if err != nil {
+		return llm.Message{}, err
+	}
+	return llm.ExtractMessage(resp)
+}
+
+func (c *Client) complete(ctx context.Context, req llm.ChatRequest) (*llm.ChatResponse, error) {
+	httpReq, err := c.newRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	respBody, statusCode, err := llm.DoRequest(c.client, httpReq)
+	if err != nil {
+		return nil, err
+	}
+
+	if statusCode != http.StatusOK {
+		if len(req.Tools) > 0 && toolsRejected(statusCode, respBody) {
+			return nil, fmt.Errorf("%w: status %d: %s", llm.ErrToolsUnsupported, statusCode, errorMessage(respBody))
+		}
+		return nil, c.httpError(statusCode, respBody)
+	}
+
+	var chatResp chatResponse
+	if err := json.Unmarshal(respBody, &chatResp); err != nil {
+		return nil, fmt.Errorf("unmarshal response: %w", err)
+	}
+
+	// часть серверов отдает ошибку со статусом 200
+	if chatResp.Error != nil {
+		return nil, fmt.Errorf("%w: %s", llm.ErrRequestFailed, chatResp.Error.Message)
+	}
+
+	model := chatResp.Model
+	if model == "" {
+		model = c.model
+	}
+	llm.ReportUsage(ctx, providerName, model, chatResp.Usage)
+
+	return &chatResp.ChatResponse, nil
+}
+
+func (c *Client) StreamWithSystem(ctx context.Context, system, prompt string) (<-chan llm.StreamDelta, error) {
+	req := llm.NewConversationRequest(c.model, system, llm.HistoryFrom(ctx), prompt)
+	req.Stream = true
+	req.StreamOptions = &llm.StreamOptions{IncludeUsage: true}
+
+	httpReq, err := c.newRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	body, statusCode, errBody, err := llm.DoStreamRequest(c.client, httpReq)
+	if err != nil {
+		return nil, err
+	}
+	if statusCode != http.StatusOK {
+		return nil, c.httpError(statusCode, errBody)
+	}
+
+	return llm.ReadSSE(ctx, body, providerName, c.model), nil
+}
+
+func (c *Client) newRequest(ctx context.Context, req llm.ChatRequest) (*http.Request, error) {
+	if c.jsonMode && llm.JSONModeFrom(ctx) {
+		req.ResponseFormat = &llm.ResponseFormat{Type: "json_object"}
+	}
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+
+	for k, v := range c.headers {
+		httpReq.Header.Set(k, v)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if c.apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+
+	return httpReq, nil
+}
+
+// httpError переводит ответ с ошибкой в ошибки llm, сохраняя текст сервера
+func (c *Client) httpError(statusCode int, body []byte) error {
+	// 403 шлюзы отдают и на временные запреты, ключ неверен только при 401
+	switch statusCode {
+	case http.StatusUnauthorized:
+		return llm.ErrAuthFailed
+	case http.StatusTooManyRequests:
+		return llm.ErrRateLimit
+	}
+
+	message := errorMessage(body)
+	c.logger.Error(providerName+" request failed",
+		zap.Int("status", statusCode),
+		zap.String("message", message),
+	)
+	return fmt.Errorf("%w: status %d: %s", llm.ErrRequestFailed, statusCode, message)
+}
+
+// toolsRejected - сервер отказался от запроса из-за функций. 400 бывает и по другим
+// причинам (длинный контекст), поэтому смотрим на текст ошибки
+func toolsRejected(statusCode int, body []byte) bool {
+	if statusCode != http.StatusBadRequest && statusCode != http.StatusNotFound {
+		return false
+	}
+	return strings.Contains(strings.ToLower(errorMessage(body)), "tool")
+}
+
+// errorMessage достает текст ошибки из известных форматов:
+// {"error": {"message": ...}} у OpenAI, {"error": "..."} у Ollama,
+// {"object": "error", "message": ...} у vLLM. Иначе - начало тела как есть
+func errorMessage(body []byte) string {
+	var resp struct {
+		Error   *llm.APIError `json:"error"`
+		Message string        `json:"message"`
+	}
+	if err := json.Unmarshal(body, &resp); err == nil {
+		if resp.Error != nil && resp.Error.Message != "" {
+			return resp.Error.Message
+		}
+		if resp.Message != "" {
+			return resp.Message
+		}
+	}
+
+	text := strings.TrimSpace(string(body))
+	if len(text) > 200 {
+		text = text[:200] + "..."
+	}
+	return text
+}
+
+var (
+	_ llm.StreamingClient = (*Client)(nil)
+	_ llm.ToolClient      = (*Client)(nil)
+)
@@ -0,0 +1,275 @@
+package openaicompat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/kitbuilder587/fintech-bot/internal/llm"
+	"github.com/kitbuilder587/fintech-bot/internal/retry"
+)
+
+func newTestClient(url string, cfg Config) *Client {
+	cfg.BaseURL = url
+	if cfg.Model == "" {
+		cfg.Model = "qwen2.5:14b"
+	}
+	cfg.Timeout = 5 * time.Second
+	if cfg.Retry == (retry.Policy{}) {
+		cfg.Retry = retry.Policy{MaxAttempts: 1}
+	}
+	return New(cfg, zap.NewNop())
+}
+
+func TestClient_CompleteWithSystem(t *testing.T) {
+	var got *http.Request
+	var req llm.ChatRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		w.Write([]byte(`{"model":"qwen2.5:14b-instruct","choices":[{"message":{"role":"assistant","content":"Ответ"}}],"usage":{"prompt_tokens":12,"completion_tokens":3,"total_tokens":15}}`))
+	}))
+	defer server.Close()
+
+	client := newTestClient(server.URL+"/v1/", Config{
+		Headers: map[string]string{"X-Tenant": "risk"},
+	})
+
+	usage := llm.NewUsageCollector()
+	ctx := llm.WithStage(llm.WithUsageCollector(context.Background(), usage), llm.StageAnalyze)
+	resp, err := client.CompleteWithSystem(ctx, "system", "prompt")
+	if err != nil {
+		t.Fatalf("CompleteWithSystem() error = %v", err)
+	}
+	if resp != "Ответ" {
+		t.Errorf("CompleteWithSystem() = %q, want %q", resp, "Ответ")
+	}
+
+	if got.URL.Path != "/v1/chat/completions" {
+		t.Errorf("path = %q, want /v1/chat/completions", got.URL.Path)
+	}
+	if got.Header.Get("Authorization") != "" {
+		t.Errorf("Authorization = %q, want none without API key", got.Header.Get("Authorization"))
+	}
+	if got.Header.Get("X-Tenant") != "risk" {
+		t.Errorf("X-Tenant = %q, want extra header", got.Header.Get("X-Tenant"))
+	}
+	if req.Model != "qwen2.5:14b" || len(req.Messages) != 2 || req.ResponseFormat != nil {
+		t.Errorf("request = %+v", req)
+	}
+
+	records := usage.Drain()
+	if len(records) != 1 || records[0].Provider != "openai" || records[0].Model != "qwen2.5:14b-instruct" || records[0].PromptTokens != 12 {
+		t.Errorf("usage records = %+v", records)
+	}
+}
+
+func TestClient_APIKeyAndJSONMode(t *testing.T) {
+	var auth string
+	var req llm.ChatRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		req = llm.ChatRequest{}
+		json.NewDecoder(r.Body).Decode(&req)
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"{}"}}]}`))
+	}))
+	defer server.Close()
+
+	ctx := llm.WithJSONMode(context.Background())
+
+	client := newTestClient(server.URL, Config{APIKey: "secret"})
+	if _, err := client.CompleteWithSystem(ctx, "system", "prompt"); err != nil {
+		t.Fatalf("CompleteWithSystem() error = %v", err)
+	}
+	if auth != "Bearer secret" {
+		t.Errorf("Authorization = %q, want Bearer secret", auth)
+	}
+	if req.ResponseFormat == nil || req.ResponseFormat.Type != "json_object" {
+		t.Errorf("response_format = %+v, want json_object", req.ResponseFormat)
+	}
+
+	client = newTestClient(server.URL, Config{DisableJSONMode: true})
+	if _, err := client.CompleteWithSystem(ctx, "system", "prompt"); err != nil {
+		t.Fatalf("CompleteWithSystem() error = %v", err)
+	}
+	if req.ResponseFormat != nil {
+		t.Errorf("response_format = %+v, want none when JSON mode is disabled", req.ResponseFormat)
+	}
+}
+
+func TestClient_ErrorShapes(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		body        string
+		wantErr     error
+		wantMessage string
+	}{
+		{"unauthorized", http.StatusUnauthorized, `{"error":{"message":"bad key"}}`, llm.ErrAuthFailed, ""},
+		{"forbidden gateway", http.StatusForbidden, `forbidden`, llm.ErrRequestFailed, "forbidden"},
+		{"rate limited", http.StatusTooManyRequests, `{}`, llm.ErrRateLimit, ""},
+		{"openai object", http.StatusBadRequest, `{"error":{"message":"context_length_exceeded: too long","type":"invalid_request_error","code":"context_length_exceeded"}}`, llm.ErrRequestFailed, "too long"},
+		{"ollama string", http.StatusNotFound, `{"error":"model \"llama3\" not found, try pulling it first"}`, llm.ErrRequestFailed, "not found, try pulling"},
+		{"vllm top level", http.StatusBadRequest, `{"object":"error","message":"max_tokens is too large","type":"BadRequestError","code":400}`, llm.ErrRequestFailed, "max_tokens is too large"},
+		{"plain text", http.StatusBadGateway, `upstream connect error`, llm.ErrRequestFailed, "upstream connect error"},
+		{"error with 200", http.StatusOK, `{"error":{"message":"model is loading","code":503}}`, llm.ErrRequestFailed, "model is loading"},
+		{"empty choices", http.StatusOK, `{"choices":[]}`, llm.ErrEmptyResponse, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			_, err := newTestClient(server.URL, Config{}).CompleteWithSystem(context.Background(), "system", "prompt")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantMessage != "" && !strings.Contains(err.Error(), tt.wantMessage) {
+				t.Errorf("error = %q, want server message %q", err.Error(), tt.wantMessage)
+			}
+		})
+	}
+}
+
+func TestClient_StreamWithSystem(t *testing.T) {
+	var req llm.ChatRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&req)
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(`data: {"choices":[{"delta":{"content":"При"}}]}` + "\n\n" +
+			`data: {"choices":[{"delta":{"content":"вет"}}]}` + "\n\n" +
+			`data: {"choices":[],"usage":{"prompt_tokens":5,"completion_tokens":2,"total_tokens":7}}` + "\n\n" +
+			"data: [DONE]\n\n"))
+	}))
+	defer server.Close()
+
+	usage := llm.NewUsageCollector()
+	ctx := llm.WithUsageCollector(context.Background(), usage)
+	deltas, err := newTestClient(server.URL, Config{}).StreamWithSystem(ctx, "system", "prompt")
+	if err != nil {
+		t.Fatalf("StreamWithSystem() error = %v", err)
+	}
+
+	var sb strings.Builder
+	for d := range deltas {
+		if d.Err != nil {
+			t.Fatalf("stream error = %v", d.Err)
+		}
+		sb.WriteString(d.Content)
+	}
+	if sb.String() != "Привет" {
+		t.Errorf("streamed = %q, want %q", sb.String(), "Привет")
+	}
+	if !req.Stream || req.StreamOptions == nil || !req.StreamOptions.IncludeUsage {
+		t.Errorf("request = %+v, want stream with usage", req)
+	}
+	if records := usage.Drain(); len(records) != 1 || records[0].CompletionTokens != 2 {
+		t.Errorf("usage records = %+v", records)
+	}
+}
+
+func TestClient_StreamErrorChunk(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`data: {"error":"out of memory"}` + "\n\n"))
+	}))
+	defer server.Close()
+
+	deltas, err := newTestClient(server.URL, Config{}).StreamWithSystem(context.Background(), "system", "prompt")
+	if err != nil {
+		t.Fatalf("StreamWithSystem() error = %v", err)
+	}
+
+	var streamErr error
+	for d := range deltas {
+		if d.Err != nil {
+			streamErr = d.Err
+		}
+	}
+	if !errors.Is(streamErr, llm.ErrRequestFailed) || !strings.Contains(streamErr.Error(), "out of memory") {
+		t.Errorf("stream error = %v, want request failed with server message", streamErr)
+	}
+}
+
+func TestClient_StreamHTTPError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error":"model not found"}`))
+	}))
+	defer server.Close()
+
+	_, err := newTestClient(server.URL, Config{}).StreamWithSystem(context.Background(), "system", "prompt")
+	if !errors.Is(err, llm.ErrRequestFailed) || !strings.Contains(err.Error(), "model not found") {
+		t.Errorf("StreamWithSystem() error = %v, want request failed with server message", err)
+	}
+}
+
+func TestClient_CompleteWithTools(t *testing.T) {
+	var req llm.ChatRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":null,"tool_calls":[
+			{"id":"call_1","type":"function","function":{"name":"search","arguments":"{\"query\":\"klarna\"}"}}]}}]}`))
+	}))
+	defer server.Close()
+
+	client := newTestClient(server.URL, Config{})
+	tool := llm.NewFunctionTool("search", "web search", json.RawMessage(`{"type":"object"}`))
+	msg, err := client.CompleteWithTools(context.Background(), []llm.Message{
+		{Role: "system", Content: "system"},
+		{Role: "user", Content: "prompt"},
+	}, []llm.Tool{tool})
+	if err != nil {
+		t.Fatalf("CompleteWithTools() error = %v", err)
+	}
+
+	if len(req.Tools) != 1 || req.Tools[0].Type != "function" || req.Tools[0].Function.Name != "search" {
+		t.Errorf("request tools = %+v", req.Tools)
+	}
+	if len(msg.ToolCalls) != 1 || msg.ToolCalls[0].ID != "call_1" || msg.ToolCalls[0].Function.Arguments != `{"query":"klarna"}` {
+		t.Errorf("message = %+v, want one search call", msg)
+	}
+}
+
+func TestClient_CompleteWithTools_Rejected(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		wantErr error
+	}{
+		{"tools disabled", `{"object":"error","message":"\"auto\" tool choice requires --enable-auto-tool-choice","code":400}`, llm.ErrToolsUnsupported},
+		// 400 по другой причине - обычная ошибка запроса, откат на простой вызов не поможет
+		{"context too long", `{"object":"error","message":"maximum context length is 8192 tokens","code":400}`, llm.ErrRequestFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			client := newTestClient(server.URL, Config{})
+			tool := llm.NewFunctionTool("search", "web search", json.RawMessage(`{"type":"object"}`))
+			_, err := client.CompleteWithTools(context.Background(), []llm.Message{{Role: "user", Content: "prompt"}}, []llm.Tool{tool})
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -11,20 +11,22 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/kitbuilder587/fintech-bot/internal/llm"
+	"github.com/kitbuilder587/fintech-bot/internal/retry"
 )
 
 type Config struct {
 	APIKey  string
 	Model   string
 	BaseURL string
-	Timeout time.Duration
+	Timeout time.Duration // на одну попытку
+	Retry   retry.Policy  // пустая - retry.DefaultPolicy()
 }
 
 type Client struct {
 	apiKey  string
 	model   string
 	baseURL string
-	client  *http.Client
+	client  *retry.Client
 	logger  *zap.Logger
 }
 
@@ -38,12 +40,15 @@ func New(cfg Config, logger *zap.Logger) *Client {
 	if cfg.Timeout == 0 {
 		cfg.Timeout = 60 * time.Second
 	}
+	if cfg.Retry == (retry.Policy{}) {
+		cfg.Retry = retry.DefaultPolicy()
+	}
 
 	return &Client{
 		apiKey:  cfg.APIKey,
 		model:   cfg.Model,
 		baseURL: cfg.BaseURL,
-		client:  &http.Client{Timeout: cfg.Timeout},
+		client:  retry.NewClient(&http.Client{Timeout: cfg.Timeout}, cfg.Retry),
 		logger:  logger,
 	}
 }
@@ -60,40 +65,105 @@ type apiError struct {
 }
 
 func (c *Client) CompleteWithSystem(ctx context.Context, system, prompt string) (string, error) {
-	req := llm.NewChatRequest(c.model, system, prompt)
-
-	body, err := json.Marshal(req)
+	resp, err := c.complete(ctx, llm.NewConversationRequest(c.model, system, llm.HistoryFrom(ctx), prompt))
 	if err != nil {
-		return "", fmt.Errorf("marshal request: %w", err)
+		return "", err
 	}
+	return llm.ExtractContent(resp)
+}
 
-	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/chat/completions", bytes.NewReader(body))
+// CompleteWithTools - один ход модели со списком функций. Модели без поддержки
+// tools OpenRouter отклоняет с 404 ("No endpoints found that support tool use"),
+// это ErrToolsUnsupported: агент ответит одним обычным вызовом
+func (c *Client) CompleteWithTools(ctx context.Context, messages []llm.Message, tools []llm.Tool) (llm.Message, error) {
+	resp, err := c.complete(ctx, llm.NewToolRequest(c.model, messages, tools))
 	if err != nil {
-		return "", fmt.Errorf("create request: %w", err)
+		return llm.Message{}, err
 	}
+	return llm.ExtractMessage(resp)
+}
 
-	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
-	httpReq.Header.Set("HTTP-Referer", "https://github.com/kitbuilder587/fintech-bot")
-	httpReq.Header.Set("X-Title", "Fintech Research Bot")
+func (c *Client) complete(ctx context.Context, req llm.ChatRequest) (*llm.ChatResponse, error) {
+	httpReq, err := c.newRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
 
 	respBody, statusCode, err := llm.DoRequest(c.client, httpReq)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
+	if statusCode == http.StatusNotFound && len(req.Tools) > 0 {
+		return nil, fmt.Errorf("%w: status %d: %s", llm.ErrToolsUnsupported, statusCode, respBody)
+	}
 	if statusCode != http.StatusOK {
-		return "", llm.HandleHTTPError(statusCode, respBody, c.logger, "openrouter")
+		return nil, llm.HandleHTTPError(statusCode, respBody, c.logger, "openrouter")
 	}
 
 	var chatResp openRouterResponse
 	if err := json.Unmarshal(respBody, &chatResp); err != nil {
-		return "", fmt.Errorf("unmarshal response: %w", err)
+		return nil, fmt.Errorf("unmarshal response: %w", err)
 	}
 
 	if chatResp.Error != nil {
-		return "", fmt.Errorf("%w: %s", llm.ErrRequestFailed, chatResp.Error.Message)
+		return nil, fmt.Errorf("%w: %s", llm.ErrRequestFailed, chatResp.Error.Message)
 	}
 
-	return llm.ExtractContent(&chatResp.ChatResponse)
+	model := chatResp.Model
+	if model == "" {
+		model = c.model
+	}
+	llm.ReportUsage(ctx, "openrouter", model, chatResp.Usage)
+
+	return &chatResp.ChatResponse, nil
 }
+
+func (c *Client) StreamWithSystem(ctx context.Context, system, prompt string) (<-chan llm.StreamDelta, error) {
+	req := llm.NewConversationRequest(c.model, system, llm.HistoryFrom(ctx), prompt)
+	req.Stream = true
+	req.StreamOptions = &llm.StreamOptions{IncludeUsage: true}
+
+	httpReq, err := c.newRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	body, statusCode, errBody, err := llm.DoStreamRequest(c.client, httpReq)
+	if err != nil {
+		return nil, err
+	}
+	if statusCode != http.StatusOK {
+		return nil, llm.HandleHTTPError(statusCode, errBody, c.logger, "openrouter")
+	}
+
+	return llm.ReadSSE(ctx, body, "openrouter", c.model), nil
+}
+
+func (c *Client) newRequest(ctx context.Context, req llm.ChatRequest) (*http.Request, error) {
+	if llm.JSONModeFrom(ctx) {
+		req.ResponseFormat = &llm.ResponseFormat{Type: "json_object"}
+	}
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
+	httpReq.Header.Set("HTTP-Referer", "https://github.com/kitbuilder587/fintech-bot")
+	httpReq.Header.Set("X-Title", "Fintech Research Bot")
+
+	return httpReq, nil
+}
+
+var (
+	_ llm.StreamingClient = (*Client)(nil)
+	_ llm.ToolClient      = (*Client)(nil)
+)
@@ -11,6 +11,7 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/kitbuilder587/fintech-bot/internal/llm"
+	"github.com/kitbuilder587/fintech-bot/internal/retry"
 )
 
 func TestClient_CompleteWithSystem(t *testing.T) {
@@ -70,6 +71,7 @@ func TestClient_CompleteWithSystem(t *testing.T) {
 				APIKey:  "test-key",
 				BaseURL: server.URL,
 				Timeout: 5 * time.Second,
+				Retry:   retry.Policy{MaxAttempts: 1},
 			}, logger)
 
 			result, err := client.CompleteWithSystem(context.Background(), "system", "prompt")
@@ -92,3 +94,173 @@ func TestClient_CompleteWithSystem(t *testing.T) {
 		})
 	}
 }
+
+func TestClient_StreamWithSystem(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req llm.ChatRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("decode request: %v", err)
+		}
+		if !req.Stream {
+			t.Error("stream flag not set")
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(": OPENROUTER PROCESSING\n\n"))
+		w.Write([]byte(`data: {"choices":[{"delta":{"content":"Привет, "}}]}` + "\n\n"))
+		w.Write([]byte(`data: {"choices":[{"delta":{"content":"мир"}}]}` + "\n\n"))
+		w.Write([]byte("data: [DONE]\n\n"))
+	}))
+	defer server.Close()
+
+	client := New(Config{APIKey: "test-key", BaseURL: server.URL, Timeout: 5 * time.Second}, zap.NewNop())
+
+	deltas, err := client.StreamWithSystem(context.Background(), "system", "prompt")
+	if err != nil {
+		t.Fatalf("StreamWithSystem() error = %v", err)
+	}
+
+	var got string
+	for d := range deltas {
+		if d.Err != nil {
+			t.Fatalf("stream error = %v", d.Err)
+		}
+		got += d.Content
+	}
+	if got != "Привет, мир" {
+		t.Errorf("streamed text = %q, want %q", got, "Привет, мир")
+	}
+}
+
+func TestClient_StreamWithSystem_HTTPError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	client := New(Config{APIKey: "test-key", BaseURL: server.URL, Timeout: 5 * time.Second, Retry: retry.Policy{MaxAttempts: 1}}, zap.NewNop())
+
+	_, err := client.StreamWithSystem(context.Background(), "system", "prompt")
+	if err != llm.ErrRateLimit {
+		t.Errorf("StreamWithSystem() error = %v, want %v", err, llm.ErrRateLimit)
+	}
+}
+
+func TestClient_CompleteWithSystem_ReportsUsage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"model":"deepseek/deepseek-chat-v3","choices":[{"message":{"role":"assistant","content":"ok"}}],"usage":{"prompt_tokens":42,"completion_tokens":7,"total_tokens":49}}`))
+	}))
+	defer server.Close()
+
+	client := New(Config{APIKey: "test-key", BaseURL: server.URL, Timeout: 5 * time.Second}, zap.NewNop())
+
+	usage := llm.NewUsageCollector()
+	ctx := llm.WithStage(llm.WithUsageCollector(context.Background(), usage), llm.StageCritic)
+	if _, err := client.CompleteWithSystem(ctx, "system", "prompt"); err != nil {
+		t.Fatalf("CompleteWithSystem() error = %v", err)
+	}
+
+	records := usage.Drain()
+	if len(records) != 1 {
+		t.Fatalf("usage records = %d, want 1", len(records))
+	}
+	r := records[0]
+	if r.Provider != "openrouter" || r.Model != "deepseek/deepseek-chat-v3" || r.Stage != llm.StageCritic || r.PromptTokens != 42 || r.CompletionTokens != 7 {
+		t.Errorf("usage record = %+v", r)
+	}
+}
+
+func TestClient_CompleteWithSystem_JSONMode(t *testing.T) {
+	var formats []*llm.ResponseFormat
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req llm.ChatRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		formats = append(formats, req.ResponseFormat)
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"{}"}}]}`))
+	}))
+	defer server.Close()
+
+	client := New(Config{APIKey: "test-key", BaseURL: server.URL, Timeout: 5 * time.Second}, zap.NewNop())
+
+	if _, err := client.CompleteWithSystem(context.Background(), "system", "prompt"); err != nil {
+		t.Fatalf("CompleteWithSystem() error = %v", err)
+	}
+	if _, err := client.CompleteWithSystem(llm.WithJSONMode(context.Background()), "system", "prompt"); err != nil {
+		t.Fatalf("CompleteWithSystem() error = %v", err)
+	}
+
+	if len(formats) != 2 || formats[0] != nil {
+		t.Fatalf("response_format without JSON mode = %v", formats)
+	}
+	if formats[1] == nil || formats[1].Type != "json_object" {
+		t.Errorf("response_format in JSON mode = %+v, want json_object", formats[1])
+	}
+}
+
+func TestClient_CompleteWithSystem_SendsHistory(t *testing.T) {
+	var got []llm.Message
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req llm.ChatRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		got = req.Messages
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"ok"}}]}`))
+	}))
+	defer server.Close()
+
+	client := New(Config{APIKey: "test-key", BaseURL: server.URL, Timeout: 5 * time.Second}, zap.NewNop())
+
+	ctx := llm.WithHistory(context.Background(), []llm.Message{
+		{Role: "user", Content: "first question"},
+		{Role: "assistant", Content: "first answer"},
+	})
+	if _, err := client.CompleteWithSystem(ctx, "system", "follow-up"); err != nil {
+		t.Fatalf("CompleteWithSystem() error = %v", err)
+	}
+
+	var roles []string
+	for _, m := range got {
+		roles = append(roles, m.Role+":"+m.Content)
+	}
+	want := []string{"system:system", "user:first question", "assistant:first answer", "user:follow-up"}
+	if len(roles) != len(want) {
+		t.Fatalf("messages = %v, want %v", roles, want)
+	}
+	for i := range want {
+		if roles[i] != want[i] {
+			t.Errorf("messages[%d] = %q, want %q", i, roles[i], want[i])
+		}
+	}
+}
+
+func TestClient_CompleteWithSystem_RetriesRateLimit(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"ok"}}]}`))
+	}))
+	defer server.Close()
+
+	client := New(Config{
+		APIKey:  "test-key",
+		BaseURL: server.URL,
+		Timeout: 5 * time.Second,
+		Retry:   retry.Policy{MaxAttempts: 2, BaseDelay: time.Millisecond},
+	}, zap.NewNop())
+
+	result, err := client.CompleteWithSystem(context.Background(), "system", "prompt")
+	if err != nil {
+		t.Fatalf("CompleteWithSystem() error = %v", err)
+	}
+	if result != "ok" || calls != 2 {
+		t.Errorf("result = %q after %d calls, want ok after 2", result, calls)
+	}
+}
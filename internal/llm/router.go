@@ -0,0 +1,48 @@
+package llm
+
+import (
+	"context"
+	"strings"
+)
+
+// Router выбирает клиента по стадии из контекста: дешевые модели для
+// расширения запроса и извлечения фактов, сильные - для анализа
+type Router struct {
+	fallback Client
+	routes   map[string]Client
+}
+
+// NewRouter - routes по стадиям (expand, agent, agent:market, ...), остальное идет в fallback
+func NewRouter(fallback Client, routes map[string]Client) *Router {
+	return &Router{fallback: fallback, routes: routes}
+}
+
+// For - клиент для стадии: точное совпадение, потом префикс до ":", потом fallback
+func (r *Router) For(stage string) Client {
+	if c, ok := r.routes[stage]; ok {
+		return c
+	}
+	if prefix, _, found := strings.Cut(stage, ":"); found {
+		if c, ok := r.routes[prefix]; ok {
+			return c
+		}
+	}
+	return r.fallback
+}
+
+func (r *Router) CompleteWithSystem(ctx context.Context, system, prompt string) (string, error) {
+	return r.For(StageFrom(ctx)).CompleteWithSystem(ctx, system, prompt)
+}
+
+func (r *Router) StreamWithSystem(ctx context.Context, system, prompt string) (<-chan StreamDelta, error) {
+	return OpenStream(ctx, r.For(StageFrom(ctx)), system, prompt)
+}
+
+func (r *Router) CompleteWithTools(ctx context.Context, messages []Message, tools []Tool) (Message, error) {
+	return CompleteWithTools(ctx, r.For(StageFrom(ctx)), messages, tools)
+}
+
+var (
+	_ StreamingClient = (*Router)(nil)
+	_ ToolClient      = (*Router)(nil)
+)
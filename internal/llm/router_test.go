@@ -0,0 +1,66 @@
+package llm_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kitbuilder587/fintech-bot/internal/llm"
+	"github.com/kitbuilder587/fintech-bot/internal/llm/mock"
+)
+
+func TestRouter_RoutesByStage(t *testing.T) {
+	strong := mock.New().WithResponse("strong")
+	cheap := mock.New().WithResponse("cheap")
+	market := mock.New().WithResponse("market")
+	router := llm.NewRouter(strong, map[string]llm.Client{
+		llm.StageExpand:          cheap,
+		"agent":                  cheap,
+		llm.AgentStage("market"): market,
+		llm.StageExtraction:      cheap,
+	})
+
+	tests := []struct {
+		stage string
+		want  string
+	}{
+		{llm.StageExpand, "cheap"},
+		{llm.StageExtraction, "cheap"},
+		{llm.AgentStage("tech"), "cheap"},
+		{llm.AgentStage("market"), "market"},
+		{llm.StageAnalyze, "strong"},
+		{llm.StageSynthesize, "strong"},
+		{"", "strong"},
+	}
+	for _, tt := range tests {
+		ctx := context.Background()
+		if tt.stage != "" {
+			ctx = llm.WithStage(ctx, tt.stage)
+		}
+		got, err := router.CompleteWithSystem(ctx, "system", "prompt")
+		if err != nil {
+			t.Fatalf("stage %q: %v", tt.stage, err)
+		}
+		if got != tt.want {
+			t.Errorf("stage %q routed to %q, want %q", tt.stage, got, tt.want)
+		}
+	}
+}
+
+func TestRouter_StreamsThroughRoutedClient(t *testing.T) {
+	strong := mock.New().WithResponse("strong answer")
+	cheap := mock.New().WithResponse("cheap answer")
+	router := llm.NewRouter(strong, map[string]llm.Client{llm.StageExpand: cheap})
+
+	var shown string
+	ctx := llm.WithStreamSink(llm.WithStage(context.Background(), llm.StageSynthesize), func(text string) { shown = text })
+	got, err := llm.CompleteStreaming(ctx, router, "system", "prompt")
+	if err != nil {
+		t.Fatalf("CompleteStreaming() error = %v", err)
+	}
+	if got != "strong answer" || shown != "strong answer" {
+		t.Errorf("got %q, shown %q, want strong answer", got, shown)
+	}
+	if strong.StreamCallCount != 1 || cheap.StreamCallCount != 0 {
+		t.Errorf("stream calls strong=%d cheap=%d, want 1 and 0", strong.StreamCallCount, cheap.StreamCallCount)
+	}
+}
@@ -0,0 +1,182 @@
+package llm
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const sseDone = "[DONE]"
+
+type streamChunk struct {
+	Model   string `json:"model,omitempty"`
+	Usage   *Usage `json:"usage,omitempty"`
+	Choices []struct {
+		Delta Message `json:"delta"`
+	} `json:"choices"`
+	Error *APIError `json:"error,omitempty"`
+}
+
+// StreamSink получает накопленный текст ответа после каждого куска
+type StreamSink func(text string)
+
+type streamSinkKey struct{}
+
+// WithStreamSink просит CompleteStreaming отдавать промежуточный текст в sink
+func WithStreamSink(ctx context.Context, sink StreamSink) context.Context {
+	return context.WithValue(ctx, streamSinkKey{}, sink)
+}
+
+func streamSinkFrom(ctx context.Context) StreamSink {
+	sink, _ := ctx.Value(streamSinkKey{}).(StreamSink)
+	return sink
+}
+
+// OpenStream стримит через c, если он это умеет, иначе отдает
+// весь ответ одним куском
+func OpenStream(ctx context.Context, c Client, system, prompt string) (<-chan StreamDelta, error) {
+	if sc, ok := c.(StreamingClient); ok {
+		return sc.StreamWithSystem(ctx, system, prompt)
+	}
+
+	out := make(chan StreamDelta, 1)
+	go func() {
+		defer close(out)
+		resp, err := c.CompleteWithSystem(ctx, system, prompt)
+		if err != nil {
+			out <- StreamDelta{Err: err}
+			return
+		}
+		out <- StreamDelta{Content: resp}
+	}()
+	return out, nil
+}
+
+// CompleteStreaming работает как CompleteWithSystem, но если в ctx есть
+// StreamSink, читает ответ потоком и показывает его по ходу генерации
+func CompleteStreaming(ctx context.Context, c Client, system, prompt string) (string, error) {
+	sink := streamSinkFrom(ctx)
+	if sink == nil {
+		return c.CompleteWithSystem(ctx, system, prompt)
+	}
+
+	deltas, err := OpenStream(ctx, c, system, prompt)
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	for d := range deltas {
+		if d.Err != nil {
+			return "", d.Err
+		}
+		if d.Content == "" {
+			continue
+		}
+		sb.WriteString(d.Content)
+		sink(sb.String())
+	}
+
+	// поток мог оборваться по отмене ctx без явной ошибки
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	if sb.Len() == 0 {
+		return "", ErrEmptyResponse
+	}
+	return sb.String(), nil
+}
+
+// DoStreamRequest отправляет запрос и при успехе отдает тело для чтения SSE.
+// При не-200 тело уже прочитано и закрыто, статус и его содержимое возвращаются
+func DoStreamRequest(client HTTPDoer, req *http.Request) (io.ReadCloser, int, []byte, error) {
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, 0, nil, fmt.Errorf("%w: %v", ErrRequestFailed, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return nil, resp.StatusCode, body, nil
+	}
+
+	return resp.Body, resp.StatusCode, nil, nil
+}
+
+// ReadSSE разбирает OpenAI-совместимый поток chat completions в канал кусков.
+// Тело закрывается, когда поток кончился или ctx отменен. Usage из последнего
+// куска уходит в ReportUsage под именем provider
+func ReadSSE(ctx context.Context, body io.ReadCloser, provider, model string) <-chan StreamDelta {
+	out := make(chan StreamDelta, 16)
+
+	go func() {
+		defer close(out)
+		defer body.Close()
+
+		// некоторые провайдеры шлют usage в нескольких кусках, учитываем последний
+		var usage *Usage
+		defer func() { ReportUsage(ctx, provider, model, usage) }()
+
+		send := func(d StreamDelta) bool {
+			select {
+			case out <- d:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		scanner := bufio.NewScanner(body)
+		scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+		for scanner.Scan() {
+			line := scanner.Text()
+			// пустые строки разделяют события, ":" - комментарии-keepalive
+			if !strings.HasPrefix(line, "data:") {
+				continue
+			}
+			data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+			if data == sseDone {
+				return
+			}
+
+			var chunk streamChunk
+			if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+				send(StreamDelta{Err: fmt.Errorf("unmarshal stream chunk: %w", err)})
+				return
+			}
+			if chunk.Model != "" {
+				model = chunk.Model
+			}
+			if chunk.Usage != nil {
+				usage = chunk.Usage
+			}
+			if chunk.Error != nil {
+				send(StreamDelta{Err: fmt.Errorf("%w: %s", ErrRequestFailed, chunk.Error.Message)})
+				return
+			}
+			if len(chunk.Choices) == 0 || chunk.Choices[0].Delta.Content == "" {
+				continue
+			}
+			if !send(StreamDelta{Content: chunk.Choices[0].Delta.Content}) {
+				return
+			}
+		}
+
+		if err := scanner.Err(); err != nil {
+			if ctx.Err() != nil {
+				err = ctx.Err()
+			}
+			send(StreamDelta{Err: fmt.Errorf("%w: read stream: %w", ErrRequestFailed, err)})
+		}
+	}()
+
+	return out
+}
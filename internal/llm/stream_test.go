@@ -0,0 +1,134 @@
+package llm_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/kitbuilder587/fintech-bot/internal/llm"
+	"github.com/kitbuilder587/fintech-bot/internal/llm/mock"
+)
+
+func collect(t *testing.T, deltas <-chan llm.StreamDelta) (string, error) {
+	t.Helper()
+	var sb strings.Builder
+	for d := range deltas {
+		if d.Err != nil {
+			return sb.String(), d.Err
+		}
+		sb.WriteString(d.Content)
+	}
+	return sb.String(), nil
+}
+
+func TestReadSSE(t *testing.T) {
+	body := ": keepalive\n\n" +
+		`data: {"choices":[{"delta":{"role":"assistant"}}]}` + "\n\n" +
+		`data: {"choices":[{"delta":{"content":"Hello"}}]}` + "\n\n" +
+		`data:{"choices":[{"delta":{"content":", world"}}]}` + "\n\n" +
+		`data: {"model":"deepseek/deepseek-chat-v3","choices":[],"usage":{"prompt_tokens":12,"completion_tokens":3,"total_tokens":15}}` + "\n\n" +
+		"data: [DONE]\n\n" +
+		`data: {"choices":[{"delta":{"content":"ignored"}}]}` + "\n\n"
+
+	usage := llm.NewUsageCollector()
+	ctx := llm.WithStage(llm.WithUsageCollector(context.Background(), usage), llm.StageSynthesize)
+
+	got, err := collect(t, llm.ReadSSE(ctx, io.NopCloser(strings.NewReader(body)), "openrouter", "deepseek/deepseek-chat"))
+	if err != nil {
+		t.Fatalf("ReadSSE() error = %v", err)
+	}
+	if got != "Hello, world" {
+		t.Errorf("ReadSSE() = %q, want %q", got, "Hello, world")
+	}
+
+	records := usage.Drain()
+	if len(records) != 1 {
+		t.Fatalf("usage records = %d, want 1", len(records))
+	}
+	want := llm.UsageRecord{
+		Provider: "openrouter",
+		Model:    "deepseek/deepseek-chat-v3",
+		Stage:    llm.StageSynthesize,
+		Usage:    llm.Usage{PromptTokens: 12, CompletionTokens: 3, TotalTokens: 15},
+	}
+	if records[0] != want {
+		t.Errorf("usage record = %+v, want %+v", records[0], want)
+	}
+}
+
+func TestReadSSE_ErrorChunk(t *testing.T) {
+	body := `data: {"choices":[{"delta":{"content":"partial"}}]}` + "\n\n" +
+		`data: {"error":{"message":"provider overloaded"}}` + "\n\n"
+
+	_, err := collect(t, llm.ReadSSE(context.Background(), io.NopCloser(strings.NewReader(body)), "openrouter", "model"))
+	if !errors.Is(err, llm.ErrRequestFailed) {
+		t.Errorf("ReadSSE() error = %v, want %v", err, llm.ErrRequestFailed)
+	}
+}
+
+type plainClient struct {
+	response string
+}
+
+func (c plainClient) CompleteWithSystem(ctx context.Context, system, prompt string) (string, error) {
+	return c.response, nil
+}
+
+func TestCompleteStreaming(t *testing.T) {
+	t.Run("without sink uses plain completion", func(t *testing.T) {
+		client := mock.New().WithResponse("one two three")
+
+		got, err := llm.CompleteStreaming(context.Background(), client, "system", "prompt")
+		if err != nil {
+			t.Fatalf("CompleteStreaming() error = %v", err)
+		}
+		if got != "one two three" || client.StreamCallCount != 0 {
+			t.Errorf("got %q with %d stream calls, want plain completion", got, client.StreamCallCount)
+		}
+	})
+
+	t.Run("with sink streams accumulated text", func(t *testing.T) {
+		client := mock.New().WithResponse("one two three")
+		var updates []string
+		ctx := llm.WithStreamSink(context.Background(), func(text string) {
+			updates = append(updates, text)
+		})
+
+		got, err := llm.CompleteStreaming(ctx, client, "system", "prompt")
+		if err != nil {
+			t.Fatalf("CompleteStreaming() error = %v", err)
+		}
+		if got != "one two three" {
+			t.Errorf("CompleteStreaming() = %q, want %q", got, "one two three")
+		}
+		want := []string{"one ", "one two ", "one two three"}
+		if strings.Join(updates, "|") != strings.Join(want, "|") {
+			t.Errorf("sink updates = %q, want %q", updates, want)
+		}
+	})
+
+	t.Run("non-streaming client falls back to one chunk", func(t *testing.T) {
+		var updates int
+		ctx := llm.WithStreamSink(context.Background(), func(string) { updates++ })
+
+		got, err := llm.CompleteStreaming(ctx, plainClient{response: "full answer"}, "system", "prompt")
+		if err != nil {
+			t.Fatalf("CompleteStreaming() error = %v", err)
+		}
+		if got != "full answer" || updates != 1 {
+			t.Errorf("got %q with %d updates, want full answer with 1 update", got, updates)
+		}
+	})
+
+	t.Run("error is returned", func(t *testing.T) {
+		ctx := llm.WithStreamSink(context.Background(), func(string) {})
+		client := mock.New().WithError(llm.ErrRateLimit)
+
+		_, err := llm.CompleteStreaming(ctx, client, "system", "prompt")
+		if !errors.Is(err, llm.ErrRateLimit) {
+			t.Errorf("CompleteStreaming() error = %v, want %v", err, llm.ErrRateLimit)
+		}
+	})
+}
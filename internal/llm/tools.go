@@ -0,0 +1,86 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+)
+
+// ErrToolsUnsupported - провайдер не умеет вызывать функции (например, GigaChat).
+// Вызывающий должен откатиться на обычный CompleteWithSystem
+var ErrToolsUnsupported = errors.New("tool calling is not supported")
+
+// Tool - описание функции в формате OpenAI tools
+type Tool struct {
+	Type     string       `json:"type"` // всегда function
+	Function ToolFunction `json:"function"`
+}
+
+type ToolFunction struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Parameters  json.RawMessage `json:"parameters"` // JSON Schema аргументов
+}
+
+// ToolCall - вызов функции, который запросила модель
+type ToolCall struct {
+	ID       string           `json:"id"`
+	Type     string           `json:"type"`
+	Function ToolCallFunction `json:"function"`
+}
+
+type ToolCallFunction struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"` // JSON строкой, как его прислала модель
+}
+
+func NewFunctionTool(name, description string, parameters json.RawMessage) Tool {
+	return Tool{
+		Type:     "function",
+		Function: ToolFunction{Name: name, Description: description, Parameters: parameters},
+	}
+}
+
+// ToolMessage - результат вызова функции для следующего хода модели
+func ToolMessage(callID, content string) Message {
+	return Message{Role: "tool", ToolCallID: callID, Content: content}
+}
+
+// ToolClient - клиент, который отдает модели список функций и возвращает ее ход:
+// либо текст ответа, либо вызовы функций в ToolCalls
+type ToolClient interface {
+	Client
+	CompleteWithTools(ctx context.Context, messages []Message, tools []Tool) (Message, error)
+}
+
+// CompleteWithTools вызывает c.CompleteWithTools, если клиент это умеет, иначе ErrToolsUnsupported
+func CompleteWithTools(ctx context.Context, c Client, messages []Message, tools []Tool) (Message, error) {
+	if tc, ok := c.(ToolClient); ok {
+		return tc.CompleteWithTools(ctx, messages, tools)
+	}
+	return Message{}, ErrToolsUnsupported
+}
+
+// NewToolRequest - запрос с готовой перепиской и описанием функций
+func NewToolRequest(model string, messages []Message, tools []Tool) ChatRequest {
+	return ChatRequest{
+		Model:    model,
+		Messages: messages,
+		Tools:    tools,
+	}
+}
+
+// ExtractMessage - ход модели из ответа: текст или вызовы функций
+func ExtractMessage(resp *ChatResponse) (Message, error) {
+	if len(resp.Choices) == 0 {
+		return Message{}, ErrEmptyResponse
+	}
+	msg := resp.Choices[0].Message
+	if msg.Content == "" && len(msg.ToolCalls) == 0 {
+		return Message{}, ErrEmptyResponse
+	}
+	if msg.Role == "" {
+		msg.Role = "assistant"
+	}
+	return msg, nil
+}
@@ -0,0 +1,96 @@
+package llm
+
+import (
+	"context"
+	"sync"
+)
+
+// стадии пайплайна, к которым привязывается расход токенов
+const (
+	StageExpand     = "expand"
+	StageAnalyze    = "analyze"
+	StageSynthesize = "synthesize"
+	StageCritic     = "critic"
+	StageImprove    = "improve"
+	StageExtraction = "extraction"
+	StageUnknown    = "unknown"
+)
+
+// AgentStage - стадия ответа конкретного агента, например agent:market
+func AgentStage(name string) string {
+	return "agent:" + name
+}
+
+// Usage - блок usage из ответа OpenAI-совместимого API
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+type UsageRecord struct {
+	Provider string
+	Model    string
+	Stage    string
+	Usage
+}
+
+// UsageCollector собирает расход всех вызовов LLM в рамках одного запроса
+type UsageCollector struct {
+	mu      sync.Mutex
+	records []UsageRecord
+}
+
+func NewUsageCollector() *UsageCollector {
+	return &UsageCollector{}
+}
+
+func (c *UsageCollector) Add(r UsageRecord) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.records = append(c.records, r)
+}
+
+// Drain отдает накопленные записи и очищает коллектор
+func (c *UsageCollector) Drain() []UsageRecord {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	records := c.records
+	c.records = nil
+	return records
+}
+
+type stageKey struct{}
+type usageCollectorKey struct{}
+
+func WithStage(ctx context.Context, stage string) context.Context {
+	return context.WithValue(ctx, stageKey{}, stage)
+}
+
+func StageFrom(ctx context.Context) string {
+	if stage, ok := ctx.Value(stageKey{}).(string); ok && stage != "" {
+		return stage
+	}
+	return StageUnknown
+}
+
+func WithUsageCollector(ctx context.Context, c *UsageCollector) context.Context {
+	return context.WithValue(ctx, usageCollectorKey{}, c)
+}
+
+// ReportUsage вызывают провайдеры после каждого ответа; без коллектора в ctx ничего не делает
+func ReportUsage(ctx context.Context, provider, model string, u *Usage) {
+	if u == nil {
+		return
+	}
+	c, _ := ctx.Value(usageCollectorKey{}).(*UsageCollector)
+	if c == nil {
+		return
+	}
+	c.Add(UsageRecord{
+		Provider: provider,
+		Model:    model,
+		Stage:    StageFrom(ctx),
+		Usage:    *u,
+	})
+}
@@ -0,0 +1,34 @@
+package llm_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kitbuilder587/fintech-bot/internal/llm"
+)
+
+func TestReportUsage(t *testing.T) {
+	// без коллектора в ctx отчет просто игнорируется
+	llm.ReportUsage(context.Background(), "openrouter", "model", &llm.Usage{PromptTokens: 1})
+
+	usage := llm.NewUsageCollector()
+	ctx := llm.WithUsageCollector(context.Background(), usage)
+
+	llm.ReportUsage(ctx, "openrouter", "model", &llm.Usage{PromptTokens: 10, CompletionTokens: 5})
+	llm.ReportUsage(llm.WithStage(ctx, llm.AgentStage("market")), "gigachat", "GigaChat", &llm.Usage{PromptTokens: 7})
+	llm.ReportUsage(ctx, "openrouter", "model", nil)
+
+	records := usage.Drain()
+	if len(records) != 2 {
+		t.Fatalf("records = %d, want 2", len(records))
+	}
+	if records[0].Stage != llm.StageUnknown || records[0].PromptTokens != 10 {
+		t.Errorf("first record = %+v, want unknown stage with 10 prompt tokens", records[0])
+	}
+	if records[1].Stage != "agent:market" || records[1].Provider != "gigachat" {
+		t.Errorf("second record = %+v, want agent:market from gigachat", records[1])
+	}
+	if len(usage.Drain()) != 0 {
+		t.Error("Drain() must clear the collector")
+	}
+}
@@ -16,6 +16,10 @@ type Metrics struct {
 
 	LLMRequestsTotal   *prometheus.CounterVec
 	LLMRequestDuration *prometheus.HistogramVec
+	LLMBreakerState    *prometheus.GaugeVec
+	LLMTokensTotal     *prometheus.CounterVec
+	LLMCostUSDTotal    *prometheus.CounterVec
+	LLMCacheTotal      *prometheus.CounterVec
 
 	SearchRequestsTotal   *prometheus.CounterVec
 	SearchRequestDuration *prometheus.HistogramVec
@@ -67,13 +71,41 @@ func New() *Metrics {
 			},
 			[]string{"provider"},
 		),
+		LLMBreakerState: promauto.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "fintech_bot_llm_breaker_state",
+				Help: "LLM provider circuit breaker state: 0 closed, 1 half-open, 2 open, 3 disabled",
+			},
+			[]string{"provider"},
+		),
+		LLMTokensTotal: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "fintech_bot_llm_tokens_total",
+				Help: "Total number of LLM tokens by pipeline stage",
+			},
+			[]string{"provider", "model", "stage", "type"},
+		),
+		LLMCostUSDTotal: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "fintech_bot_llm_cost_usd_total",
+				Help: "Estimated LLM cost in USD by pipeline stage",
+			},
+			[]string{"provider", "model", "stage"},
+		),
+		LLMCacheTotal: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "fintech_bot_llm_cache_total",
+				Help: "LLM response cache lookups by pipeline stage and result (hit or miss)",
+			},
+			[]string{"provider", "stage", "result"},
+		),
 
 		SearchRequestsTotal: promauto.NewCounterVec(
 			prometheus.CounterOpts{
 				Name: "fintech_bot_search_requests_total",
 				Help: "Total number of search API requests",
 			},
-			[]string{"status"},
+			[]string{"provider", "status"},
 		),
 		SearchRequestDuration: promauto.NewHistogramVec(
 			prometheus.HistogramOpts{
@@ -81,7 +113,7 @@ func New() *Metrics {
 				Help:    "Search request duration in seconds",
 				Buckets: []float64{0.1, 0.5, 1, 2, 5, 10},
 			},
-			[]string{},
+			[]string{"provider"},
 		),
 
 		CacheHitsTotal: promauto.NewCounter(
@@ -130,9 +162,27 @@ func (m *Metrics) RecordLLMRequest(provider, status string, duration time.Durati
 	m.LLMRequestDuration.WithLabelValues(provider).Observe(duration.Seconds())
 }
 
-func (m *Metrics) RecordSearchRequest(status string, duration time.Duration) {
-	m.SearchRequestsTotal.WithLabelValues(status).Inc()
-	m.SearchRequestDuration.WithLabelValues().Observe(duration.Seconds())
+func (m *Metrics) SetLLMBreakerState(provider string, state int) {
+	m.LLMBreakerState.WithLabelValues(provider).Set(float64(state))
+}
+
+func (m *Metrics) RecordLLMUsage(provider, model, stage string, promptTokens, completionTokens int, costUSD float64) {
+	m.LLMTokensTotal.WithLabelValues(provider, model, stage, "prompt").Add(float64(promptTokens))
+	m.LLMTokensTotal.WithLabelValues(provider, model, stage, "completion").Add(float64(completionTokens))
+	m.LLMCostUSDTotal.WithLabelValues(provider, model, stage).Add(costUSD)
+}
+
+func (m *Metrics) RecordLLMCacheHit(provider, stage string) {
+	m.LLMCacheTotal.WithLabelValues(provider, stage, "hit").Inc()
+}
+
+func (m *Metrics) RecordLLMCacheMiss(provider, stage string) {
+	m.LLMCacheTotal.WithLabelValues(provider, stage, "miss").Inc()
+}
+
+func (m *Metrics) RecordSearchRequest(provider, status string, duration time.Duration) {
+	m.SearchRequestsTotal.WithLabelValues(provider, status).Inc()
+	m.SearchRequestDuration.WithLabelValues(provider).Observe(duration.Seconds())
 }
 
 func (m *Metrics) RecordCacheHit() {
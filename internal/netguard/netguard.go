@@ -0,0 +1,71 @@
+// Package netguard не пускает запросы по адресам от пользователей во внутреннюю сеть.
+// Источник, лента или ссылка из выдачи могут указывать на 127.0.0.1, метаданные
+// облака 169.254.169.254 или сервисы в docker-сети - такие соединения отклоняются
+package netguard
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+)
+
+var ErrPrivateAddress = errors.New("non-public address")
+
+// непубличные сети, которых нет среди проверок netip.Addr
+var reserved = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"), // CGNAT
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"), // вместе с 255.255.255.255
+	netip.MustParsePrefix("2001:db8::/32"),
+}
+
+// IsPublic - можно ли ходить на адрес из интернета
+func IsPublic(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	if !ip.IsGlobalUnicast() || ip.IsPrivate() {
+		return false
+	}
+	for _, p := range reserved {
+		if p.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// Control - хук net.Dialer.Control. Проверяется уже разрешенный IP, поэтому
+// не проходят ни DNS-имена, указывающие внутрь, ни редиректы на внутренние адреса
+func Control(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrPrivateAddress, address)
+	}
+	if !IsPublic(ip) {
+		return fmt.Errorf("%w: %s", ErrPrivateAddress, ip)
+	}
+	return nil
+}
+
+// Transport - копия http.DefaultTransport, которая соединяется только с публичными адресами.
+// Прокси из окружения не используется: иначе проверялся бы адрес прокси, а не сайта
+func Transport() *http.Transport {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   Control,
+	}
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.Proxy = nil
+	t.DialContext = dialer.DialContext
+	return t
+}
@@ -0,0 +1,28 @@
+package ops
+
+import (
+	"context"
+	"errors"
+
+	"github.com/kitbuilder587/fintech-bot/internal/llm"
+	"github.com/kitbuilder587/fintech-bot/internal/search"
+)
+
+// LLMProbe делает минимальный запрос к LLM, лучше оборачивать в CachedCheck
+func LLMProbe(client llm.Client) Check {
+	return func(ctx context.Context) error {
+		_, err := client.CompleteWithSystem(ctx, "Reply with a single word: OK", "ping")
+		return err
+	}
+}
+
+// SearchProbe проверяет что поисковый API отвечает, пустая выдача - не ошибка
+func SearchProbe(client search.SearchClient) Check {
+	return func(ctx context.Context) error {
+		_, err := client.Search(ctx, search.SearchRequest{Query: "fintech", MaxResults: 1})
+		if errors.Is(err, search.ErrEmptyResults) {
+			return nil
+		}
+		return err
+	}
+}
@@ -0,0 +1,186 @@
+package ops
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/kitbuilder587/fintech-bot/internal/metrics"
+)
+
+// Check - проверка готовности зависимости, nil = все ок
+type Check func(ctx context.Context) error
+
+type Config struct {
+	Addr         string
+	EnablePprof  bool
+	CheckTimeout time.Duration
+}
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// Server - служебный HTTP сервер: /health, /ready, /metrics и опционально pprof
+type Server struct {
+	cfg    Config
+	logger *zap.Logger
+	srv    *http.Server
+
+	mu     sync.RWMutex
+	checks []namedCheck
+}
+
+type readyResponse struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks"`
+}
+
+func New(cfg Config, logger *zap.Logger) *Server {
+	if cfg.Addr == "" {
+		cfg.Addr = ":8080"
+	}
+	if cfg.CheckTimeout == 0 {
+		cfg.CheckTimeout = 3 * time.Second
+	}
+
+	s := &Server{
+		cfg:    cfg,
+		logger: logger,
+	}
+	s.srv = &http.Server{
+		Addr:              cfg.Addr,
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	return s
+}
+
+// AddCheck регистрирует проверку для /ready
+func (s *Server) AddCheck(name string, check Check) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.checks = append(s.checks, namedCheck{name: name, check: check})
+}
+
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", s.handleHealth)
+	mux.HandleFunc("/ready", s.handleReady)
+	mux.Handle("/metrics", metrics.Handler())
+
+	if s.cfg.EnablePprof {
+		mux.HandleFunc("/debug/pprof/", pprof.Index)
+		mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+		mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+		mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+		mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	}
+
+	return mux
+}
+
+// Start слушает адрес и обслуживает запросы в фоне
+func (s *Server) Start() error {
+	ln, err := net.Listen("tcp", s.cfg.Addr)
+	if err != nil {
+		return err
+	}
+
+	s.logger.Info("ops server started",
+		zap.String("addr", ln.Addr().String()),
+		zap.Bool("pprof", s.cfg.EnablePprof),
+	)
+
+	go func() {
+		if err := s.srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.logger.Error("ops server failed", zap.Error(err))
+		}
+	}()
+	return nil
+}
+
+func (s *Server) Shutdown(ctx context.Context) error {
+	return s.srv.Shutdown(ctx)
+}
+
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("ok"))
+}
+
+func (s *Server) handleReady(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), s.cfg.CheckTimeout)
+	defer cancel()
+
+	s.mu.RLock()
+	checks := make([]namedCheck, len(s.checks))
+	copy(checks, s.checks)
+	s.mu.RUnlock()
+
+	resp := readyResponse{
+		Status: "ok",
+		Checks: make(map[string]string, len(checks)),
+	}
+
+	// проверки гоняем параллельно, чтобы одна медленная не съела весь таймаут
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, c := range checks {
+		wg.Add(1)
+		go func(c namedCheck) {
+			defer wg.Done()
+			result := "ok"
+			if err := c.check(ctx); err != nil {
+				result = err.Error()
+			}
+
+			mu.Lock()
+			resp.Checks[c.name] = result
+			if result != "ok" {
+				resp.Status = "unavailable"
+			}
+			mu.Unlock()
+		}(c)
+	}
+	wg.Wait()
+
+	status := http.StatusOK
+	if resp.Status != "ok" {
+		status = http.StatusServiceUnavailable
+		s.logger.Warn("readiness check failed", zap.Any("checks", resp.Checks))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// CachedCheck запоминает результат проверки на ttl - для платных проб (LLM, поиск)
+func CachedCheck(check Check, ttl time.Duration) Check {
+	var mu sync.Mutex
+	var lastErr error
+	var checkedAt time.Time
+
+	return func(ctx context.Context) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if !checkedAt.IsZero() && time.Since(checkedAt) < ttl {
+			return lastErr
+		}
+
+		lastErr = check(ctx)
+		checkedAt = time.Now()
+		return lastErr
+	}
+}
@@ -0,0 +1,176 @@
+package ops
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	llmMock "github.com/kitbuilder587/fintech-bot/internal/llm/mock"
+	"github.com/kitbuilder587/fintech-bot/internal/search"
+	searchMock "github.com/kitbuilder587/fintech-bot/internal/search/mock"
+)
+
+func TestServer_Health(t *testing.T) {
+	srv := New(Config{}, zap.NewNop())
+	srv.AddCheck("db", func(ctx context.Context) error { return errors.New("down") })
+
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+
+	// liveness не зависит от readiness проверок
+	if rec.Code != http.StatusOK {
+		t.Errorf("/health status = %d, want 200", rec.Code)
+	}
+}
+
+func TestServer_Ready(t *testing.T) {
+	tests := []struct {
+		name       string
+		checks     map[string]Check
+		wantStatus int
+		wantChecks map[string]string
+	}{
+		{
+			name:       "no checks",
+			checks:     nil,
+			wantStatus: http.StatusOK,
+			wantChecks: map[string]string{},
+		},
+		{
+			name: "all ok",
+			checks: map[string]Check{
+				"db":       func(ctx context.Context) error { return nil },
+				"telegram": func(ctx context.Context) error { return nil },
+			},
+			wantStatus: http.StatusOK,
+			wantChecks: map[string]string{"db": "ok", "telegram": "ok"},
+		},
+		{
+			name: "one failing",
+			checks: map[string]Check{
+				"db":       func(ctx context.Context) error { return errors.New("connection refused") },
+				"telegram": func(ctx context.Context) error { return nil },
+			},
+			wantStatus: http.StatusServiceUnavailable,
+			wantChecks: map[string]string{"db": "connection refused", "telegram": "ok"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := New(Config{}, zap.NewNop())
+			for name, check := range tt.checks {
+				srv.AddCheck(name, check)
+			}
+
+			rec := httptest.NewRecorder()
+			srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ready", nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("/ready status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+
+			var resp readyResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			for name, want := range tt.wantChecks {
+				if resp.Checks[name] != want {
+					t.Errorf("check %q = %q, want %q", name, resp.Checks[name], want)
+				}
+			}
+		})
+	}
+}
+
+func TestServer_ReadyTimeout(t *testing.T) {
+	srv := New(Config{CheckTimeout: 50 * time.Millisecond}, zap.NewNop())
+	srv.AddCheck("slow", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ready", nil))
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("/ready status = %d, want 503 for hanging check", rec.Code)
+	}
+}
+
+func TestServer_Metrics(t *testing.T) {
+	srv := New(Config{}, zap.NewNop())
+
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("/metrics status = %d, want 200", rec.Code)
+	}
+}
+
+func TestServer_Pprof(t *testing.T) {
+	tests := []struct {
+		name       string
+		enabled    bool
+		wantStatus int
+	}{
+		{"disabled", false, http.StatusNotFound},
+		{"enabled", true, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := New(Config{EnablePprof: tt.enabled}, zap.NewNop())
+
+			rec := httptest.NewRecorder()
+			srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/pprof/", nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("/debug/pprof/ status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestCachedCheck(t *testing.T) {
+	calls := 0
+	check := CachedCheck(func(ctx context.Context) error {
+		calls++
+		return nil
+	}, time.Hour)
+
+	for i := 0; i < 3; i++ {
+		if err := check(context.Background()); err != nil {
+			t.Fatalf("check() error = %v", err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("underlying check called %d times, want 1", calls)
+	}
+}
+
+func TestProbes(t *testing.T) {
+	ctx := context.Background()
+
+	if err := LLMProbe(llmMock.New())(ctx); err != nil {
+		t.Errorf("LLMProbe() error = %v", err)
+	}
+	if err := LLMProbe(llmMock.New().WithError(errors.New("boom")))(ctx); err == nil {
+		t.Error("LLMProbe() expected error")
+	}
+
+	// пустая выдача не делает поиск неготовым
+	if err := SearchProbe(searchMock.New())(ctx); err != nil {
+		t.Errorf("SearchProbe() with empty results error = %v", err)
+	}
+	if err := SearchProbe(searchMock.New().WithError(search.ErrUnauthorized))(ctx); err == nil {
+		t.Error("SearchProbe() expected error")
+	}
+}
@@ -0,0 +1,169 @@
+// Package prompt рендерит системные промпты из text/template шаблонов.
+// Шаблоны лежат по версиям: <каталог>/<версия>/<имя>.tmpl
+package prompt
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/kitbuilder587/fintech-bot/configs/prompts"
+)
+
+// имена шаблонов, которые должны быть в каждой версии
+const (
+	Expand     = "expand"
+	Analyze    = "analyze"
+	Improve    = "improve"
+	Synthesize = "synthesize"
+	Critic     = "critic"
+	Extraction = "extraction"
+)
+
+const (
+	DefaultVersion  = "v2"
+	DefaultLanguage = "Russian"
+)
+
+var ErrMissingTemplate = errors.New("prompt template not found")
+
+// Agent - имя шаблона специализированного агента, например agent_market
+func Agent(name string) string {
+	return "agent_" + name
+}
+
+// Required - шаблоны, без которых версия не загрузится
+var Required = []string{
+	Expand, Analyze, Improve, Synthesize, Critic, Extraction,
+	Agent("market"), Agent("regulatory"), Agent("tech"), Agent("trends"),
+}
+
+// Vars - переменные, доступные в шаблонах
+type Vars struct {
+	Year       int    // текущий год, если не задан
+	Language   string // язык ответа, по умолчанию из настроек реестра
+	MaxQueries int
+	TimeWindow string // окно свежести стратегии: day, week, month, year; пусто - без окна
+	Experts    string // ответы экспертов для синтеза
+}
+
+// Set - загруженная версия шаблонов, после загрузки не меняется
+type Set struct {
+	version  string
+	checksum string
+	tmpl     *template.Template
+	language string
+}
+
+// Load читает шаблоны версии из fsys и проверяет, что все обязательные
+// есть и рендерятся на тестовых переменных
+func Load(fsys fs.FS, version, language string) (*Set, error) {
+	files, err := fs.Glob(fsys, path.Join(version, "*.tmpl"))
+	if err != nil {
+		return nil, fmt.Errorf("list prompts %s: %w", version, err)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no prompt templates in version %q", version)
+	}
+	sort.Strings(files)
+
+	h := sha256.New()
+	root := template.New(version).Option("missingkey=error")
+	for _, file := range files {
+		content, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, fmt.Errorf("read prompt %s: %w", file, err)
+		}
+		fmt.Fprintf(h, "%d:%s%d:%s", len(file), file, len(content), content)
+
+		name := strings.TrimSuffix(path.Base(file), ".tmpl")
+		if _, err := root.New(name).Parse(string(content)); err != nil {
+			return nil, fmt.Errorf("parse prompt %s: %w", file, err)
+		}
+	}
+
+	set := &Set{
+		version:  version,
+		checksum: fmt.Sprintf("%x", h.Sum(nil)[:4]),
+		tmpl:     root,
+		language: language,
+	}
+	if err := set.validate(); err != nil {
+		return nil, err
+	}
+	return set, nil
+}
+
+func (s *Set) validate() error {
+	sample := Vars{MaxQueries: 3, TimeWindow: "month", Experts: "[Expert 1: sample]\nsample answer"}
+	for _, name := range Required {
+		out, err := s.Render(name, sample)
+		if err != nil {
+			return err
+		}
+		if out == "" {
+			return fmt.Errorf("prompt %s/%s renders empty", s.version, name)
+		}
+	}
+	return nil
+}
+
+// Version - имя версии и хеш содержимого, например v1@3f2a9c1d.
+// Хеш меняется при правке шаблонов без смены имени версии
+func (s *Set) Version() string {
+	return s.version + "@" + s.checksum
+}
+
+// Render подставляет vars в шаблон name
+func (s *Set) Render(name string, vars Vars) (string, error) {
+	t := s.tmpl.Lookup(name)
+	if t == nil {
+		return "", fmt.Errorf("%w: %s/%s", ErrMissingTemplate, s.version, name)
+	}
+	if vars.Year == 0 {
+		vars.Year = time.Now().Year()
+	}
+	if vars.Language == "" {
+		vars.Language = s.language
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, vars); err != nil {
+		return "", fmt.Errorf("render prompt %s/%s: %w", s.version, name, err)
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
+
+// Default - встроенная в бинарник версия по умолчанию
+var Default = sync.OnceValue(func() *Set {
+	set, err := Load(prompts.FS, DefaultVersion, DefaultLanguage)
+	if err != nil {
+		panic(fmt.Sprintf("embedded prompts: %v", err))
+	}
+	return set
+})
+
+type setKey struct{}
+
+// WithSet фиксирует версию промптов на время запроса, чтобы перезагрузка
+// посреди обработки не смешала версии
+func WithSet(ctx context.Context, s *Set) context.Context {
+	return context.WithValue(ctx, setKey{}, s)
+}
+
+// FromContext - версия из ctx или встроенная по умолчанию
+func FromContext(ctx context.Context) *Set {
+	if s, ok := ctx.Value(setKey{}).(*Set); ok && s != nil {
+		return s
+	}
+	return Default()
+}
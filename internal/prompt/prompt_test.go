@@ -0,0 +1,231 @@
+package prompt_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/kitbuilder587/fintech-bot/configs/prompts"
+	"github.com/kitbuilder587/fintech-bot/internal/prompt"
+)
+
+// validFS - минимальная версия со всеми обязательными шаблонами
+func validFS(version string) fstest.MapFS {
+	fsys := fstest.MapFS{}
+	for _, name := range prompt.Required {
+		fsys[version+"/"+name+".tmpl"] = &fstest.MapFile{Data: []byte(name + " in {{.Language}}")}
+	}
+	return fsys
+}
+
+func TestDefault_LoadsEmbeddedTemplates(t *testing.T) {
+	set := prompt.Default()
+
+	if !strings.HasPrefix(set.Version(), prompt.DefaultVersion+"@") {
+		t.Errorf("Version() = %q, want %s@<hash>", set.Version(), prompt.DefaultVersion)
+	}
+	for _, name := range prompt.Required {
+		if _, err := set.Render(name, prompt.Vars{MaxQueries: 3}); err != nil {
+			t.Errorf("Render(%s): %v", name, err)
+		}
+	}
+}
+
+// TestEmbeddedVersions - старая версия остается для отката: правки шаблонов идут
+// в новую версию, а не поверх старой
+func TestEmbeddedVersions(t *testing.T) {
+	v1, err := prompt.Load(prompts.FS, "v1", "Russian")
+	if err != nil {
+		t.Fatalf("Load(v1) error = %v", err)
+	}
+	v2, err := prompt.Load(prompts.FS, "v2", "Russian")
+	if err != nil {
+		t.Fatalf("Load(v2) error = %v", err)
+	}
+
+	old, _ := v1.Render(prompt.Analyze, prompt.Vars{})
+	cur, _ := v2.Render(prompt.Analyze, prompt.Vars{})
+	if strings.Contains(old, "[p.N]") || strings.Contains(old, "trust level") {
+		t.Errorf("v1 analyze prompt has page or trust markup: %q", old)
+	}
+	if !strings.Contains(cur, "[p.N]") || !strings.Contains(cur, "trust level") {
+		t.Errorf("v2 analyze prompt lacks page or trust markup: %q", cur)
+	}
+}
+
+func TestRender_Vars(t *testing.T) {
+	set := prompt.Default()
+
+	out, err := set.Render(prompt.Expand, prompt.Vars{MaxQueries: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "1-4 optimal") {
+		t.Errorf("expand prompt does not contain max queries: %q", out)
+	}
+	if !strings.Contains(out, strconv.Itoa(time.Now().Year())) {
+		t.Errorf("expand prompt does not contain current year: %q", out)
+	}
+	if strings.Contains(out, "past") {
+		t.Errorf("expand prompt without time window mentions it: %q", out)
+	}
+
+	out, err = set.Render(prompt.Expand, prompt.Vars{MaxQueries: 4, TimeWindow: "week"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "past week") {
+		t.Errorf("expand prompt does not contain time window: %q", out)
+	}
+
+	out, err = set.Render(prompt.Analyze, prompt.Vars{Language: "English"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "Answer in English") {
+		t.Errorf("analyze prompt ignores language: %q", out)
+	}
+
+	if _, err := set.Render("nope", prompt.Vars{}); !errors.Is(err, prompt.ErrMissingTemplate) {
+		t.Errorf("Render(nope) error = %v, want ErrMissingTemplate", err)
+	}
+}
+
+func TestLoad_Validation(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(fstest.MapFS)
+	}{
+		{"missing required", func(fsys fstest.MapFS) { delete(fsys, "v1/critic.tmpl") }},
+		{"parse error", func(fsys fstest.MapFS) { fsys["v1/critic.tmpl"].Data = []byte("{{.Language") }},
+		{"unknown variable", func(fsys fstest.MapFS) { fsys["v1/critic.tmpl"].Data = []byte("{{.Country}}") }},
+		{"empty", func(fsys fstest.MapFS) { fsys["v1/critic.tmpl"].Data = []byte("  \n") }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := validFS("v1")
+			tt.modify(fsys)
+			if _, err := prompt.Load(fsys, "v1", "Russian"); err == nil {
+				t.Error("Load() succeeded, want error")
+			}
+		})
+	}
+
+	if _, err := prompt.Load(validFS("v1"), "v2", "Russian"); err == nil {
+		t.Error("Load() of unknown version succeeded, want error")
+	}
+}
+
+func TestLoad_VersionChangesWithContent(t *testing.T) {
+	fsys := validFS("v1")
+	first, err := prompt.Load(fsys, "v1", "Russian")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fsys["v1/critic.tmpl"].Data = []byte("stricter critic")
+	second, err := prompt.Load(fsys, "v1", "Russian")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if first.Version() == second.Version() {
+		t.Errorf("Version() = %q for both, want different hashes", first.Version())
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	if prompt.FromContext(context.Background()) != prompt.Default() {
+		t.Error("FromContext without set should return Default()")
+	}
+
+	set, err := prompt.Load(validFS("v1"), "v1", "Russian")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prompt.FromContext(prompt.WithSet(context.Background(), set)) != set {
+		t.Error("FromContext should return the set from ctx")
+	}
+}
+
+func writeTemplates(t *testing.T, dir string, fsys fstest.MapFS) {
+	t.Helper()
+	for name, f := range fsys {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, f.Data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestRegistry_ReloadKeepsCurrentOnError(t *testing.T) {
+	dir := t.TempDir()
+	writeTemplates(t, dir, validFS("v1"))
+
+	reg, err := prompt.New(prompt.Config{Dir: dir, Version: "v1"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := reg.Current()
+
+	writeTemplates(t, dir, fstest.MapFS{"v1/critic.tmpl": {Data: []byte("{{.Broken")}})
+	if err := reg.Reload(); err == nil {
+		t.Fatal("Reload() succeeded with broken template")
+	}
+	if reg.Current() != before {
+		t.Error("broken reload replaced the current set")
+	}
+
+	writeTemplates(t, dir, fstest.MapFS{"v1/critic.tmpl": {Data: []byte("fixed critic")}})
+	if err := reg.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	out, err := reg.Current().Render(prompt.Critic, prompt.Vars{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "fixed critic" {
+		t.Errorf("critic = %q, want fixed critic", out)
+	}
+}
+
+func TestRegistry_WatchReloadsChangedFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeTemplates(t, dir, validFS("v1"))
+
+	reg, err := prompt.New(prompt.Config{Dir: dir, Version: "v1"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := reg.Current().Version()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go reg.Watch(ctx, 10*time.Millisecond)
+
+	writeTemplates(t, dir, fstest.MapFS{"v1/analyze.tmpl": {Data: []byte("edited analyze prompt")}})
+
+	deadline := time.Now().Add(2 * time.Second)
+	for reg.Current().Version() == before {
+		if time.Now().After(deadline) {
+			t.Fatal("Watch did not pick up the edited template")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestNew_MissingDir(t *testing.T) {
+	if _, err := prompt.New(prompt.Config{Dir: filepath.Join(t.TempDir(), "nope")}, nil); err == nil {
+		t.Error("New() with missing dir succeeded, want error")
+	}
+}
@@ -0,0 +1,133 @@
+package prompt
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/kitbuilder587/fintech-bot/configs/prompts"
+)
+
+type Config struct {
+	Dir      string // пусто - встроенные шаблоны, без перезагрузки
+	Version  string
+	Language string
+}
+
+// Registry держит текущую версию шаблонов и подменяет ее при перезагрузке.
+// Если новые шаблоны не проходят проверку, остается старая версия
+type Registry struct {
+	fsys     fs.FS
+	cfg      Config
+	logger   *zap.Logger
+	current  atomic.Pointer[Set]
+	mu       sync.Mutex // одна перезагрузка за раз
+	snapshot string     // имена, размеры и время изменения файлов на момент загрузки
+}
+
+func New(cfg Config, logger *zap.Logger) (*Registry, error) {
+	if cfg.Version == "" {
+		cfg.Version = DefaultVersion
+	}
+	if cfg.Language == "" {
+		cfg.Language = DefaultLanguage
+	}
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	var fsys fs.FS = prompts.FS
+	if cfg.Dir != "" {
+		if _, err := os.Stat(cfg.Dir); err != nil {
+			return nil, fmt.Errorf("prompts dir: %w", err)
+		}
+		fsys = os.DirFS(cfg.Dir)
+	}
+
+	r := &Registry{fsys: fsys, cfg: cfg, logger: logger}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *Registry) Current() *Set {
+	return r.current.Load()
+}
+
+// Reload перечитывает шаблоны активной версии
+func (r *Registry) Reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	snapshot := r.stat()
+	set, err := Load(r.fsys, r.cfg.Version, r.cfg.Language)
+	if err != nil {
+		return err
+	}
+	// неудачная загрузка не запоминает снимок, чтобы Watch попробовал еще раз после правки
+	r.snapshot = snapshot
+
+	prev := r.current.Swap(set)
+	if prev == nil || prev.Version() != set.Version() {
+		r.logger.Info("prompts loaded", zap.String("version", set.Version()))
+	}
+	return nil
+}
+
+// Watch раз в interval проверяет файлы шаблонов и перезагружает их при изменении.
+// Для встроенных шаблонов ничего не делает
+func (r *Registry) Watch(ctx context.Context, interval time.Duration) {
+	if r.cfg.Dir == "" || interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var failed string
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		r.mu.Lock()
+		snapshot := r.stat()
+		changed := snapshot != r.snapshot && snapshot != failed
+		r.mu.Unlock()
+		if !changed {
+			continue
+		}
+
+		if err := r.Reload(); err != nil {
+			// одну и ту же ошибку логируем один раз, а не каждый тик
+			failed = snapshot
+			r.logger.Error("prompts reload failed, keeping current version",
+				zap.String("version", r.Current().Version()),
+				zap.Error(err),
+			)
+		}
+	}
+}
+
+func (r *Registry) stat() string {
+	files, _ := fs.Glob(r.fsys, path.Join(r.cfg.Version, "*.tmpl"))
+	var snapshot string
+	for _, file := range files {
+		info, err := fs.Stat(r.fsys, file)
+		if err != nil {
+			continue
+		}
+		snapshot += fmt.Sprintf("%s:%d:%d;", file, info.Size(), info.ModTime().UnixNano())
+	}
+	return snapshot
+}
@@ -11,6 +11,9 @@ type Limiter struct {
 	requests map[int64][]time.Time
 	limit    int
 	window   time.Duration
+
+	stopChan chan struct{}
+	stopOnce sync.Once
 }
 
 type Config struct {
@@ -27,6 +30,7 @@ func New(cfg Config) *Limiter {
 		requests: make(map[int64][]time.Time),
 		limit:    limit,
 		window:   time.Minute,
+		stopChan: make(chan struct{}),
 	}
 	go l.cleanup()
 	return l
@@ -95,28 +99,44 @@ func (l *Limiter) ResetTime(userID int64) time.Time {
 	return oldest.Add(l.window)
 }
 
+// Stop останавливает фоновую очистку, повторный вызов безопасен
+func (l *Limiter) Stop() {
+	l.stopOnce.Do(func() {
+		close(l.stopChan)
+	})
+}
+
 // cleanup - фоновая очистка старых записей
-// TODO: добавить graceful shutdown
 func (l *Limiter) cleanup() {
 	tick := time.NewTicker(5 * time.Minute)
-	for range tick.C {
-		l.mu.Lock()
-		now := time.Now()
-		cutoff := now.Add(-l.window)
-
-		for uid, ts := range l.requests {
-			var fresh []time.Time
-			for _, t := range ts {
-				if t.After(cutoff) {
-					fresh = append(fresh, t)
-				}
-			}
-			if len(fresh) == 0 {
-				delete(l.requests, uid)
-			} else {
-				l.requests[uid] = fresh
+	defer tick.Stop()
+
+	for {
+		select {
+		case <-l.stopChan:
+			return
+		case <-tick.C:
+			l.removeStale()
+		}
+	}
+}
+
+func (l *Limiter) removeStale() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	cutoff := time.Now().Add(-l.window)
+	for uid, ts := range l.requests {
+		var fresh []time.Time
+		for _, t := range ts {
+			if t.After(cutoff) {
+				fresh = append(fresh, t)
 			}
 		}
-		l.mu.Unlock()
+		if len(fresh) == 0 {
+			delete(l.requests, uid)
+		} else {
+			l.requests[uid] = fresh
+		}
 	}
 }
@@ -140,3 +140,16 @@ func TestLimiter_Concurrent(t *testing.T) {
 		t.Errorf("RemainingRequests() = %d, want 0 after concurrent access", remaining)
 	}
 }
+
+func TestLimiter_Stop(t *testing.T) {
+	limiter := New(Config{
+		RequestsPerMinute: 1,
+	})
+
+	limiter.Stop()
+	limiter.Stop() // повторный вызов не должен паниковать
+
+	if !limiter.Allow(1) {
+		t.Error("Allow() should still work after Stop()")
+	}
+}
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/kitbuilder587/fintech-bot/internal/domain"
 )
@@ -23,6 +24,13 @@ type SourceRepository interface {
 	CountByUser(ctx context.Context, userID int64) (int, error)
 	GetDomainsByUserID(ctx context.Context, userID int64) ([]string, error)
 	UpdateTrustLevel(ctx context.Context, userID, sourceID int64, level domain.TrustLevel) error
+	// ListURLs - URL источников всех пользователей без повторов, для сбора лент
+	ListURLs(ctx context.Context) ([]string, error)
+
+	// блок-лист доменов пользователя
+	BlockDomain(ctx context.Context, userID int64, domain string) error
+	UnblockDomain(ctx context.Context, userID int64, domain string) error
+	ListBlockedDomains(ctx context.Context, userID int64) ([]string, error)
 }
 
 // WorldModelRepository - хранилище для модели мира (факты, сущности, сессии).
@@ -47,3 +55,33 @@ type WorldModelRepository interface {
 	AddFactToSession(ctx context.Context, sessionID, factID string) error
 	AddEntityToSession(ctx context.Context, sessionID, entityID string) error
 }
+
+// UsageRepository - расход токенов LLM по пользователям
+type UsageRepository interface {
+	SaveUsage(ctx context.Context, records []domain.UsageRecord) error
+	// GetUsageByStage суммирует расход пользователя с момента since по стадиям
+	GetUsageByStage(ctx context.Context, userID int64, since time.Time) ([]domain.UsageTotals, error)
+}
+
+// ConversationRepository - ответы бота для продолжения диалога реплаем
+type ConversationRepository interface {
+	// SaveTurn сохраняет ход и удаляет самые старые ходы чата сверх лимита
+	SaveTurn(ctx context.Context, turn *domain.ConversationTurn) error
+	GetTurn(ctx context.Context, id int64) (*domain.ConversationTurn, error)
+	// GetTurnByMessage ищет ход по id любого из сообщений ответа
+	GetTurnByMessage(ctx context.Context, chatID int64, messageID int) (*domain.ConversationTurn, error)
+}
+
+// ArticleRepository - RSS/Atom ленты источников и статьи из них
+type ArticleRepository interface {
+	// UpsertFeed добавляет ленту; если лента с таким URL уже есть, заполняет feed из базы
+	UpsertFeed(ctx context.Context, feed *domain.Feed) error
+	ListFeeds(ctx context.Context) ([]domain.Feed, error)
+	// UpdateFeedState сохраняет ETag, Last-Modified и время опроса
+	UpdateFeedState(ctx context.Context, feed *domain.Feed) error
+	ArticleExists(ctx context.Context, url string) (bool, error)
+	// SaveArticles пропускает уже известные URL и возвращает число новых статей
+	SaveArticles(ctx context.Context, articles []domain.Article) (int, error)
+	// SearchArticles - полнотекстовый поиск, лучшие совпадения первыми
+	SearchArticles(ctx context.Context, q domain.ArticleQuery) ([]domain.ArticleHit, error)
+}
@@ -2,6 +2,8 @@ package repository
 
 import (
 	"context"
+	"slices"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -95,12 +97,14 @@ func (m *MockUserRepository) Create(ctx context.Context, user *domain.User) erro
 type MockSourceRepository struct {
 	mu      sync.RWMutex
 	sources map[int64]*domain.Source // key: Source ID
+	blocked map[int64][]string       // user_id -> домены
 	nextID  int64
 }
 
 func NewMockSourceRepository() *MockSourceRepository {
 	return &MockSourceRepository{
 		sources: make(map[int64]*domain.Source),
+		blocked: make(map[int64][]string),
 		nextID:  1,
 	}
 }
@@ -217,6 +221,54 @@ func (m *MockSourceRepository) UpdateTrustLevel(ctx context.Context, userID, sou
 	return nil
 }
 
+func (m *MockSourceRepository) ListURLs(ctx context.Context) ([]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	seen := make(map[string]bool)
+	var urls []string
+	for _, s := range m.sources {
+		if !seen[s.URL] {
+			seen[s.URL] = true
+			urls = append(urls, s.URL)
+		}
+	}
+	sort.Strings(urls)
+	return urls, nil
+}
+
+func (m *MockSourceRepository) BlockDomain(ctx context.Context, userID int64, d string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if slices.Contains(m.blocked[userID], d) {
+		return domain.ErrDomainAlreadyBlocked
+	}
+	m.blocked[userID] = append(m.blocked[userID], d)
+	return nil
+}
+
+func (m *MockSourceRepository) UnblockDomain(ctx context.Context, userID int64, d string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	i := slices.Index(m.blocked[userID], d)
+	if i < 0 {
+		return domain.ErrDomainNotBlocked
+	}
+	m.blocked[userID] = slices.Delete(m.blocked[userID], i, i+1)
+	return nil
+}
+
+func (m *MockSourceRepository) ListBlockedDomains(ctx context.Context, userID int64) ([]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	result := slices.Clone(m.blocked[userID])
+	sort.Strings(result)
+	return result, nil
+}
+
 type MockWorldModelRepository struct {
 	mu              sync.RWMutex
 	facts           map[string]*domain.Fact            // key: Fact ID
@@ -449,3 +501,239 @@ func (m *MockWorldModelRepository) AddEntityToSession(ctx context.Context, sessi
 	m.sessionEntities[sessionID] = append(m.sessionEntities[sessionID], entityID)
 	return nil
 }
+
+type MockUsageRepository struct {
+	mu      sync.RWMutex
+	records []domain.UsageRecord
+}
+
+func NewMockUsageRepository() *MockUsageRepository {
+	return &MockUsageRepository{}
+}
+
+func (m *MockUsageRepository) SaveUsage(ctx context.Context, records []domain.UsageRecord) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.records = append(m.records, records...)
+	return nil
+}
+
+func (m *MockUsageRepository) GetUsageByStage(ctx context.Context, userID int64, since time.Time) ([]domain.UsageTotals, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	byStage := make(map[string]*domain.UsageTotals)
+	var order []string
+	for _, rec := range m.records {
+		if rec.UserID != userID || rec.CreatedAt.Before(since) {
+			continue
+		}
+		t, ok := byStage[rec.Stage]
+		if !ok {
+			t = &domain.UsageTotals{Stage: rec.Stage}
+			byStage[rec.Stage] = t
+			order = append(order, rec.Stage)
+		}
+		t.Calls++
+		t.PromptTokens += int64(rec.PromptTokens)
+		t.CompletionTokens += int64(rec.CompletionTokens)
+		t.CostUSD += rec.CostUSD
+	}
+
+	totals := make([]domain.UsageTotals, 0, len(order))
+	for _, stage := range order {
+		totals = append(totals, *byStage[stage])
+	}
+	return totals, nil
+}
+
+// Records - все сохраненные записи (для проверок в тестах)
+func (m *MockUsageRepository) Records() []domain.UsageRecord {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	out := make([]domain.UsageRecord, len(m.records))
+	copy(out, m.records)
+	return out
+}
+
+type MockConversationRepository struct {
+	mu     sync.RWMutex
+	turns  []domain.ConversationTurn
+	nextID int64
+}
+
+func NewMockConversationRepository() *MockConversationRepository {
+	return &MockConversationRepository{nextID: 1}
+}
+
+func (m *MockConversationRepository) SaveTurn(ctx context.Context, turn *domain.ConversationTurn) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	turn.ID = m.nextID
+	m.nextID++
+	if turn.CreatedAt.IsZero() {
+		turn.CreatedAt = time.Now()
+	}
+	m.turns = append(m.turns, *turn)
+	return nil
+}
+
+func (m *MockConversationRepository) GetTurn(ctx context.Context, id int64) (*domain.ConversationTurn, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for i := range m.turns {
+		if m.turns[i].ID == id {
+			turn := m.turns[i]
+			return &turn, nil
+		}
+	}
+	return nil, domain.ErrNotFound
+}
+
+func (m *MockConversationRepository) GetTurnByMessage(ctx context.Context, chatID int64, messageID int) (*domain.ConversationTurn, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for i := len(m.turns) - 1; i >= 0; i-- {
+		if m.turns[i].ChatID != chatID {
+			continue
+		}
+		for _, id := range m.turns[i].MessageIDs {
+			if id == messageID {
+				turn := m.turns[i]
+				return &turn, nil
+			}
+		}
+	}
+	return nil, domain.ErrNotFound
+}
+
+// MockArticleRepository ищет по вхождению слов запроса вместо полнотекстового индекса
+type MockArticleRepository struct {
+	mu       sync.RWMutex
+	feeds    []domain.Feed
+	articles []domain.Article
+	nextID   int64
+}
+
+func NewMockArticleRepository() *MockArticleRepository {
+	return &MockArticleRepository{nextID: 1}
+}
+
+func (m *MockArticleRepository) UpsertFeed(ctx context.Context, feed *domain.Feed) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, f := range m.feeds {
+		if f.URL == feed.URL {
+			*feed = f
+			return nil
+		}
+	}
+	feed.ID = m.nextID
+	m.nextID++
+	feed.CreatedAt = time.Now()
+	m.feeds = append(m.feeds, *feed)
+	return nil
+}
+
+func (m *MockArticleRepository) ListFeeds(ctx context.Context) ([]domain.Feed, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return append([]domain.Feed(nil), m.feeds...), nil
+}
+
+func (m *MockArticleRepository) UpdateFeedState(ctx context.Context, feed *domain.Feed) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.feeds {
+		if m.feeds[i].ID == feed.ID {
+			m.feeds[i].ETag = feed.ETag
+			m.feeds[i].LastModified = feed.LastModified
+			m.feeds[i].PolledAt = feed.PolledAt
+			return nil
+		}
+	}
+	return domain.ErrNotFound
+}
+
+func (m *MockArticleRepository) ArticleExists(ctx context.Context, url string) (bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, a := range m.articles {
+		if a.URL == url {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *MockArticleRepository) SaveArticles(ctx context.Context, articles []domain.Article) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	saved := 0
+	for _, a := range articles {
+		exists := false
+		for _, known := range m.articles {
+			if known.URL == a.URL {
+				exists = true
+				break
+			}
+		}
+		if exists {
+			continue
+		}
+		a.ID = m.nextID
+		m.nextID++
+		if a.FetchedAt.IsZero() {
+			a.FetchedAt = time.Now()
+		}
+		m.articles = append(m.articles, a)
+		saved++
+	}
+	return saved, nil
+}
+
+func (m *MockArticleRepository) SearchArticles(ctx context.Context, q domain.ArticleQuery) ([]domain.ArticleHit, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	words := strings.Fields(strings.ToLower(q.Text))
+	var hits []domain.ArticleHit
+	for _, a := range m.articles {
+		if len(q.Domains) > 0 && !slices.Contains(q.Domains, a.Domain) {
+			continue
+		}
+		if !q.Since.IsZero() && a.PublishedAt.Before(q.Since) {
+			continue
+		}
+		text := strings.ToLower(a.Title + " " + a.Content)
+		rank := 0.0
+		for _, w := range words {
+			if strings.Contains(text, w) {
+				rank++
+			}
+		}
+		if rank > 0 {
+			hits = append(hits, domain.ArticleHit{Article: a, Rank: rank})
+		}
+	}
+
+	sort.SliceStable(hits, func(i, j int) bool { return hits[i].Rank > hits[j].Rank })
+	if q.Limit > 0 && len(hits) > q.Limit {
+		hits = hits[:q.Limit]
+	}
+	return hits, nil
+}
+
+// Articles - все сохраненные статьи, для проверок в тестах
+func (m *MockArticleRepository) Articles() []domain.Article {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return append([]domain.Article(nil), m.articles...)
+}
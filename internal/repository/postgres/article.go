@@ -0,0 +1,201 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/kitbuilder587/fintech-bot/internal/domain"
+)
+
+type ArticleRepo struct {
+	db *DB
+}
+
+func NewArticleRepo(db *DB) *ArticleRepo {
+	return &ArticleRepo{db: db}
+}
+
+func (r *ArticleRepo) UpsertFeed(ctx context.Context, feed *domain.Feed) error {
+	// DO UPDATE без изменений, чтобы RETURNING вернул и уже существующую строку
+	query := `
+		INSERT INTO feeds (url, domain)
+		VALUES ($1, $2)
+		ON CONFLICT (url) DO UPDATE SET url = EXCLUDED.url
+		RETURNING id, domain, etag, last_modified, polled_at, created_at
+	`
+
+	var polledAt *time.Time
+	err := r.db.Pool.QueryRow(ctx, query, feed.URL, feed.Domain).Scan(
+		&feed.ID,
+		&feed.Domain,
+		&feed.ETag,
+		&feed.LastModified,
+		&polledAt,
+		&feed.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("upsert feed: %w", err)
+	}
+	if polledAt != nil {
+		feed.PolledAt = *polledAt
+	}
+
+	return nil
+}
+
+func (r *ArticleRepo) ListFeeds(ctx context.Context) ([]domain.Feed, error) {
+	query := `
+		SELECT id, url, domain, etag, last_modified, polled_at, created_at
+		FROM feeds
+		ORDER BY id
+	`
+
+	rows, err := r.db.Pool.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("list feeds: %w", err)
+	}
+	defer rows.Close()
+
+	var feeds []domain.Feed
+	for rows.Next() {
+		var f domain.Feed
+		var polledAt *time.Time
+		if err := rows.Scan(&f.ID, &f.URL, &f.Domain, &f.ETag, &f.LastModified, &polledAt, &f.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan feed: %w", err)
+		}
+		if polledAt != nil {
+			f.PolledAt = *polledAt
+		}
+		feeds = append(feeds, f)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return feeds, nil
+}
+
+func (r *ArticleRepo) UpdateFeedState(ctx context.Context, feed *domain.Feed) error {
+	query := `UPDATE feeds SET etag = $2, last_modified = $3, polled_at = $4 WHERE id = $1`
+
+	result, err := r.db.Pool.Exec(ctx, query, feed.ID, feed.ETag, feed.LastModified, feed.PolledAt)
+	if err != nil {
+		return fmt.Errorf("update feed: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return domain.ErrNotFound
+	}
+
+	return nil
+}
+
+func (r *ArticleRepo) ArticleExists(ctx context.Context, url string) (bool, error) {
+	query := `SELECT EXISTS(SELECT 1 FROM articles WHERE url = $1)`
+
+	var exists bool
+	if err := r.db.Pool.QueryRow(ctx, query, url).Scan(&exists); err != nil {
+		return false, fmt.Errorf("check article exists: %w", err)
+	}
+	return exists, nil
+}
+
+func (r *ArticleRepo) SaveArticles(ctx context.Context, articles []domain.Article) (int, error) {
+	if len(articles) == 0 {
+		return 0, nil
+	}
+
+	query := `
+		INSERT INTO articles (feed_id, domain, url, title, content, published_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (url) DO NOTHING
+	`
+
+	batch := &pgx.Batch{}
+	for _, a := range articles {
+		var publishedAt *time.Time
+		if !a.PublishedAt.IsZero() {
+			publishedAt = &a.PublishedAt
+		}
+		batch.Queue(query, a.FeedID, a.Domain, a.URL, a.Title, a.Content, publishedAt)
+	}
+
+	results := r.db.Pool.SendBatch(ctx, batch)
+	defer results.Close()
+
+	saved := 0
+	for range articles {
+		tag, err := results.Exec()
+		if err != nil {
+			return saved, fmt.Errorf("save article: %w", err)
+		}
+		saved += int(tag.RowsAffected())
+	}
+
+	return saved, nil
+}
+
+func (r *ArticleRepo) SearchArticles(ctx context.Context, q domain.ArticleQuery) ([]domain.ArticleHit, error) {
+	// запрос разбирается обоими словарями, как и индекс
+	query := `
+		WITH q AS (
+			SELECT websearch_to_tsquery('english', $1) || websearch_to_tsquery('russian', $1) AS query
+		)
+		SELECT a.id, a.feed_id, a.domain, a.url, a.title, a.content, a.published_at, a.fetched_at,
+			ts_rank(a.search_vector, q.query)::float8 AS rank
+		FROM articles a, q
+		WHERE a.search_vector @@ q.query
+			AND (cardinality($2::text[]) = 0 OR a.domain = ANY($2))
+			AND ($3::timestamptz IS NULL OR a.published_at >= $3)
+		ORDER BY rank DESC, a.published_at DESC NULLS LAST
+		LIMIT $4
+	`
+
+	domains := q.Domains
+	if domains == nil {
+		domains = []string{}
+	}
+	var since *time.Time
+	if !q.Since.IsZero() {
+		since = &q.Since
+	}
+
+	rows, err := r.db.Pool.Query(ctx, query, q.Text, domains, since, q.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("search articles: %w", err)
+	}
+	defer rows.Close()
+
+	var hits []domain.ArticleHit
+	for rows.Next() {
+		var h domain.ArticleHit
+		var publishedAt *time.Time
+		err := rows.Scan(
+			&h.ID,
+			&h.FeedID,
+			&h.Domain,
+			&h.URL,
+			&h.Title,
+			&h.Content,
+			&publishedAt,
+			&h.FetchedAt,
+			&h.Rank,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("scan article: %w", err)
+		}
+		if publishedAt != nil {
+			h.PublishedAt = *publishedAt
+		}
+		hits = append(hits, h)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return hits, nil
+}
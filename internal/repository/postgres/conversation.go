@@ -0,0 +1,140 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/kitbuilder587/fintech-bot/internal/domain"
+)
+
+// KeepTurnsPerChat - сколько последних ответов храним на чат, более старые удаляются при записи
+const KeepTurnsPerChat = 50
+
+type ConversationRepo struct {
+	db *DB
+}
+
+func NewConversationRepo(db *DB) *ConversationRepo {
+	return &ConversationRepo{db: db}
+}
+
+// turnSource - источник ответа в колонке sources
+type turnSource struct {
+	Marker     string `json:"marker"`
+	Title      string `json:"title"`
+	URL        string `json:"url"`
+	TrustLevel string `json:"trust_level"`
+}
+
+func (r *ConversationRepo) SaveTurn(ctx context.Context, turn *domain.ConversationTurn) error {
+	sources := make([]turnSource, len(turn.Sources))
+	for i, s := range turn.Sources {
+		sources[i] = turnSource{Marker: s.Marker, Title: s.Title, URL: s.URL, TrustLevel: s.TrustLevel.String()}
+	}
+	sourcesJSON, err := json.Marshal(sources)
+	if err != nil {
+		return fmt.Errorf("marshal sources: %w", err)
+	}
+
+	messageIDs := make([]int64, len(turn.MessageIDs))
+	for i, id := range turn.MessageIDs {
+		messageIDs[i] = int64(id)
+	}
+
+	var parentID *int64
+	if turn.ParentID != 0 {
+		parentID = &turn.ParentID
+	}
+
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	err = tx.QueryRow(ctx, `
+		INSERT INTO conversation_turns (chat_id, user_id, parent_id, message_ids, question, answer, sources)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at
+	`, turn.ChatID, turn.UserID, parentID, messageIDs, turn.Question, turn.Answer, sourcesJSON,
+	).Scan(&turn.ID, &turn.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("insert turn: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		DELETE FROM conversation_turns
+		WHERE chat_id = $1 AND id NOT IN (
+			SELECT id FROM conversation_turns WHERE chat_id = $1 ORDER BY id DESC LIMIT $2
+		)
+	`, turn.ChatID, KeepTurnsPerChat)
+	if err != nil {
+		return fmt.Errorf("prune turns: %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+	return nil
+}
+
+const turnColumns = `id, chat_id, user_id, COALESCE(parent_id, 0), message_ids, question, answer, sources, created_at`
+
+func (r *ConversationRepo) GetTurn(ctx context.Context, id int64) (*domain.ConversationTurn, error) {
+	row := r.db.Pool.QueryRow(ctx, `SELECT `+turnColumns+` FROM conversation_turns WHERE id = $1`, id)
+	return scanTurn(row)
+}
+
+func (r *ConversationRepo) GetTurnByMessage(ctx context.Context, chatID int64, messageID int) (*domain.ConversationTurn, error) {
+	row := r.db.Pool.QueryRow(ctx, `
+		SELECT `+turnColumns+` FROM conversation_turns
+		WHERE chat_id = $1 AND $2 = ANY(message_ids)
+		ORDER BY id DESC
+		LIMIT 1
+	`, chatID, int64(messageID))
+	return scanTurn(row)
+}
+
+func scanTurn(row pgx.Row) (*domain.ConversationTurn, error) {
+	var (
+		turn        domain.ConversationTurn
+		messageIDs  []int64
+		sourcesJSON []byte
+	)
+	err := row.Scan(
+		&turn.ID,
+		&turn.ChatID,
+		&turn.UserID,
+		&turn.ParentID,
+		&messageIDs,
+		&turn.Question,
+		&turn.Answer,
+		&sourcesJSON,
+		&turn.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrNotFound
+		}
+		return nil, fmt.Errorf("get turn: %w", err)
+	}
+
+	turn.MessageIDs = make([]int, len(messageIDs))
+	for i, id := range messageIDs {
+		turn.MessageIDs[i] = int(id)
+	}
+
+	var sources []turnSource
+	if err := json.Unmarshal(sourcesJSON, &sources); err != nil {
+		return nil, fmt.Errorf("unmarshal sources: %w", err)
+	}
+	turn.Sources = make([]domain.SourceRef, len(sources))
+	for i, s := range sources {
+		turn.Sources[i] = domain.SourceRef{Marker: s.Marker, Title: s.Title, URL: s.URL, TrustLevel: domain.TrustLevel(s.TrustLevel)}
+	}
+
+	return &turn, nil
+}
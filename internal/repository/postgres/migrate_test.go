@@ -0,0 +1,87 @@
+package postgres_test
+
+import (
+	"errors"
+	"testing"
+	"testing/fstest"
+
+	"github.com/kitbuilder587/fintech-bot/internal/repository/postgres"
+	"github.com/kitbuilder587/fintech-bot/migrations"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	fsys := fstest.MapFS{
+		"002_world_model.up.sql":   {Data: []byte("CREATE TABLE facts();")},
+		"002_world_model.down.sql": {Data: []byte("DROP TABLE facts;")},
+		"001_init.up.sql":          {Data: []byte("CREATE TABLE users();")},
+		"001_init.down.sql":        {Data: []byte("DROP TABLE users;")},
+		"README.md":                {Data: []byte("not a migration")},
+	}
+
+	got, err := postgres.LoadMigrations(fsys)
+	if err != nil {
+		t.Fatalf("LoadMigrations() error = %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("LoadMigrations() got %d migrations, want 2", len(got))
+	}
+	if got[0].Version != 1 || got[0].Name != "init" {
+		t.Errorf("first migration = %d_%s, want 1_init", got[0].Version, got[0].Name)
+	}
+	if got[1].Version != 2 || got[1].Down != "DROP TABLE facts;" {
+		t.Errorf("second migration = %+v, want version 2 with down sql", got[1])
+	}
+}
+
+func TestLoadMigrations_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		fsys    fstest.MapFS
+		wantErr error
+	}{
+		{
+			name:    "empty dir",
+			fsys:    fstest.MapFS{"notes.txt": {Data: []byte("x")}},
+			wantErr: postgres.ErrNoMigrations,
+		},
+		{
+			name: "down without up",
+			fsys: fstest.MapFS{"001_init.down.sql": {Data: []byte("DROP TABLE users;")}},
+		},
+		{
+			name: "conflicting names",
+			fsys: fstest.MapFS{
+				"001_init.up.sql":  {Data: []byte("CREATE TABLE users();")},
+				"001_other.up.sql": {Data: []byte("CREATE TABLE other();")},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := postgres.LoadMigrations(tt.fsys)
+			if err == nil {
+				t.Fatal("LoadMigrations() expected error")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("LoadMigrations() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadMigrations_Embedded(t *testing.T) {
+	got, err := postgres.LoadMigrations(migrations.FS)
+	if err != nil {
+		t.Fatalf("LoadMigrations(embedded) error = %v", err)
+	}
+
+	for i, m := range got {
+		if m.Up == "" || m.Down == "" {
+			t.Errorf("migration %d_%s must have both up and down files", m.Version, m.Name)
+		}
+		if i > 0 && got[i-1].Version >= m.Version {
+			t.Errorf("migrations not sorted: %d before %d", got[i-1].Version, m.Version)
+		}
+	}
+}
@@ -198,3 +198,82 @@ func (r *SourceRepo) UpdateTrustLevel(ctx context.Context, userID, sourceID int6
 
 	return nil
 }
+
+func (r *SourceRepo) ListURLs(ctx context.Context) ([]string, error) {
+	query := `SELECT DISTINCT url FROM sources ORDER BY url`
+
+	rows, err := r.db.Pool.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("list source urls: %w", err)
+	}
+	defer rows.Close()
+
+	var urls []string
+	for rows.Next() {
+		var u string
+		if err := rows.Scan(&u); err != nil {
+			return nil, fmt.Errorf("scan source url: %w", err)
+		}
+		urls = append(urls, u)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return urls, nil
+}
+
+func (r *SourceRepo) BlockDomain(ctx context.Context, userID int64, d string) error {
+	query := `INSERT INTO blocked_domains (user_id, domain) VALUES ($1, $2)`
+
+	if _, err := r.db.Pool.Exec(ctx, query, userID, d); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return domain.ErrDomainAlreadyBlocked
+		}
+		return fmt.Errorf("block domain: %w", err)
+	}
+
+	return nil
+}
+
+func (r *SourceRepo) UnblockDomain(ctx context.Context, userID int64, d string) error {
+	query := `DELETE FROM blocked_domains WHERE user_id = $1 AND domain = $2`
+
+	result, err := r.db.Pool.Exec(ctx, query, userID, d)
+	if err != nil {
+		return fmt.Errorf("unblock domain: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return domain.ErrDomainNotBlocked
+	}
+
+	return nil
+}
+
+func (r *SourceRepo) ListBlockedDomains(ctx context.Context, userID int64) ([]string, error) {
+	query := `SELECT domain FROM blocked_domains WHERE user_id = $1 ORDER BY domain`
+
+	rows, err := r.db.Pool.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("list blocked domains: %w", err)
+	}
+	defer rows.Close()
+
+	var domains []string
+	for rows.Next() {
+		var d string
+		if err := rows.Scan(&d); err != nil {
+			return nil, fmt.Errorf("scan blocked domain: %w", err)
+		}
+		domains = append(domains, d)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return domains, nil
+}
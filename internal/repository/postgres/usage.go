@@ -0,0 +1,83 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/kitbuilder587/fintech-bot/internal/domain"
+)
+
+type UsageRepo struct {
+	db *DB
+}
+
+func NewUsageRepo(db *DB) *UsageRepo {
+	return &UsageRepo{db: db}
+}
+
+func (r *UsageRepo) SaveUsage(ctx context.Context, records []domain.UsageRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	query := `
+		INSERT INTO llm_usage (user_id, provider, model, stage, prompt_tokens, completion_tokens, cost_usd, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+
+	batch := &pgx.Batch{}
+	for _, rec := range records {
+		createdAt := rec.CreatedAt
+		if createdAt.IsZero() {
+			createdAt = time.Now()
+		}
+		batch.Queue(query,
+			rec.UserID,
+			rec.Provider,
+			rec.Model,
+			rec.Stage,
+			rec.PromptTokens,
+			rec.CompletionTokens,
+			rec.CostUSD,
+			createdAt,
+		)
+	}
+
+	if err := r.db.Pool.SendBatch(ctx, batch).Close(); err != nil {
+		return fmt.Errorf("save usage: %w", err)
+	}
+	return nil
+}
+
+func (r *UsageRepo) GetUsageByStage(ctx context.Context, userID int64, since time.Time) ([]domain.UsageTotals, error) {
+	query := `
+		SELECT stage, COUNT(*), SUM(prompt_tokens), SUM(completion_tokens), SUM(cost_usd)::float8
+		FROM llm_usage
+		WHERE user_id = $1 AND created_at >= $2
+		GROUP BY stage
+		ORDER BY SUM(cost_usd) DESC, stage
+	`
+
+	rows, err := r.db.Pool.Query(ctx, query, userID, since)
+	if err != nil {
+		return nil, fmt.Errorf("get usage by stage: %w", err)
+	}
+	defer rows.Close()
+
+	var totals []domain.UsageTotals
+	for rows.Next() {
+		var t domain.UsageTotals
+		if err := rows.Scan(&t.Stage, &t.Calls, &t.PromptTokens, &t.CompletionTokens, &t.CostUSD); err != nil {
+			return nil, fmt.Errorf("scan usage: %w", err)
+		}
+		totals = append(totals, t)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return totals, nil
+}
@@ -290,8 +290,8 @@ func (r *WorldModelRepo) UpdateEntity(ctx context.Context, entity *domain.Entity
 
 func (r *WorldModelRepo) CreateSession(ctx context.Context, session *domain.ResearchSession) error {
 	query := `
-		INSERT INTO research_sessions (id, user_id, question, strategy, created_at)
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO research_sessions (id, user_id, question, strategy, prompt_version, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
 		RETURNING created_at
 	`
 
@@ -300,6 +300,7 @@ func (r *WorldModelRepo) CreateSession(ctx context.Context, session *domain.Rese
 		session.UserID,
 		session.Question,
 		nullString(session.Strategy),
+		session.PromptVersion,
 		session.CreatedAt,
 	).Scan(&session.CreatedAt)
 
@@ -312,7 +313,7 @@ func (r *WorldModelRepo) CreateSession(ctx context.Context, session *domain.Rese
 
 func (r *WorldModelRepo) GetRecentSessions(ctx context.Context, userID int64, limit int) ([]domain.ResearchSession, error) {
 	query := `
-		SELECT id, user_id, question, strategy, created_at
+		SELECT id, user_id, question, strategy, prompt_version, created_at
 		FROM research_sessions
 		WHERE user_id = $1
 		ORDER BY created_at DESC
@@ -448,6 +449,7 @@ func scanSessions(rows pgx.Rows) ([]domain.ResearchSession, error) {
 			&s.UserID,
 			&s.Question,
 			&strategy,
+			&s.PromptVersion,
 			&s.CreatedAt,
 		)
 		if err != nil {
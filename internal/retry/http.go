@@ -0,0 +1,118 @@
+package retry
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Client - http.Client с повторами при сетевых ошибках, 408, 429 и 5xx.
+// Таймаут HTTP действует на каждую попытку, общее время ограничивают ctx запроса и Policy
+type Client struct {
+	http   *http.Client
+	policy Policy
+}
+
+func NewClient(c *http.Client, p Policy) *Client {
+	return &Client{http: c, policy: p}
+}
+
+type statusError struct {
+	code int
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("status %d", e.code)
+}
+
+// Do отправляет запрос. Если попытки кончились на повторяемом статусе,
+// последний ответ отдается как есть, его разбирает вызывающий
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	// тело без GetBody второй раз не отправить
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return c.http.Do(req)
+	}
+
+	ctx := req.Context()
+	var resp *http.Response
+	attempt := 0
+	err := Do(ctx, c.policy, func() error {
+		attempt++
+		if resp != nil {
+			discard(resp)
+			resp = nil
+		}
+
+		r := req
+		if attempt > 1 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return err
+			}
+			r = req.Clone(ctx)
+			r.Body = body
+		}
+
+		var err error
+		resp, err = c.http.Do(r)
+		if err != nil {
+			if ctx.Err() != nil {
+				return err
+			}
+			return Temporary(err, 0)
+		}
+		if RetryableStatus(resp.StatusCode) {
+			return Temporary(&statusError{code: resp.StatusCode}, ParseRetryAfter(resp.Header.Get("Retry-After"), time.Now()))
+		}
+		return nil
+	})
+
+	if _, ok := err.(*statusError); ok {
+		return resp, nil
+	}
+	if err != nil {
+		if resp != nil {
+			discard(resp)
+		}
+		return nil, err
+	}
+	return resp, nil
+}
+
+func discard(resp *http.Response) {
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+}
+
+// RetryableStatus - статусы, после которых запрос имеет смысл повторить
+func RetryableStatus(code int) bool {
+	switch code {
+	case http.StatusRequestTimeout, http.StatusTooManyRequests,
+		http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// ParseRetryAfter читает Retry-After в секундах или как HTTP-дату.
+// 0 - заголовка нет, он кривой или время уже прошло
+func ParseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds <= 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+	return 0
+}
@@ -0,0 +1,113 @@
+// Package retry повторяет временные ошибки с экспоненциальной задержкой и джиттером
+package retry
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+)
+
+type Policy struct {
+	MaxAttempts int           // всего попыток вместе с первой, 1 - без повторов
+	BaseDelay   time.Duration // пауза перед второй попыткой, дальше удваивается
+	MaxDelay    time.Duration
+	MaxElapsed  time.Duration // 0 - ограничение только по ctx
+}
+
+func DefaultPolicy() Policy {
+	return Policy{
+		MaxAttempts: 3,
+		BaseDelay:   500 * time.Millisecond,
+		MaxDelay:    8 * time.Second,
+		MaxElapsed:  20 * time.Second,
+	}
+}
+
+// Backoff - пауза после attempt-й неудачной попытки: BaseDelay*2^(attempt-1)
+// не больше MaxDelay, случайно урезанная до половины, чтобы клиенты не ломились разом
+func (p Policy) Backoff(attempt int) time.Duration {
+	d := p.BaseDelay
+	for i := 1; i < attempt && (p.MaxDelay <= 0 || d < p.MaxDelay); i++ {
+		d *= 2
+	}
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	if d <= 0 {
+		return 0
+	}
+	half := d / 2
+	return half + time.Duration(rand.Int64N(int64(d-half)+1))
+}
+
+type temporaryError struct {
+	err   error
+	after time.Duration
+}
+
+func (e *temporaryError) Error() string { return e.err.Error() }
+func (e *temporaryError) Unwrap() error { return e.err }
+
+// Temporary помечает ошибку как временную. after - пауза, которую попросил
+// сервер (Retry-After), 0 - по расписанию Policy
+func Temporary(err error, after time.Duration) error {
+	if err == nil {
+		return nil
+	}
+	return &temporaryError{err: err, after: after}
+}
+
+func IsTemporary(err error) bool {
+	var temp *temporaryError
+	return errors.As(err, &temp)
+}
+
+// Do вызывает op, пока тот возвращает временную ошибку и есть попытки и время.
+// Постоянная ошибка возвращается сразу. Если пауза не укладывается в дедлайн ctx
+// или MaxElapsed, ждать бессмысленно - возвращается последняя ошибка
+func Do(ctx context.Context, p Policy, op func() error) error {
+	start := time.Now()
+	for attempt := 1; ; attempt++ {
+		err := op()
+		if err == nil {
+			return nil
+		}
+
+		var temp *temporaryError
+		if !errors.As(err, &temp) {
+			return err
+		}
+		err = unwrapTemporary(err)
+		if attempt >= p.MaxAttempts {
+			return err
+		}
+
+		delay := temp.after
+		if delay <= 0 {
+			delay = p.Backoff(attempt)
+		}
+		if p.MaxElapsed > 0 && time.Since(start)+delay > p.MaxElapsed {
+			return err
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return err
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// unwrapTemporary снимает пометку, чтобы она не уходила наружу из Do
+func unwrapTemporary(err error) error {
+	if temp, ok := err.(*temporaryError); ok {
+		return temp.err
+	}
+	return err
+}
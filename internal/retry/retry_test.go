@@ -0,0 +1,127 @@
+package retry_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kitbuilder587/fintech-bot/internal/retry"
+)
+
+var errBoom = errors.New("boom")
+
+func fastPolicy(attempts int) retry.Policy {
+	return retry.Policy{MaxAttempts: attempts, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}
+}
+
+func TestDo_RetriesTemporaryErrors(t *testing.T) {
+	calls := 0
+	err := retry.Do(context.Background(), fastPolicy(3), func() error {
+		calls++
+		if calls < 3 {
+			return retry.Temporary(errBoom, 0)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("calls = %d, want 3", calls)
+	}
+}
+
+func TestDo_PermanentErrorStopsImmediately(t *testing.T) {
+	calls := 0
+	err := retry.Do(context.Background(), fastPolicy(5), func() error {
+		calls++
+		return errBoom
+	})
+	if !errors.Is(err, errBoom) || calls != 1 {
+		t.Errorf("error = %v after %d calls, want boom after 1", err, calls)
+	}
+}
+
+func TestDo_ReturnsLastErrorUnmarked(t *testing.T) {
+	calls := 0
+	err := retry.Do(context.Background(), fastPolicy(3), func() error {
+		calls++
+		return retry.Temporary(errBoom, 0)
+	})
+	if !errors.Is(err, errBoom) || calls != 3 {
+		t.Errorf("error = %v after %d calls, want boom after 3", err, calls)
+	}
+	if retry.IsTemporary(err) {
+		t.Error("error returned from Do should not be marked temporary")
+	}
+}
+
+func TestDo_GivesUpWhenDelayExceedsDeadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	calls := 0
+	start := time.Now()
+	err := retry.Do(ctx, fastPolicy(5), func() error {
+		calls++
+		// сервер просит подождать дольше, чем осталось до дедлайна
+		return retry.Temporary(errBoom, time.Second)
+	})
+	if !errors.Is(err, errBoom) || calls != 1 {
+		t.Errorf("error = %v after %d calls, want boom after 1", err, calls)
+	}
+	if time.Since(start) > 40*time.Millisecond {
+		t.Errorf("Do() waited %v despite the deadline", time.Since(start))
+	}
+}
+
+func TestDo_MaxElapsed(t *testing.T) {
+	p := retry.Policy{MaxAttempts: 100, BaseDelay: 10 * time.Millisecond, MaxDelay: 10 * time.Millisecond, MaxElapsed: 35 * time.Millisecond}
+
+	calls := 0
+	err := retry.Do(context.Background(), p, func() error {
+		calls++
+		return retry.Temporary(errBoom, 0)
+	})
+	if !errors.Is(err, errBoom) {
+		t.Fatalf("error = %v, want boom", err)
+	}
+	if calls < 2 || calls > 8 {
+		t.Errorf("calls = %d, want a few attempts within MaxElapsed", calls)
+	}
+}
+
+func TestDo_ContextCanceledDuringBackoff(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	p := retry.Policy{MaxAttempts: 3, BaseDelay: time.Second}
+
+	err := retry.Do(ctx, p, func() error {
+		cancel()
+		return retry.Temporary(errBoom, 0)
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("error = %v, want context.Canceled", err)
+	}
+}
+
+func TestPolicy_Backoff(t *testing.T) {
+	p := retry.Policy{BaseDelay: 100 * time.Millisecond, MaxDelay: 400 * time.Millisecond}
+
+	tests := []struct {
+		attempt  int
+		min, max time.Duration
+	}{
+		{1, 50 * time.Millisecond, 100 * time.Millisecond},
+		{2, 100 * time.Millisecond, 200 * time.Millisecond},
+		{3, 200 * time.Millisecond, 400 * time.Millisecond},
+		{10, 200 * time.Millisecond, 400 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		for i := 0; i < 20; i++ {
+			if d := p.Backoff(tt.attempt); d < tt.min || d > tt.max {
+				t.Fatalf("Backoff(%d) = %v, want within [%v, %v]", tt.attempt, d, tt.min, tt.max)
+			}
+		}
+	}
+}
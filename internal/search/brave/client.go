@@ -0,0 +1,159 @@
+// Package brave - клиент Brave Search API (web search)
+package brave
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/kitbuilder587/fintech-bot/internal/retry"
+	"github.com/kitbuilder587/fintech-bot/internal/search"
+)
+
+// больше за один запрос API не отдает
+const maxCount = 20
+
+type Config struct {
+	APIKey  string
+	BaseURL string
+	Timeout time.Duration // на одну попытку
+	Retry   retry.Policy  // пустая - retry.DefaultPolicy()
+}
+
+type Client struct {
+	apiKey  string
+	baseURL string
+	client  *retry.Client
+	logger  *zap.Logger
+}
+
+func New(cfg Config, logger *zap.Logger) *Client {
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = "https://api.search.brave.com/res/v1"
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = 30 * time.Second
+	}
+	if cfg.Retry == (retry.Policy{}) {
+		cfg.Retry = retry.DefaultPolicy()
+	}
+
+	return &Client{
+		apiKey:  cfg.APIKey,
+		baseURL: strings.TrimRight(cfg.BaseURL, "/"),
+		client:  retry.NewClient(&http.Client{Timeout: cfg.Timeout}, cfg.Retry),
+		logger:  logger,
+	}
+}
+
+type braveResponse struct {
+	Web struct {
+		Results []braveResult `json:"results"`
+	} `json:"web"`
+}
+
+type braveResult struct {
+	Title       string `json:"title"`
+	URL         string `json:"url"`
+	Description string `json:"description"`
+	PageAge     string `json:"page_age"`
+}
+
+// freshness - TimeRange в терминах Tavily -> freshness Brave
+var freshness = map[string]string{
+	"day": "pd", "d": "pd",
+	"week": "pw", "w": "pw",
+	"month": "pm", "m": "pm",
+	"year": "py", "y": "py",
+}
+
+// в описаниях Brave подсвечивает совпадения тегами
+var highlight = strings.NewReplacer("<strong>", "", "</strong>", "")
+
+func (c *Client) Search(ctx context.Context, req search.SearchRequest) (*search.SearchResponse, error) {
+	if req.MaxResults == 0 {
+		req.MaxResults = 5
+	}
+
+	params := url.Values{}
+	params.Set("q", search.SiteQuery(req.Query, req.IncludeDomains, req.ExcludeDomains))
+	params.Set("count", strconv.Itoa(min(req.MaxResults, maxCount)))
+	if f, ok := freshness[req.TimeRange]; ok {
+		params.Set("freshness", f)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/web/search?"+params.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	httpReq.Header.Set("Accept", "application/json")
+	httpReq.Header.Set("X-Subscription-Token", c.apiKey)
+
+	start := time.Now()
+	// сетевые ошибки, 429 и 5xx повторяет retry.Client
+	resp, err := c.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("%w: do request: %w", search.ErrSearchFailed, err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("%w: read response: %v", search.ErrSearchFailed, err)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		var braveResp braveResponse
+		if err := json.Unmarshal(respBody, &braveResp); err != nil {
+			return nil, fmt.Errorf("unmarshal response: %w", err)
+		}
+
+		if len(braveResp.Web.Results) == 0 {
+			return nil, search.ErrEmptyResults
+		}
+
+		out := toSearchResponse(&braveResp)
+		out.Query = req.Query
+		out.ResponseTime = time.Since(start).Seconds()
+		return out, nil
+
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return nil, search.ErrUnauthorized
+
+	case http.StatusTooManyRequests:
+		return nil, search.ErrRateLimit
+
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return nil, search.ErrInvalidRequest
+
+	default:
+		return nil, fmt.Errorf("%w: status %d", search.ErrSearchFailed, resp.StatusCode)
+	}
+}
+
+// toSearchResponse - оценок Brave не отдает, они считаются по позиции в выдаче
+func toSearchResponse(resp *braveResponse) *search.SearchResponse {
+	results := make([]search.SearchResult, len(resp.Web.Results))
+	for i, r := range resp.Web.Results {
+		results[i] = search.SearchResult{
+			Title:         r.Title,
+			URL:           r.URL,
+			Content:       highlight.Replace(r.Description),
+			PublishedDate: r.PageAge,
+		}
+	}
+	search.NormalizeScores(results)
+
+	return &search.SearchResponse{Results: results}
+}
+
+var _ search.SearchClient = (*Client)(nil)
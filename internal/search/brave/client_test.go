@@ -0,0 +1,179 @@
+package brave
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/kitbuilder587/fintech-bot/internal/retry"
+	"github.com/kitbuilder587/fintech-bot/internal/search"
+)
+
+func webResults(results ...braveResult) braveResponse {
+	var resp braveResponse
+	resp.Web.Results = results
+	return resp
+}
+
+func TestClient_Search(t *testing.T) {
+	logger := zap.NewNop()
+
+	tests := []struct {
+		name       string
+		response   interface{}
+		statusCode int
+		wantErr    error
+	}{
+		{
+			name:       "successful search",
+			response:   webResults(braveResult{Title: "Test", URL: "https://example.com", Description: "Content"}),
+			statusCode: http.StatusOK,
+			wantErr:    nil,
+		},
+		{
+			name:       "empty results",
+			response:   webResults(),
+			statusCode: http.StatusOK,
+			wantErr:    search.ErrEmptyResults,
+		},
+		{
+			name:       "unauthorized",
+			response:   map[string]string{"error": "unauthorized"},
+			statusCode: http.StatusUnauthorized,
+			wantErr:    search.ErrUnauthorized,
+		},
+		{
+			name:       "rate limit",
+			response:   map[string]string{"error": "rate limit"},
+			statusCode: http.StatusTooManyRequests,
+			wantErr:    search.ErrRateLimit,
+		},
+		{
+			name:       "invalid params",
+			response:   map[string]string{"error": "validation"},
+			statusCode: http.StatusUnprocessableEntity,
+			wantErr:    search.ErrInvalidRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(tt.statusCode)
+				json.NewEncoder(w).Encode(tt.response)
+			}))
+			defer server.Close()
+
+			client := New(Config{
+				APIKey:  "test-key",
+				BaseURL: server.URL,
+				Timeout: 5 * time.Second,
+				Retry:   retry.Policy{MaxAttempts: 1},
+			}, logger)
+
+			resp, err := client.Search(context.Background(), search.SearchRequest{
+				Query:      "test query",
+				MaxResults: 5,
+			})
+
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("Search() error = %v, wantErr %v", err, tt.wantErr)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Search() unexpected error = %v", err)
+			}
+			if len(resp.Results) != 1 || resp.Results[0].Content != "Content" {
+				t.Errorf("Search() results = %+v", resp.Results)
+			}
+		})
+	}
+}
+
+func TestClient_Search_RequestParams(t *testing.T) {
+	var got *http.Request
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		json.NewEncoder(w).Encode(webResults(braveResult{Title: "Test", URL: "https://mckinsey.com"}))
+	}))
+	defer server.Close()
+
+	client := New(Config{APIKey: "test-key", BaseURL: server.URL}, zap.NewNop())
+
+	_, err := client.Search(context.Background(), search.SearchRequest{
+		Query:          "fintech",
+		IncludeDomains: []string{"mckinsey.com"},
+		ExcludeDomains: []string{"reddit.com"},
+		MaxResults:     50,
+		TimeRange:      "month",
+	})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+
+	q := got.URL.Query()
+	if got.URL.Path != "/web/search" || got.Header.Get("X-Subscription-Token") != "test-key" {
+		t.Errorf("request = %s, token = %q", got.URL, got.Header.Get("X-Subscription-Token"))
+	}
+	if want := "fintech site:mckinsey.com -site:reddit.com"; q.Get("q") != want {
+		t.Errorf("q = %q, want %q", q.Get("q"), want)
+	}
+	if q.Get("count") != "20" || q.Get("freshness") != "pm" {
+		t.Errorf("count = %q, freshness = %q; want 20 and pm", q.Get("count"), q.Get("freshness"))
+	}
+}
+
+func TestClient_Search_ScoresByRank(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(webResults(
+			braveResult{Title: "A", URL: "https://a.com", Description: "<strong>Klarna</strong> IPO"},
+			braveResult{Title: "B", URL: "https://b.com"},
+		))
+	}))
+	defer server.Close()
+
+	client := New(Config{APIKey: "test-key", BaseURL: server.URL}, zap.NewNop())
+
+	resp, err := client.Search(context.Background(), search.SearchRequest{Query: "klarna"})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+
+	if resp.Results[0].Score != 1 || resp.Results[1].Score != 0.5 {
+		t.Errorf("scores = %v, %v; want 1 and 0.5", resp.Results[0].Score, resp.Results[1].Score)
+	}
+	if resp.Results[0].Content != "Klarna IPO" {
+		t.Errorf("content = %q, want highlight tags stripped", resp.Results[0].Content)
+	}
+}
+
+func TestClient_Search_Timeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(2 * time.Second)
+	}))
+	defer server.Close()
+
+	client := New(Config{
+		APIKey:  "test-key",
+		BaseURL: server.URL,
+		Timeout: 100 * time.Millisecond,
+	}, zap.NewNop())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	if _, err := client.Search(ctx, search.SearchRequest{Query: "test"}); err == nil {
+		t.Error("Search() expected timeout error")
+	}
+}
@@ -0,0 +1,47 @@
+package search
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+)
+
+// Provider - поисковик в цепочке Fallback
+type Provider struct {
+	Name   string
+	Client SearchClient
+}
+
+// Fallback опрашивает поисковики по порядку, пока один не вернет результаты:
+// кончилась квота Tavily - ищем в SearXNG
+type Fallback struct {
+	providers []Provider
+	logger    *zap.Logger
+}
+
+func NewFallback(providers []Provider, logger *zap.Logger) *Fallback {
+	return &Fallback{providers: providers, logger: logger}
+}
+
+func (f *Fallback) Search(ctx context.Context, req SearchRequest) (*SearchResponse, error) {
+	var lastErr error
+	for _, p := range f.providers {
+		resp, err := p.Client.Search(ctx, req)
+		if err == nil {
+			return resp, nil
+		}
+		// отмененный запрос другие поисковики не спасут
+		if ctx.Err() != nil {
+			return nil, err
+		}
+
+		f.logger.Warn("search provider failed, trying next",
+			zap.String("provider", p.Name),
+			zap.Error(err),
+		)
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+var _ SearchClient = (*Fallback)(nil)
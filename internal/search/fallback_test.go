@@ -0,0 +1,58 @@
+package search_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/kitbuilder587/fintech-bot/internal/search"
+	"github.com/kitbuilder587/fintech-bot/internal/search/mock"
+)
+
+func TestFallback_NextProviderOnError(t *testing.T) {
+	tavily := mock.New().WithError(search.ErrRateLimit)
+	searxng := mock.New().WithResults([]search.SearchResult{{Title: "t", URL: "https://example.com"}})
+	client := search.NewFallback([]search.Provider{
+		{Name: "tavily", Client: tavily},
+		{Name: "searxng", Client: searxng},
+	}, zap.NewNop())
+
+	resp, err := client.Search(context.Background(), search.SearchRequest{Query: "q"})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if len(resp.Results) != 1 || tavily.CallCount != 1 || searxng.CallCount != 1 {
+		t.Errorf("results = %d, calls = %d/%d", len(resp.Results), tavily.CallCount, searxng.CallCount)
+	}
+}
+
+func TestFallback_AllFailed(t *testing.T) {
+	client := search.NewFallback([]search.Provider{
+		{Name: "tavily", Client: mock.New().WithError(search.ErrRateLimit)},
+		{Name: "brave", Client: mock.New().WithError(search.ErrUnauthorized)},
+	}, zap.NewNop())
+
+	_, err := client.Search(context.Background(), search.SearchRequest{Query: "q"})
+	if !errors.Is(err, search.ErrUnauthorized) {
+		t.Errorf("error = %v, want last provider's error", err)
+	}
+}
+
+func TestFallback_StopsOnCanceledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	next := mock.New()
+	client := search.NewFallback([]search.Provider{
+		{Name: "tavily", Client: mock.New().WithError(context.Canceled)},
+		{Name: "searxng", Client: next},
+	}, zap.NewNop())
+
+	if _, err := client.Search(ctx, search.SearchRequest{Query: "q"}); !errors.Is(err, context.Canceled) {
+		t.Errorf("error = %v, want context.Canceled", err)
+	}
+	if next.CallCount != 0 {
+		t.Error("canceled request should not reach the next provider")
+	}
+}
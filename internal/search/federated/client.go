@@ -0,0 +1,113 @@
+// Package federated опрашивает несколько поисковиков параллельно и сливает выдачу
+// через reciprocal rank fusion. Нужен глубокому исследованию: у каждого движка свой индекс
+package federated
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/kitbuilder587/fintech-bot/internal/search"
+)
+
+type Provider struct {
+	Name    string
+	Client  search.SearchClient
+	Timeout time.Duration // 0 - Config.Timeout
+}
+
+type Config struct {
+	Timeout time.Duration // на поисковик; медленный не задерживает остальных
+	K       int           // константа RRF, 0 - search.RRFK
+}
+
+type Client struct {
+	providers []Provider
+	timeout   time.Duration
+	k         int
+	logger    *zap.Logger
+}
+
+func New(providers []Provider, cfg Config, logger *zap.Logger) *Client {
+	if cfg.Timeout == 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	if cfg.K == 0 {
+		cfg.K = search.RRFK
+	}
+
+	return &Client{
+		providers: providers,
+		timeout:   cfg.Timeout,
+		k:         cfg.K,
+		logger:    logger,
+	}
+}
+
+// Search отвечает, если ответил хотя бы один поисковик. Ошибка - только когда
+// не ответил никто; ErrEmptyResults - когда все ответили пустой выдачей
+func (c *Client) Search(ctx context.Context, req search.SearchRequest) (*search.SearchResponse, error) {
+	start := time.Now()
+
+	// по слоту на поисковик, чтобы порядок слияния не зависел от того, кто ответил первым
+	lists := make([][]search.SearchResult, len(c.providers))
+	errs := make([]error, len(c.providers))
+
+	done := make(chan struct{}, len(c.providers))
+	for i, p := range c.providers {
+		go func() {
+			defer func() { done <- struct{}{} }()
+
+			timeout := p.Timeout
+			if timeout == 0 {
+				timeout = c.timeout
+			}
+			pctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			resp, err := p.Client.Search(pctx, req)
+			if err != nil {
+				errs[i] = fmt.Errorf("%s: %w", p.Name, err)
+				return
+			}
+			lists[i] = resp.Results
+		}()
+	}
+	for range c.providers {
+		<-done
+	}
+
+	var failed []error
+	for i, err := range errs {
+		if err == nil || errors.Is(err, search.ErrEmptyResults) {
+			continue
+		}
+		c.logger.Warn("federated search provider failed",
+			zap.String("provider", c.providers[i].Name),
+			zap.Error(err),
+		)
+		failed = append(failed, err)
+	}
+	if len(failed) == len(c.providers) {
+		return nil, errors.Join(failed...)
+	}
+
+	results := search.FuseRRF(lists, c.k)
+	if len(results) == 0 {
+		return nil, search.ErrEmptyResults
+	}
+	if req.MaxResults > 0 && len(results) > req.MaxResults {
+		results = results[:req.MaxResults]
+	}
+
+	return &search.SearchResponse{
+		Query:        req.Query,
+		Results:      results,
+		ResponseTime: time.Since(start).Seconds(),
+	}, nil
+}
+
+var _ search.SearchClient = (*Client)(nil)
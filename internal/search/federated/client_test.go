@@ -0,0 +1,95 @@
+package federated
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/kitbuilder587/fintech-bot/internal/search"
+	"github.com/kitbuilder587/fintech-bot/internal/search/mock"
+)
+
+func TestClient_Search_FusesProviders(t *testing.T) {
+	tavily := mock.New().WithResults([]search.SearchResult{
+		{Title: "A", URL: "https://a.com", Score: 0.9},
+		{Title: "B", URL: "https://b.com", Score: 0.5},
+	})
+	searxng := mock.New().WithResults([]search.SearchResult{
+		{Title: "B", URL: "https://www.b.com/", Score: 12},
+		{Title: "C", URL: "https://c.com", Score: 3},
+	})
+	client := New([]Provider{
+		{Name: "tavily", Client: tavily},
+		{Name: "searxng", Client: searxng},
+	}, Config{}, zap.NewNop())
+
+	resp, err := client.Search(context.Background(), search.SearchRequest{Query: "klarna", MaxResults: 2})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+
+	if len(resp.Results) != 2 || resp.Results[0].URL != "https://b.com" || resp.Results[1].URL != "https://a.com" {
+		t.Errorf("results = %+v, want b.com (in both) then a.com, cut to MaxResults", resp.Results)
+	}
+	if tavily.LastRequest.Query != "klarna" || searxng.LastRequest.Query != "klarna" {
+		t.Error("every provider should get the request")
+	}
+}
+
+func TestClient_Search_PartialFailure(t *testing.T) {
+	client := New([]Provider{
+		{Name: "tavily", Client: mock.New().WithError(search.ErrRateLimit)},
+		{Name: "brave", Client: mock.New().WithResults([]search.SearchResult{{Title: "A", URL: "https://a.com"}})},
+	}, Config{}, zap.NewNop())
+
+	resp, err := client.Search(context.Background(), search.SearchRequest{Query: "q"})
+	if err != nil {
+		t.Fatalf("Search() error = %v, want results from the provider that answered", err)
+	}
+	if len(resp.Results) != 1 {
+		t.Errorf("results = %+v", resp.Results)
+	}
+}
+
+func TestClient_Search_SlowProviderTimesOut(t *testing.T) {
+	slow := mock.New().WithDelay(time.Second).WithResults([]search.SearchResult{{URL: "https://slow.com"}})
+	client := New([]Provider{
+		{Name: "slow", Client: slow, Timeout: 20 * time.Millisecond},
+		{Name: "fast", Client: mock.New().WithResults([]search.SearchResult{{URL: "https://fast.com"}})},
+	}, Config{Timeout: time.Second}, zap.NewNop())
+
+	start := time.Now()
+	resp, err := client.Search(context.Background(), search.SearchRequest{Query: "q"})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Search() took %v, slow provider should be cut by its timeout", elapsed)
+	}
+	if len(resp.Results) != 1 || resp.Results[0].URL != "https://fast.com" {
+		t.Errorf("results = %+v", resp.Results)
+	}
+}
+
+func TestClient_Search_AllFailed(t *testing.T) {
+	client := New([]Provider{
+		{Name: "tavily", Client: mock.New().WithError(search.ErrRateLimit)},
+		{Name: "brave", Client: mock.New().WithError(search.ErrUnauthorized)},
+	}, Config{}, zap.NewNop())
+
+	_, err := client.Search(context.Background(), search.SearchRequest{Query: "q"})
+	if !errors.Is(err, search.ErrRateLimit) || !errors.Is(err, search.ErrUnauthorized) {
+		t.Errorf("error = %v, want errors of all providers", err)
+	}
+
+	empty := New([]Provider{
+		{Name: "tavily", Client: mock.New()},
+		{Name: "brave", Client: mock.New().WithError(search.ErrSearchFailed)},
+	}, Config{}, zap.NewNop())
+	if _, err := empty.Search(context.Background(), search.SearchRequest{Query: "q"}); !errors.Is(err, search.ErrEmptyResults) {
+		t.Errorf("error = %v, want ErrEmptyResults when the only answer is empty", err)
+	}
+}
@@ -0,0 +1,60 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// MetricsRecorder - часть metrics.Metrics, нужная декоратору
+type MetricsRecorder interface {
+	RecordSearchRequest(provider, status string, duration time.Duration)
+}
+
+// InstrumentedClient пишет в метрики статус и длительность каждого поиска
+type InstrumentedClient struct {
+	next     SearchClient
+	provider string
+	metrics  MetricsRecorder
+}
+
+func NewInstrumentedClient(next SearchClient, provider string, m MetricsRecorder) *InstrumentedClient {
+	return &InstrumentedClient{
+		next:     next,
+		provider: provider,
+		metrics:  m,
+	}
+}
+
+func (c *InstrumentedClient) Search(ctx context.Context, req SearchRequest) (*SearchResponse, error) {
+	start := time.Now()
+	resp, err := c.next.Search(ctx, req)
+	c.metrics.RecordSearchRequest(c.provider, Status(err), time.Since(start))
+	return resp, err
+}
+
+// Status переводит ошибку клиента в значение label status
+func Status(err error) string {
+	switch {
+	case err == nil:
+		return "success"
+	case errors.Is(err, ErrEmptyResults):
+		return "empty"
+	case errors.Is(err, ErrRateLimit):
+		return "rate_limited"
+	case errors.Is(err, ErrUnauthorized):
+		return "auth_failed"
+	case errors.Is(err, ErrInvalidRequest):
+		return "invalid_request"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.Is(err, ErrSearchFailed):
+		return "request_failed"
+	default:
+		return "error"
+	}
+}
+
+var _ SearchClient = (*InstrumentedClient)(nil)
@@ -0,0 +1,54 @@
+package search_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/kitbuilder587/fintech-bot/internal/search"
+	"github.com/kitbuilder587/fintech-bot/internal/search/mock"
+)
+
+type fakeRecorder struct {
+	statuses []string
+}
+
+func (r *fakeRecorder) RecordSearchRequest(provider, status string, _ time.Duration) {
+	r.statuses = append(r.statuses, provider+"/"+status)
+}
+
+func TestInstrumentedClient_RecordsStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"success", nil, "tavily/success"},
+		{"empty", search.ErrEmptyResults, "tavily/empty"},
+		{"rate limit", fmt.Errorf("tavily: %w", search.ErrRateLimit), "tavily/rate_limited"},
+		{"unauthorized", search.ErrUnauthorized, "tavily/auth_failed"},
+		{"timeout", context.DeadlineExceeded, "tavily/timeout"},
+		{"failed", fmt.Errorf("%w: status 502", search.ErrSearchFailed), "tavily/request_failed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := &fakeRecorder{}
+			next := mock.New().WithResults([]search.SearchResult{{Title: "t", URL: "https://example.com"}})
+			if tt.err != nil {
+				next = next.WithError(tt.err)
+			}
+			client := search.NewInstrumentedClient(next, "tavily", rec)
+
+			_, err := client.Search(context.Background(), search.SearchRequest{Query: "q"})
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("error = %v, want %v", err, tt.err)
+			}
+			if len(rec.statuses) != 1 || rec.statuses[0] != tt.want {
+				t.Errorf("recorded %v, want [%s]", rec.statuses, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+// Package local ищет по статьям из лент источников, собранным feeds.Ingester.
+// Полный текст вместо коротких сниппетов, и квоты внешнего API не тратятся
+package local
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/kitbuilder587/fintech-bot/internal/domain"
+	"github.com/kitbuilder587/fintech-bot/internal/search"
+)
+
+// ArticleSearcher - часть repository.ArticleRepository, нужная клиенту
+type ArticleSearcher interface {
+	SearchArticles(ctx context.Context, q domain.ArticleQuery) ([]domain.ArticleHit, error)
+}
+
+type Config struct {
+	MaxContent int // символов текста статьи в результате, 0 - 4000
+}
+
+type Client struct {
+	articles   ArticleSearcher
+	maxContent int
+	now        func() time.Time
+}
+
+func New(articles ArticleSearcher, cfg Config) *Client {
+	if cfg.MaxContent == 0 {
+		cfg.MaxContent = 4000
+	}
+	return &Client{
+		articles:   articles,
+		maxContent: cfg.MaxContent,
+		now:        time.Now,
+	}
+}
+
+// timeRanges - TimeRange в терминах Tavily -> глубина поиска по дате публикации
+var timeRanges = map[string]time.Duration{
+	"day": 24 * time.Hour, "d": 24 * time.Hour,
+	"week": 7 * 24 * time.Hour, "w": 7 * 24 * time.Hour,
+	"month": 30 * 24 * time.Hour, "m": 30 * 24 * time.Hour,
+	"year": 365 * 24 * time.Hour, "y": 365 * 24 * time.Hour,
+}
+
+func (c *Client) Search(ctx context.Context, req search.SearchRequest) (*search.SearchResponse, error) {
+	if req.MaxResults == 0 {
+		req.MaxResults = 5
+	}
+
+	start := c.now()
+	q := domain.ArticleQuery{
+		Text:    req.Query,
+		Domains: req.IncludeDomains,
+		// исключенные домены отсеиваются после запроса, берем с запасом
+		Limit: req.MaxResults + len(req.ExcludeDomains)*req.MaxResults,
+	}
+	if d, ok := timeRanges[req.TimeRange]; ok {
+		q.Since = start.Add(-d)
+	}
+
+	hits, err := c.articles.SearchArticles(ctx, q)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", search.ErrSearchFailed, err)
+	}
+
+	var results []search.SearchResult
+	for _, h := range hits {
+		if slices.Contains(req.ExcludeDomains, h.Domain) {
+			continue
+		}
+		if len(results) == req.MaxResults {
+			break
+		}
+		r := search.SearchResult{
+			Title:   h.Title,
+			URL:     h.URL,
+			Content: truncate(h.Content, c.maxContent),
+			Score:   h.Rank,
+		}
+		if !h.PublishedAt.IsZero() {
+			r.PublishedDate = h.PublishedAt.Format(time.DateOnly)
+		}
+		results = append(results, r)
+	}
+	if len(results) == 0 {
+		return nil, search.ErrEmptyResults
+	}
+	search.NormalizeScores(results)
+
+	return &search.SearchResponse{
+		Query:        req.Query,
+		Results:      results,
+		ResponseTime: c.now().Sub(start).Seconds(),
+	}, nil
+}
+
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n]) + "..."
+}
+
+var _ search.SearchClient = (*Client)(nil)
@@ -0,0 +1,117 @@
+package local
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kitbuilder587/fintech-bot/internal/domain"
+	"github.com/kitbuilder587/fintech-bot/internal/repository"
+	"github.com/kitbuilder587/fintech-bot/internal/search"
+)
+
+type failingSearcher struct{}
+
+func (failingSearcher) SearchArticles(context.Context, domain.ArticleQuery) ([]domain.ArticleHit, error) {
+	return nil, errors.New("connection refused")
+}
+
+func newRepo(t *testing.T) *repository.MockArticleRepository {
+	t.Helper()
+	repo := repository.NewMockArticleRepository()
+	now := time.Now()
+	repo.SaveArticles(context.Background(), []domain.Article{
+		{Domain: "mckinsey.com", URL: "https://mckinsey.com/bnpl", Title: "BNPL in Europe", Content: "Klarna and BNPL growth", PublishedAt: now.Add(-2 * time.Hour)},
+		{Domain: "bcg.com", URL: "https://bcg.com/bnpl", Title: "BNPL risks", Content: "BNPL credit risk", PublishedAt: now.AddDate(0, -2, 0)},
+		{Domain: "dealroom.co", URL: "https://dealroom.co/klarna", Title: "Klarna funding", Content: "Klarna raised"},
+	})
+	return repo
+}
+
+func TestClient_Search(t *testing.T) {
+	client := New(newRepo(t), Config{})
+
+	resp, err := client.Search(context.Background(), search.SearchRequest{Query: "klarna bnpl"})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+
+	if len(resp.Results) != 3 || resp.Results[0].URL != "https://mckinsey.com/bnpl" {
+		t.Fatalf("results = %+v, want best match first", resp.Results)
+	}
+	if resp.Results[0].Score != 1 || resp.Results[2].Score != 0.5 {
+		t.Errorf("scores = %v, %v; want normalized to 0..1", resp.Results[0].Score, resp.Results[2].Score)
+	}
+	if resp.Results[0].PublishedDate == "" || resp.Results[2].PublishedDate != "" {
+		t.Errorf("published dates = %q, %q", resp.Results[0].PublishedDate, resp.Results[2].PublishedDate)
+	}
+}
+
+func TestClient_Search_Filters(t *testing.T) {
+	client := New(newRepo(t), Config{})
+
+	tests := []struct {
+		name string
+		req  search.SearchRequest
+		want []string
+	}{
+		{
+			name: "include domains",
+			req:  search.SearchRequest{Query: "bnpl", IncludeDomains: []string{"bcg.com"}},
+			want: []string{"https://bcg.com/bnpl"},
+		},
+		{
+			name: "exclude domains",
+			req:  search.SearchRequest{Query: "bnpl", ExcludeDomains: []string{"mckinsey.com"}},
+			want: []string{"https://bcg.com/bnpl"},
+		},
+		{
+			name: "time range",
+			req:  search.SearchRequest{Query: "bnpl", TimeRange: "week"},
+			want: []string{"https://mckinsey.com/bnpl"},
+		},
+		{
+			name: "max results",
+			req:  search.SearchRequest{Query: "klarna", MaxResults: 1},
+			want: []string{"https://mckinsey.com/bnpl"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := client.Search(context.Background(), tt.req)
+			if err != nil {
+				t.Fatalf("Search() error = %v", err)
+			}
+			var got []string
+			for _, r := range resp.Results {
+				got = append(got, r.URL)
+			}
+			if len(got) != len(tt.want) || got[0] != tt.want[0] {
+				t.Errorf("results = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClient_Search_Errors(t *testing.T) {
+	if _, err := New(newRepo(t), Config{}).Search(context.Background(), search.SearchRequest{Query: "cbdc"}); !errors.Is(err, search.ErrEmptyResults) {
+		t.Errorf("no match error = %v, want ErrEmptyResults", err)
+	}
+	if _, err := New(failingSearcher{}, Config{}).Search(context.Background(), search.SearchRequest{Query: "q"}); !errors.Is(err, search.ErrSearchFailed) {
+		t.Errorf("store error = %v, want ErrSearchFailed", err)
+	}
+}
+
+func TestClient_Search_TruncatesContent(t *testing.T) {
+	client := New(newRepo(t), Config{MaxContent: 6})
+
+	resp, err := client.Search(context.Background(), search.SearchRequest{Query: "funding"})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if resp.Results[0].Content != "Klarna..." {
+		t.Errorf("Content = %q", resp.Results[0].Content)
+	}
+}
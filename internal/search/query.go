@@ -0,0 +1,78 @@
+package search
+
+import (
+	"strings"
+
+	"github.com/kitbuilder587/fintech-bot/internal/domain"
+)
+
+// SiteQuery дописывает к запросу операторы site: для движков без фильтра по доменам:
+// "q (site:a.com OR site:b.com) -site:c.com"
+func SiteQuery(query string, include, exclude []string) string {
+	var sb strings.Builder
+	sb.WriteString(query)
+
+	if len(include) > 0 {
+		sites := make([]string, len(include))
+		for i, d := range include {
+			sites[i] = "site:" + d
+		}
+		if len(sites) == 1 {
+			sb.WriteString(" " + sites[0])
+		} else {
+			sb.WriteString(" (" + strings.Join(sites, " OR ") + ")")
+		}
+	}
+	for _, d := range exclude {
+		sb.WriteString(" -site:" + d)
+	}
+
+	return sb.String()
+}
+
+// WithoutBlocked убирает результаты с заблокированных доменов и их поддоменов.
+// Нужен после поиска: не все движки понимают ExcludeDomains, и поддомены не исключает никто
+func WithoutBlocked(results []SearchResult, blocked []string) []SearchResult {
+	if len(blocked) == 0 {
+		return results
+	}
+	out := results[:0:0]
+	for _, r := range results {
+		if !domain.IsBlocked(r.URL, blocked) {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// NormalizeScores приводит оценки к 0..1 делением на максимальную. Если движок
+// оценок не дал, оценка считается по позиции: первый результат 1, дальше меньше
+func NormalizeScores(results []SearchResult) {
+	var maxScore float64
+	for _, r := range results {
+		maxScore = max(maxScore, r.Score)
+	}
+
+	for i := range results {
+		if maxScore > 0 {
+			results[i].Score /= maxScore
+		} else {
+			results[i].Score = 1 - float64(i)/float64(len(results))
+		}
+	}
+}
+
+// WithinScopes убирает результаты с сайтов источников, лежащие вне их разделов:
+// источник kpmg.com/xx/en/ не должен приводить всю выдачу kpmg.com. Сайты без источников не трогает
+func WithinScopes(results []SearchResult, scopes []domain.SourceScope) []SearchResult {
+	if len(scopes) == 0 {
+		return results
+	}
+	out := results[:0:0]
+	for _, r := range results {
+		if domain.InScope(r.URL, scopes) {
+			out = append(out, r)
+		}
+	}
+	return out
+}
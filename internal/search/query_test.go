@@ -0,0 +1,74 @@
+package search_test
+
+import (
+	"testing"
+
+	"github.com/kitbuilder587/fintech-bot/internal/domain"
+	"github.com/kitbuilder587/fintech-bot/internal/search"
+)
+
+func TestSiteQuery(t *testing.T) {
+	tests := []struct {
+		name             string
+		include, exclude []string
+		want             string
+	}{
+		{"no domains", nil, nil, "klarna"},
+		{"one domain", []string{"a.com"}, nil, "klarna site:a.com"},
+		{"several domains", []string{"a.com", "b.com"}, nil, "klarna (site:a.com OR site:b.com)"},
+		{"exclude", nil, []string{"c.com"}, "klarna -site:c.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := search.SiteQuery("klarna", tt.include, tt.exclude); got != tt.want {
+				t.Errorf("SiteQuery() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithoutBlocked(t *testing.T) {
+	results := []search.SearchResult{
+		{URL: "https://reddit.com/r/fintech"},
+		{URL: "https://old.reddit.com/r/banking"},
+		{URL: "https://notreddit.com/post"},
+		{URL: "https://www.mckinsey.com/report"},
+	}
+
+	got := search.WithoutBlocked(results, []string{"reddit.com"})
+	if len(got) != 2 || got[0].URL != "https://notreddit.com/post" || got[1].URL != "https://www.mckinsey.com/report" {
+		t.Errorf("WithoutBlocked() = %+v, want notreddit.com and mckinsey.com", got)
+	}
+	if len(results) != 4 || results[0].URL != "https://reddit.com/r/fintech" {
+		t.Errorf("WithoutBlocked() modified input: %+v", results)
+	}
+}
+
+func TestWithinScopes(t *testing.T) {
+	results := []search.SearchResult{
+		{URL: "https://kpmg.com/xx/en/industries/fintech.html"},
+		{URL: "https://kpmg.com/us/en/careers.html"},
+		{URL: "https://example.com/news"},
+	}
+	scopes := []domain.SourceScope{domain.ScopeOf("https://kpmg.com/xx/en/industries/financial-services.html")}
+
+	got := search.WithinScopes(results, scopes)
+	if len(got) != 2 || got[0].URL != results[0].URL || got[1].URL != results[2].URL {
+		t.Errorf("WithinScopes() = %+v, want kpmg section and unrelated site", got)
+	}
+}
+
+func TestNormalizeScores(t *testing.T) {
+	scored := []search.SearchResult{{Score: 12}, {Score: 3}}
+	search.NormalizeScores(scored)
+	if scored[0].Score != 1 || scored[1].Score != 0.25 {
+		t.Errorf("scores = %v, %v; want 1 and 0.25", scored[0].Score, scored[1].Score)
+	}
+
+	unscored := make([]search.SearchResult, 4)
+	search.NormalizeScores(unscored)
+	if unscored[0].Score != 1 || unscored[3].Score != 0.25 {
+		t.Errorf("rank scores = %+v, want 1 down to 0.25", unscored)
+	}
+}
@@ -0,0 +1,61 @@
+package search
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// форматы PublishedDate: Tavily отдает RFC1123, SearXNG и Brave - ISO без зоны,
+// local - просто дату
+var publishedLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	time.DateOnly,
+	time.RFC1123,
+	time.RFC1123Z,
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+}
+
+// undatedDecay - множитель для результата без даты: справочные страницы часто
+// без даты и бывают полезны, но свежая датированная публикация должна их обгонять
+const undatedDecay = 0.5
+
+// Published разбирает PublishedDate. false - даты нет или формат незнакомый
+func (r SearchResult) Published() (time.Time, bool) {
+	s := strings.TrimSpace(r.PublishedDate)
+	if s == "" {
+		return time.Time{}, false
+	}
+	for _, layout := range publishedLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// DecayByAge умножает оценки на 2^(-возраст/halfLife), пересортировывает
+// и снова приводит к 0..1. Результат без даты получает undatedDecay
+func DecayByAge(results []SearchResult, halfLife time.Duration, now time.Time) {
+	if halfLife <= 0 || len(results) == 0 {
+		return
+	}
+
+	for i := range results {
+		decay := undatedDecay
+		if published, ok := results[i].Published(); ok {
+			age := max(now.Sub(published), 0) // дата из будущего - часовой пояс или ошибка сайта
+			decay = math.Exp2(-float64(age) / float64(halfLife))
+		}
+		results[i].Score *= decay
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+	NormalizeScores(results)
+}
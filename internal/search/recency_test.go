@@ -0,0 +1,61 @@
+package search_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kitbuilder587/fintech-bot/internal/search"
+)
+
+func TestSearchResult_Published(t *testing.T) {
+	want := time.Date(2025, 3, 14, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		date string
+		ok   bool
+	}{
+		{"2025-03-14", true},
+		{"2025-03-14T00:00:00", true},
+		{"2025-03-14T00:00:00Z", true},
+		{"Fri, 14 Mar 2025 00:00:00 GMT", true},
+		{"", false},
+		{"3 days ago", false},
+	}
+	for _, tt := range tests {
+		got, ok := search.SearchResult{PublishedDate: tt.date}.Published()
+		if ok != tt.ok || (ok && !got.Equal(want)) {
+			t.Errorf("Published(%q) = %v, %v; want %v, %v", tt.date, got, ok, want, tt.ok)
+		}
+	}
+}
+
+func TestDecayByAge(t *testing.T) {
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+
+	results := []search.SearchResult{
+		{URL: "old", Score: 1, PublishedDate: now.Add(-730 * day).Format(time.DateOnly)},
+		{URL: "undated", Score: 0.9},
+		{URL: "fresh", Score: 0.8, PublishedDate: now.Add(-7 * day).Format(time.DateOnly)},
+	}
+
+	search.DecayByAge(results, 180*day, now)
+
+	order := []string{results[0].URL, results[1].URL, results[2].URL}
+	if order[0] != "fresh" || order[1] != "undated" || order[2] != "old" {
+		t.Errorf("order = %v, want fresh, undated, old", order)
+	}
+	if results[0].Score != 1 {
+		t.Errorf("top score = %v, want normalized to 1", results[0].Score)
+	}
+}
+
+func TestDecayByAge_Disabled(t *testing.T) {
+	results := []search.SearchResult{{URL: "a", Score: 0.3}, {URL: "b", Score: 0.9}}
+
+	search.DecayByAge(results, 0, time.Now())
+
+	if results[0].URL != "a" || results[0].Score != 0.3 {
+		t.Errorf("results changed with zero half-life: %+v", results)
+	}
+}
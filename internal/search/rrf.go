@@ -0,0 +1,83 @@
+package search
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/kitbuilder587/fintech-bot/internal/domain"
+)
+
+// RRFK - константа k из reciprocal rank fusion (Cormack et al.): сглаживает разницу
+// между первыми местами, чтобы один список не перевешивал остальные
+const RRFK = 60
+
+// CanonicalURL приводит URL к виду для поиска дублей: без схемы, www, фрагмента,
+// utm-меток и завершающего слеша. Разобрать не удалось - URL как есть
+func CanonicalURL(raw string) string {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || u.Host == "" {
+		return raw
+	}
+
+	host := domain.Host(raw)
+
+	q := u.Query()
+	for key := range q {
+		if strings.HasPrefix(key, "utm_") {
+			q.Del(key)
+		}
+	}
+
+	canonical := host + strings.TrimRight(u.EscapedPath(), "/")
+	if len(q) > 0 {
+		canonical += "?" + q.Encode() // Encode сортирует параметры
+	}
+	return canonical
+}
+
+// FuseRRF объединяет ранжированные списки разных запросов или поисковиков.
+// Оценки движков между собой несравнимы, поэтому важно только место в каждом списке:
+// score = сумма 1/(k+rank). Дубли по CanonicalURL склеиваются, итоговые оценки
+// приведены к 0..1. При равных оценках порядок - как в первом списке
+func FuseRRF(lists [][]SearchResult, k int) []SearchResult {
+	if k <= 0 {
+		k = RRFK
+	}
+
+	var fused []SearchResult
+	var scores []float64
+	index := make(map[string]int)
+
+	for _, list := range lists {
+		for rank, r := range list {
+			key := CanonicalURL(r.URL)
+			i, ok := index[key]
+			if !ok {
+				i = len(fused)
+				index[key] = i
+				fused = append(fused, r)
+				scores = append(scores, 0)
+			} else {
+				// дубль может принести то, чего не было в первой выдаче
+				if fused[i].Content == "" {
+					fused[i].Content = r.Content
+				}
+				if fused[i].PublishedDate == "" {
+					fused[i].PublishedDate = r.PublishedDate
+				}
+			}
+			scores[i] += 1 / float64(k+rank+1)
+		}
+	}
+
+	for i := range fused {
+		fused[i].Score = scores[i]
+	}
+	sort.SliceStable(fused, func(i, j int) bool {
+		return fused[i].Score > fused[j].Score
+	})
+	NormalizeScores(fused)
+
+	return fused
+}
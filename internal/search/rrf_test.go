@@ -0,0 +1,57 @@
+package search_test
+
+import (
+	"testing"
+
+	"github.com/kitbuilder587/fintech-bot/internal/search"
+)
+
+func TestCanonicalURL(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"https://www.Example.com/path/", "example.com/path"},
+		{"http://example.com/path#section", "example.com/path"},
+		{"https://example.com/a?utm_source=x&b=2&a=1", "example.com/a?a=1&b=2"},
+		{"not a url", "not a url"},
+	}
+
+	for _, tt := range tests {
+		if got := search.CanonicalURL(tt.in); got != tt.want {
+			t.Errorf("CanonicalURL(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestFuseRRF(t *testing.T) {
+	// у tavily оценки около 0.9, у searxng - единицы: сравнивать их напрямую нельзя
+	tavily := []search.SearchResult{
+		{URL: "https://a.com", Score: 0.9},
+		{URL: "https://b.com", Score: 0.8, Content: "b"},
+	}
+	searxng := []search.SearchResult{
+		{URL: "https://www.b.com/", Score: 7, PublishedDate: "2025-01-01"},
+		{URL: "https://c.com", Score: 5},
+	}
+
+	fused := search.FuseRRF([][]search.SearchResult{tavily, searxng}, search.RRFK)
+
+	if len(fused) != 3 {
+		t.Fatalf("fused = %+v, want 3 results after merging b.com", fused)
+	}
+	// b.com есть в обоих списках и обгоняет лидеров каждого
+	if fused[0].URL != "https://b.com" || fused[0].Score != 1 {
+		t.Errorf("top = %+v, want b.com with score 1", fused[0])
+	}
+	if fused[0].Content != "b" || fused[0].PublishedDate != "2025-01-01" {
+		t.Errorf("merged duplicate = %+v, want fields from both lists", fused[0])
+	}
+	if fused[1].URL != "https://a.com" || fused[2].URL != "https://c.com" {
+		t.Errorf("order = %s, %s; want a.com before c.com on equal rank", fused[1].URL, fused[2].URL)
+	}
+	for _, r := range fused {
+		if r.Score <= 0 || r.Score > 1 {
+			t.Errorf("score %v out of 0..1", r.Score)
+		}
+	}
+}
@@ -3,6 +3,10 @@ package search
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/kitbuilder587/fintech-bot/internal/domain"
 )
 
 var (
@@ -39,4 +43,23 @@ type SearchResult struct {
 	Content       string
 	Score         float64
 	PublishedDate string
+	// доверие пользователя к домену; ставит QueryService, поисковики не заполняют
+	Trust domain.TrustLevel
+	// страницы PDF, попавшие в Content; заполняет fetch, у HTML пусто
+	Pages []DocPage
+}
+
+// DocPage - страница документа с номером как в самом файле, начиная с 1
+type DocPage struct {
+	Number int
+	Text   string
+}
+
+// PagesContent - текст страниц с пометками [p.N], на которые модель ссылается как [S1 p.12]
+func PagesContent(pages []DocPage) string {
+	parts := make([]string, len(pages))
+	for i, p := range pages {
+		parts[i] = fmt.Sprintf("[p.%d] %s", p.Number, p.Text)
+	}
+	return strings.Join(parts, "\n\n")
 }
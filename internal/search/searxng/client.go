@@ -0,0 +1,162 @@
+// Package searxng - клиент для своего инстанса SearXNG. В settings.yml инстанса
+// должен быть включен формат json (search.formats), иначе он отвечает 403
+package searxng
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/kitbuilder587/fintech-bot/internal/retry"
+	"github.com/kitbuilder587/fintech-bot/internal/search"
+)
+
+type Config struct {
+	BaseURL string        // например http://searxng:8080
+	APIKey  string        // для инстанса за прокси с авторизацией, уходит в Authorization
+	Timeout time.Duration // на одну попытку
+	Retry   retry.Policy  // пустая - retry.DefaultPolicy()
+}
+
+type Client struct {
+	baseURL string
+	apiKey  string
+	client  *retry.Client
+	logger  *zap.Logger
+}
+
+func New(cfg Config, logger *zap.Logger) *Client {
+	if cfg.Timeout == 0 {
+		cfg.Timeout = 30 * time.Second
+	}
+	if cfg.Retry == (retry.Policy{}) {
+		cfg.Retry = retry.DefaultPolicy()
+	}
+
+	return &Client{
+		baseURL: strings.TrimRight(cfg.BaseURL, "/"),
+		apiKey:  cfg.APIKey,
+		client:  retry.NewClient(&http.Client{Timeout: cfg.Timeout}, cfg.Retry),
+		logger:  logger,
+	}
+}
+
+type searxngResponse struct {
+	Query   string          `json:"query"`
+	Results []searxngResult `json:"results"`
+}
+
+type searxngResult struct {
+	Title         string  `json:"title"`
+	URL           string  `json:"url"`
+	Content       string  `json:"content"`
+	Score         float64 `json:"score"`
+	PublishedDate string  `json:"publishedDate"`
+}
+
+// timeRanges - TimeRange в терминах Tavily -> time_range SearXNG
+var timeRanges = map[string]string{
+	"day": "day", "d": "day",
+	"week": "week", "w": "week",
+	"month": "month", "m": "month",
+	"year": "year", "y": "year",
+}
+
+func (c *Client) Search(ctx context.Context, req search.SearchRequest) (*search.SearchResponse, error) {
+	if req.MaxResults == 0 {
+		req.MaxResults = 5
+	}
+
+	params := url.Values{}
+	params.Set("q", search.SiteQuery(req.Query, req.IncludeDomains, req.ExcludeDomains))
+	params.Set("format", "json")
+	if tr, ok := timeRanges[req.TimeRange]; ok {
+		params.Set("time_range", tr)
+	}
+	if req.Topic == "news" {
+		params.Set("categories", "news")
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/search?"+params.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	httpReq.Header.Set("Accept", "application/json")
+	if c.apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+
+	start := time.Now()
+	// сетевые ошибки, 429 и 5xx повторяет retry.Client
+	resp, err := c.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("%w: do request: %w", search.ErrSearchFailed, err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("%w: read response: %v", search.ErrSearchFailed, err)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		var sxResp searxngResponse
+		if err := json.Unmarshal(respBody, &sxResp); err != nil {
+			return nil, fmt.Errorf("unmarshal response: %w", err)
+		}
+
+		if len(sxResp.Results) == 0 {
+			return nil, search.ErrEmptyResults
+		}
+
+		out := toSearchResponse(&sxResp, req.MaxResults)
+		out.Query = req.Query
+		out.ResponseTime = time.Since(start).Seconds()
+		return out, nil
+
+	case http.StatusUnauthorized:
+		return nil, search.ErrUnauthorized
+
+	case http.StatusForbidden:
+		// так SearXNG отвечает, когда формат json выключен
+		return nil, fmt.Errorf("%w: status 403, is json format enabled on the instance?", search.ErrUnauthorized)
+
+	case http.StatusTooManyRequests:
+		return nil, search.ErrRateLimit
+
+	case http.StatusBadRequest:
+		return nil, search.ErrInvalidRequest
+
+	default:
+		return nil, fmt.Errorf("%w: status %d", search.ErrSearchFailed, resp.StatusCode)
+	}
+}
+
+// toSearchResponse - у SearXNG нет max_results, лишнее отрезаем сами.
+// Оценки у него ничем не ограничены, приводим к 0..1
+func toSearchResponse(resp *searxngResponse, maxResults int) *search.SearchResponse {
+	n := min(len(resp.Results), maxResults)
+	results := make([]search.SearchResult, n)
+	for i, r := range resp.Results[:n] {
+		results[i] = search.SearchResult{
+			Title:         r.Title,
+			URL:           r.URL,
+			Content:       r.Content,
+			Score:         r.Score,
+			PublishedDate: r.PublishedDate,
+		}
+	}
+	search.NormalizeScores(results)
+
+	return &search.SearchResponse{Results: results}
+}
+
+var _ search.SearchClient = (*Client)(nil)
@@ -0,0 +1,187 @@
+package searxng
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/kitbuilder587/fintech-bot/internal/retry"
+	"github.com/kitbuilder587/fintech-bot/internal/search"
+)
+
+func TestClient_Search(t *testing.T) {
+	logger := zap.NewNop()
+
+	tests := []struct {
+		name       string
+		response   interface{}
+		statusCode int
+		wantErr    error
+	}{
+		{
+			name: "successful search",
+			response: searxngResponse{
+				Query: "test query",
+				Results: []searxngResult{
+					{Title: "Test", URL: "https://example.com", Content: "Content", Score: 4.5},
+				},
+			},
+			statusCode: http.StatusOK,
+			wantErr:    nil,
+		},
+		{
+			name:       "empty results",
+			response:   searxngResponse{Query: "test query", Results: []searxngResult{}},
+			statusCode: http.StatusOK,
+			wantErr:    search.ErrEmptyResults,
+		},
+		{
+			name:       "json format disabled",
+			response:   map[string]string{"error": "forbidden"},
+			statusCode: http.StatusForbidden,
+			wantErr:    search.ErrUnauthorized,
+		},
+		{
+			name:       "rate limit",
+			response:   map[string]string{"error": "rate limit"},
+			statusCode: http.StatusTooManyRequests,
+			wantErr:    search.ErrRateLimit,
+		},
+		{
+			name:       "server error",
+			response:   map[string]string{"error": "boom"},
+			statusCode: http.StatusInternalServerError,
+			wantErr:    search.ErrSearchFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(tt.statusCode)
+				json.NewEncoder(w).Encode(tt.response)
+			}))
+			defer server.Close()
+
+			client := New(Config{
+				BaseURL: server.URL,
+				Timeout: 5 * time.Second,
+				Retry:   retry.Policy{MaxAttempts: 1},
+			}, logger)
+
+			resp, err := client.Search(context.Background(), search.SearchRequest{
+				Query:      "test query",
+				MaxResults: 5,
+			})
+
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("Search() error = %v, wantErr %v", err, tt.wantErr)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Search() unexpected error = %v", err)
+			}
+			if len(resp.Results) != 1 || resp.Results[0].Score != 1 {
+				t.Errorf("Search() results = %+v, want one result with score 1", resp.Results)
+			}
+		})
+	}
+}
+
+func TestClient_Search_RequestParams(t *testing.T) {
+	var got *http.Request
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		json.NewEncoder(w).Encode(searxngResponse{
+			Results: []searxngResult{{Title: "Test", URL: "https://mckinsey.com", Content: "Content"}},
+		})
+	}))
+	defer server.Close()
+
+	client := New(Config{BaseURL: server.URL + "/"}, zap.NewNop())
+
+	_, err := client.Search(context.Background(), search.SearchRequest{
+		Query:          "fintech",
+		IncludeDomains: []string{"mckinsey.com", "gartner.com"},
+		TimeRange:      "week",
+	})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+
+	q := got.URL.Query()
+	if got.URL.Path != "/search" || q.Get("format") != "json" {
+		t.Errorf("request = %s, want /search with format=json", got.URL)
+	}
+	if want := "fintech (site:mckinsey.com OR site:gartner.com)"; q.Get("q") != want {
+		t.Errorf("q = %q, want %q", q.Get("q"), want)
+	}
+	if q.Get("time_range") != "week" {
+		t.Errorf("time_range = %q, want week", q.Get("time_range"))
+	}
+}
+
+func TestClient_Search_LimitsAndNormalizesScores(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(searxngResponse{Results: []searxngResult{
+			{Title: "A", URL: "https://a.com", Score: 8},
+			{Title: "B", URL: "https://b.com", Score: 2},
+			{Title: "C", URL: "https://c.com", Score: 1},
+		}})
+	}))
+	defer server.Close()
+
+	client := New(Config{BaseURL: server.URL}, zap.NewNop())
+
+	resp, err := client.Search(context.Background(), search.SearchRequest{Query: "q", MaxResults: 2})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+
+	if len(resp.Results) != 2 {
+		t.Fatalf("results = %d, want MaxResults = 2", len(resp.Results))
+	}
+	if resp.Results[0].Score != 1 || resp.Results[1].Score != 0.25 {
+		t.Errorf("scores = %v, %v; want 1 and 0.25", resp.Results[0].Score, resp.Results[1].Score)
+	}
+}
+
+func TestClient_Search_RetriesServerErrors(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		json.NewEncoder(w).Encode(searxngResponse{
+			Results: []searxngResult{{Title: "Test", URL: "https://example.com", Content: "Content"}},
+		})
+	}))
+	defer server.Close()
+
+	client := New(Config{
+		BaseURL: server.URL,
+		Timeout: 5 * time.Second,
+		Retry:   retry.Policy{MaxAttempts: 4, BaseDelay: time.Millisecond},
+	}, zap.NewNop())
+
+	resp, err := client.Search(context.Background(), search.SearchRequest{Query: "test"})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if len(resp.Results) != 1 || calls != 3 {
+		t.Errorf("results = %d, calls = %d, want 1 result after 3 calls", len(resp.Results), calls)
+	}
+}
@@ -11,19 +11,21 @@ import (
 
 	"go.uber.org/zap"
 
+	"github.com/kitbuilder587/fintech-bot/internal/retry"
 	"github.com/kitbuilder587/fintech-bot/internal/search"
 )
 
 type Config struct {
 	APIKey  string
 	BaseURL string
-	Timeout time.Duration
+	Timeout time.Duration // на одну попытку
+	Retry   retry.Policy  // пустая - 4 попытки с паузами около 1, 2 и 4 секунд
 }
 
 type Client struct {
 	apiKey  string
 	baseURL string
-	client  *http.Client
+	client  *retry.Client
 	logger  *zap.Logger
 }
 
@@ -34,11 +36,18 @@ func New(cfg Config, logger *zap.Logger) *Client {
 	if cfg.Timeout == 0 {
 		cfg.Timeout = 30 * time.Second
 	}
+	if cfg.Retry == (retry.Policy{}) {
+		cfg.Retry = retry.Policy{
+			MaxAttempts: 4,
+			BaseDelay:   time.Second,
+			MaxDelay:    4 * time.Second,
+		}
+	}
 
 	return &Client{
 		apiKey:  cfg.APIKey,
 		baseURL: cfg.BaseURL,
-		client:  &http.Client{Timeout: cfg.Timeout},
+		client:  retry.NewClient(&http.Client{Timeout: cfg.Timeout}, cfg.Retry),
 		logger:  logger,
 	}
 }
@@ -96,72 +105,49 @@ func (c *Client) Search(ctx context.Context, req search.SearchRequest) (*search.
 		return nil, fmt.Errorf("marshal request: %w", err)
 	}
 
-	var lastErr error
-	backoff := []time.Duration{1 * time.Second, 2 * time.Second, 4 * time.Second}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/search", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
 
-	for attempt := 0; attempt <= len(backoff); attempt++ {
-		if attempt > 0 {
-			select {
-			case <-ctx.Done():
-				return nil, ctx.Err()
-			case <-time.After(backoff[attempt-1]):
-			}
-		}
+	// сетевые ошибки, 429 и 5xx повторяет retry.Client
+	resp, err := c.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("%w: do request: %w", search.ErrSearchFailed, err)
+	}
+	defer resp.Body.Close()
 
-		httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/search", bytes.NewReader(body))
-		if err != nil {
-			return nil, fmt.Errorf("create request: %w", err)
-		}
-		httpReq.Header.Set("Content-Type", "application/json")
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("%w: read response: %v", search.ErrSearchFailed, err)
+	}
 
-		resp, err := c.client.Do(httpReq)
-		if err != nil {
-			lastErr = fmt.Errorf("do request: %w", err)
-			continue
+	switch resp.StatusCode {
+	case http.StatusOK:
+		var tavilyResp tavilyResponse
+		if err := json.Unmarshal(respBody, &tavilyResp); err != nil {
+			return nil, fmt.Errorf("unmarshal response: %w", err)
 		}
 
-		respBody, err := io.ReadAll(resp.Body)
-		resp.Body.Close()
-		if err != nil {
-			lastErr = fmt.Errorf("read response: %w", err)
-			continue
+		if len(tavilyResp.Results) == 0 {
+			return nil, search.ErrEmptyResults
 		}
 
-		switch resp.StatusCode {
-		case http.StatusOK:
-			var tavilyResp tavilyResponse
-			if err := json.Unmarshal(respBody, &tavilyResp); err != nil {
-				return nil, fmt.Errorf("unmarshal response: %w", err)
-			}
-
-			if len(tavilyResp.Results) == 0 {
-				return nil, search.ErrEmptyResults
-			}
-
-			return c.toSearchResponse(&tavilyResp), nil
+		return c.toSearchResponse(&tavilyResp), nil
 
-		case http.StatusUnauthorized:
-			return nil, search.ErrUnauthorized
+	case http.StatusUnauthorized:
+		return nil, search.ErrUnauthorized
 
-		case http.StatusTooManyRequests:
-			return nil, search.ErrRateLimit
+	case http.StatusTooManyRequests:
+		return nil, search.ErrRateLimit
 
-		case http.StatusBadRequest:
-			return nil, search.ErrInvalidRequest
-
-		default:
-			if resp.StatusCode >= 500 {
-				lastErr = fmt.Errorf("server error: %d", resp.StatusCode)
-				continue
-			}
-			return nil, fmt.Errorf("%w: status %d", search.ErrSearchFailed, resp.StatusCode)
-		}
-	}
+	case http.StatusBadRequest:
+		return nil, search.ErrInvalidRequest
 
-	if lastErr != nil {
-		return nil, fmt.Errorf("%w: %v", search.ErrSearchFailed, lastErr)
+	default:
+		return nil, fmt.Errorf("%w: status %d", search.ErrSearchFailed, resp.StatusCode)
 	}
-	return nil, search.ErrSearchFailed
 }
 
 func (c *Client) toSearchResponse(resp *tavilyResponse) *search.SearchResponse {
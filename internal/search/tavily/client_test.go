@@ -10,6 +10,7 @@ import (
 
 	"go.uber.org/zap"
 
+	"github.com/kitbuilder587/fintech-bot/internal/retry"
 	"github.com/kitbuilder587/fintech-bot/internal/search"
 )
 
@@ -76,6 +77,7 @@ func TestClient_Search(t *testing.T) {
 				APIKey:  "test-key",
 				BaseURL: server.URL,
 				Timeout: 5 * time.Second,
+				Retry:   retry.Policy{MaxAttempts: 1},
 			}, logger)
 
 			req := search.SearchRequest{
@@ -164,3 +166,33 @@ func TestClient_Search_Timeout(t *testing.T) {
 		t.Error("Search() expected timeout error")
 	}
 }
+
+func TestClient_Search_RetriesServerErrors(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		json.NewEncoder(w).Encode(tavilyResponse{
+			Results: []tavilyResult{{Title: "Test", URL: "https://example.com", Content: "Content"}},
+		})
+	}))
+	defer server.Close()
+
+	client := New(Config{
+		APIKey:  "test-key",
+		BaseURL: server.URL,
+		Timeout: 5 * time.Second,
+		Retry:   retry.Policy{MaxAttempts: 4, BaseDelay: time.Millisecond},
+	}, zap.NewNop())
+
+	resp, err := client.Search(context.Background(), search.SearchRequest{Query: "test"})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if len(resp.Results) != 1 || calls != 3 {
+		t.Errorf("results = %d, calls = %d, want 1 result after 3 calls", len(resp.Results), calls)
+	}
+}
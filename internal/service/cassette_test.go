@@ -0,0 +1,115 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/kitbuilder587/fintech-bot/internal/agent"
+	"github.com/kitbuilder587/fintech-bot/internal/cache/memory"
+	"github.com/kitbuilder587/fintech-bot/internal/cassette"
+	"github.com/kitbuilder587/fintech-bot/internal/domain"
+	"github.com/kitbuilder587/fintech-bot/internal/llm"
+	"github.com/kitbuilder587/fintech-bot/internal/repository"
+	"github.com/kitbuilder587/fintech-bot/internal/search"
+	searchMock "github.com/kitbuilder587/fintech-bot/internal/search/mock"
+)
+
+// stageLLM отвечает по стадии, как ответила бы настоящая модель
+type stageLLM struct{}
+
+func (stageLLM) CompleteWithSystem(ctx context.Context, system, prompt string) (string, error) {
+	stage := llm.StageFrom(ctx)
+	switch {
+	case stage == llm.StageExpand:
+		return `{"queries": ["klarna ipo 2025", "bnpl regulation europe"]}`, nil
+	case stage == llm.StageCritic:
+		return `{"approved": true, "issues": [], "suggestions": [], "confidence": 0.9}`, nil
+	case stage == llm.StageExtraction:
+		return `{"facts": [{"content": "Klarna filed for IPO", "source_url": "https://example.com/klarna", "confidence": 0.8}],
+			"entities": [{"name": "Klarna", "type": "company", "attributes": {"country": "Sweden"}}]}`, nil
+	case stage == llm.StageSynthesize:
+		return "Synthesized: Klarna is going public [S1]", nil
+	case strings.HasPrefix(stage, "agent"):
+		return stage + " view on Klarna [S1]", nil
+	default:
+		return "Klarna answer [S1]", nil
+	}
+}
+
+// deepPipeline собирает QueryService со всеми стадиями, как в cmd/bot
+func deepPipeline(client llm.Client, searcher search.SearchClient) (QueryService, *repository.MockWorldModelRepository) {
+	logger := zap.NewNop()
+	sources := repository.NewMockSourceRepository()
+	sources.Create(context.Background(), &domain.Source{UserID: 1, URL: "https://example.com", Name: "Example"})
+	worldRepo := repository.NewMockWorldModelRepository()
+
+	return NewQueryService(QueryServiceDeps{
+		Sources:      sources,
+		LLM:          client,
+		Search:       searcher,
+		Cache:        memory.New(),
+		Logger:       logger,
+		Critic:       NewCriticService(client, logger, domain.CriticConfig{MaxRetries: 1}),
+		CriticConfig: domain.CriticConfig{MaxRetries: 1},
+		WorldModel:   NewWorldModelService(worldRepo, client, logger),
+		Coordinator:  NewCoordinatorAdapter(agent.NewCoordinator(agent.NewAllAgents(client, logger), client, logger)),
+		Config: QueryConfig{
+			MaxSearchQueries:   3,
+			MaxResultsPerQuery: 5,
+			CacheTTL:           time.Hour,
+			SearchTimeout:      10 * time.Second,
+		},
+	}), worldRepo
+}
+
+func runDeep(t *testing.T, svc QueryService) string {
+	t.Helper()
+	resp, err := svc.Process(context.Background(), &domain.QueryRequest{
+		UserID: 1, Text: "Klarna IPO market and regulation outlook", Strategy: domain.DeepStrategy(),
+	})
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	if err := svc.(BackgroundWaiter).WaitBackground(context.Background()); err != nil {
+		t.Fatalf("WaitBackground() error = %v", err)
+	}
+	return resp.Text
+}
+
+func TestQueryService_DeepReplayFromCassette(t *testing.T) {
+	dir := t.TempDir()
+
+	rec, err := cassette.Open(dir, cassette.ModeRecord)
+	if err != nil {
+		t.Fatalf("Open(record) error = %v", err)
+	}
+	searcher := searchMock.New()
+	searcher.Results = []search.SearchResult{{Title: "Klarna IPO", URL: "https://example.com/klarna", Content: "Klarna filed for IPO"}}
+	recorded, _ := deepPipeline(rec.LLM(stageLLM{}), rec.Search(searcher))
+	want := runDeep(t, recorded)
+
+	play, err := cassette.Open(dir, cassette.ModeReplay)
+	if err != nil {
+		t.Fatalf("Open(replay) error = %v", err)
+	}
+	// при воспроизведении настоящих клиентов нет вовсе
+	replayed, worldRepo := deepPipeline(play.LLM(nil), play.Search(nil))
+	got := runDeep(t, replayed)
+
+	if misses := play.Misses(); len(misses) != 0 {
+		t.Fatalf("replay missed %d recordings: %v", len(misses), misses)
+	}
+	if !strings.HasPrefix(want, "Synthesized") {
+		t.Fatalf("recorded answer = %q, want coordinator synthesis", want)
+	}
+	if got != want {
+		t.Errorf("replayed answer = %q, want recorded %q", got, want)
+	}
+	if facts, _ := worldRepo.GetFactsByUser(context.Background(), 1, 10); len(facts) == 0 {
+		t.Error("extraction was not replayed: no facts stored")
+	}
+}
@@ -0,0 +1,68 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"go.uber.org/zap"
+
+	"github.com/kitbuilder587/fintech-bot/internal/domain"
+	"github.com/kitbuilder587/fintech-bot/internal/repository"
+)
+
+type ConversationService interface {
+	Remember(ctx context.Context, turn *domain.ConversationTurn) error
+	// Thread возвращает цепочку ходов, которая заканчивается ответом с сообщением messageID,
+	// от старых к новым и не длиннее domain.MaxConversationTurns. Nil если это не ответ бота
+	Thread(ctx context.Context, chatID int64, messageID int) ([]domain.ConversationTurn, error)
+}
+
+type conversationService struct {
+	repo   repository.ConversationRepository
+	logger *zap.Logger
+}
+
+func NewConversationService(repo repository.ConversationRepository, logger *zap.Logger) ConversationService {
+	return &conversationService{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+func (s *conversationService) Remember(ctx context.Context, turn *domain.ConversationTurn) error {
+	if len(turn.MessageIDs) == 0 {
+		// без id сообщений на ответ нельзя ответить реплаем, хранить незачем
+		return nil
+	}
+	return s.repo.SaveTurn(ctx, turn)
+}
+
+func (s *conversationService) Thread(ctx context.Context, chatID int64, messageID int) ([]domain.ConversationTurn, error) {
+	turn, err := s.repo.GetTurnByMessage(ctx, chatID, messageID)
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	thread := []domain.ConversationTurn{*turn}
+	for parentID := turn.ParentID; parentID != 0 && len(thread) < domain.MaxConversationTurns; {
+		parent, err := s.repo.GetTurn(ctx, parentID)
+		if errors.Is(err, domain.ErrNotFound) {
+			// старые ходы чистятся, цепочка просто обрывается
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		thread = append(thread, *parent)
+		parentID = parent.ParentID
+	}
+
+	// собирали от нового к старому
+	for i, j := 0, len(thread)-1; i < j; i, j = i+1, j-1 {
+		thread[i], thread[j] = thread[j], thread[i]
+	}
+	return thread, nil
+}
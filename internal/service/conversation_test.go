@@ -0,0 +1,67 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/kitbuilder587/fintech-bot/internal/domain"
+	"github.com/kitbuilder587/fintech-bot/internal/repository"
+)
+
+func TestConversationService_Thread(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMockConversationRepository()
+	svc := NewConversationService(repo, zap.NewNop())
+
+	// цепочка из четырех уточнений, в контекст попадают только последние три
+	var parentID int64
+	for i, q := range []string{"q1", "q2", "q3", "q4"} {
+		turn := &domain.ConversationTurn{ChatID: 1, UserID: 1, ParentID: parentID, MessageIDs: []int{10 + i}, Question: q}
+		if err := svc.Remember(ctx, turn); err != nil {
+			t.Fatalf("Remember() error = %v", err)
+		}
+		parentID = turn.ID
+	}
+
+	thread, err := svc.Thread(ctx, 1, 13)
+	if err != nil {
+		t.Fatalf("Thread() error = %v", err)
+	}
+	var questions []string
+	for _, turn := range thread {
+		questions = append(questions, turn.Question)
+	}
+	if len(questions) != domain.MaxConversationTurns || questions[0] != "q2" || questions[len(questions)-1] != "q4" {
+		t.Errorf("Thread() questions = %v, want [q2 q3 q4]", questions)
+	}
+
+	thread, err = svc.Thread(ctx, 1, 11)
+	if err != nil || len(thread) != 2 || thread[0].Question != "q1" {
+		t.Errorf("Thread() for middle answer = %v, %v; want [q1 q2]", thread, err)
+	}
+}
+
+func TestConversationService_ThreadUnknownMessage(t *testing.T) {
+	svc := NewConversationService(repository.NewMockConversationRepository(), zap.NewNop())
+
+	thread, err := svc.Thread(context.Background(), 1, 42)
+	if err != nil || thread != nil {
+		t.Errorf("Thread() = %v, %v; want nil, nil for a message that is not a bot answer", thread, err)
+	}
+}
+
+func TestConversationService_RememberWithoutMessages(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMockConversationRepository()
+	svc := NewConversationService(repo, zap.NewNop())
+
+	turn := &domain.ConversationTurn{ChatID: 1, UserID: 1, Question: "q"}
+	if err := svc.Remember(ctx, turn); err != nil {
+		t.Fatalf("Remember() error = %v", err)
+	}
+	if turn.ID != 0 {
+		t.Error("turn without message ids should not be stored")
+	}
+}
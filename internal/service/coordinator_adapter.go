@@ -22,6 +22,9 @@ func (a *CoordinatorAdapter) Process(ctx context.Context, req AgentCoordinatorRe
 		SearchResults: req.SearchResults,
 		Context:       req.Context,
 		Strategy:      req.Strategy,
+		Domains:       req.Domains,
+		Exclude:       req.Exclude,
+		Scopes:        req.Scopes,
 	}
 
 	resp, err := a.coordinator.Process(ctx, agentReq)
@@ -30,7 +33,8 @@ func (a *CoordinatorAdapter) Process(ctx context.Context, req AgentCoordinatorRe
 	}
 
 	return &CoordinatorResponse{
-		FinalAnswer: resp.FinalAnswer,
-		AgentsUsed:  resp.AgentsUsed,
+		FinalAnswer:   resp.FinalAnswer,
+		AgentsUsed:    resp.AgentsUsed,
+		SearchResults: resp.SearchResults,
 	}, nil
 }
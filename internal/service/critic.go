@@ -2,35 +2,19 @@ package service
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"slices"
 	"strings"
 
 	"go.uber.org/zap"
 
 	"github.com/kitbuilder587/fintech-bot/internal/domain"
 	"github.com/kitbuilder587/fintech-bot/internal/llm"
+	"github.com/kitbuilder587/fintech-bot/internal/prompt"
 	"github.com/kitbuilder587/fintech-bot/internal/search"
 )
 
-const CriticSystemPrompt = `You are a critical reviewer for financial research answers.
-
-Your task: Evaluate if the answer is accurate, complete, and well-sourced.
-
-Check for:
-1. ACCURACY: Are all claims supported by the provided sources?
-2. COMPLETENESS: Does it fully answer the question?
-3. HALLUCINATIONS: Are there any facts not from sources?
-4. STRUCTURE: Is it well-organized?
-
-Response format (JSON only):
-{
-  "approved": true/false,
-  "issues": ["issue1", "issue2"],
-  "suggestions": ["suggestion1"],
-  "confidence": 0.0-1.0
-}`
-
 type CriticService struct {
 	llm    llm.Client
 	logger *zap.Logger
@@ -57,17 +41,30 @@ func (s *CriticService) Review(ctx context.Context, answer string, sources []sea
 		zap.Int("sources_count", len(sources)),
 	)
 
-	userPrompt := s.buildPrompt(answer, sources, question)
-	response, err := s.llm.CompleteWithSystem(ctx, CriticSystemPrompt, userPrompt)
+	systemPrompt, err := prompt.FromContext(ctx).Render(prompt.Critic, prompt.Vars{})
 	if err != nil {
+		return nil, err
+	}
+
+	userPrompt := s.buildPrompt(answer, sources, question)
+	verdict, err := llm.CompleteJSON[criticVerdict](llm.WithStage(ctx, llm.StageCritic), s.llm, systemPrompt, userPrompt, llm.DefaultJSONAttempts)
+	var result *domain.CriticResult
+	switch {
+	case errors.Is(err, llm.ErrInvalidJSON):
+		// замечаний нет, есть только сломанный ответ - дорабатывать по нему нечего
+		s.logger.Warn("failed to parse critic response as JSON",
+			zap.Error(err),
+		)
+		return nil, fmt.Errorf("%w: %w", domain.ErrCriticUnparsable, err)
+	case err != nil:
 		s.logger.Error("LLM review failed",
 			zap.Error(err),
 		)
 		return nil, err
+	default:
+		result = verdict.toResult()
 	}
 
-	result := s.parseResponse(response)
-
 	s.logger.Info("review completed",
 		zap.Bool("approved", result.Approved),
 		zap.Int("issues_count", len(result.Issues)),
@@ -88,13 +85,13 @@ func (s *CriticService) buildPrompt(answer string, sources []search.SearchResult
 	if len(sources) == 0 {
 		sb.WriteString("No sources provided.\n")
 	} else {
+		cited := domain.CitedPages(answer)
 		for i, src := range sources {
 			fmt.Fprintf(&sb, "[S%d] %s (%s)\n", i+1, src.Title, src.URL)
-			content := src.Content
-			if len(content) > 1500 {
-				content = content[:1500] + "..."
+			if src.Trust != "" {
+				fmt.Fprintf(&sb, "Trust: %s\n", src.Trust)
 			}
-			fmt.Fprintf(&sb, "Content: %s\n\n", content)
+			fmt.Fprintf(&sb, "Content: %s\n\n", reviewSourceText(src, cited[i+1]))
 		}
 	}
 
@@ -110,57 +107,49 @@ func (s *CriticService) buildPrompt(answer string, sources []search.SearchResult
 	return sb.String()
 }
 
-func (s *CriticService) parseResponse(llmResponse string) *domain.CriticResult {
-	jsonStr := extractJSON(llmResponse)
+// criticVerdict - JSON ответа критика; approved указателем, чтобы отличать false от отсутствия поля
+type criticVerdict struct {
+	Approved    *bool    `json:"approved"`
+	Issues      []string `json:"issues"`
+	Suggestions []string `json:"suggestions"`
+	Confidence  float64  `json:"confidence"`
+}
 
-	var result struct {
-		Approved    bool     `json:"approved"`
-		Issues      []string `json:"issues"`
-		Suggestions []string `json:"suggestions"`
-		Confidence  float64  `json:"confidence"`
+func (v *criticVerdict) Validate() error {
+	if v.Approved == nil {
+		return errors.New(`field "approved" is required`)
 	}
-
-	if err := json.Unmarshal([]byte(jsonStr), &result); err != nil {
-		s.logger.Warn("failed to parse critic response as JSON",
-			zap.Error(err),
-			zap.String("response", llmResponse),
-		)
-
-		return &domain.CriticResult{
-			Approved:    true,
-			Issues:      []string{"critic_parse_failed: could not parse LLM response"},
-			Suggestions: nil,
-			Confidence:  0.3,
-		}
+	if v.Confidence < 0 || v.Confidence > 1 {
+		return fmt.Errorf(`field "confidence" must be between 0 and 1, got %v`, v.Confidence)
 	}
+	return nil
+}
 
+func (v *criticVerdict) toResult() *domain.CriticResult {
 	return &domain.CriticResult{
-		Approved:    result.Approved,
-		Issues:      result.Issues,
-		Suggestions: result.Suggestions,
-		Confidence:  result.Confidence,
+		Approved:    *v.Approved,
+		Issues:      v.Issues,
+		Suggestions: v.Suggestions,
+		Confidence:  v.Confidence,
 	}
 }
 
-// extractJSON достает JSON из ответа LLM который может содержать текст вокруг
-func extractJSON(s string) string {
-	start := strings.Index(s, "{")
-	if start == -1 {
-		return s
+// reviewSourceText - текст источника для критика и доработки ответа. Из длинного
+// документа нужнее страницы, на которые ссылается ответ; пометки [p.N] сохраняются
+func reviewSourceText(src search.SearchResult, citedPages []int) string {
+	content := src.Content
+	if pages := pagesByNumber(src.Pages, citedPages); len(pages) > 0 {
+		content = search.PagesContent(pages)
 	}
+	return truncateRunes(content, maxReviewSourceRunes)
+}
 
-	depth := 0
-	for i := start; i < len(s); i++ {
-		switch s[i] {
-		case '{':
-			depth++
-		case '}':
-			depth--
-			if depth == 0 {
-				return s[start : i+1]
-			}
+func pagesByNumber(pages []search.DocPage, numbers []int) []search.DocPage {
+	var out []search.DocPage
+	for _, p := range pages {
+		if slices.Contains(numbers, p.Number) {
+			out = append(out, p)
 		}
 	}
-
-	return s[start:]
+	return out
 }
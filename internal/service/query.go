@@ -3,10 +3,12 @@ package service
 import (
 	"context"
 	"crypto/sha256"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"slices"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"go.uber.org/zap"
@@ -16,6 +18,7 @@ import (
 	"github.com/kitbuilder587/fintech-bot/internal/domain"
 	"github.com/kitbuilder587/fintech-bot/internal/llm"
 	"github.com/kitbuilder587/fintech-bot/internal/metrics"
+	"github.com/kitbuilder587/fintech-bot/internal/prompt"
 	"github.com/kitbuilder587/fintech-bot/internal/repository"
 	"github.com/kitbuilder587/fintech-bot/internal/search"
 )
@@ -32,6 +35,9 @@ type WorldModel interface {
 type CoordinatorResponse struct {
 	FinalAnswer string
 	AgentsUsed  []string
+	// источники, на которые ссылается ответ: исходные и найденные агентами.
+	// Пустой список - только исходные
+	SearchResults []search.SearchResult
 }
 
 type AgentCoordinator interface {
@@ -43,17 +49,42 @@ type AgentCoordinatorRequest struct {
 	SearchResults []search.SearchResult
 	Context       string
 	Strategy      domain.Strategy
+	Domains       []string
+	Exclude       []string // блок-лист пользователя
+	Scopes        []domain.SourceScope
+}
+
+// UsageRecorder сохраняет расход токенов, собранный за запрос
+type UsageRecorder interface {
+	Record(ctx context.Context, userID int64, records []llm.UsageRecord) error
+}
+
+// ContentFetcher подменяет сниппеты поисковика текстом самих страниц
+type ContentFetcher interface {
+	Enrich(ctx context.Context, query string, results []search.SearchResult, topK int) []search.SearchResult
 }
 
 type QueryService interface {
 	Process(ctx context.Context, req *domain.QueryRequest) (*domain.QueryResponse, error)
 }
 
+// BackgroundWaiter - сервис с фоновыми задачами (запись в world model),
+// которых нужно дождаться при graceful shutdown
+type BackgroundWaiter interface {
+	WaitBackground(ctx context.Context) error
+}
+
 type QueryConfig struct {
 	MaxSearchQueries   int
 	MaxResultsPerQuery int
 	CacheTTL           time.Duration
 	SearchTimeout      time.Duration
+	SnippetChars       int // текста источника в промпте analyze
+	FetchTopK          int // сколько первых результатов глубокой стратегии скачивать целиком
+	FetchedChars       int // текста источника в промпте, когда он скачан целиком
+	// за сколько вдвое падает оценка результата без окна времени;
+	// с окном - за половину окна
+	RecencyHalfLife time.Duration
 }
 
 // QueryServiceDeps - зависимости для QueryService.
@@ -72,6 +103,10 @@ type QueryServiceDeps struct {
 	CriticConfig domain.CriticConfig
 	WorldModel   WorldModel
 	Coordinator  AgentCoordinator
+	Usage        UsageRecorder
+	Prompts      *prompt.Registry    // без реестра - встроенные шаблоны
+	DeepSearch   search.SearchClient // поиск для глубокой стратегии, например federated
+	Fetcher      ContentFetcher      // полный текст страниц для глубокой стратегии
 }
 
 type queryService struct {
@@ -87,6 +122,12 @@ type queryService struct {
 
 	worldModel  WorldModel
 	coordinator AgentCoordinator
+	usage       UsageRecorder
+	prompts     *prompt.Registry
+	deepSearch  search.SearchClient
+	fetcher     ContentFetcher
+
+	background sync.WaitGroup
 }
 
 func NewQueryService(deps QueryServiceDeps) QueryService {
@@ -102,6 +143,18 @@ func NewQueryService(deps QueryServiceDeps) QueryService {
 	if deps.Config.SearchTimeout == 0 {
 		deps.Config.SearchTimeout = 30 * time.Second
 	}
+	if deps.Config.SnippetChars == 0 {
+		deps.Config.SnippetChars = 2000
+	}
+	if deps.Config.FetchTopK == 0 {
+		deps.Config.FetchTopK = 5
+	}
+	if deps.Config.FetchedChars == 0 {
+		deps.Config.FetchedChars = 6000
+	}
+	if deps.Config.RecencyHalfLife == 0 {
+		deps.Config.RecencyHalfLife = 180 * 24 * time.Hour
+	}
 
 	if deps.CriticConfig.MaxRetries == 0 {
 		deps.CriticConfig.MaxRetries = 2
@@ -119,6 +172,10 @@ func NewQueryService(deps QueryServiceDeps) QueryService {
 		criticConfig: deps.CriticConfig,
 		worldModel:   deps.WorldModel,
 		coordinator:  deps.Coordinator,
+		usage:        deps.Usage,
+		prompts:      deps.Prompts,
+		deepSearch:   deps.DeepSearch,
+		fetcher:      deps.Fetcher,
 	}
 }
 
@@ -138,6 +195,18 @@ func (s *queryService) Process(ctx context.Context, req *domain.QueryRequest) (*
 	}
 	req.Sanitize()
 
+	// все вызовы LLM ниже отчитываются о токенах в этот коллектор
+	usage := llm.NewUsageCollector()
+	ctx = llm.WithUsageCollector(ctx, usage)
+	defer s.saveUsage(ctx, req.UserID, usage)
+
+	// все промпты запроса, включая агентов и world model, берутся из одной версии
+	prompts := prompt.Default()
+	if s.prompts != nil {
+		prompts = s.prompts.Current()
+	}
+	ctx = prompt.WithSet(ctx, prompts)
+
 	if req.Strategy.TimeoutSeconds > 0 {
 		var cancel context.CancelFunc
 		ctx, cancel = context.WithTimeout(ctx, time.Duration(req.Strategy.TimeoutSeconds)*time.Second)
@@ -151,6 +220,8 @@ func (s *queryService) Process(ctx context.Context, req *domain.QueryRequest) (*
 		zap.Int("strategy_max_queries", req.Strategy.MaxQueries),
 		zap.Int("strategy_max_results", req.Strategy.MaxResults),
 		zap.Bool("strategy_use_critic", req.Strategy.UseCritic),
+		zap.Int("history_turns", len(req.History)),
+		zap.String("prompt_version", prompts.Version()),
 	)
 
 	var worldContext string
@@ -172,22 +243,38 @@ func (s *queryService) Process(ctx context.Context, req *domain.QueryRequest) (*
 		return nil, domain.ErrNoSources
 	}
 
+	blocked, err := s.sources.ListBlockedDomains(ctx, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	// поисковики фильтруют только по доменам, разделы сайтов отсекаются после поиска
 	domains := make([]string, 0, len(userSources))
-	trustMap := make(map[string]domain.TrustLevel)
+	var trust sourceTrust
 	for _, src := range userSources {
 		d := src.Domain()
-		if d != "" {
+		if d == "" || domain.IsBlocked(src.URL, blocked) {
+			continue
+		}
+		trust.add(src.Scope(), src.TrustLevel)
+		// OnlyReliable - ищем только по источникам с высоким доверием
+		if (!req.OnlyReliable || src.TrustLevel == domain.TrustHigh) && !slices.Contains(domains, d) {
 			domains = append(domains, d)
-			trustMap[d] = src.TrustLevel
 		}
 	}
+	if len(domains) == 0 {
+		if req.OnlyReliable {
+			return nil, domain.ErrNoTrustedSources
+		}
+		return nil, domain.ErrNoSources
+	}
 
 	// расширяем запрос через LLM
 	maxQueries := req.Strategy.MaxQueries
 	if maxQueries <= 0 {
 		maxQueries = 3
 	}
-	searchQueries, err := s.expandQuery(ctx, req.Text, maxQueries)
+	searchQueries, err := s.expandQuery(ctx, req.Text, req.History, maxQueries, req.Strategy.TimeWindow)
 	if err != nil {
 		s.logger.Warn("query expansion failed, using original", zap.Error(err))
 		searchQueries = []string{req.Text}
@@ -198,7 +285,7 @@ func (s *queryService) Process(ctx context.Context, req *domain.QueryRequest) (*
 	if maxResults <= 0 {
 		maxResults = 15
 	}
-	results, err := s.searchWithCache(ctx, searchQueries, domains, maxResults)
+	results, err := s.searchWithCache(ctx, searchQueries, domains, blocked, maxResults, req.Strategy)
 	if err != nil {
 		return nil, fmt.Errorf("search: %w", err)
 	}
@@ -207,18 +294,39 @@ func (s *queryService) Process(ctx context.Context, req *domain.QueryRequest) (*
 		return nil, ctx.Err()
 	}
 
+	results = search.WithoutBlocked(results, blocked)
+	results = search.WithinScopes(results, trust.scopes)
+	rankByTrust(results, trust)
+	if req.OnlyReliable {
+		// поисковик из цепочки мог не соблюсти IncludeDomains
+		results = onlyTrusted(results)
+	}
+	if len(results) > maxResults {
+		results = results[:maxResults]
+	}
+
 	if len(results) == 0 {
 		return nil, domain.ErrNoResults
 	}
 
+	// глубокой стратегии мало сниппетов: первые результаты читаем целиком
+	contentChars := s.config.SnippetChars
+	if s.fetcher != nil && req.Strategy.Type == domain.StrategyDeep {
+		results = s.fetcher.Enrich(ctx, req.Text, results, s.config.FetchTopK)
+		contentChars = s.config.FetchedChars
+	}
+
 	// мультиагентный анализ (если настроен координатор)
 	var answer string
 	if s.coordinator != nil {
 		coordResp, coordErr := s.coordinator.Process(ctx, AgentCoordinatorRequest{
 			Question:      req.Text,
 			SearchResults: results,
-			Context:       worldContext,
+			Context:       joinContext(conversationContext(req.History), worldContext),
 			Strategy:      req.Strategy,
+			Domains:       domains,
+			Exclude:       blocked,
+			Scopes:        trust.scopes,
 		})
 		if coordErr != nil {
 			s.logger.Warn("coordinator processing failed, falling back to analyze",
@@ -226,8 +334,14 @@ func (s *queryService) Process(ctx context.Context, req *domain.QueryRequest) (*
 			)
 		} else if coordResp != nil && coordResp.FinalAnswer != "" {
 			answer = coordResp.FinalAnswer
+			// агенты могли найти новые источники, ответ ссылается на них по номерам
+			if len(coordResp.SearchResults) > 0 {
+				results = coordResp.SearchResults
+				setTrust(results, trust)
+			}
 			s.logger.Debug("using coordinator answer",
 				zap.Int("agents_used", len(coordResp.AgentsUsed)),
+				zap.Int("sources", len(results)),
 			)
 		}
 	}
@@ -235,7 +349,9 @@ func (s *queryService) Process(ctx context.Context, req *domain.QueryRequest) (*
 	// fallback если координатор не вернул ответ
 	if answer == "" {
 		var err error
-		answer, err = s.analyze(ctx, req.Text, results)
+		// уточняющий вопрос отвечается с учетом предыдущих вопросов и ответов
+		analyzeCtx := llm.WithHistory(ctx, conversationMessages(req.History))
+		answer, err = s.analyze(analyzeCtx, req.Text, results, contentChars)
 		if err != nil {
 			return nil, err
 		}
@@ -248,7 +364,7 @@ func (s *queryService) Process(ctx context.Context, req *domain.QueryRequest) (*
 
 	response := &domain.QueryResponse{
 		Text:    answer,
-		Sources: s.toSourceRefs(results, trustMap),
+		Sources: s.toSourceRefs(results, trust, answer),
 	}
 
 	s.logger.Info("query processed",
@@ -262,52 +378,96 @@ func (s *queryService) Process(ctx context.Context, req *domain.QueryRequest) (*
 
 	// в фоне сохраняем в world model
 	if s.worldModel != nil {
+		s.background.Add(1)
 		go func() {
-			if err := s.worldModel.ExtractAndStore(context.Background(), req.UserID, answer, results, req.Text, req.Strategy); err != nil {
+			defer s.background.Done()
+			// запрос уже отвечен, но коллектор расхода из ctx нужен и тут
+			bgCtx := context.WithoutCancel(ctx)
+			if err := s.worldModel.ExtractAndStore(bgCtx, req.UserID, answer, results, req.Text, req.Strategy); err != nil {
 				s.logger.Warn("failed to save to world model",
 					zap.Error(err),
 					zap.Int64("user_id", req.UserID),
 				)
 			}
+			s.saveUsage(bgCtx, req.UserID, usage)
 		}()
 	}
 
 	return response, nil
 }
 
-func (s *queryService) expandQuery(ctx context.Context, userQuery string, maxQueries int) ([]string, error) {
-	currentYear := time.Now().Year()
-	systemPrompt := fmt.Sprintf(`You are a search query optimizer for financial and technology research.
+// WaitBackground ждет завершения фоновых записей в world model или отмены ctx
+func (s *queryService) WaitBackground(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.background.Wait()
+		close(done)
+	}()
 
-Task: Generate 1-%d optimal web search queries.
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
 
-Rules:
-1. Queries in ENGLISH (sources are English)
-2. Use keywords, not full sentences
-3. Add year "%d" for current topics when relevant
-4. Split complex questions into sub-topics
-5. Simple questions need only 1 query
+// saveUsage сохраняет накопленный расход даже если запрос отменен или упал по таймауту
+func (s *queryService) saveUsage(ctx context.Context, userID int64, usage *llm.UsageCollector) {
+	records := usage.Drain()
+	if s.usage == nil || len(records) == 0 {
+		return
+	}
 
-Response format (JSON only):
-{"queries": ["query1", "query2"]}`, maxQueries, currentYear)
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
 
-	userPrompt := fmt.Sprintf("User question: %s", userQuery)
+	if err := s.usage.Record(ctx, userID, records); err != nil {
+		s.logger.Warn("failed to save LLM usage",
+			zap.Error(err),
+			zap.Int64("user_id", userID),
+		)
+	}
+}
+
+type expandedQueries struct {
+	Queries []string `json:"queries"`
+}
+
+func (e *expandedQueries) Validate() error {
+	if len(e.Queries) == 0 {
+		return errors.New("queries must contain at least one search query")
+	}
+	for _, q := range e.Queries {
+		if strings.TrimSpace(q) == "" {
+			return errors.New("queries must not contain empty strings")
+		}
+	}
+	return nil
+}
 
-	response, err := s.llm.CompleteWithSystem(ctx, systemPrompt, userPrompt)
+func (s *queryService) expandQuery(ctx context.Context, userQuery string, history []domain.ConversationTurn, maxQueries int, window domain.TimeWindow) ([]string, error) {
+	systemPrompt, err := prompt.FromContext(ctx).Render(prompt.Expand, prompt.Vars{MaxQueries: maxQueries, TimeWindow: string(window)})
 	if err != nil {
 		return nil, err
 	}
 
-	var result struct {
-		Queries []string `json:"queries"`
-	}
-	if err := json.Unmarshal([]byte(response), &result); err != nil {
-		return []string{userQuery}, nil
+	userPrompt := fmt.Sprintf("User question: %s", userQuery)
+	if len(history) > 0 {
+		// "а что в Европе?" без предыдущих вопросов превращается в бессмысленный поиск
+		userPrompt = fmt.Sprintf("%s\n\nThe question is a follow-up, every query must be self-contained.\n\n%s",
+			conversationContext(history), userPrompt)
 	}
 
-	if len(result.Queries) == 0 {
+	result, err := llm.CompleteJSON[expandedQueries](llm.WithStage(ctx, llm.StageExpand), s.llm, systemPrompt, userPrompt, llm.DefaultJSONAttempts)
+	if errors.Is(err, llm.ErrInvalidJSON) {
+		// без расширения поиск все равно работает, просто по исходному вопросу
+		s.logger.Warn("query expansion returned invalid JSON, using original query", zap.Error(err))
 		return []string{userQuery}, nil
 	}
+	if err != nil {
+		return nil, err
+	}
 
 	if len(result.Queries) > maxQueries {
 		result.Queries = result.Queries[:maxQueries]
@@ -316,18 +476,30 @@ Response format (JSON only):
 	return result.Queries, nil
 }
 
-func (s *queryService) searchWithCache(ctx context.Context, queries []string, domains []string, maxResults int) ([]search.SearchResult, error) {
+// searchWithCache ищет по всем запросам параллельно и сливает выдачи через RRF:
+// оценки разных запросов и поисковиков между собой несравнимы. Слитая выдача
+// штрафуется за возраст: старые новости финтеха часто уже неверны
+func (s *queryService) searchWithCache(ctx context.Context, queries []string, domains, exclude []string, maxResults int, strategy domain.Strategy) ([]search.SearchResult, error) {
 	ctx, cancel := context.WithTimeout(ctx, s.config.SearchTimeout)
 	defer cancel()
 
-	resultsChan := make(chan []search.SearchResult, len(queries))
+	client, keyPrefix := s.search, "search"
+	if strategy.Type == domain.StrategyDeep && s.deepSearch != nil {
+		client, keyPrefix = s.deepSearch, "search:deep"
+	}
+	window := strategy.TimeWindow
+	if window != domain.TimeWindowAny {
+		keyPrefix += ":" + string(window)
+	}
+
+	// по слоту на запрос, чтобы порядок слияния не зависел от того, кто ответил первым
+	lists := make([][]search.SearchResult, len(queries))
 	g, ctx := errgroup.WithContext(ctx)
 
-	for _, query := range queries {
-		query := query       // capture for goroutine
-		maxRes := maxResults // capture for goroutine - each query gets full maxResults
+	for i, query := range queries {
 		g.Go(func() error {
-			results, err := s.searchSingleQuery(ctx, query, domains, maxRes)
+			// каждому запросу полный maxResults
+			results, err := s.searchSingleQuery(ctx, client, s.cacheKey(keyPrefix, query, domains, exclude), query, domains, exclude, maxResults, window)
 			if err != nil {
 				s.logger.Warn("search query failed",
 					zap.Error(err),
@@ -335,40 +507,29 @@ func (s *queryService) searchWithCache(ctx context.Context, queries []string, do
 				)
 				return nil
 			}
-			resultsChan <- results
+			lists[i] = results
 			return nil
 		})
 	}
 
 	g.Wait()
-	close(resultsChan)
 
-	seen := make(map[string]bool)
-	var allResults []search.SearchResult
-
-	for results := range resultsChan {
-		for _, r := range results {
-			if !seen[r.URL] {
-				seen[r.URL] = true
-				allResults = append(allResults, r)
-			}
-		}
-	}
-
-	sort.Slice(allResults, func(i, j int) bool {
-		return allResults[i].Score > allResults[j].Score
-	})
-
-	if len(allResults) > maxResults {
-		allResults = allResults[:maxResults]
-	}
+	// до maxResults обрезает вызывающий, уже после учета доверия
+	allResults := search.FuseRRF(lists, search.RRFK)
+	search.DecayByAge(allResults, s.recencyHalfLife(window), time.Now())
 
 	return allResults, nil
 }
 
-func (s *queryService) searchSingleQuery(ctx context.Context, query string, domains []string, maxResults int) ([]search.SearchResult, error) {
-	cacheKey := s.cacheKey(query, domains)
+// recencyHalfLife - в окне времени свежесть важна в масштабе самого окна
+func (s *queryService) recencyHalfLife(window domain.TimeWindow) time.Duration {
+	if d := window.Duration(); d > 0 {
+		return d / 2
+	}
+	return s.config.RecencyHalfLife
+}
 
+func (s *queryService) searchSingleQuery(ctx context.Context, client search.SearchClient, cacheKey, query string, domains, exclude []string, maxResults int, window domain.TimeWindow) ([]search.SearchResult, error) {
 	if cached, ok := s.cache.Get(cacheKey); ok {
 		if results, ok := cached.([]search.SearchResult); ok {
 			if s.metrics != nil {
@@ -382,37 +543,37 @@ func (s *queryService) searchSingleQuery(ctx context.Context, query string, doma
 		s.metrics.RecordCacheMiss()
 	}
 
-	searchStart := time.Now()
-	resp, err := s.search.Search(ctx, search.SearchRequest{
+	resp, err := client.Search(ctx, search.SearchRequest{
 		Query:          query,
 		IncludeDomains: domains,
+		ExcludeDomains: exclude,
 		MaxResults:     maxResults,
 		SearchDepth:    "basic",
+		TimeRange:      string(window),
 	})
 	if err != nil {
-		if s.metrics != nil {
-			s.metrics.RecordSearchRequest("error", time.Since(searchStart))
-		}
 		return nil, err
 	}
 
-	if s.metrics != nil {
-		s.metrics.RecordSearchRequest("success", time.Since(searchStart))
-	}
-
 	s.cache.Set(cacheKey, resp.Results, s.config.CacheTTL)
 
 	return resp.Results, nil
 }
 
-func (s *queryService) cacheKey(query string, domains []string) string {
+func (s *queryService) cacheKey(prefix, query string, domains, exclude []string) string {
 	normalized := s.normalizeQuery(query)
 	sortedDomains := make([]string, len(domains))
 	copy(sortedDomains, domains)
 	sort.Strings(sortedDomains)
 	data := normalized + strings.Join(sortedDomains, ",")
+	if len(exclude) > 0 {
+		sortedExclude := make([]string, len(exclude))
+		copy(sortedExclude, exclude)
+		sort.Strings(sortedExclude)
+		data += "|-" + strings.Join(sortedExclude, ",")
+	}
 	hash := sha256.Sum256([]byte(data))
-	return fmt.Sprintf("search:%x", hash[:8])
+	return fmt.Sprintf("%s:%x", prefix, hash[:8])
 }
 
 func (s *queryService) normalizeQuery(q string) string {
@@ -421,16 +582,11 @@ func (s *queryService) normalizeQuery(q string) string {
 	return strings.Join(strings.Fields(q), " ")
 }
 
-func (s *queryService) analyze(ctx context.Context, userQuery string, results []search.SearchResult) (string, error) {
-	systemPrompt := `You are an expert analyst in financial technology and banking.
-
-Rules:
-1. Answer in Russian
-2. Use ONLY information from provided sources
-3. Reference sources as [S1], [S2], etc.
-4. If information is insufficient, say so honestly
-5. Structure: key points, examples, conclusions
-6. Be objective, present different viewpoints`
+func (s *queryService) analyze(ctx context.Context, userQuery string, results []search.SearchResult, contentChars int) (string, error) {
+	systemPrompt, err := prompt.FromContext(ctx).Render(prompt.Analyze, prompt.Vars{})
+	if err != nil {
+		return "", err
+	}
 
 	var sb strings.Builder
 	sb.WriteString("Sources:\n\n")
@@ -438,46 +594,50 @@ Rules:
 	for i, r := range results {
 		fmt.Fprintf(&sb, "[S%d] %s (%s)\n", i+1, r.Title, r.URL)
 		fmt.Fprintf(&sb, "Score: %.2f\n", r.Score)
-		content := r.Content
-		if len(content) > 2000 {
-			content = content[:2000] + "..."
+		if r.Trust != "" {
+			fmt.Fprintf(&sb, "Trust: %s\n", r.Trust)
+		}
+		if published, ok := r.Published(); ok {
+			fmt.Fprintf(&sb, "Published: %s\n", published.Format(time.DateOnly))
 		}
-		fmt.Fprintf(&sb, "%s\n\n", content)
+		fmt.Fprintf(&sb, "%s\n\n", truncateRunes(r.Content, contentChars))
 	}
 
 	sb.WriteString("---\n\n")
 	fmt.Fprintf(&sb, "User question: %s", userQuery)
 
-	return s.llm.CompleteWithSystem(ctx, systemPrompt, sb.String())
+	// финальный ответ можно показывать пользователю по мере генерации
+	return llm.CompleteStreaming(llm.WithStage(ctx, llm.StageAnalyze), s.llm, systemPrompt, sb.String())
 }
 
-func (s *queryService) toSourceRefs(results []search.SearchResult, trustMap map[string]domain.TrustLevel) []domain.SourceRef {
+func (s *queryService) toSourceRefs(results []search.SearchResult, trust sourceTrust, answer string) []domain.SourceRef {
+	cited := domain.CitedPages(answer)
 	refs := make([]domain.SourceRef, len(results))
 	for i, r := range results {
-		trustLevel := domain.TrustMedium
-		resultDomain := extractDomain(r.URL)
-		if level, ok := trustMap[resultDomain]; ok {
-			trustLevel = level
-		}
+		trustLevel := trustOf(r.URL, trust)
+		published, _ := r.Published()
 
 		refs[i] = domain.SourceRef{
-			Marker:     fmt.Sprintf("[S%d]", i+1),
-			Title:      r.Title,
-			URL:        r.URL,
-			TrustLevel: trustLevel,
+			Marker:      fmt.Sprintf("[S%d]", i+1),
+			Title:       r.Title,
+			URL:         r.URL,
+			TrustLevel:  trustLevel,
+			PublishedAt: published,
+			Pages:       citedPages(r, cited[i+1]),
 		}
 	}
 	return refs
 }
 
-func extractDomain(url string) string {
-	url = strings.TrimPrefix(url, "https://")
-	url = strings.TrimPrefix(url, "http://")
-	if idx := strings.Index(url, "/"); idx != -1 {
-		url = url[:idx]
+// citedPages оставляет только страницы, которые модель действительно видела:
+// номер страницы из головы не должен превращаться в ссылку
+func citedPages(r search.SearchResult, numbers []int) []int {
+	var out []int
+	for _, p := range pagesByNumber(r.Pages, numbers) {
+		out = append(out, p.Number)
 	}
-	url = strings.TrimPrefix(url, "www.")
-	return url
+	slices.Sort(out)
+	return out
 }
 
 func (s *queryService) reviewWithCritic(ctx context.Context, answer string, sources []search.SearchResult, question string) string {
@@ -485,6 +645,14 @@ func (s *queryService) reviewWithCritic(ctx context.Context, answer string, sour
 
 	for attempt := 0; attempt <= s.criticConfig.MaxRetries; attempt++ {
 		result, err := s.critic.Review(ctx, currentAnswer, sources, question)
+		if errors.Is(err, domain.ErrCriticUnparsable) {
+			// без разобранных замечаний доработка - платная переписка вслепую
+			s.logger.Warn("critic response unparsable, returning unreviewed answer",
+				zap.Error(err),
+				zap.Int("attempt", attempt),
+			)
+			return currentAnswer
+		}
 		if err != nil {
 			s.logger.Warn("critic review failed, returning current answer",
 				zap.Error(err),
@@ -525,17 +693,10 @@ func (s *queryService) reviewWithCritic(ctx context.Context, answer string, sour
 }
 
 func (s *queryService) improveAnswer(ctx context.Context, currentAnswer string, criticResult *domain.CriticResult, sources []search.SearchResult, question string) (string, error) {
-	systemPrompt := `You are an expert analyst in financial technology and banking.
-
-Your task is to improve an answer based on reviewer feedback.
-
-Rules:
-1. Answer in Russian
-2. Use ONLY information from provided sources
-3. Reference sources as [S1], [S2], etc.
-4. Fix ALL issues mentioned by the reviewer
-5. Keep the good parts of the original answer
-6. Be objective, present different viewpoints`
+	systemPrompt, err := prompt.FromContext(ctx).Render(prompt.Improve, prompt.Vars{})
+	if err != nil {
+		return "", err
+	}
 
 	var sb strings.Builder
 	sb.WriteString("=== REVIEWER FEEDBACK ===\n")
@@ -560,13 +721,16 @@ Rules:
 	sb.WriteString("\n\n")
 
 	sb.WriteString("=== SOURCES ===\n")
+	cited := domain.CitedPages(currentAnswer)
 	for i, src := range sources {
 		fmt.Fprintf(&sb, "[S%d] %s (%s)\n", i+1, src.Title, src.URL)
-		content := src.Content
-		if len(content) > 1500 {
-			content = content[:1500] + "..."
+		if src.Trust != "" {
+			fmt.Fprintf(&sb, "Trust: %s\n", src.Trust)
 		}
-		fmt.Fprintf(&sb, "%s\n\n", content)
+		if published, ok := src.Published(); ok {
+			fmt.Fprintf(&sb, "Published: %s\n", published.Format(time.DateOnly))
+		}
+		fmt.Fprintf(&sb, "%s\n\n", reviewSourceText(src, cited[i+1]))
 	}
 
 	sb.WriteString("=== ORIGINAL QUESTION ===\n")
@@ -576,7 +740,70 @@ Rules:
 	sb.WriteString("=== INSTRUCTIONS ===\n")
 	sb.WriteString("Please fix these issues and provide an improved answer. ")
 	sb.WriteString("Keep using only the provided sources. ")
-	sb.WriteString("Make sure all claims are properly cited.")
+	sb.WriteString("Make sure all claims are properly cited, keeping page references like [S1 p.12].")
+
+	return s.llm.CompleteWithSystem(llm.WithStage(ctx, llm.StageImprove), systemPrompt, sb.String())
+}
+
+// conversationMessages превращает предыдущие ходы в реплики диалога для LLM.
+// Источники идут в реплику ассистента, чтобы модель понимала, на что ссылался ответ
+func conversationMessages(history []domain.ConversationTurn) []llm.Message {
+	if len(history) == 0 {
+		return nil
+	}
+
+	messages := make([]llm.Message, 0, len(history)*2)
+	for _, turn := range history {
+		var sb strings.Builder
+		sb.WriteString(truncateRunes(turn.Answer, maxHistoryAnswerRunes))
+		if len(turn.Sources) > 0 {
+			sb.WriteString("\n\nSources:")
+			for _, src := range turn.Sources {
+				fmt.Fprintf(&sb, "\n%s %s (%s)", src.Marker, src.Title, src.URL)
+			}
+		}
+		messages = append(messages,
+			llm.Message{Role: "user", Content: turn.Question},
+			llm.Message{Role: "assistant", Content: sb.String()},
+		)
+	}
+	return messages
+}
 
-	return s.llm.CompleteWithSystem(ctx, systemPrompt, sb.String())
+// conversationContext - предыдущие ходы одним текстом, для промптов без истории сообщений
+func conversationContext(history []domain.ConversationTurn) string {
+	if len(history) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString("Previous conversation:")
+	for _, turn := range history {
+		fmt.Fprintf(&sb, "\nQ: %s\nA: %s", turn.Question, truncateRunes(turn.Answer, maxContextAnswerRunes))
+	}
+	return sb.String()
+}
+
+func joinContext(parts ...string) string {
+	nonEmpty := parts[:0:0]
+	for _, p := range parts {
+		if p != "" {
+			nonEmpty = append(nonEmpty, p)
+		}
+	}
+	return strings.Join(nonEmpty, "\n\n")
+}
+
+const (
+	maxHistoryAnswerRunes = 3000
+	maxContextAnswerRunes = 500
+	maxReviewSourceRunes  = 1500
+)
+
+func truncateRunes(s string, limit int) string {
+	runes := []rune(s)
+	if len(runes) <= limit {
+		return s
+	}
+	return string(runes[:limit]) + "..."
 }
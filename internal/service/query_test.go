@@ -3,15 +3,19 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
+	"slices"
 	"strings"
 	"sync"
 	"testing"
 	"time"
+	"unicode/utf8"
 
 	"go.uber.org/zap"
 
 	"github.com/kitbuilder587/fintech-bot/internal/cache/memory"
 	"github.com/kitbuilder587/fintech-bot/internal/domain"
+	"github.com/kitbuilder587/fintech-bot/internal/llm"
 	llmMock "github.com/kitbuilder587/fintech-bot/internal/llm/mock"
 	"github.com/kitbuilder587/fintech-bot/internal/repository"
 	"github.com/kitbuilder587/fintech-bot/internal/search"
@@ -83,7 +87,7 @@ func TestQueryService_Process(t *testing.T) {
 	}{
 		{"ok", &domain.QueryRequest{UserID: 1, Text: "What are fintech trends?", Strategy: domain.QuickStrategy()},
 			func(sr *repository.MockSourceRepository, sc *searchMock.Client, lc *llmMock.Client) {
-				sr.Create(context.Background(), &domain.Source{UserID: 1, URL: "https://mckinsey.com/fintech", Name: "McKinsey"})
+				sr.Create(context.Background(), &domain.Source{UserID: 1, URL: "https://mckinsey.com", Name: "McKinsey"})
 				sc.Results = []search.SearchResult{{Title: "Fintech Trends", URL: "https://mckinsey.com/trends", Content: "Content about trends"}}
 				lc.Response = `{"queries": ["fintech trends 2025"]}`
 			}, nil},
@@ -232,6 +236,58 @@ func TestQueryService_CriticApproved(t *testing.T) {
 	}
 }
 
+func TestQueryService_CriticUnparsableSkipsImprove(t *testing.T) {
+	sourceRepo := repository.NewMockSourceRepository()
+	searchClient := searchMock.New()
+	llmClient := llmMock.New().
+		WithResponses(`{"queries": ["fintech trends"]}`).
+		WithResponse("Fintech grows [S1]")
+	cacheClient := memory.New()
+	mockCritic := NewMockCritic().WithError(fmt.Errorf("%w: %w", domain.ErrCriticUnparsable, llm.ErrInvalidJSON))
+
+	sourceRepo.Create(context.Background(), &domain.Source{UserID: 1, URL: "https://example.com", Name: "Example"})
+	searchClient.Results = []search.SearchResult{
+		{Title: "Test", URL: "https://example.com/1", Content: "Content about fintech"},
+	}
+
+	svc := NewQueryService(QueryServiceDeps{
+		Sources:      sourceRepo,
+		LLM:          llmClient,
+		Search:       searchClient,
+		Cache:        cacheClient,
+		Logger:       zap.NewNop(),
+		Critic:       mockCritic,
+		CriticConfig: domain.CriticConfig{MaxRetries: 3},
+		Config: QueryConfig{
+			MaxSearchQueries:   3,
+			MaxResultsPerQuery: 5,
+			CacheTTL:           time.Hour,
+			SearchTimeout:      10 * time.Second,
+		},
+	})
+
+	strategy := domain.StandardStrategy()
+	strategy.UseCritic = true
+
+	resp, err := svc.Process(context.Background(), &domain.QueryRequest{
+		UserID: 1, Text: "What are fintech trends?", Strategy: strategy,
+	})
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	if resp.Text != "Fintech grows [S1]" {
+		t.Errorf("Text = %q, want the unreviewed answer as is", resp.Text)
+	}
+	if mockCritic.CallCount != 1 {
+		t.Errorf("Critic called %d times, want 1", mockCritic.CallCount)
+	}
+	for _, call := range llmClient.AllCalls {
+		if strings.Contains(call.Prompt, "REVIEWER FEEDBACK") {
+			t.Errorf("improve step called without parsed critic issues: %q", call.Prompt)
+		}
+	}
+}
+
 func TestQueryService_CriticRejectedThenApproved(t *testing.T) {
 	logger := zap.NewNop()
 
@@ -304,6 +360,40 @@ func TestQueryService_CriticRejectedThenApproved(t *testing.T) {
 	}
 }
 
+func TestQueryService_ImproveAnswer_CitedPages(t *testing.T) {
+	llmClient := llmMock.New().WithResponse("Improved [S1 p.9]")
+	svc := NewQueryService(QueryServiceDeps{LLM: llmClient, Logger: zap.NewNop()}).(*queryService)
+
+	sources := []search.SearchResult{{
+		Title:   "Отчёт",
+		URL:     "https://example.com/report.pdf",
+		Content: "[p.1] " + strings.Repeat("введение ", 400) + "\n\n[p.9] BNPL вырос на 30%",
+		Trust:   domain.TrustHigh,
+		Pages: []search.DocPage{
+			{Number: 1, Text: strings.Repeat("введение ", 400)},
+			{Number: 9, Text: "BNPL вырос на 30%"},
+		},
+	}, {
+		Title: "Новость",
+		URL:   "https://example.com/news",
+		// со сдвигом на байт срез по байтам попадает внутрь буквы
+		Content: "x" + strings.Repeat("рынок ", 400),
+	}}
+	critic := &domain.CriticResult{Issues: []string{"unsupported claim"}}
+
+	if _, err := svc.improveAnswer(context.Background(), "BNPL вырос на 30% [S1 p.9]", critic, sources, "BNPL"); err != nil {
+		t.Fatalf("improveAnswer() error = %v", err)
+	}
+
+	prompt := llmClient.LastPrompt
+	if !utf8.ValidString(prompt) {
+		t.Error("prompt is not valid UTF-8: source text cut inside a character")
+	}
+	if !strings.Contains(prompt, "Trust: high\n[p.9] BNPL вырос на 30%") || strings.Contains(prompt, "введение") {
+		t.Errorf("prompt should show the cited page with its marker and trust, got %q", prompt)
+	}
+}
+
 func TestQueryService_CriticMaxRetries(t *testing.T) {
 	logger := zap.NewNop()
 
@@ -553,7 +643,7 @@ func TestQueryService_QuickStrategy(t *testing.T) {
 
 	sourceRepo.Create(context.Background(), &domain.Source{
 		UserID: 1,
-		URL:    "https://mckinsey.com/fintech",
+		URL:    "https://mckinsey.com",
 		Name:   "McKinsey",
 	})
 	searchClient.Results = []search.SearchResult{
@@ -598,7 +688,7 @@ func TestQueryService_DeepStrategy(t *testing.T) {
 
 	sourceRepo.Create(context.Background(), &domain.Source{
 		UserID: 1,
-		URL:    "https://mckinsey.com/fintech",
+		URL:    "https://mckinsey.com",
 		Name:   "McKinsey",
 	})
 	searchClient.Results = []search.SearchResult{
@@ -810,7 +900,7 @@ func TestQueryService_FullFlow(t *testing.T) {
 
 	sourceRepo.Create(context.Background(), &domain.Source{
 		UserID: 1,
-		URL:    "https://mckinsey.com/fintech",
+		URL:    "https://mckinsey.com",
 		Name:   "McKinsey",
 	})
 	searchClient.Results = []search.SearchResult{
@@ -1229,3 +1319,562 @@ func TestQueryService_CoordinatorError(t *testing.T) {
 		t.Errorf("Response = %q, want fallback", resp.Text)
 	}
 }
+
+func TestQueryService_WaitBackground(t *testing.T) {
+	logger := zap.NewNop()
+
+	sourceRepo := repository.NewMockSourceRepository()
+	searchClient := searchMock.New()
+	llmClient := llmMock.New()
+
+	release := make(chan struct{})
+	mockWorldModel := &MockWorldModel{
+		ExtractAndStoreFn: func(ctx context.Context, userID int64, answer string, sources []search.SearchResult, question string, strategy domain.Strategy) error {
+			<-release
+			return nil
+		},
+	}
+
+	sourceRepo.Create(context.Background(), &domain.Source{UserID: 1, URL: "https://example.com", Name: "Example"})
+	searchClient.Results = []search.SearchResult{{Title: "Test", URL: "https://example.com/1", Content: "Content"}}
+
+	svc := NewQueryService(QueryServiceDeps{
+		Sources:    sourceRepo,
+		LLM:        llmClient,
+		Search:     searchClient,
+		Cache:      memory.New(),
+		Logger:     logger,
+		WorldModel: mockWorldModel,
+	})
+
+	if _, err := svc.Process(context.Background(), &domain.QueryRequest{
+		UserID: 1, Text: "Test query", Strategy: domain.QuickStrategy(),
+	}); err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	waiter, ok := svc.(BackgroundWaiter)
+	if !ok {
+		t.Fatal("query service should implement BackgroundWaiter")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := waiter.WaitBackground(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("WaitBackground() error = %v, want deadline exceeded while write is pending", err)
+	}
+
+	close(release)
+	if err := waiter.WaitBackground(context.Background()); err != nil {
+		t.Errorf("WaitBackground() error = %v, want nil after write finished", err)
+	}
+}
+
+type recordingUsage struct {
+	mu      sync.Mutex
+	userID  int64
+	records []llm.UsageRecord
+}
+
+func (r *recordingUsage) Record(ctx context.Context, userID int64, records []llm.UsageRecord) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.userID = userID
+	r.records = append(r.records, records...)
+	return nil
+}
+
+func TestQueryService_RecordsUsageByStage(t *testing.T) {
+	sourceRepo := repository.NewMockSourceRepository()
+	searchClient := searchMock.New()
+	llmClient := llmMock.New().
+		WithUsage(llm.Usage{PromptTokens: 100, CompletionTokens: 20}).
+		WithResponses(`{"queries": ["test query"]}`)
+	usage := &recordingUsage{}
+
+	sourceRepo.Create(context.Background(), &domain.Source{UserID: 1, URL: "https://example.com", Name: "Example"})
+	searchClient.Results = []search.SearchResult{{Title: "Test", URL: "https://example.com/1", Content: "Content"}}
+
+	svc := NewQueryService(QueryServiceDeps{
+		Sources: sourceRepo,
+		LLM:     llmClient,
+		Search:  searchClient,
+		Cache:   memory.New(),
+		Logger:  zap.NewNop(),
+		Usage:   usage,
+	})
+
+	if _, err := svc.Process(context.Background(), &domain.QueryRequest{
+		UserID: 1, Text: "Test query", Strategy: domain.QuickStrategy(),
+	}); err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	var stages []string
+	for _, r := range usage.records {
+		stages = append(stages, r.Stage)
+	}
+	if usage.userID != 1 || strings.Join(stages, ",") != "expand,analyze" {
+		t.Errorf("recorded user %d stages %v, want user 1 with expand,analyze", usage.userID, stages)
+	}
+}
+
+func TestQueryService_FollowUpUsesHistory(t *testing.T) {
+	sourceRepo := repository.NewMockSourceRepository()
+	searchClient := searchMock.New()
+	llmClient := llmMock.New().WithResponses(`{"queries": ["BNPL market Europe"]}`, "Answer about Europe [S1]")
+
+	sourceRepo.Create(context.Background(), &domain.Source{UserID: 1, URL: "https://example.com", Name: "Example"})
+	searchClient.Results = []search.SearchResult{{Title: "Test", URL: "https://example.com/1", Content: "Content"}}
+
+	svc := NewQueryService(QueryServiceDeps{
+		Sources: sourceRepo,
+		LLM:     llmClient,
+		Search:  searchClient,
+		Cache:   memory.New(),
+		Logger:  zap.NewNop(),
+	})
+
+	history := []domain.ConversationTurn{{
+		ID:       7,
+		Question: "Рынок BNPL в США?",
+		Answer:   "Рынок BNPL в США растет [S1]",
+		Sources:  []domain.SourceRef{{Marker: "[S1]", Title: "BNPL report", URL: "https://example.com/bnpl"}},
+	}}
+	if _, err := svc.Process(context.Background(), &domain.QueryRequest{
+		UserID: 1, Text: "А что в Европе?", Strategy: domain.QuickStrategy(), History: history,
+	}); err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	if len(llmClient.AllCalls) != 2 {
+		t.Fatalf("LLM calls = %d, want expand and analyze", len(llmClient.AllCalls))
+	}
+	expand, analyze := llmClient.AllCalls[0], llmClient.AllCalls[1]
+
+	if !strings.Contains(expand.Prompt, "Рынок BNPL в США?") || len(expand.History) != 0 {
+		t.Errorf("expand prompt should inline previous question: %q", expand.Prompt)
+	}
+	if searchClient.LastRequest.Query != "BNPL market Europe" {
+		t.Errorf("search query = %q, want expanded follow-up query", searchClient.LastRequest.Query)
+	}
+
+	if len(analyze.History) != 2 {
+		t.Fatalf("analyze history = %+v, want previous question and answer", analyze.History)
+	}
+	if analyze.History[0].Role != "user" || analyze.History[0].Content != "Рынок BNPL в США?" {
+		t.Errorf("history[0] = %+v", analyze.History[0])
+	}
+	if analyze.History[1].Role != "assistant" || !strings.Contains(analyze.History[1].Content, "https://example.com/bnpl") {
+		t.Errorf("history[1] should carry previous answer with its sources: %+v", analyze.History[1])
+	}
+}
+
+func TestQueryService_CoordinatorFoundSources(t *testing.T) {
+	sourceRepo := repository.NewMockSourceRepository()
+	searchClient := searchMock.New()
+	sourceRepo.Create(context.Background(), &domain.Source{UserID: 1, URL: "https://example.com", Name: "Example"})
+	initial := search.SearchResult{Title: "Initial", URL: "https://example.com/1", Content: "Content"}
+	searchClient.Results = []search.SearchResult{initial}
+
+	coordinator := &MockCoordinator{
+		ProcessResp: &CoordinatorResponse{
+			FinalAnswer: "Answer citing a source found by an agent [S2]",
+			SearchResults: []search.SearchResult{
+				initial,
+				{Title: "Found by agent", URL: "https://example.com/2", Content: "More"},
+			},
+		},
+	}
+
+	svc := NewQueryService(QueryServiceDeps{
+		Sources:     sourceRepo,
+		LLM:         llmMock.New().WithResponses(`{"queries": ["test query"]}`),
+		Search:      searchClient,
+		Cache:       memory.New(),
+		Logger:      zap.NewNop(),
+		Coordinator: coordinator,
+	})
+
+	resp, err := svc.Process(context.Background(), &domain.QueryRequest{
+		UserID: 1, Text: "Test query", Strategy: domain.DeepStrategy(),
+	})
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	if domains := coordinator.LastRequest.Domains; len(domains) != 1 || domains[0] != "example.com" {
+		t.Errorf("coordinator domains = %v, want user source domains", domains)
+	}
+	if len(resp.Sources) != 2 || resp.Sources[1].URL != "https://example.com/2" {
+		t.Errorf("Sources = %+v, want initial and agent-found sources", resp.Sources)
+	}
+}
+
+func TestQueryService_DeepSearchClient(t *testing.T) {
+	sourceRepo := repository.NewMockSourceRepository()
+	sourceRepo.Create(context.Background(), &domain.Source{UserID: 1, URL: "https://mckinsey.com", Name: "McKinsey"})
+	results := []search.SearchResult{{Title: "Test", URL: "https://mckinsey.com/1", Content: "Content"}}
+	searchClient := searchMock.New().WithResults(results)
+	deepSearch := searchMock.New().WithResults(results)
+	llmClient := llmMock.New().WithResponse(`{"queries": ["klarna ipo"]}`)
+
+	svc := NewQueryService(QueryServiceDeps{
+		Sources:    sourceRepo,
+		LLM:        llmClient,
+		Search:     searchClient,
+		DeepSearch: deepSearch,
+		Cache:      memory.New(),
+		Logger:     zap.NewNop(),
+	})
+
+	for _, strategy := range []domain.Strategy{domain.StandardStrategy(), domain.DeepStrategy()} {
+		if _, err := svc.Process(context.Background(), &domain.QueryRequest{UserID: 1, Text: "Klarna IPO", Strategy: strategy}); err != nil {
+			t.Fatalf("Process(%s) error = %v", strategy.Type, err)
+		}
+	}
+
+	// у глубокой стратегии свой ключ кеша, ответ стандартной ей не подходит
+	if searchClient.CallCount != 1 || deepSearch.CallCount != 1 {
+		t.Errorf("search calls = %d, deep search calls = %d; want one each", searchClient.CallCount, deepSearch.CallCount)
+	}
+}
+
+// searchByQuery отдает свою выдачу на каждый запрос
+type searchByQuery map[string][]search.SearchResult
+
+func (s searchByQuery) Search(_ context.Context, req search.SearchRequest) (*search.SearchResponse, error) {
+	return &search.SearchResponse{Query: req.Query, Results: s[req.Query]}, nil
+}
+
+func TestQueryService_FusesQueriesByRank(t *testing.T) {
+	sourceRepo := repository.NewMockSourceRepository()
+	sourceRepo.Create(context.Background(), &domain.Source{UserID: 1, URL: "https://mckinsey.com", Name: "McKinsey"})
+	llmClient := llmMock.New().WithResponse(`{"queries": ["q1", "q2"]}`)
+	// сырые оценки вывели бы вперед a, хотя b нашелся по обоим запросам
+	searchClient := searchByQuery{
+		"q1": {{Title: "A", URL: "https://mckinsey.com/a", Score: 0.99}, {Title: "B", URL: "https://mckinsey.com/b", Score: 0.2}},
+		"q2": {{Title: "B", URL: "https://www.mckinsey.com/b/", Score: 0.3}},
+	}
+
+	svc := NewQueryService(QueryServiceDeps{
+		Sources: sourceRepo,
+		LLM:     llmClient,
+		Search:  searchClient,
+		Cache:   memory.New(),
+		Logger:  zap.NewNop(),
+	})
+
+	resp, err := svc.Process(context.Background(), &domain.QueryRequest{UserID: 1, Text: "Klarna", Strategy: domain.StandardStrategy()})
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	if len(resp.Sources) != 2 || resp.Sources[0].URL != "https://mckinsey.com/b" {
+		t.Errorf("sources = %+v, want duplicate merged and ranked first", resp.Sources)
+	}
+}
+
+// fakeFetcher подставляет текст страницы первым topK результатам, с pages - как PDF
+type fakeFetcher struct {
+	calls int
+	topK  int
+	query string
+	pages []search.DocPage
+}
+
+func (f *fakeFetcher) Enrich(_ context.Context, query string, results []search.SearchResult, topK int) []search.SearchResult {
+	f.calls++
+	f.topK = topK
+	f.query = query
+	out := append([]search.SearchResult(nil), results...)
+	for i := range min(topK, len(out)) {
+		if len(f.pages) > 0 {
+			out[i].Pages = f.pages
+			out[i].Content = search.PagesContent(f.pages)
+			continue
+		}
+		out[i].Content = "full article text " + strings.Repeat("x", 3000)
+	}
+	return out
+}
+
+func TestQueryService_FetchesFullTextForDeepStrategy(t *testing.T) {
+	sourceRepo := repository.NewMockSourceRepository()
+	sourceRepo.Create(context.Background(), &domain.Source{UserID: 1, URL: "https://mckinsey.com", Name: "McKinsey"})
+	results := []search.SearchResult{{Title: "Test", URL: "https://mckinsey.com/1", Content: "snippet"}}
+	fetcher := &fakeFetcher{}
+
+	for _, strategy := range []domain.Strategy{domain.StandardStrategy(), domain.DeepStrategy()} {
+		llmClient := llmMock.New().WithResponse(`{"queries": ["klarna ipo"]}`)
+		svc := NewQueryService(QueryServiceDeps{
+			Sources: sourceRepo,
+			LLM:     llmClient,
+			Search:  searchMock.New().WithResults(results),
+			Fetcher: fetcher,
+			Cache:   memory.New(),
+			Logger:  zap.NewNop(),
+			Config:  QueryConfig{FetchTopK: 3},
+		})
+
+		if _, err := svc.Process(context.Background(), &domain.QueryRequest{UserID: 1, Text: "Klarna IPO", Strategy: strategy}); err != nil {
+			t.Fatalf("Process(%s) error = %v", strategy.Type, err)
+		}
+
+		deep := strategy.Type == domain.StrategyDeep
+		if got := strings.Contains(llmClient.LastPrompt, "full article text"); got != deep {
+			t.Errorf("%s: analyze prompt has page text = %v, want %v", strategy.Type, got, deep)
+		}
+		// скачанный текст не режется до лимита сниппета
+		if deep && !strings.Contains(llmClient.LastPrompt, strings.Repeat("x", 3000)) {
+			t.Errorf("deep: page text truncated to snippet limit")
+		}
+	}
+
+	if fetcher.calls != 1 || fetcher.topK != 3 || fetcher.query != "Klarna IPO" {
+		t.Errorf("fetcher calls = %d, topK = %d, query = %q; want 1 call with topK 3 and the question", fetcher.calls, fetcher.topK, fetcher.query)
+	}
+}
+
+func TestQueryService_PDFPageCitations(t *testing.T) {
+	sourceRepo := repository.NewMockSourceRepository()
+	sourceRepo.Create(context.Background(), &domain.Source{UserID: 1, URL: "https://mckinsey.com", Name: "McKinsey"})
+	results := []search.SearchResult{
+		{Title: "Global Payments Report", URL: "https://mckinsey.com/report.pdf", Content: "snippet"},
+		{Title: "Article", URL: "https://mckinsey.com/article", Content: "snippet"},
+	}
+	fetcher := &fakeFetcher{pages: []search.DocPage{
+		{Number: 12, Text: "BNPL volume grew 30%"},
+		{Number: 3, Text: "Contents"},
+	}}
+	// страницы 40 модель не видела, ссылка на нее не должна попасть в источники
+	llmClient := llmMock.New().WithResponses(`{"queries": ["bnpl volume"]}`,
+		"BNPL вырос на 30% [S1 p.12], [S1, p. 3] и [S1 p.40] [S2]")
+
+	svc := NewQueryService(QueryServiceDeps{
+		Sources: sourceRepo,
+		LLM:     llmClient,
+		Search:  searchMock.New().WithResults(results),
+		Fetcher: fetcher,
+		Cache:   memory.New(),
+		Logger:  zap.NewNop(),
+		Config:  QueryConfig{FetchTopK: 1},
+	})
+
+	resp, err := svc.Process(context.Background(), &domain.QueryRequest{UserID: 1, Text: "BNPL volume", Strategy: domain.DeepStrategy()})
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	if !strings.Contains(llmClient.LastPrompt, "[p.12] BNPL volume grew 30%") {
+		t.Errorf("analyze prompt should contain page-numbered text, got %q", llmClient.LastPrompt)
+	}
+	if len(resp.Sources) != 2 || !slices.Equal(resp.Sources[0].Pages, []int{3, 12}) || resp.Sources[1].Pages != nil {
+		t.Errorf("sources = %+v, want pages [3 12] on the report only", resp.Sources)
+	}
+}
+
+func TestQueryService_TimeWindowAndRecency(t *testing.T) {
+	sourceRepo := repository.NewMockSourceRepository()
+	sourceRepo.Create(context.Background(), &domain.Source{UserID: 1, URL: "https://mckinsey.com", Name: "McKinsey"})
+	now := time.Now()
+	// по месту в выдаче вперед вышла бы старая статья
+	searchClient := searchMock.New().WithResults([]search.SearchResult{
+		{Title: "Old", URL: "https://mckinsey.com/old", Content: "old", PublishedDate: now.AddDate(-2, 0, 0).Format(time.DateOnly)},
+		{Title: "Fresh", URL: "https://mckinsey.com/fresh", Content: "fresh", PublishedDate: now.AddDate(0, 0, -3).Format(time.RFC3339)},
+	})
+	llmClient := llmMock.New().WithResponse(`{"queries": ["klarna ipo"]}`)
+
+	svc := NewQueryService(QueryServiceDeps{
+		Sources: sourceRepo,
+		LLM:     llmClient,
+		Search:  searchClient,
+		Cache:   memory.New(),
+		Logger:  zap.NewNop(),
+	})
+
+	strategy := domain.StandardStrategy()
+	resp, err := svc.Process(context.Background(), &domain.QueryRequest{UserID: 1, Text: "Klarna IPO", Strategy: strategy})
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	if resp.Sources[0].Title != "Fresh" || resp.Sources[0].PublishedAt.IsZero() {
+		t.Errorf("sources = %+v, want fresh article first with its date", resp.Sources)
+	}
+	if searchClient.LastRequest.TimeRange != "" {
+		t.Errorf("TimeRange = %q, want none without window", searchClient.LastRequest.TimeRange)
+	}
+
+	// с окном - другой ключ кеша и TimeRange в запросе
+	strategy.TimeWindow = domain.TimeWindowWeek
+	if _, err := svc.Process(context.Background(), &domain.QueryRequest{UserID: 1, Text: "Klarna IPO", Strategy: strategy}); err != nil {
+		t.Fatalf("Process(week) error = %v", err)
+	}
+	if searchClient.CallCount != 2 || searchClient.LastRequest.TimeRange != "week" {
+		t.Errorf("search calls = %d, TimeRange = %q; want 2 calls, week", searchClient.CallCount, searchClient.LastRequest.TimeRange)
+	}
+}
+
+func TestQueryService_OnlyReliable(t *testing.T) {
+	sourceRepo := repository.NewMockSourceRepository()
+	sourceRepo.Create(context.Background(), &domain.Source{UserID: 1, URL: "https://mckinsey.com", Name: "McKinsey", TrustLevel: domain.TrustHigh})
+	sourceRepo.Create(context.Background(), &domain.Source{UserID: 1, URL: "https://reddit.com", Name: "Reddit", TrustLevel: domain.TrustLow})
+	searchClient := searchMock.New().WithResults([]search.SearchResult{
+		{Title: "Thread", URL: "https://reddit.com/r/fintech/1", Content: "rumor", Score: 1},
+		{Title: "Report", URL: "https://mckinsey.com/report", Content: "facts", Score: 0.5},
+	})
+	llmClient := llmMock.New().WithResponse(`{"queries": ["bnpl"]}`)
+
+	svc := NewQueryService(QueryServiceDeps{
+		Sources: sourceRepo,
+		LLM:     llmClient,
+		Search:  searchClient,
+		Cache:   memory.New(),
+		Logger:  zap.NewNop(),
+	})
+
+	// без флага ищем везде, но источник с высоким доверием выше
+	resp, err := svc.Process(context.Background(), &domain.QueryRequest{UserID: 1, Text: "BNPL", Strategy: domain.QuickStrategy()})
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	if len(resp.Sources) != 2 || resp.Sources[0].TrustLevel != domain.TrustHigh {
+		t.Errorf("sources = %+v, want high-trust source first", resp.Sources)
+	}
+	if !strings.Contains(llmClient.LastPrompt, "Trust: high") || !strings.Contains(llmClient.LastPrompt, "Trust: low") {
+		t.Errorf("analyze prompt should contain trust levels: %q", llmClient.LastPrompt)
+	}
+
+	resp, err = svc.Process(context.Background(), &domain.QueryRequest{UserID: 1, Text: "BNPL", OnlyReliable: true, Strategy: domain.QuickStrategy()})
+	if err != nil {
+		t.Fatalf("Process(OnlyReliable) error = %v", err)
+	}
+	if domains := searchClient.LastRequest.IncludeDomains; len(domains) != 1 || domains[0] != "mckinsey.com" {
+		t.Errorf("IncludeDomains = %v, want only high-trust domain", domains)
+	}
+	if len(resp.Sources) != 1 || resp.Sources[0].URL != "https://mckinsey.com/report" {
+		t.Errorf("sources = %+v, want only high-trust results", resp.Sources)
+	}
+}
+
+func TestQueryService_OnlyReliableWithoutTrustedSources(t *testing.T) {
+	sourceRepo := repository.NewMockSourceRepository()
+	sourceRepo.Create(context.Background(), &domain.Source{UserID: 1, URL: "https://reddit.com", Name: "Reddit", TrustLevel: domain.TrustLow})
+	searchClient := searchMock.New()
+
+	svc := NewQueryService(QueryServiceDeps{
+		Sources: sourceRepo,
+		LLM:     llmMock.New(),
+		Search:  searchClient,
+		Cache:   memory.New(),
+		Logger:  zap.NewNop(),
+	})
+
+	_, err := svc.Process(context.Background(), &domain.QueryRequest{UserID: 1, Text: "BNPL", OnlyReliable: true, Strategy: domain.QuickStrategy()})
+	if !errors.Is(err, domain.ErrNoTrustedSources) {
+		t.Errorf("Process() error = %v, want ErrNoTrustedSources", err)
+	}
+	if searchClient.CallCount != 0 {
+		t.Errorf("search calls = %d, want none", searchClient.CallCount)
+	}
+}
+
+func TestQueryService_BlockedDomains(t *testing.T) {
+	ctx := context.Background()
+	sourceRepo := repository.NewMockSourceRepository()
+	sourceRepo.Create(ctx, &domain.Source{UserID: 1, URL: "https://example.com", Name: "Example", TrustLevel: domain.TrustHigh})
+	sourceRepo.Create(ctx, &domain.Source{UserID: 1, URL: "https://reddit.com", Name: "Reddit", TrustLevel: domain.TrustLow})
+	sourceRepo.BlockDomain(ctx, 1, "reddit.com")
+	sourceRepo.BlockDomain(ctx, 1, "forum.example.com")
+	// поисковик не соблюдает ExcludeDomains
+	searchClient := searchMock.New().WithResults([]search.SearchResult{
+		{Title: "Thread", URL: "https://forum.example.com/t/1", Content: "rumor", Score: 1},
+		{Title: "Report", URL: "https://example.com/report", Content: "facts", Score: 0.5},
+	})
+
+	svc := NewQueryService(QueryServiceDeps{
+		Sources: sourceRepo,
+		LLM:     llmMock.New().WithResponse(`{"queries": ["bnpl"]}`),
+		Search:  searchClient,
+		Cache:   memory.New(),
+		Logger:  zap.NewNop(),
+	})
+
+	resp, err := svc.Process(ctx, &domain.QueryRequest{UserID: 1, Text: "BNPL", Strategy: domain.QuickStrategy()})
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	req := searchClient.LastRequest
+	if len(req.IncludeDomains) != 1 || req.IncludeDomains[0] != "example.com" {
+		t.Errorf("IncludeDomains = %v, want blocked source skipped", req.IncludeDomains)
+	}
+	if len(req.ExcludeDomains) != 2 {
+		t.Errorf("ExcludeDomains = %v, want blocklist", req.ExcludeDomains)
+	}
+	if len(resp.Sources) != 1 || resp.Sources[0].URL != "https://example.com/report" {
+		t.Errorf("sources = %+v, want blocked subdomain filtered out", resp.Sources)
+	}
+}
+
+func TestQueryService_AllSourcesBlocked(t *testing.T) {
+	ctx := context.Background()
+	sourceRepo := repository.NewMockSourceRepository()
+	sourceRepo.Create(ctx, &domain.Source{UserID: 1, URL: "https://reddit.com", Name: "Reddit"})
+	sourceRepo.BlockDomain(ctx, 1, "reddit.com")
+	searchClient := searchMock.New()
+
+	svc := NewQueryService(QueryServiceDeps{
+		Sources: sourceRepo,
+		LLM:     llmMock.New(),
+		Search:  searchClient,
+		Cache:   memory.New(),
+		Logger:  zap.NewNop(),
+	})
+
+	_, err := svc.Process(ctx, &domain.QueryRequest{UserID: 1, Text: "BNPL", Strategy: domain.QuickStrategy()})
+	if !errors.Is(err, domain.ErrNoSources) {
+		t.Errorf("Process() error = %v, want ErrNoSources", err)
+	}
+	if searchClient.CallCount != 0 {
+		t.Errorf("search calls = %d, want none", searchClient.CallCount)
+	}
+}
+
+func TestQueryService_PathScopedSources(t *testing.T) {
+	ctx := context.Background()
+	sourceRepo := repository.NewMockSourceRepository()
+	sourceRepo.Create(ctx, &domain.Source{UserID: 1, URL: "https://kpmg.com/xx/en/what-we-do/industries/financial-services.html", Name: "KPMG", TrustLevel: domain.TrustHigh})
+	sourceRepo.Create(ctx, &domain.Source{UserID: 1, URL: "https://www.kpmg.com/xx/en/careers/", Name: "KPMG careers", TrustLevel: domain.TrustLow})
+	searchClient := searchMock.New().WithResults([]search.SearchResult{
+		{Title: "Careers", URL: "https://kpmg.com/xx/en/careers/fintech-jobs.html", Content: "jobs", Score: 1},
+		{Title: "Tax", URL: "https://kpmg.com/us/en/tax.html", Content: "tax", Score: 0.9},
+		{Title: "Fintech pulse", URL: "https://kpmg.com/xx/en/what-we-do/industries/pulse.html", Content: "facts", Score: 0.5},
+	})
+
+	svc := NewQueryService(QueryServiceDeps{
+		Sources: sourceRepo,
+		LLM:     llmMock.New().WithResponse(`{"queries": ["bnpl"]}`),
+		Search:  searchClient,
+		Cache:   memory.New(),
+		Logger:  zap.NewNop(),
+	})
+
+	resp, err := svc.Process(ctx, &domain.QueryRequest{UserID: 1, Text: "BNPL", Strategy: domain.QuickStrategy()})
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	if domains := searchClient.LastRequest.IncludeDomains; len(domains) != 1 || domains[0] != "kpmg.com" {
+		t.Errorf("IncludeDomains = %v, want one kpmg.com", domains)
+	}
+	if len(resp.Sources) != 2 {
+		t.Fatalf("sources = %+v, want results outside source sections dropped", resp.Sources)
+	}
+	if resp.Sources[0].Title != "Fintech pulse" || resp.Sources[0].TrustLevel != domain.TrustHigh {
+		t.Errorf("top = %+v, want high-trust section first", resp.Sources[0])
+	}
+	if resp.Sources[1].TrustLevel != domain.TrustLow {
+		t.Errorf("careers trust = %s, want low from its own section", resp.Sources[1].TrustLevel)
+	}
+}
@@ -25,6 +25,10 @@ type SourceService interface {
 	List(ctx context.Context, userID int64) ([]domain.Source, error)
 	ImportSeed(ctx context.Context, userID int64) (int, error)
 	SetTrustLevel(ctx context.Context, userID, sourceID int64, level domain.TrustLevel) error
+	// Block и Unblock принимают домен или URL и возвращают нормализованный домен
+	Block(ctx context.Context, userID int64, input string) (string, error)
+	Unblock(ctx context.Context, userID int64, input string) (string, error)
+	ListBlocked(ctx context.Context, userID int64) ([]string, error)
 }
 
 type sourceService struct {
@@ -150,6 +154,54 @@ func (s *sourceService) SetTrustLevel(ctx context.Context, userID, sourceID int6
 	return nil
 }
 
+func (s *sourceService) Block(ctx context.Context, userID int64, input string) (string, error) {
+	d, err := domain.NormalizeDomain(input)
+	if err != nil {
+		return "", err
+	}
+
+	blocked, err := s.repo.ListBlockedDomains(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	if len(blocked) >= domain.MaxBlockedDomainsPerUser {
+		return "", domain.ErrBlocklistFull
+	}
+
+	if err := s.repo.BlockDomain(ctx, userID, d); err != nil {
+		return "", err
+	}
+
+	s.logger.Info("domain blocked",
+		zap.Int64("user_id", userID),
+		zap.String("domain", d),
+	)
+
+	return d, nil
+}
+
+func (s *sourceService) Unblock(ctx context.Context, userID int64, input string) (string, error) {
+	d, err := domain.NormalizeDomain(input)
+	if err != nil {
+		return "", err
+	}
+
+	if err := s.repo.UnblockDomain(ctx, userID, d); err != nil {
+		return "", err
+	}
+
+	s.logger.Info("domain unblocked",
+		zap.Int64("user_id", userID),
+		zap.String("domain", d),
+	)
+
+	return d, nil
+}
+
+func (s *sourceService) ListBlocked(ctx context.Context, userID int64) ([]string, error) {
+	return s.repo.ListBlockedDomains(ctx, userID)
+}
+
 func loadSeedSources() ([]SeedSource, error) {
 	var sources []SeedSource
 	if err := json.Unmarshal(seedSourcesJSON, &sources); err != nil {
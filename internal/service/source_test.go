@@ -2,6 +2,8 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"testing"
 
 	"go.uber.org/zap"
@@ -169,3 +171,52 @@ func TestSourceService_ImportSeed(t *testing.T) {
 		t.Errorf("ImportSeed() second import = %d, want 0 (all should exist)", count2)
 	}
 }
+
+func TestSourceService_Block(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMockSourceRepository()
+	svc := NewSourceService(repo, zap.NewNop())
+
+	d, err := svc.Block(ctx, 1, "https://www.Reddit.com/r/fintech")
+	if err != nil {
+		t.Fatalf("Block() error = %v", err)
+	}
+	if d != "reddit.com" {
+		t.Errorf("Block() = %q, want reddit.com", d)
+	}
+
+	if _, err := svc.Block(ctx, 1, "reddit.com"); !errors.Is(err, domain.ErrDomainAlreadyBlocked) {
+		t.Errorf("Block() duplicate error = %v, want ErrDomainAlreadyBlocked", err)
+	}
+	if _, err := svc.Block(ctx, 1, "not a domain"); !errors.Is(err, domain.ErrInvalidDomain) {
+		t.Errorf("Block() invalid error = %v, want ErrInvalidDomain", err)
+	}
+
+	blocked, _ := svc.ListBlocked(ctx, 1)
+	if len(blocked) != 1 || blocked[0] != "reddit.com" {
+		t.Errorf("ListBlocked() = %v, want [reddit.com]", blocked)
+	}
+	if other, _ := svc.ListBlocked(ctx, 2); len(other) != 0 {
+		t.Errorf("ListBlocked() for other user = %v, want empty", other)
+	}
+
+	if _, err := svc.Unblock(ctx, 1, "reddit.com"); err != nil {
+		t.Fatalf("Unblock() error = %v", err)
+	}
+	if _, err := svc.Unblock(ctx, 1, "reddit.com"); !errors.Is(err, domain.ErrDomainNotBlocked) {
+		t.Errorf("Unblock() twice error = %v, want ErrDomainNotBlocked", err)
+	}
+}
+
+func TestSourceService_BlockLimit(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMockSourceRepository()
+	for i := range domain.MaxBlockedDomainsPerUser {
+		repo.BlockDomain(ctx, 1, fmt.Sprintf("site%d.com", i))
+	}
+
+	svc := NewSourceService(repo, zap.NewNop())
+	if _, err := svc.Block(ctx, 1, "one-more.com"); !errors.Is(err, domain.ErrBlocklistFull) {
+		t.Errorf("Block() over limit error = %v, want ErrBlocklistFull", err)
+	}
+}
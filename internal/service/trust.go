@@ -0,0 +1,60 @@
+package service
+
+import (
+	"sort"
+
+	"github.com/kitbuilder587/fintech-bot/internal/domain"
+	"github.com/kitbuilder587/fintech-bot/internal/search"
+)
+
+// sourceTrust - доверие по областям источников. URL получает уровень самой узкой
+// подходящей области: раздел kpmg.com/xx/en/ может быть high при medium на весь kpmg.com
+type sourceTrust struct {
+	scopes []domain.SourceScope
+	levels []domain.TrustLevel
+}
+
+func (t *sourceTrust) add(scope domain.SourceScope, level domain.TrustLevel) {
+	t.scopes = append(t.scopes, scope)
+	t.levels = append(t.levels, level)
+}
+
+// trustOf - уровень доверия к результату. Поддомены наследуют уровень источника
+// на весь сайт (insights.mckinsey.com -> mckinsey.com), чужой сайт - medium
+func trustOf(url string, trust sourceTrust) domain.TrustLevel {
+	if i := domain.NarrowestScope(url, trust.scopes); i >= 0 {
+		return trust.levels[i]
+	}
+	return domain.TrustMedium
+}
+
+// setTrust проставляет уровень доверия, порядок не меняет: на номера [S#] уже могут ссылаться
+func setTrust(results []search.SearchResult, trust sourceTrust) {
+	for i := range results {
+		results[i].Trust = trustOf(results[i].URL, trust)
+	}
+}
+
+// rankByTrust умножает оценку поисковика на вес доверия и пересортировывает.
+// Релевантный результат с низким доверием может уступить чуть менее релевантному с высоким
+func rankByTrust(results []search.SearchResult, trust sourceTrust) {
+	setTrust(results, trust)
+	for i := range results {
+		results[i].Score *= results[i].Trust.Weight()
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+	search.NormalizeScores(results)
+}
+
+// onlyTrusted оставляет результаты с высоким доверием
+func onlyTrusted(results []search.SearchResult) []search.SearchResult {
+	var out []search.SearchResult
+	for _, r := range results {
+		if r.Trust == domain.TrustHigh {
+			out = append(out, r)
+		}
+	}
+	return out
+}
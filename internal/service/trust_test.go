@@ -0,0 +1,70 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/kitbuilder587/fintech-bot/internal/domain"
+	"github.com/kitbuilder587/fintech-bot/internal/search"
+)
+
+func newTrust(sources map[string]domain.TrustLevel) sourceTrust {
+	var trust sourceTrust
+	for url, level := range sources {
+		trust.add(domain.ScopeOf(url), level)
+	}
+	return trust
+}
+
+func TestTrustOf(t *testing.T) {
+	trust := newTrust(map[string]domain.TrustLevel{
+		"https://mckinsey.com":                   domain.TrustHigh,
+		"https://reddit.com":                     domain.TrustLow,
+		"https://kpmg.com":                       domain.TrustLow,
+		"https://kpmg.com/xx/en/industries.html": domain.TrustHigh,
+	})
+
+	tests := []struct {
+		url  string
+		want domain.TrustLevel
+	}{
+		{"https://www.mckinsey.com/insights", domain.TrustHigh},
+		{"https://insights.mckinsey.com/report", domain.TrustHigh},
+		{"https://old.reddit.com/r/fintech", domain.TrustLow},
+		{"https://example.com/news", domain.TrustMedium},
+		{"https://com/", domain.TrustMedium},
+		{"https://kpmg.com/xx/en/fintech-report.html", domain.TrustHigh},
+		{"https://kpmg.com/us/en/careers.html", domain.TrustLow},
+	}
+	for _, tt := range tests {
+		if got := trustOf(tt.url, trust); got != tt.want {
+			t.Errorf("trustOf(%q) = %s, want %s", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestRankByTrust(t *testing.T) {
+	trust := newTrust(map[string]domain.TrustLevel{
+		"https://reddit.com":   domain.TrustLow,
+		"https://mckinsey.com": domain.TrustHigh,
+	})
+	results := []search.SearchResult{
+		{URL: "https://reddit.com/a", Score: 1},
+		{URL: "https://mckinsey.com/b", Score: 0.6},
+		{URL: "https://example.com/c", Score: 0.5},
+	}
+
+	rankByTrust(results, trust)
+
+	if results[0].URL != "https://mckinsey.com/b" || results[0].Trust != domain.TrustHigh {
+		t.Errorf("top = %+v, want high-trust result first", results[0])
+	}
+	if results[2].URL != "https://example.com/c" || results[2].Trust != domain.TrustMedium {
+		t.Errorf("last = %+v, want unknown domain with medium trust last", results[2])
+	}
+	if results[0].Score != 1 {
+		t.Errorf("top score = %v, want normalized to 1", results[0].Score)
+	}
+	if got := onlyTrusted(results); len(got) != 1 || got[0].URL != "https://mckinsey.com/b" {
+		t.Errorf("onlyTrusted() = %+v, want only mckinsey", got)
+	}
+}
@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/kitbuilder587/fintech-bot/internal/domain"
+	"github.com/kitbuilder587/fintech-bot/internal/llm"
+	"github.com/kitbuilder587/fintech-bot/internal/metrics"
+	"github.com/kitbuilder587/fintech-bot/internal/repository"
+)
+
+const usageReportWindow = 30 * 24 * time.Hour
+
+type UsageService interface {
+	Record(ctx context.Context, userID int64, records []llm.UsageRecord) error
+	Report(ctx context.Context, userID int64) (*domain.UsageReport, error)
+}
+
+type usageService struct {
+	repo    repository.UsageRepository
+	logger  *zap.Logger
+	metrics *metrics.Metrics
+	now     func() time.Time
+
+	// о модели без цены предупреждаем один раз, а не на каждый вызов
+	unpriced sync.Map
+}
+
+func NewUsageService(repo repository.UsageRepository, logger *zap.Logger, m *metrics.Metrics) UsageService {
+	return &usageService{
+		repo:    repo,
+		logger:  logger,
+		metrics: m,
+		now:     time.Now,
+	}
+}
+
+// Record считает стоимость по таблице цен, пишет метрики и сохраняет расход пользователя
+func (s *usageService) Record(ctx context.Context, userID int64, records []llm.UsageRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	now := s.now()
+	rows := make([]domain.UsageRecord, 0, len(records))
+	for _, r := range records {
+		price, ok := domain.PriceFor(r.Model)
+		if !ok {
+			if _, warned := s.unpriced.LoadOrStore(r.Model, true); !warned {
+				s.logger.Warn("no price for LLM model, cost will be zero", zap.String("model", r.Model))
+			}
+		}
+		cost := price.Cost(r.PromptTokens, r.CompletionTokens)
+
+		if s.metrics != nil {
+			s.metrics.RecordLLMUsage(r.Provider, r.Model, r.Stage, r.PromptTokens, r.CompletionTokens, cost)
+		}
+
+		rows = append(rows, domain.UsageRecord{
+			UserID:           userID,
+			Provider:         r.Provider,
+			Model:            r.Model,
+			Stage:            r.Stage,
+			PromptTokens:     r.PromptTokens,
+			CompletionTokens: r.CompletionTokens,
+			CostUSD:          cost,
+			CreatedAt:        now,
+		})
+	}
+
+	return s.repo.SaveUsage(ctx, rows)
+}
+
+func (s *usageService) Report(ctx context.Context, userID int64) (*domain.UsageReport, error) {
+	now := s.now()
+
+	monthByStage, err := s.repo.GetUsageByStage(ctx, userID, now.Add(-usageReportWindow))
+	if err != nil {
+		return nil, err
+	}
+
+	y, m, d := now.Date()
+	today, err := s.repo.GetUsageByStage(ctx, userID, time.Date(y, m, d, 0, 0, 0, 0, now.Location()))
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.UsageReport{
+		Today:        domain.SumUsage(today),
+		Month:        domain.SumUsage(monthByStage),
+		MonthByStage: monthByStage,
+	}, nil
+}
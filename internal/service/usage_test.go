@@ -0,0 +1,62 @@
+package service
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/kitbuilder587/fintech-bot/internal/domain"
+	"github.com/kitbuilder587/fintech-bot/internal/llm"
+	"github.com/kitbuilder587/fintech-bot/internal/repository"
+)
+
+func TestUsageService_RecordAndReport(t *testing.T) {
+	repo := repository.NewMockUsageRepository()
+	svc := NewUsageService(repo, zap.NewNop(), nil).(*usageService)
+
+	now := time.Date(2025, 3, 10, 15, 0, 0, 0, time.UTC)
+	svc.now = func() time.Time { return now.Add(-48 * time.Hour) }
+
+	ctx := context.Background()
+	err := svc.Record(ctx, 1, []llm.UsageRecord{
+		{Provider: "openrouter", Model: "deepseek/deepseek-chat", Stage: llm.StageExpand, Usage: llm.Usage{PromptTokens: 1000, CompletionTokens: 100}},
+	})
+	if err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+
+	svc.now = func() time.Time { return now }
+	err = svc.Record(ctx, 1, []llm.UsageRecord{
+		{Provider: "openrouter", Model: "deepseek/deepseek-chat-v3-0324", Stage: llm.StageSynthesize, Usage: llm.Usage{PromptTokens: 2000, CompletionTokens: 500}},
+		{Provider: "mock", Model: "unknown-model", Stage: llm.StageCritic, Usage: llm.Usage{PromptTokens: 10}},
+	})
+	if err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+	svc.Record(ctx, 2, []llm.UsageRecord{
+		{Provider: "openrouter", Model: "deepseek/deepseek-chat", Stage: llm.StageExpand, Usage: llm.Usage{PromptTokens: 5}},
+	})
+
+	records := repo.Records()
+	price := domain.ModelPrices["deepseek/deepseek-chat"]
+	if want := price.Cost(2000, 500); math.Abs(records[1].CostUSD-want) > 1e-12 {
+		t.Errorf("synthesize cost = %v, want %v", records[1].CostUSD, want)
+	}
+	if records[2].CostUSD != 0 {
+		t.Errorf("unpriced model cost = %v, want 0", records[2].CostUSD)
+	}
+
+	report, err := svc.Report(ctx, 1)
+	if err != nil {
+		t.Fatalf("Report() error = %v", err)
+	}
+	if report.Today.Calls != 2 || report.Today.Tokens() != 2510 {
+		t.Errorf("Today = %+v, want 2 calls and 2510 tokens", report.Today)
+	}
+	if report.Month.Calls != 3 || len(report.MonthByStage) != 3 {
+		t.Errorf("Month = %+v with %d stages, want 3 calls in 3 stages", report.Month, len(report.MonthByStage))
+	}
+}
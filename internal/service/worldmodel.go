@@ -2,7 +2,7 @@ package service
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -12,15 +12,13 @@ import (
 
 	"github.com/kitbuilder587/fintech-bot/internal/domain"
 	"github.com/kitbuilder587/fintech-bot/internal/llm"
+	"github.com/kitbuilder587/fintech-bot/internal/prompt"
 	"github.com/kitbuilder587/fintech-bot/internal/repository"
 	"github.com/kitbuilder587/fintech-bot/internal/search"
 )
 
 const MaxContextSize = 2000
 
-const ExtractionSystemPrompt = `You are a fact extraction assistant. Extract key facts and named entities from research answers.
-Always respond with valid JSON only, no markdown formatting.`
-
 const ExtractionUserPromptTemplate = `Extract key facts and entities from this research answer.
 
 Answer:
@@ -38,10 +36,16 @@ Answer:
 
 Entity types: company, person, concept, product, market`
 
+// Blocklist - домены, которые пользователь запретил цитировать
+type Blocklist interface {
+	ListBlockedDomains(ctx context.Context, userID int64) ([]string, error)
+}
+
 type WorldModelService struct {
-	repo   repository.WorldModelRepository
-	llm    llm.Client
-	logger *zap.Logger
+	repo      repository.WorldModelRepository
+	llm       llm.Client
+	blocklist Blocklist
+	logger    *zap.Logger
 }
 
 type KnowledgeSummary struct {
@@ -76,31 +80,59 @@ func NewWorldModelService(repo repository.WorldModelRepository, llmClient llm.Cl
 	}
 }
 
+// WithBlocklist - факты с заблокированных доменов не сохраняются и не попадают в контекст,
+// в том числе сохраненные до блокировки
+func (s *WorldModelService) WithBlocklist(b Blocklist) *WorldModelService {
+	s.blocklist = b
+	return s
+}
+
+// blocked - ошибка блок-листа не должна ломать запрос: без него факты просто не фильтруются
+func (s *WorldModelService) blocked(ctx context.Context, userID int64) []string {
+	if s.blocklist == nil {
+		return nil
+	}
+	domains, err := s.blocklist.ListBlockedDomains(ctx, userID)
+	if err != nil {
+		s.logger.Warn("list blocked domains failed", zap.Error(err), zap.Int64("user_id", userID))
+	}
+	return domains
+}
+
 func (s *WorldModelService) ExtractAndStore(ctx context.Context, userID int64, answer string, sources []search.SearchResult, question string, strategy domain.Strategy) error {
+	prompts := prompt.FromContext(ctx)
+	systemPrompt, err := prompts.Render(prompt.Extraction, prompt.Vars{})
+	if err != nil {
+		return err
+	}
+
 	session := &domain.ResearchSession{
-		ID:        uuid.New().String(),
-		UserID:    userID,
-		Question:  question,
-		Strategy:  strategy.Type.String(),
-		CreatedAt: time.Now(),
+		ID:            uuid.New().String(),
+		UserID:        userID,
+		Question:      question,
+		Strategy:      strategy.Type.String(),
+		PromptVersion: prompts.Version(),
+		CreatedAt:     time.Now(),
 	}
 
 	if err := s.repo.CreateSession(ctx, session); err != nil {
 		return err
 	}
 
-	prompt := s.buildExtractionPrompt(answer, sources)
-	response, err := s.llm.CompleteWithSystem(ctx, ExtractionSystemPrompt, prompt)
-	if err != nil {
-		return fmt.Errorf("LLM extraction: %w", err)
+	userPrompt := s.buildExtractionPrompt(answer, sources)
+	extracted, err := llm.CompleteJSON[extractionResponse](llm.WithStage(ctx, llm.StageExtraction), s.llm, systemPrompt, userPrompt, llm.DefaultJSONAttempts)
+	if errors.Is(err, llm.ErrInvalidJSON) {
+		return fmt.Errorf("parse response: %w", err)
 	}
-
-	extracted, err := s.parseExtractionResponse(response)
 	if err != nil {
-		return fmt.Errorf("parse response: %w", err)
+		return fmt.Errorf("LLM extraction: %w", err)
 	}
 
+	blocked := s.blocked(ctx, userID)
 	for _, f := range extracted.Facts {
+		if domain.IsBlocked(f.SourceURL, blocked) {
+			continue
+		}
 		if err := s.saveFact(ctx, userID, session.ID, f); err != nil {
 			s.logger.Warn("failed to save fact",
 				zap.Error(err),
@@ -137,6 +169,7 @@ func (s *WorldModelService) GetRelevantContext(ctx context.Context, userID int64
 		return "", nil
 	}
 
+	blocked := s.blocked(ctx, userID)
 	factSet := make(map[string]domain.Fact)
 	for _, keyword := range keywords {
 		if len(keyword) < 3 { // skip very short words
@@ -148,7 +181,9 @@ func (s *WorldModelService) GetRelevantContext(ctx context.Context, userID int64
 			continue
 		}
 		for _, f := range facts {
-			factSet[f.ID] = f
+			if !domain.IsBlocked(f.SourceURL, blocked) {
+				factSet[f.ID] = f
+			}
 		}
 	}
 
@@ -258,33 +293,6 @@ func (s *WorldModelService) buildExtractionPrompt(answer string, sources []searc
 	return fmt.Sprintf(ExtractionUserPromptTemplate, answer, sourcesSection)
 }
 
-func (s *WorldModelService) parseExtractionResponse(response string) (*extractionResponse, error) {
-	// убираем markdown блоки если LLM их добавил
-	cleaned := response
-	if strings.HasPrefix(strings.TrimSpace(response), "```") {
-		lines := strings.Split(response, "\n")
-		var jsonLines []string
-		inBlock := false
-		for _, line := range lines {
-			if strings.HasPrefix(strings.TrimSpace(line), "```") {
-				inBlock = !inBlock
-				continue
-			}
-			if inBlock {
-				jsonLines = append(jsonLines, line)
-			}
-		}
-		cleaned = strings.Join(jsonLines, "\n")
-	}
-
-	var result extractionResponse
-	if err := json.Unmarshal([]byte(cleaned), &result); err != nil {
-		return nil, err
-	}
-
-	return &result, nil
-}
-
 func (s *WorldModelService) saveFact(ctx context.Context, userID int64, sessionID string, f extractedFact) error {
 	if strings.TrimSpace(f.Content) == "" {
 		return nil
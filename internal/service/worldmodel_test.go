@@ -12,6 +12,7 @@ import (
 
 	"github.com/kitbuilder587/fintech-bot/internal/domain"
 	"github.com/kitbuilder587/fintech-bot/internal/llm/mock"
+	"github.com/kitbuilder587/fintech-bot/internal/prompt"
 	"github.com/kitbuilder587/fintech-bot/internal/repository"
 	"github.com/kitbuilder587/fintech-bot/internal/search"
 )
@@ -56,6 +57,7 @@ func TestWorldModelService_ExtractAndStore(t *testing.T) {
 		assert.Len(t, sessions, 1)
 		assert.Equal(t, "What is Klarna?", sessions[0].Question)
 		assert.Equal(t, strategy.Type.String(), sessions[0].Strategy)
+		assert.Equal(t, prompt.Default().Version(), sessions[0].PromptVersion)
 		assert.Equal(t, 1, mockLLM.CallCount)
 		assert.Contains(t, mockLLM.LastPrompt, "Klarna is a fintech company")
 	})
@@ -122,6 +124,29 @@ func TestWorldModelService_ExtractAndStore(t *testing.T) {
 		assert.Equal(t, "2005", entity.Attributes["founded"])
 	})
 
+	t.Run("skips blocked sources", func(t *testing.T) {
+		repo := repository.NewMockWorldModelRepository()
+		mockLLM := mock.New().WithResponse(`{
+			"facts": [
+				{"content": "Klarna IPO is rumoured", "source_url": "https://old.reddit.com/r/fintech", "confidence": 0.5},
+				{"content": "Klarna filed for IPO", "source_url": "https://example.com/ipo", "confidence": 0.9}
+			],
+			"entities": []
+		}`)
+		sources := repository.NewMockSourceRepository()
+		require.NoError(t, sources.BlockDomain(ctx, 1, "reddit.com"))
+
+		svc := NewWorldModelService(repo, mockLLM, logger).WithBlocklist(sources)
+
+		err := svc.ExtractAndStore(ctx, 1, "Answer about Klarna", nil, "Question?", domain.QuickStrategy())
+		require.NoError(t, err)
+
+		facts, err := repo.GetFactsByUser(ctx, 1, 10)
+		require.NoError(t, err)
+		require.Len(t, facts, 1)
+		assert.Equal(t, "Klarna filed for IPO", facts[0].Content)
+	})
+
 	t.Run("llm error", func(t *testing.T) {
 		repo := repository.NewMockWorldModelRepository()
 		mockLLM := mock.New().WithError(errors.New("LLM unavailable"))
@@ -232,6 +257,21 @@ func TestWorldModelService_GetRelevantContext(t *testing.T) {
 		assert.NotContains(t, context, "Bitcoin")
 	})
 
+	t.Run("blocked sources", func(t *testing.T) {
+		repo := repository.NewMockWorldModelRepository()
+		require.NoError(t, repo.CreateFact(ctx, &domain.Fact{ID: "1", UserID: 1, Content: "Klarna was founded in 2005", SourceURL: "https://example.com"}))
+		require.NoError(t, repo.CreateFact(ctx, &domain.Fact{ID: "2", UserID: 1, Content: "Klarna IPO is rumoured", SourceURL: "https://www.reddit.com/r/fintech"}))
+		sources := repository.NewMockSourceRepository()
+		require.NoError(t, sources.BlockDomain(ctx, 1, "reddit.com"))
+
+		svc := NewWorldModelService(repo, mock.New(), logger).WithBlocklist(sources)
+
+		context, err := svc.GetRelevantContext(ctx, 1, "Tell me about Klarna")
+		require.NoError(t, err)
+		assert.Contains(t, context, "Klarna was founded in 2005")
+		assert.NotContains(t, context, "reddit")
+	})
+
 	t.Run("limit size", func(t *testing.T) {
 		repo := repository.NewMockWorldModelRepository()
 		for i := 0; i < 50; i++ {
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
@@ -22,18 +23,21 @@ type BotConfig struct {
 }
 
 type Bot struct {
-	api           *tgbotapi.BotAPI
-	userService   service.UserService
-	sourceService service.SourceService
-	queryService  service.QueryService
-	logger        *zap.Logger
-	metrics       *metrics.Metrics
-	handler       *Handler
-	rateLimiter   *ratelimit.Limiter
-	wg            sync.WaitGroup
+	api                 *tgbotapi.BotAPI
+	userService         service.UserService
+	sourceService       service.SourceService
+	queryService        service.QueryService
+	usageService        service.UsageService
+	conversationService service.ConversationService
+	logger              *zap.Logger
+	metrics             *metrics.Metrics
+	handler             *Handler
+	rateLimiter         *ratelimit.Limiter
+	wg                  sync.WaitGroup
+	running             atomic.Bool
 }
 
-func New(cfg BotConfig, userSvc service.UserService, sourceSvc service.SourceService, querySvc service.QueryService, logger *zap.Logger, m *metrics.Metrics) (*Bot, error) {
+func New(cfg BotConfig, userSvc service.UserService, sourceSvc service.SourceService, querySvc service.QueryService, usageSvc service.UsageService, conversationSvc service.ConversationService, logger *zap.Logger, m *metrics.Metrics) (*Bot, error) {
 	api, err := tgbotapi.NewBotAPI(cfg.Token)
 	if err != nil {
 		return nil, fmt.Errorf("create bot api: %w", err)
@@ -46,13 +50,15 @@ func New(cfg BotConfig, userSvc service.UserService, sourceSvc service.SourceSer
 	})
 
 	bot := &Bot{
-		api:           api,
-		userService:   userSvc,
-		sourceService: sourceSvc,
-		queryService:  querySvc,
-		logger:        logger,
-		metrics:       m,
-		rateLimiter:   rateLimiter,
+		api:                 api,
+		userService:         userSvc,
+		sourceService:       sourceSvc,
+		queryService:        querySvc,
+		usageService:        usageSvc,
+		conversationService: conversationSvc,
+		logger:              logger,
+		metrics:             m,
+		rateLimiter:         rateLimiter,
 	}
 
 	bot.handler = NewHandler(bot)
@@ -69,6 +75,8 @@ func (b *Bot) Run(ctx context.Context) error {
 	u.Timeout = 60
 
 	updates := b.api.GetUpdatesChan(u)
+	b.running.Store(true)
+	defer b.running.Store(false)
 
 	b.logger.Info("bot started, waiting for updates")
 
@@ -93,6 +101,11 @@ func (b *Bot) Run(ctx context.Context) error {
 	}
 }
 
+// IsRunning - получает ли бот апдейты (для readiness проверки)
+func (b *Bot) IsRunning() bool {
+	return b.running.Load()
+}
+
 func (b *Bot) handleUpdate(ctx context.Context, update tgbotapi.Update) {
 	startTime := time.Now()
 
@@ -134,6 +147,32 @@ func (b *Bot) Send(chatID int64, text string) error {
 	return err
 }
 
+// SendMessage отправляет сообщение и возвращает его id для последующих правок
+func (b *Bot) SendMessage(chatID int64, text string) (int, error) {
+	if b.api == nil {
+		return 0, nil
+	}
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ParseMode = "HTML"
+	msg.DisableWebPagePreview = true
+	sent, err := b.api.Send(msg)
+	if err != nil {
+		return 0, err
+	}
+	return sent.MessageID, nil
+}
+
+func (b *Bot) EditMessage(chatID int64, messageID int, text string) error {
+	if b.api == nil {
+		return nil
+	}
+	edit := tgbotapi.NewEditMessageText(chatID, messageID, text)
+	edit.ParseMode = "HTML"
+	edit.DisableWebPagePreview = true
+	_, err := b.api.Send(edit)
+	return err
+}
+
 func (b *Bot) SendTyping(chatID int64) {
 	if b.api == nil {
 		return
@@ -147,3 +186,8 @@ func (b *Bot) RecordRateLimitHit(userID int64) {
 		b.metrics.RecordRateLimitHit(strconv.FormatInt(userID, 10))
 	}
 }
+
+// Close освобождает ресурсы бота, вызывать после завершения Run
+func (b *Bot) Close() {
+	b.rateLimiter.Stop()
+}
@@ -31,6 +31,9 @@ type MockSourceService struct {
 	ListFunc          func(ctx context.Context, userID int64) ([]domain.Source, error)
 	ImportSeedFunc    func(ctx context.Context, userID int64) (int, error)
 	SetTrustLevelFunc func(ctx context.Context, userID, sourceID int64, level domain.TrustLevel) error
+	BlockFunc         func(ctx context.Context, userID int64, input string) (string, error)
+	UnblockFunc       func(ctx context.Context, userID int64, input string) (string, error)
+	ListBlockedFunc   func(ctx context.Context, userID int64) ([]string, error)
 }
 
 func (m *MockSourceService) Add(ctx context.Context, userID int64, url string) error {
@@ -68,6 +71,27 @@ func (m *MockSourceService) SetTrustLevel(ctx context.Context, userID, sourceID
 	return nil
 }
 
+func (m *MockSourceService) Block(ctx context.Context, userID int64, input string) (string, error) {
+	if m.BlockFunc != nil {
+		return m.BlockFunc(ctx, userID, input)
+	}
+	return input, nil
+}
+
+func (m *MockSourceService) Unblock(ctx context.Context, userID int64, input string) (string, error) {
+	if m.UnblockFunc != nil {
+		return m.UnblockFunc(ctx, userID, input)
+	}
+	return input, nil
+}
+
+func (m *MockSourceService) ListBlocked(ctx context.Context, userID int64) ([]string, error) {
+	if m.ListBlockedFunc != nil {
+		return m.ListBlockedFunc(ctx, userID)
+	}
+	return nil, nil
+}
+
 type MockQueryService struct {
 	ProcessFunc func(ctx context.Context, req *domain.QueryRequest) (*domain.QueryResponse, error)
 }
@@ -1,14 +1,26 @@
 package telegram
 
 import (
+	"regexp"
 	"strings"
 
 	"github.com/kitbuilder587/fintech-bot/internal/domain"
 )
 
 // /quick, /deep, /research -> соответствующая стратегия
+// /recent -> defaultStrategy за последний месяц
+// /reliable -> defaultStrategy, только источники с высоким доверием (см. IsReliableCommand)
 // обычный текст -> defaultStrategy
+// Окно времени из вопроса ("за последнюю неделю") применяется к любой стратегии
 func ParseQueryCommand(text string, defaultStrategy domain.Strategy) (question string, strategy domain.Strategy) {
+	question, strategy = parseCommand(text, defaultStrategy)
+	if window, ok := parseTimeWindow(question); ok {
+		strategy.TimeWindow = window
+	}
+	return question, strategy
+}
+
+func parseCommand(text string, defaultStrategy domain.Strategy) (string, domain.Strategy) {
 	text = strings.TrimSpace(text)
 
 	if text == "" {
@@ -34,11 +46,52 @@ func ParseQueryCommand(text string, defaultStrategy domain.Strategy) (question s
 		return rest, domain.DeepStrategy()
 	case "/research":
 		return rest, domain.StandardStrategy()
+	case "/recent":
+		strategy := defaultStrategy
+		strategy.TimeWindow = domain.TimeWindowMonth
+		return rest, strategy
+	case "/reliable":
+		return rest, defaultStrategy
 	default:
 		return text, defaultStrategy
 	}
 }
 
+// timeQualifiers - уточнения периода в вопросе. Проверяются по порядку,
+// поэтому "за последние 24 часа" не путается с "за последний год"
+var timeQualifiers = []struct {
+	re     *regexp.Regexp
+	window domain.TimeWindow
+}{
+	{qualifier(`сегодня|за (последние |прошедшие )?(сутки|24 часа)|за (последний |прошедший )?день|today|(past|last) (day|24 hours)`), domain.TimeWindowDay},
+	{qualifier(`за (последнюю |прошлую |эту )?неделю|на (этой|прошлой) неделе|за (последние )?7 дней|(past|last|this) week`), domain.TimeWindowWeek},
+	{qualifier(`за (последний |прошлый |этот )?месяц|в (этом|прошлом) месяце|за (последние )?30 дней|(past|last|this) month`), domain.TimeWindowMonth},
+	{qualifier(`за (последний |прошлый |этот )?год|в (этом|прошлом) году|за (последние )?12 месяцев|(past|last|this) year`), domain.TimeWindowYear},
+}
+
+// qualifier - фраза целыми словами: \b в Go знает только ASCII,
+// а "за год" не должно находиться в "за годовой отчет"
+func qualifier(expr string) *regexp.Regexp {
+	return regexp.MustCompile(`(?i)(^|[^\pL])(` + expr + `)($|[^\pL])`)
+}
+
+// parseTimeWindow ищет в вопросе уточнение периода. Сам вопрос не меняется:
+// модели полезно видеть формулировку пользователя
+func parseTimeWindow(question string) (domain.TimeWindow, bool) {
+	for _, q := range timeQualifiers {
+		if q.re.MatchString(question) {
+			return q.window, true
+		}
+	}
+	return domain.TimeWindowAny, false
+}
+
+// IsReliableCommand - запрос через /reliable: искать только по источникам с доверием high
+func IsReliableCommand(text string) bool {
+	command, _, _ := strings.Cut(strings.TrimSpace(text), " ")
+	return strings.EqualFold(command, "/reliable")
+}
+
 func normalizeSpaces(s string) string {
 	fields := strings.Fields(s)
 	return strings.Join(fields, " ")
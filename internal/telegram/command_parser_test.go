@@ -181,3 +181,55 @@ func TestParseQueryCommand_StrategyParams(t *testing.T) {
 		})
 	}
 }
+
+func TestParseQueryCommand_TimeWindow(t *testing.T) {
+	tests := []struct {
+		name             string
+		text             string
+		wantQuestion     string
+		wantStrategyType domain.StrategyType
+		wantWindow       domain.TimeWindow
+	}{
+		{"no qualifier", "тренды BNPL", "тренды BNPL", domain.StrategyStandard, domain.TimeWindowAny},
+		{"/recent", "/recent   тренды BNPL", "тренды BNPL", domain.StrategyStandard, domain.TimeWindowMonth},
+		{"last month", "что нового в BNPL за последний месяц", "что нового в BNPL за последний месяц", domain.StrategyStandard, domain.TimeWindowMonth},
+		{"week with deep", "/deep новости Revolut за неделю", "новости Revolut за неделю", domain.StrategyDeep, domain.TimeWindowWeek},
+		{"qualifier overrides /recent", "/recent сделки M&A за последние сутки", "сделки M&A за последние сутки", domain.StrategyStandard, domain.TimeWindowDay},
+		{"this year", "Stripe acquisitions this year", "Stripe acquisitions this year", domain.StrategyStandard, domain.TimeWindowYear},
+		{"part of a word", "выручка Klarna за годовой период", "выручка Klarna за годовой период", domain.StrategyStandard, domain.TimeWindowAny},
+		{"capitalized", "Сегодня что с курсом биткоина?", "Сегодня что с курсом биткоина?", domain.StrategyStandard, domain.TimeWindowDay},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			question, strategy := ParseQueryCommand(tt.text, domain.StandardStrategy())
+
+			if question != tt.wantQuestion {
+				t.Errorf("question = %q, want %q", question, tt.wantQuestion)
+			}
+			if strategy.Type != tt.wantStrategyType || strategy.TimeWindow != tt.wantWindow {
+				t.Errorf("strategy = %s/%q, want %s/%q", strategy.Type, strategy.TimeWindow, tt.wantStrategyType, tt.wantWindow)
+			}
+		})
+	}
+}
+
+func TestParseQueryCommand_Reliable(t *testing.T) {
+	question, strategy := ParseQueryCommand("/reliable  регулирование BNPL", domain.DeepStrategy())
+
+	if question != "регулирование BNPL" || strategy.Type != domain.StrategyDeep {
+		t.Errorf("ParseQueryCommand() = %q, %s; want question and default strategy", question, strategy.Type)
+	}
+
+	for text, want := range map[string]bool{
+		"/reliable регулирование BNPL": true,
+		"/RELIABLE тест":               true,
+		"/reliable":                    true,
+		"/deep тест":                   false,
+		"reliable источники по BNPL":   false,
+	} {
+		if got := IsReliableCommand(text); got != want {
+			t.Errorf("IsReliableCommand(%q) = %v, want %v", text, got, want)
+		}
+	}
+}
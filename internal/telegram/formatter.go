@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"html"
 	"strings"
+	"time"
 
 	"github.com/kitbuilder587/fintech-bot/internal/domain"
 )
@@ -18,7 +19,7 @@ func FormatSourcesList(sources []domain.Source) string {
 			i+1,
 			trustIcon,
 			html.EscapeString(s.Name),
-			html.EscapeString(s.Domain()),
+			html.EscapeString(s.Scope().String()),
 			s.TrustLevel,
 		))
 	}
@@ -27,6 +28,20 @@ func FormatSourcesList(sources []domain.Source) string {
 	return sb.String()
 }
 
+func FormatBlockedList(domains []string) string {
+	if len(domains) == 0 {
+		return "Заблокированных доменов нет. Используйте /block example.com, чтобы исключить домен из ответов."
+	}
+
+	var sb strings.Builder
+	sb.WriteString("<b>Заблокированные домены:</b>\n\n")
+	for i, d := range domains {
+		sb.WriteString(fmt.Sprintf("%d. %s\n", i+1, html.EscapeString(d)))
+	}
+	sb.WriteString("\nСнять блокировку: /unblock домен")
+	return sb.String()
+}
+
 func FormatQueryResponse(resp *domain.QueryResponse) string {
 	var sb strings.Builder
 	sb.WriteString(html.EscapeString(resp.Text))
@@ -38,13 +53,15 @@ func FormatQueryResponse(resp *domain.QueryResponse) string {
 		for _, src := range resp.Sources {
 			trustIcon := getTrustIcon(src.TrustLevel)
 			escapedURL := html.EscapeString(src.URL)
-			sb.WriteString(fmt.Sprintf("%s %s %s\n   <a href=\"%s\">%s</a> [%s]\n",
+			sb.WriteString(fmt.Sprintf("%s %s %s%s\n   <a href=\"%s\">%s</a> [%s]%s\n",
 				src.Marker,
 				trustIcon,
 				html.EscapeString(src.Title),
+				formatPublished(src.PublishedAt),
 				escapedURL,
 				html.EscapeString(truncateURL(src.URL, 50)),
 				src.TrustLevel,
+				formatPages(src.URL, src.Pages),
 			))
 		}
 	}
@@ -52,6 +69,53 @@ func FormatQueryResponse(resp *domain.QueryResponse) string {
 	return sb.String()
 }
 
+// formatPublished - дата публикации после заголовка: читателю сразу видно,
+// что источник устарел. Неизвестная дата не выводится
+func formatPublished(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return " (" + t.Format("02.01.2006") + ")"
+}
+
+// formatPages - страницы документа, на которые ссылается ответ. Ссылка #page=N
+// открывает PDF сразу на нужной странице в браузере
+func formatPages(url string, pages []int) string {
+	if len(pages) == 0 {
+		return ""
+	}
+	links := make([]string, len(pages))
+	for i, p := range pages {
+		links[i] = fmt.Sprintf("<a href=\"%s#page=%d\">%d</a>", html.EscapeString(url), p, p)
+	}
+	return "\n   стр. " + strings.Join(links, ", ")
+}
+
+func FormatUsageReport(r *domain.UsageReport) string {
+	if r.Month.Calls == 0 {
+		return "За последние 30 дней запросов к LLM не было."
+	}
+
+	var sb strings.Builder
+	sb.WriteString("<b>Расход LLM</b>\n\n")
+	sb.WriteString("Сегодня: " + formatUsageTotals(r.Today) + "\n")
+	sb.WriteString("За 30 дней: " + formatUsageTotals(r.Month) + "\n")
+
+	if len(r.MonthByStage) > 0 {
+		sb.WriteString("\n<b>По стадиям за 30 дней:</b>\n")
+		for _, t := range r.MonthByStage {
+			sb.WriteString(fmt.Sprintf("• %s: %s\n", html.EscapeString(t.Stage), formatUsageTotals(t)))
+		}
+	}
+
+	sb.WriteString("\n<i>Стоимость примерная, по прайсу провайдеров.</i>")
+	return sb.String()
+}
+
+func formatUsageTotals(t domain.UsageTotals) string {
+	return fmt.Sprintf("%d вызовов, %d токенов, $%.4f", t.Calls, t.Tokens(), t.CostUSD)
+}
+
 func SplitMessage(text string, maxLen int) []string {
 	if len(text) <= maxLen {
 		return []string{text}
@@ -3,6 +3,7 @@ package telegram
 import (
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/kitbuilder587/fintech-bot/internal/domain"
 )
@@ -36,6 +37,17 @@ func TestFormatSourcesList(t *testing.T) {
 	}
 }
 
+func TestFormatBlockedList(t *testing.T) {
+	result := FormatBlockedList([]string{"reddit.com", "forum.example.com"})
+	if !strings.Contains(result, "1. reddit.com") || !strings.Contains(result, "2. forum.example.com") {
+		t.Errorf("FormatBlockedList() = %q, want numbered domains", result)
+	}
+
+	if empty := FormatBlockedList(nil); !strings.Contains(empty, "/block") {
+		t.Errorf("FormatBlockedList(nil) = %q, want usage hint", empty)
+	}
+}
+
 func TestFormatQueryResponse(t *testing.T) {
 	resp := &domain.QueryResponse{
 		Text: "This is the answer with [S1] reference.",
@@ -60,6 +72,68 @@ func TestFormatQueryResponse(t *testing.T) {
 	if !strings.Contains(result, "[S1]") {
 		t.Error("FormatQueryResponse() should contain source marker")
 	}
+	if strings.Contains(result, "Source Title (") {
+		t.Errorf("FormatQueryResponse() should not show unknown date, got %q", result)
+	}
+}
+
+func TestFormatQueryResponse_PublishedDate(t *testing.T) {
+	resp := &domain.QueryResponse{
+		Text: "Answer [S1]",
+		Sources: []domain.SourceRef{{
+			Marker:      "[S1]",
+			Title:       "Klarna files for IPO",
+			URL:         "https://example.com/klarna",
+			TrustLevel:  domain.TrustHigh,
+			PublishedAt: time.Date(2025, 3, 14, 9, 0, 0, 0, time.UTC),
+		}},
+	}
+
+	result := FormatQueryResponse(resp)
+	if !strings.Contains(result, "Klarna files for IPO (14.03.2025)") {
+		t.Errorf("FormatQueryResponse() should show publication date, got %q", result)
+	}
+}
+
+func TestFormatQueryResponse_Pages(t *testing.T) {
+	resp := &domain.QueryResponse{
+		Text: "BNPL вырос на 30% [S1 p.12]",
+		Sources: []domain.SourceRef{
+			{Marker: "[S1]", Title: "Payments Report", URL: "https://example.com/report.pdf", TrustLevel: domain.TrustHigh, Pages: []int{3, 12}},
+			{Marker: "[S2]", Title: "Article", URL: "https://example.com/article", TrustLevel: domain.TrustMedium},
+		},
+	}
+
+	result := FormatQueryResponse(resp)
+	want := `стр. <a href="https://example.com/report.pdf#page=3">3</a>, <a href="https://example.com/report.pdf#page=12">12</a>`
+	if !strings.Contains(result, want) {
+		t.Errorf("FormatQueryResponse() should link cited pages, got %q", result)
+	}
+	if strings.Count(result, "стр.") != 1 {
+		t.Errorf("FormatQueryResponse() should show pages only for documents, got %q", result)
+	}
+}
+
+func TestFormatUsageReport(t *testing.T) {
+	empty := FormatUsageReport(&domain.UsageReport{})
+	if !strings.Contains(empty, "не было") {
+		t.Errorf("FormatUsageReport(empty) = %q, want no-usage message", empty)
+	}
+
+	report := &domain.UsageReport{
+		Today: domain.UsageTotals{Calls: 2, PromptTokens: 1000, CompletionTokens: 200, CostUSD: 0.0012},
+		Month: domain.UsageTotals{Calls: 5, PromptTokens: 3000, CompletionTokens: 600, CostUSD: 0.0034},
+		MonthByStage: []domain.UsageTotals{
+			{Stage: "agent:market-analyst", Calls: 3, PromptTokens: 2000, CompletionTokens: 400, CostUSD: 0.002},
+		},
+	}
+	result := FormatUsageReport(report)
+
+	for _, want := range []string{"Сегодня: 2 вызовов, 1200 токенов, $0.0012", "agent:market-analyst", "$0.0034"} {
+		if !strings.Contains(result, want) {
+			t.Errorf("FormatUsageReport() should contain %q, got %q", want, result)
+		}
+	}
 }
 
 func TestSplitMessage(t *testing.T) {
@@ -11,6 +11,7 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/kitbuilder587/fintech-bot/internal/domain"
+	"github.com/kitbuilder587/fintech-bot/internal/llm"
 )
 
 type Handler struct {
@@ -32,7 +33,7 @@ func (h *Handler) HandleMessage(ctx context.Context, msg *tgbotapi.Message) {
 
 	if msg.IsCommand() {
 		cmd := msg.Command()
-		if cmd == "quick" || cmd == "deep" || cmd == "research" {
+		if cmd == "quick" || cmd == "deep" || cmd == "research" || cmd == "recent" || cmd == "reliable" {
 			h.handleQuery(ctx, msg)
 			return
 		}
@@ -56,6 +57,12 @@ func (h *Handler) handleCommand(ctx context.Context, msg *tgbotapi.Message) {
 		h.handleRemove(ctx, msg)
 	case "trust":
 		h.handleTrust(ctx, msg)
+	case "block":
+		h.handleBlock(ctx, msg)
+	case "unblock":
+		h.handleUnblock(ctx, msg)
+	case "usage":
+		h.handleUsage(ctx, msg)
 	default:
 		h.bot.Send(msg.Chat.ID, "Неизвестная команда. Используйте /help для справки.")
 	}
@@ -90,22 +97,31 @@ func (h *Handler) handleHelp(ctx context.Context, msg *tgbotapi.Message) {
 /start - Регистрация и импорт источников
 /help - Показать эту справку
 /sources - Список ваших источников
-/add URL - Добавить источник
+/add URL - Добавить источник (URL с путем - только этот раздел сайта)
 /remove N - Удалить источник по номеру
 /trust N уровень - Изменить уровень доверия
+/block домен - Не использовать домен и его поддомены в ответах
+/block - Список заблокированных доменов
+/unblock домен - Снять блокировку
+/usage - Расход токенов и стоимость запросов
 
 <b>Режимы поиска:</b>
 /quick вопрос - Быстрый поиск (1 запрос, без критика)
 /research вопрос - Стандартный поиск (3 запроса, с критиком)
 /deep вопрос - Глубокий анализ (5 запросов, с критиком)
+/recent вопрос - Только публикации за последний месяц
+/reliable вопрос - Только источники с уровнем доверия high
+
+Период можно указать и в самом вопросе: "за последнюю неделю", "за сутки", "в этом году".
 
 <b>Уровни доверия:</b>
-• high - высокий (приоритет в ответах)
+• high - высокий (выше в выдаче, приоритет в ответах)
 • medium - средний
 • low - низкий
 
 <b>Как использовать:</b>
 Просто отправьте ваш вопрос о финтехе, и я найду информацию из ваших доверенных источников.
+Чтобы уточнить ответ, ответьте на него реплаем - я учту предыдущий вопрос и источники.
 
 <b>Примеры:</b>
 • Обычный вопрос: "Какие тренды в финтехе в 2025 году?"
@@ -252,13 +268,85 @@ func (h *Handler) handleTrust(ctx context.Context, msg *tgbotapi.Message) {
 	h.bot.Send(msg.Chat.ID, fmt.Sprintf("Уровень доверия источника #%d изменен на %s.", num, level.String()))
 }
 
+func (h *Handler) handleBlock(ctx context.Context, msg *tgbotapi.Message) {
+	user, err := h.bot.userService.GetOrCreate(ctx, msg.From.ID, msg.From.UserName)
+	if err != nil {
+		h.bot.Send(msg.Chat.ID, "Произошла ошибка. Попробуйте позже.")
+		return
+	}
+
+	input := strings.TrimSpace(msg.CommandArguments())
+	if input == "" {
+		blocked, err := h.bot.sourceService.ListBlocked(ctx, user.ID)
+		if err != nil {
+			h.bot.logger.Error("failed to list blocked domains", zap.Error(err))
+			h.bot.Send(msg.Chat.ID, "Произошла ошибка. Попробуйте позже.")
+			return
+		}
+		h.bot.Send(msg.Chat.ID, FormatBlockedList(blocked))
+		return
+	}
+
+	d, err := h.bot.sourceService.Block(ctx, user.ID, input)
+	if err != nil {
+		h.bot.Send(msg.Chat.ID, mapErrorToMessage(err))
+		return
+	}
+
+	h.bot.Send(msg.Chat.ID, fmt.Sprintf("Домен %s и его поддомены больше не используются в ответах.", d))
+}
+
+func (h *Handler) handleUnblock(ctx context.Context, msg *tgbotapi.Message) {
+	user, err := h.bot.userService.GetOrCreate(ctx, msg.From.ID, msg.From.UserName)
+	if err != nil {
+		h.bot.Send(msg.Chat.ID, "Произошла ошибка. Попробуйте позже.")
+		return
+	}
+
+	input := strings.TrimSpace(msg.CommandArguments())
+	if input == "" {
+		h.bot.Send(msg.Chat.ID, "Укажите домен: /unblock example.com")
+		return
+	}
+
+	d, err := h.bot.sourceService.Unblock(ctx, user.ID, input)
+	if err != nil {
+		h.bot.Send(msg.Chat.ID, mapErrorToMessage(err))
+		return
+	}
+
+	h.bot.Send(msg.Chat.ID, fmt.Sprintf("Домен %s разблокирован.", d))
+}
+
+func (h *Handler) handleUsage(ctx context.Context, msg *tgbotapi.Message) {
+	if h.bot.usageService == nil {
+		h.bot.Send(msg.Chat.ID, "Статистика расхода недоступна.")
+		return
+	}
+
+	user, err := h.bot.userService.GetOrCreate(ctx, msg.From.ID, msg.From.UserName)
+	if err != nil {
+		h.bot.Send(msg.Chat.ID, "Произошла ошибка. Попробуйте позже.")
+		return
+	}
+
+	report, err := h.bot.usageService.Report(ctx, user.ID)
+	if err != nil {
+		h.bot.logger.Error("failed to load usage report", zap.Error(err))
+		h.bot.Send(msg.Chat.ID, "Произошла ошибка. Попробуйте позже.")
+		return
+	}
+
+	h.bot.Send(msg.Chat.ID, FormatUsageReport(report))
+}
+
 func (h *Handler) handleQuery(ctx context.Context, msg *tgbotapi.Message) {
 	question, strategy := ParseQueryCommand(msg.Text, DefaultStrategy())
 
-	h.processQueryWithStrategy(ctx, msg, question, strategy)
+	h.processQueryWithStrategy(ctx, msg, question, strategy, IsReliableCommand(msg.Text))
 }
 
-func (h *Handler) processQueryWithStrategy(ctx context.Context, msg *tgbotapi.Message, question string, strategy domain.Strategy) {
+func (h *Handler) processQueryWithStrategy(ctx context.Context, msg *tgbotapi.Message, question string, strategy domain.Strategy, onlyReliable bool) {
 	if !h.bot.rateLimiter.Allow(msg.From.ID) {
 		resetTime := h.bot.rateLimiter.ResetTime(msg.From.ID)
 		h.bot.logger.Warn("rate limit exceeded",
@@ -278,10 +366,20 @@ func (h *Handler) processQueryWithStrategy(ctx context.Context, msg *tgbotapi.Me
 
 	h.bot.SendTyping(msg.Chat.ID)
 
+	// заглушку правим по мере генерации финального ответа
+	placeholderID, err := h.bot.SendMessage(msg.Chat.ID, streamPlaceholder)
+	if err != nil {
+		h.bot.logger.Warn("failed to send placeholder", zap.Error(err))
+	}
+	live := newLiveMessage(h.bot, h.bot.logger, msg.Chat.ID, placeholderID)
+	ctx = llm.WithStreamSink(ctx, live.Update)
+
 	req := &domain.QueryRequest{
-		UserID:   user.ID,
-		Text:     question,
-		Strategy: strategy,
+		UserID:       user.ID,
+		Text:         question,
+		OnlyReliable: onlyReliable,
+		Strategy:     strategy,
+		History:      h.conversationThread(ctx, msg),
 	}
 
 	h.bot.logger.Info("processing query with strategy",
@@ -290,6 +388,7 @@ func (h *Handler) processQueryWithStrategy(ctx context.Context, msg *tgbotapi.Me
 		zap.Int("max_queries", strategy.MaxQueries),
 		zap.Int("max_results", strategy.MaxResults),
 		zap.Bool("use_critic", strategy.UseCritic),
+		zap.Bool("only_reliable", onlyReliable),
 	)
 
 	response, err := h.bot.queryService.Process(ctx, req)
@@ -298,7 +397,9 @@ func (h *Handler) processQueryWithStrategy(ctx context.Context, msg *tgbotapi.Me
 			zap.Error(err),
 			zap.Int64("user_id", user.ID),
 		)
-		h.bot.Send(msg.Chat.ID, mapErrorToMessage(err))
+		if !live.Finish(mapErrorToMessage(err)) {
+			h.bot.Send(msg.Chat.ID, mapErrorToMessage(err))
+		}
 		return
 	}
 
@@ -309,11 +410,65 @@ func (h *Handler) processQueryWithStrategy(ctx context.Context, msg *tgbotapi.Me
 	}
 
 	messages := SplitMessage(formattedResponse, 4096) // лимит телеграма
+	var messageIDs []int
+	if live.Finish(messages[0]) {
+		messageIDs = append(messageIDs, placeholderID)
+		messages = messages[1:]
+	}
 	for _, m := range messages {
-		if err := h.bot.Send(msg.Chat.ID, m); err != nil {
+		id, err := h.bot.SendMessage(msg.Chat.ID, m)
+		if err != nil {
 			h.bot.logger.Error("failed to send message", zap.Error(err))
+			continue
 		}
+		if id != 0 {
+			messageIDs = append(messageIDs, id)
+		}
+	}
+
+	h.rememberTurn(ctx, &domain.ConversationTurn{
+		ChatID:     msg.Chat.ID,
+		UserID:     user.ID,
+		ParentID:   lastTurnID(req.History),
+		MessageIDs: messageIDs,
+		Question:   question,
+		Answer:     response.Text,
+		Sources:    response.Sources,
+	})
+}
+
+// conversationThread - предыдущие ходы, если сообщение - реплай на ответ бота
+func (h *Handler) conversationThread(ctx context.Context, msg *tgbotapi.Message) []domain.ConversationTurn {
+	if msg.ReplyToMessage == nil || h.bot.conversationService == nil {
+		return nil
+	}
+
+	thread, err := h.bot.conversationService.Thread(ctx, msg.Chat.ID, msg.ReplyToMessage.MessageID)
+	if err != nil {
+		// без истории вопрос все равно можно ответить
+		h.bot.logger.Warn("failed to load conversation thread", zap.Error(err))
+		return nil
+	}
+	return thread
+}
+
+func (h *Handler) rememberTurn(ctx context.Context, turn *domain.ConversationTurn) {
+	if h.bot.conversationService == nil {
+		return
+	}
+	if err := h.bot.conversationService.Remember(ctx, turn); err != nil {
+		h.bot.logger.Warn("failed to save conversation turn",
+			zap.Error(err),
+			zap.Int64("chat_id", turn.ChatID),
+		)
+	}
+}
+
+func lastTurnID(history []domain.ConversationTurn) int64 {
+	if len(history) == 0 {
+		return 0
 	}
+	return history[len(history)-1].ID
 }
 
 func (h *Handler) formatStrategyIndicator(strategy domain.Strategy) string {
@@ -339,8 +494,18 @@ func mapErrorToMessage(err error) string {
 		return "Источник не найден."
 	case errors.Is(err, domain.ErrSourceLimitReached):
 		return "Достигнут лимит источников (100)."
+	case errors.Is(err, domain.ErrInvalidDomain):
+		return "Некорректный домен. Пример: /block example.com"
+	case errors.Is(err, domain.ErrDomainAlreadyBlocked):
+		return "Домен уже заблокирован."
+	case errors.Is(err, domain.ErrDomainNotBlocked):
+		return "Домен не заблокирован. Список: /block"
+	case errors.Is(err, domain.ErrBlocklistFull):
+		return "Достигнут лимит заблокированных доменов (100)."
 	case errors.Is(err, domain.ErrNoSources):
 		return "Нет источников для запроса. Добавьте источники с помощью /add."
+	case errors.Is(err, domain.ErrNoTrustedSources):
+		return "Нет источников с уровнем доверия high. Повысьте доверие командой /trust N high."
 	case errors.Is(err, domain.ErrNoResults):
 		return "Не найдено результатов по вашему запросу."
 	case errors.Is(err, domain.ErrEmptyQuery):
@@ -22,7 +22,12 @@ func TestMapErrorToMessage(t *testing.T) {
 		{"duplicate", domain.ErrDuplicateSource, "Источник уже добавлен."},
 		{"not found", domain.ErrSourceNotFound, "Источник не найден."},
 		{"limit", domain.ErrSourceLimitReached, "Достигнут лимит источников (100)."},
+		{"invalid domain", domain.ErrInvalidDomain, "Некорректный домен. Пример: /block example.com"},
+		{"already blocked", domain.ErrDomainAlreadyBlocked, "Домен уже заблокирован."},
+		{"not blocked", domain.ErrDomainNotBlocked, "Домен не заблокирован. Список: /block"},
+		{"blocklist full", domain.ErrBlocklistFull, "Достигнут лимит заблокированных доменов (100)."},
 		{"no sources", domain.ErrNoSources, "Нет источников для запроса. Добавьте источники с помощью /add."},
+		{"no trusted sources", domain.ErrNoTrustedSources, "Нет источников с уровнем доверия high. Повысьте доверие командой /trust N high."},
 		{"no results", domain.ErrNoResults, "Не найдено результатов по вашему запросу."},
 		{"empty", domain.ErrEmptyQuery, "Пустой запрос. Введите ваш вопрос."},
 		{"too long", domain.ErrQueryTooLong, "Запрос слишком длинный. Максимум 1000 символов."},
@@ -57,7 +62,12 @@ func TestMapErrorToMessage_AllDomainErrors(t *testing.T) {
 		domain.ErrDuplicateSource,
 		domain.ErrSourceNotFound,
 		domain.ErrSourceLimitReached,
+		domain.ErrInvalidDomain,
+		domain.ErrDomainAlreadyBlocked,
+		domain.ErrDomainNotBlocked,
+		domain.ErrBlocklistFull,
 		domain.ErrNoSources,
+		domain.ErrNoTrustedSources,
 		domain.ErrNoResults,
 		domain.ErrEmptyQuery,
 		domain.ErrQueryTooLong,
@@ -192,6 +202,26 @@ func TestHandler_DeepCommand(t *testing.T) {
 	}
 }
 
+func TestHandler_ReliableCommand(t *testing.T) {
+	querySvc := &TrackingQueryService{}
+	bot := createTestBot(querySvc)
+	handler := NewHandler(bot)
+
+	handler.HandleMessage(context.Background(), createTestMessage(123, "/reliable регулирование BNPL"))
+
+	if querySvc.CallCount != 1 || !querySvc.LastRequest.OnlyReliable {
+		t.Fatalf("CallCount = %d, OnlyReliable = %v; want one reliable request", querySvc.CallCount, querySvc.LastRequest.OnlyReliable)
+	}
+	if querySvc.LastRequest.Text != "регулирование BNPL" {
+		t.Errorf("Text = %q, want 'регулирование BNPL'", querySvc.LastRequest.Text)
+	}
+
+	handler.HandleMessage(context.Background(), createTestMessage(123, "регулирование BNPL"))
+	if querySvc.LastRequest.OnlyReliable {
+		t.Error("plain question should not be restricted to reliable sources")
+	}
+}
+
 func TestHandler_ResearchCommand(t *testing.T) {
 	querySvc := &TrackingQueryService{
 		Response: &domain.QueryResponse{
@@ -257,3 +287,68 @@ func TestHandler_EmptyQuery(t *testing.T) {
 		t.Errorf("Strategy = %v, want Quick", querySvc.LastStrategy.Type)
 	}
 }
+
+type fakeConversationService struct {
+	thread     []domain.ConversationTurn
+	threadFor  int
+	remembered []domain.ConversationTurn
+}
+
+func (f *fakeConversationService) Remember(ctx context.Context, turn *domain.ConversationTurn) error {
+	f.remembered = append(f.remembered, *turn)
+	return nil
+}
+
+func (f *fakeConversationService) Thread(ctx context.Context, chatID int64, messageID int) ([]domain.ConversationTurn, error) {
+	f.threadFor = messageID
+	return f.thread, nil
+}
+
+func TestHandler_ReplyIsFollowUp(t *testing.T) {
+	querySvc := &TrackingQueryService{
+		Response: &domain.QueryResponse{Text: "В Европе медленнее"},
+	}
+	conversations := &fakeConversationService{
+		thread: []domain.ConversationTurn{{ID: 5, Question: "Рынок BNPL в США?", Answer: "Растет"}},
+	}
+	bot := createTestBot(querySvc)
+	bot.conversationService = conversations
+	handler := NewHandler(bot)
+
+	msg := createTestMessage(123, "А в Европе?")
+	msg.ReplyToMessage = &tgbotapi.Message{MessageID: 42}
+	handler.HandleMessage(context.Background(), msg)
+
+	if conversations.threadFor != 42 {
+		t.Errorf("Thread() looked up message %d, want 42", conversations.threadFor)
+	}
+	if len(querySvc.LastRequest.History) != 1 || querySvc.LastRequest.History[0].ID != 5 {
+		t.Errorf("History = %+v, want the replied-to turn", querySvc.LastRequest.History)
+	}
+	if len(conversations.remembered) != 1 {
+		t.Fatalf("remembered %d turns, want 1", len(conversations.remembered))
+	}
+	turn := conversations.remembered[0]
+	if turn.ParentID != 5 || turn.Question != "А в Европе?" || turn.Answer != "В Европе медленнее" {
+		t.Errorf("remembered turn = %+v, want follow-up linked to turn 5", turn)
+	}
+}
+
+func TestHandler_PlainMessageHasNoHistory(t *testing.T) {
+	querySvc := &TrackingQueryService{}
+	conversations := &fakeConversationService{
+		thread: []domain.ConversationTurn{{ID: 5}},
+	}
+	bot := createTestBot(querySvc)
+	bot.conversationService = conversations
+	handler := NewHandler(bot)
+
+	handler.HandleMessage(context.Background(), createTestMessage(123, "новый вопрос"))
+
+	if querySvc.LastRequest.History != nil {
+		t.Errorf("History = %+v, want none for a message that is not a reply", querySvc.LastRequest.History)
+	}
+	if len(conversations.remembered) != 1 || conversations.remembered[0].ParentID != 0 {
+		t.Errorf("remembered = %+v, want a new thread", conversations.remembered)
+	}
+}
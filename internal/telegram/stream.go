@@ -0,0 +1,102 @@
+package telegram
+
+import (
+	"html"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// телеграм режет частые правки одного сообщения, чаще раза в секунду не стоит
+	streamEditInterval = 1500 * time.Millisecond
+	// запас под курсор и HTML-экранирование до лимита в 4096
+	streamPreviewLimit = 3500
+	streamCursor       = " ▌"
+	streamPlaceholder  = "<i>Ищу информацию...</i>"
+)
+
+// messageEditor - часть Bot, нужная liveMessage (в тестах подменяется)
+type messageEditor interface {
+	EditMessage(chatID int64, messageID int, text string) error
+}
+
+// liveMessage - сообщение-заглушка, которое переписывается по мере генерации ответа
+type liveMessage struct {
+	editor    messageEditor
+	logger    *zap.Logger
+	chatID    int64
+	messageID int
+	interval  time.Duration
+	now       func() time.Time
+
+	mu       sync.Mutex
+	lastEdit time.Time
+	lastText string
+	finished bool
+}
+
+func newLiveMessage(editor messageEditor, logger *zap.Logger, chatID int64, messageID int) *liveMessage {
+	return &liveMessage{
+		editor:    editor,
+		logger:    logger,
+		chatID:    chatID,
+		messageID: messageID,
+		interval:  streamEditInterval,
+		now:       time.Now,
+	}
+}
+
+// Update показывает промежуточный текст, не чаще interval.
+// Пропущенные обновления не страшны - следующее принесет весь накопленный текст
+func (m *liveMessage) Update(text string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.finished || m.messageID == 0 {
+		return
+	}
+	now := m.now()
+	if !m.lastEdit.IsZero() && now.Sub(m.lastEdit) < m.interval {
+		return
+	}
+
+	preview := html.EscapeString(truncateRunes(text, streamPreviewLimit)) + streamCursor
+	if preview == m.lastText {
+		return
+	}
+
+	m.lastEdit = now
+	if err := m.editor.EditMessage(m.chatID, m.messageID, preview); err != nil {
+		m.logger.Debug("failed to update streaming message", zap.Error(err))
+		return
+	}
+	m.lastText = preview
+}
+
+// Finish заменяет заглушку финальным текстом и останавливает обновления.
+// false - править не удалось, текст надо отправить отдельным сообщением
+func (m *liveMessage) Finish(text string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.finished = true
+	if m.messageID == 0 {
+		return false
+	}
+	if err := m.editor.EditMessage(m.chatID, m.messageID, text); err != nil {
+		m.logger.Warn("failed to finalize streaming message", zap.Error(err))
+		return false
+	}
+	return true
+}
+
+func truncateRunes(s string, limit int) string {
+	if utf8.RuneCountInString(s) <= limit {
+		return s
+	}
+	runes := []rune(s)
+	return string(runes[:limit]) + "..."
+}
@@ -0,0 +1,89 @@
+package telegram
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type fakeEditor struct {
+	edits []string
+	err   error
+}
+
+func (e *fakeEditor) EditMessage(chatID int64, messageID int, text string) error {
+	if e.err != nil {
+		return e.err
+	}
+	e.edits = append(e.edits, text)
+	return nil
+}
+
+func TestLiveMessage_ThrottlesUpdates(t *testing.T) {
+	editor := &fakeEditor{}
+	now := time.Unix(0, 0)
+	m := newLiveMessage(editor, zap.NewNop(), 1, 42)
+	m.now = func() time.Time { return now }
+
+	m.Update("a")
+	m.Update("a b") // слишком рано, пропускаем
+	now = now.Add(streamEditInterval)
+	m.Update("a b c")
+
+	if len(editor.edits) != 2 {
+		t.Fatalf("edits = %d, want 2: %q", len(editor.edits), editor.edits)
+	}
+	if editor.edits[1] != "a b c"+streamCursor {
+		t.Errorf("second edit = %q, want accumulated text with cursor", editor.edits[1])
+	}
+
+	if !m.Finish("<b>final</b>") {
+		t.Fatal("Finish() = false, want true")
+	}
+	now = now.Add(time.Hour)
+	m.Update("late chunk")
+	if last := editor.edits[len(editor.edits)-1]; last != "<b>final</b>" {
+		t.Errorf("last edit = %q, updates after Finish must be ignored", last)
+	}
+}
+
+func TestLiveMessage_EscapesAndTruncates(t *testing.T) {
+	editor := &fakeEditor{}
+	m := newLiveMessage(editor, zap.NewNop(), 1, 42)
+
+	m.Update("<script>" + strings.Repeat("я", streamPreviewLimit))
+
+	if len(editor.edits) != 1 {
+		t.Fatalf("edits = %d, want 1", len(editor.edits))
+	}
+	if strings.Contains(editor.edits[0], "<script>") {
+		t.Error("streamed text must be HTML-escaped")
+	}
+	if !strings.HasSuffix(editor.edits[0], "..."+streamCursor) {
+		t.Error("long preview must be truncated")
+	}
+}
+
+func TestLiveMessage_NoPlaceholder(t *testing.T) {
+	editor := &fakeEditor{}
+	m := newLiveMessage(editor, zap.NewNop(), 1, 0)
+
+	m.Update("text")
+	if len(editor.edits) != 0 {
+		t.Error("no edits expected without placeholder message")
+	}
+	if m.Finish("final") {
+		t.Error("Finish() must report failure without placeholder message")
+	}
+}
+
+func TestLiveMessage_FinishEditError(t *testing.T) {
+	m := newLiveMessage(&fakeEditor{err: errors.New("message to edit not found")}, zap.NewNop(), 1, 42)
+
+	if m.Finish("final") {
+		t.Error("Finish() = true, want false when edit fails")
+	}
+}
@@ -0,0 +1,13 @@
+-- Ответы бота в чатах, чтобы реплай на ответ продолжал диалог
+CREATE TABLE conversation_turns (
+    id BIGSERIAL PRIMARY KEY,
+    chat_id BIGINT NOT NULL,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    parent_id BIGINT REFERENCES conversation_turns(id) ON DELETE SET NULL,
+    message_ids BIGINT[] NOT NULL,
+    question TEXT NOT NULL,
+    answer TEXT NOT NULL,
+    sources JSONB NOT NULL DEFAULT '[]',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX idx_conversation_turns_chat ON conversation_turns(chat_id, id DESC);
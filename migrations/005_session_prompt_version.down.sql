@@ -0,0 +1 @@
+ALTER TABLE research_sessions DROP COLUMN IF EXISTS prompt_version;
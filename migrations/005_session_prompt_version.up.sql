@@ -0,0 +1,2 @@
+-- Версия шаблонов промптов, с которой прошла сессия; у старых сессий пустая
+ALTER TABLE research_sessions ADD COLUMN prompt_version TEXT NOT NULL DEFAULT '';
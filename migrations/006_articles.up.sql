@@ -0,0 +1,31 @@
+-- RSS/Atom ленты источников, общие для всех пользователей
+CREATE TABLE feeds (
+    id BIGSERIAL PRIMARY KEY,
+    url TEXT NOT NULL UNIQUE,
+    domain TEXT NOT NULL,
+    etag TEXT NOT NULL DEFAULT '',
+    last_modified TEXT NOT NULL DEFAULT '',
+    polled_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Статьи из лент с полным текстом. Источники англоязычные и русскоязычные,
+-- поэтому индекс по обоим словарям
+CREATE TABLE articles (
+    id BIGSERIAL PRIMARY KEY,
+    feed_id BIGINT NOT NULL REFERENCES feeds(id) ON DELETE CASCADE,
+    domain TEXT NOT NULL,
+    url TEXT NOT NULL UNIQUE,
+    title TEXT NOT NULL,
+    content TEXT NOT NULL DEFAULT '',
+    published_at TIMESTAMPTZ,
+    fetched_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    search_vector tsvector GENERATED ALWAYS AS (
+        setweight(to_tsvector('english', title), 'A') ||
+        setweight(to_tsvector('russian', title), 'A') ||
+        setweight(to_tsvector('english', content), 'B') ||
+        setweight(to_tsvector('russian', content), 'B')
+    ) STORED
+);
+CREATE INDEX idx_articles_search ON articles USING gin(search_vector);
+CREATE INDEX idx_articles_domain_published ON articles(domain, published_at DESC);
@@ -0,0 +1,9 @@
+-- Домены, которые пользователь запретил цитировать: исключаются из поиска
+-- и контекста world model вместе с поддоменами
+CREATE TABLE blocked_domains (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    domain TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (user_id, domain)
+);
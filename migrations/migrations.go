@@ -0,0 +1,7 @@
+// Package migrations вшивает SQL-миграции в бинарник, применяет их postgres.Migrator
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
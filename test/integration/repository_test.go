@@ -2,6 +2,7 @@ package integration
 
 import (
 	"context"
+	"errors"
 	"os"
 	"testing"
 	"time"
@@ -9,9 +10,11 @@ import (
 	"github.com/testcontainers/testcontainers-go"
 	"github.com/testcontainers/testcontainers-go/modules/postgres"
 	"github.com/testcontainers/testcontainers-go/wait"
+	"go.uber.org/zap"
 
 	"github.com/kitbuilder587/fintech-bot/internal/domain"
 	pgRepo "github.com/kitbuilder587/fintech-bot/internal/repository/postgres"
+	"github.com/kitbuilder587/fintech-bot/migrations"
 )
 
 var testDB *pgRepo.DB
@@ -48,26 +51,13 @@ func TestMain(m *testing.M) {
 		panic(err)
 	}
 
-	_, err = testDB.Pool.Exec(ctx, `
-        CREATE TABLE IF NOT EXISTS users (
-            id BIGINT PRIMARY KEY,
-            username TEXT,
-            created_at TIMESTAMPTZ DEFAULT NOW()
-        );
-        CREATE TABLE IF NOT EXISTS sources (
-            id BIGSERIAL PRIMARY KEY,
-            user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
-            url TEXT NOT NULL,
-            name TEXT NOT NULL,
-            trust_level TEXT NOT NULL DEFAULT 'medium',
-            is_user_added BOOLEAN NOT NULL DEFAULT false,
-            created_at TIMESTAMPTZ DEFAULT NOW(),
-            UNIQUE(user_id, url)
-        );
-    `)
+	migrator, err := pgRepo.NewMigrator(testDB, migrations.FS, zap.NewNop())
 	if err != nil {
 		panic(err)
 	}
+	if _, err := migrator.Up(ctx); err != nil {
+		panic(err)
+	}
 
 	code := m.Run()
 
@@ -258,3 +248,252 @@ func TestSourceRepository_GetDomainsByUserID_Integration(t *testing.T) {
 		sourceRepo.Delete(ctx, user.ID, s.ID)
 	}
 }
+
+func TestSourceRepository_Blocklist_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	ctx := context.Background()
+	userRepo := pgRepo.NewUserRepo(testDB)
+	sourceRepo := pgRepo.NewSourceRepo(testDB)
+
+	user, _ := userRepo.GetOrCreate(ctx, 99998, "blocktest")
+	existing, _ := sourceRepo.ListBlockedDomains(ctx, user.ID)
+	for _, d := range existing {
+		sourceRepo.UnblockDomain(ctx, user.ID, d)
+	}
+
+	for _, d := range []string{"reddit.com", "forum.example.com"} {
+		if err := sourceRepo.BlockDomain(ctx, user.ID, d); err != nil {
+			t.Fatalf("BlockDomain(%s) error = %v", d, err)
+		}
+	}
+	if err := sourceRepo.BlockDomain(ctx, user.ID, "reddit.com"); !errors.Is(err, domain.ErrDomainAlreadyBlocked) {
+		t.Errorf("BlockDomain() duplicate error = %v, want ErrDomainAlreadyBlocked", err)
+	}
+
+	blocked, err := sourceRepo.ListBlockedDomains(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("ListBlockedDomains() error = %v", err)
+	}
+	if len(blocked) != 2 || blocked[0] != "forum.example.com" || blocked[1] != "reddit.com" {
+		t.Errorf("ListBlockedDomains() = %v, want sorted blocklist", blocked)
+	}
+
+	if err := sourceRepo.UnblockDomain(ctx, user.ID, "reddit.com"); err != nil {
+		t.Fatalf("UnblockDomain() error = %v", err)
+	}
+	if err := sourceRepo.UnblockDomain(ctx, user.ID, "reddit.com"); !errors.Is(err, domain.ErrDomainNotBlocked) {
+		t.Errorf("UnblockDomain() twice error = %v, want ErrDomainNotBlocked", err)
+	}
+	sourceRepo.UnblockDomain(ctx, user.ID, "forum.example.com")
+}
+
+func TestMigrator_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	ctx := context.Background()
+	migrator, err := pgRepo.NewMigrator(testDB, migrations.FS, zap.NewNop())
+	if err != nil {
+		t.Fatalf("NewMigrator() error = %v", err)
+	}
+
+	status, err := migrator.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status() error = %v", err)
+	}
+	if len(status.Pending) != 0 || status.Dirty {
+		t.Fatalf("Status() after Up = %+v, want all applied and clean", status)
+	}
+	latest := status.Version
+
+	applied, err := migrator.Up(ctx)
+	if err != nil {
+		t.Fatalf("Up() error = %v", err)
+	}
+	if applied != 0 {
+		t.Errorf("Up() applied %d migrations on up-to-date schema, want 0", applied)
+	}
+
+	if err := migrator.Down(ctx); err != nil {
+		t.Fatalf("Down() error = %v", err)
+	}
+	status, err = migrator.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status() error = %v", err)
+	}
+	if status.Version >= latest || len(status.Pending) != 1 {
+		t.Errorf("Status() after Down = version %d, %d pending; want one step back", status.Version, len(status.Pending))
+	}
+
+	applied, err = migrator.Up(ctx)
+	if err != nil {
+		t.Fatalf("Up() error = %v", err)
+	}
+	if applied != 1 {
+		t.Errorf("Up() applied %d migrations, want 1", applied)
+	}
+}
+
+func TestUsageRepository_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	ctx := context.Background()
+	if _, err := pgRepo.NewUserRepo(testDB).GetOrCreate(ctx, 54321, "usageuser"); err != nil {
+		t.Fatalf("GetOrCreate() error = %v", err)
+	}
+	repo := pgRepo.NewUsageRepo(testDB)
+
+	now := time.Now()
+	err := repo.SaveUsage(ctx, []domain.UsageRecord{
+		{UserID: 54321, Provider: "openrouter", Model: "m", Stage: "expand", PromptTokens: 100, CompletionTokens: 10, CostUSD: 0.001, CreatedAt: now},
+		{UserID: 54321, Provider: "openrouter", Model: "m", Stage: "synthesize", PromptTokens: 1000, CompletionTokens: 500, CostUSD: 0.01, CreatedAt: now},
+		{UserID: 54321, Provider: "openrouter", Model: "m", Stage: "synthesize", PromptTokens: 10, CompletionTokens: 5, CostUSD: 0.0001, CreatedAt: now.Add(-48 * time.Hour)},
+	})
+	if err != nil {
+		t.Fatalf("SaveUsage() error = %v", err)
+	}
+
+	totals, err := repo.GetUsageByStage(ctx, 54321, now.Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("GetUsageByStage() error = %v", err)
+	}
+	if len(totals) != 2 {
+		t.Fatalf("GetUsageByStage() returned %d stages, want 2", len(totals))
+	}
+	if totals[0].Stage != "synthesize" || totals[0].Calls != 1 || totals[0].CompletionTokens != 500 {
+		t.Errorf("most expensive stage = %+v, want single synthesize call from the last hour", totals[0])
+	}
+}
+
+func TestConversationRepository_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	ctx := context.Background()
+	user, err := pgRepo.NewUserRepo(testDB).GetOrCreate(ctx, 65432, "conversationuser")
+	if err != nil {
+		t.Fatalf("GetOrCreate() error = %v", err)
+	}
+	repo := pgRepo.NewConversationRepo(testDB)
+
+	first := &domain.ConversationTurn{
+		ChatID:     777,
+		UserID:     user.ID,
+		MessageIDs: []int{10, 11},
+		Question:   "Рынок BNPL в США?",
+		Answer:     "Рынок растет [S1]",
+		Sources: []domain.SourceRef{
+			{Marker: "[S1]", Title: "Report", URL: "https://example.com/bnpl", TrustLevel: domain.TrustHigh},
+		},
+	}
+	if err := repo.SaveTurn(ctx, first); err != nil {
+		t.Fatalf("SaveTurn() error = %v", err)
+	}
+	followUp := &domain.ConversationTurn{
+		ChatID:     777,
+		UserID:     user.ID,
+		ParentID:   first.ID,
+		MessageIDs: []int{12},
+		Question:   "А в Европе?",
+		Answer:     "В Европе медленнее",
+	}
+	if err := repo.SaveTurn(ctx, followUp); err != nil {
+		t.Fatalf("SaveTurn() error = %v", err)
+	}
+
+	got, err := repo.GetTurnByMessage(ctx, 777, 11)
+	if err != nil {
+		t.Fatalf("GetTurnByMessage() error = %v", err)
+	}
+	if got.ID != first.ID || len(got.Sources) != 1 || got.Sources[0].TrustLevel != domain.TrustHigh {
+		t.Errorf("GetTurnByMessage() = %+v, want first turn with its sources", got)
+	}
+
+	got, err = repo.GetTurn(ctx, followUp.ID)
+	if err != nil {
+		t.Fatalf("GetTurn() error = %v", err)
+	}
+	if got.ParentID != first.ID || len(got.MessageIDs) != 1 || got.MessageIDs[0] != 12 {
+		t.Errorf("GetTurn() = %+v, want follow-up linked to first turn", got)
+	}
+
+	if _, err := repo.GetTurnByMessage(ctx, 778, 11); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("GetTurnByMessage() in other chat error = %v, want %v", err, domain.ErrNotFound)
+	}
+
+	// сверх лимита старые ходы удаляются, ссылка на родителя обнуляется
+	for i := 0; i < pgRepo.KeepTurnsPerChat; i++ {
+		if err := repo.SaveTurn(ctx, &domain.ConversationTurn{ChatID: 777, UserID: user.ID, MessageIDs: []int{100 + i}, Question: "q", Answer: "a"}); err != nil {
+			t.Fatalf("SaveTurn() error = %v", err)
+		}
+	}
+	if _, err := repo.GetTurn(ctx, first.ID); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("GetTurn() for pruned turn error = %v, want %v", err, domain.ErrNotFound)
+	}
+}
+
+func TestArticleRepository_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	ctx := context.Background()
+	repo := pgRepo.NewArticleRepo(testDB)
+
+	feed := &domain.Feed{URL: "https://mckinsey.com/feed", Domain: "mckinsey.com"}
+	if err := repo.UpsertFeed(ctx, feed); err != nil {
+		t.Fatalf("UpsertFeed() error = %v", err)
+	}
+	feed.ETag = `"v1"`
+	feed.PolledAt = time.Now()
+	if err := repo.UpdateFeedState(ctx, feed); err != nil {
+		t.Fatalf("UpdateFeedState() error = %v", err)
+	}
+	again := &domain.Feed{URL: "https://mckinsey.com/feed", Domain: "mckinsey.com"}
+	if err := repo.UpsertFeed(ctx, again); err != nil {
+		t.Fatalf("UpsertFeed() again error = %v", err)
+	}
+	if again.ID != feed.ID || again.ETag != `"v1"` {
+		t.Errorf("UpsertFeed() of known feed = %+v, want stored state", again)
+	}
+
+	articles := []domain.Article{
+		{FeedID: feed.ID, Domain: "mckinsey.com", URL: "https://mckinsey.com/bnpl", Title: "Buy now, pay later in Europe", Content: "BNPL lenders are growing", PublishedAt: time.Now()},
+		{FeedID: feed.ID, Domain: "mckinsey.com", URL: "https://mckinsey.com/cbdc", Title: "Цифровой рубль", Content: "Банк России тестирует цифровой рубль"},
+	}
+	saved, err := repo.SaveArticles(ctx, articles)
+	if err != nil || saved != 2 {
+		t.Fatalf("SaveArticles() = %d, %v; want 2 new", saved, err)
+	}
+	if saved, _ := repo.SaveArticles(ctx, articles[:1]); saved != 0 {
+		t.Errorf("SaveArticles() of known URL saved %d, want 0", saved)
+	}
+
+	hits, err := repo.SearchArticles(ctx, domain.ArticleQuery{Text: "lender growth", Domains: []string{"mckinsey.com"}, Limit: 5})
+	if err != nil {
+		t.Fatalf("SearchArticles() error = %v", err)
+	}
+	if len(hits) != 1 || hits[0].URL != "https://mckinsey.com/bnpl" {
+		t.Errorf("SearchArticles(english) = %+v, want stemmed match on BNPL article", hits)
+	}
+
+	hits, err = repo.SearchArticles(ctx, domain.ArticleQuery{Text: "цифровые рубли", Limit: 5})
+	if err != nil {
+		t.Fatalf("SearchArticles() error = %v", err)
+	}
+	if len(hits) != 1 || hits[0].URL != "https://mckinsey.com/cbdc" {
+		t.Errorf("SearchArticles(russian) = %+v, want stemmed match on CBDC article", hits)
+	}
+
+	hits, _ = repo.SearchArticles(ctx, domain.ArticleQuery{Text: "lender", Since: time.Now().Add(time.Hour), Limit: 5})
+	if len(hits) != 0 {
+		t.Errorf("SearchArticles(since future) = %+v, want none", hits)
+	}
+}